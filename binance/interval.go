@@ -0,0 +1,37 @@
+package binance
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// IntervalDuration 将K线间隔（如 1m, 15m, 4h, 1d）转换为时长
+// 周线和月线的边界不是固定时长，不支持
+func IntervalDuration(interval string) (time.Duration, error) {
+	if len(interval) < 2 {
+		return 0, fmt.Errorf("无效的K线间隔: %q", interval)
+	}
+
+	n, err := strconv.Atoi(interval[:len(interval)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("无效的K线间隔: %q", interval)
+	}
+
+	switch interval[len(interval)-1] {
+	case 'm':
+		return time.Duration(n) * time.Minute, nil
+	case 'h':
+		return time.Duration(n) * time.Hour, nil
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return 0, fmt.Errorf("不支持的K线间隔: %q", interval)
+}
+
+// NextBarClose 返回 t 之后的下一个K线收盘时刻
+// 币安K线按 UTC 对齐，例如日线在北京时间 08:00 收盘，4小时线在北京时间 00/04/08/12/16/20 点收盘
+func NextBarClose(t time.Time, d time.Duration) time.Time {
+	step := d.Milliseconds()
+	return time.UnixMilli((t.UnixMilli()/step + 1) * step)
+}
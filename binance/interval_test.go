@@ -0,0 +1,60 @@
+package binance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIntervalDuration(t *testing.T) {
+	tests := []struct {
+		interval string
+		want     time.Duration
+		ok       bool
+	}{
+		{"1m", time.Minute, true},
+		{"15m", 15 * time.Minute, true},
+		{"4h", 4 * time.Hour, true},
+		{"1d", 24 * time.Hour, true},
+		{"1w", 0, false},
+		{"0m", 0, false},
+		{"m", 0, false},
+		{"xh", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := IntervalDuration(tt.interval)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("IntervalDuration(%q) = %v, %v，应为 %v", tt.interval, got, err, tt.want)
+		}
+	}
+}
+
+func TestNextBarClose(t *testing.T) {
+	utc := func(day, hour, minute, second int) time.Time {
+		return time.Date(2024, 3, day, hour, minute, second, 0, time.UTC)
+	}
+	beijing := time.FixedZone("Beijing", 8*3600)
+
+	tests := []struct {
+		name string
+		t    time.Time
+		d    time.Duration
+		want time.Time
+	}{
+		{"5分钟线", utc(1, 10, 2, 30), 5 * time.Minute, utc(1, 10, 5, 0)},
+		// 正好在收盘时刻，返回下一根的收盘时刻
+		{"收盘时刻", utc(1, 10, 5, 0), 5 * time.Minute, utc(1, 10, 10, 0)},
+		{"收盘前1毫秒", utc(1, 10, 5, 0).Add(-time.Millisecond), 5 * time.Minute, utc(1, 10, 5, 0)},
+		{"4小时线", utc(1, 13, 0, 0), 4 * time.Hour, utc(1, 16, 0, 0)},
+		// 北京时间 3 月 1 日 07:59 为 UTC 2 月 29 日 23:59，日线在北京时间 08:00 收盘
+		{"北京时间日线", time.Date(2024, 3, 1, 7, 59, 0, 0, beijing), 24 * time.Hour, utc(1, 0, 0, 0)},
+		{"北京时间日线收盘后", time.Date(2024, 3, 1, 8, 0, 1, 0, beijing), 24 * time.Hour, utc(2, 0, 0, 0)},
+		{"跨月", time.Date(2024, 2, 29, 23, 59, 59, 0, time.UTC), time.Hour, utc(1, 0, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NextBarClose(tt.t, tt.d); !got.Equal(tt.want) {
+				t.Errorf("NextBarClose(%v, %v) = %v，应为 %v", tt.t, tt.d, got.UTC(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"binance-kline/indicators"
+)
+
+// BaseURL 币安 REST API 地址（测试时可替换为本地服务器地址）
+var BaseURL = "https://api.binance.com"
+
+// Kline 币安K线原始数据（价格和数量保持API返回的字符串格式）
+type Kline struct {
+	OpenTime                 int64
+	Open                     string
+	High                     string
+	Low                      string
+	Close                    string
+	Volume                   string
+	CloseTime                int64
+	QuoteAssetVolume         string
+	NumberOfTrades           int
+	TakerBuyBaseAssetVolume  string
+	TakerBuyQuoteAssetVolume string
+	Ignore                   string
+}
+
+// GetKlines 获取K线数据，单次最多1000条；startTime/endTime/limit 为0时不传该参数
+func GetKlines(symbol string, interval string, startTime, endTime int64, limit int) ([]Kline, error) {
+	url := fmt.Sprintf("%s/api/v3/klines?symbol=%s&interval=%s", BaseURL, symbol, interval)
+
+	if startTime > 0 {
+		url += fmt.Sprintf("&startTime=%d", startTime)
+	}
+	if endTime > 0 {
+		url += fmt.Sprintf("&endTime=%d", endTime)
+	}
+	if limit > 0 {
+		url += fmt.Sprintf("&limit=%d", limit)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API 返回错误: %s, 状态码: %d", string(body), resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	var rawKlines [][]interface{}
+	if err := json.Unmarshal(body, &rawKlines); err != nil {
+		return nil, fmt.Errorf("解析 JSON 失败: %w", err)
+	}
+
+	klines := make([]Kline, len(rawKlines))
+	for i, raw := range rawKlines {
+		klines[i] = Kline{
+			OpenTime:                 int64(raw[0].(float64)),
+			Open:                     raw[1].(string),
+			High:                     raw[2].(string),
+			Low:                      raw[3].(string),
+			Close:                    raw[4].(string),
+			Volume:                   raw[5].(string),
+			CloseTime:                int64(raw[6].(float64)),
+			QuoteAssetVolume:         raw[7].(string),
+			NumberOfTrades:           int(raw[8].(float64)),
+			TakerBuyBaseAssetVolume:  raw[9].(string),
+			TakerBuyQuoteAssetVolume: raw[10].(string),
+			Ignore:                   raw[11].(string),
+		}
+	}
+
+	return klines, nil
+}
+
+// ProgressFunc 批量获取的进度回调，参数同 fmt.Printf（不含换行）
+type ProgressFunc func(format string, args ...any)
+
+// GetKlinesBatch 批量获取K线数据，支持超过1000条的请求，按时间从新到旧排列。
+// progress 接收每批的进度，为 nil 时不输出（多个交易对并发获取时进度会交错）
+func GetKlinesBatch(symbol string, interval string, totalLimit int, progress ProgressFunc) ([]Kline, error) {
+	if totalLimit <= 1000 {
+		return GetKlines(symbol, interval, 0, 0, totalLimit)
+	}
+	report := func(format string, args ...any) {
+		if progress != nil {
+			progress(format, args...)
+		}
+	}
+
+	var allKlines []Kline
+	seen := make(map[int64]bool) // 用于去重
+	batchSize := 1000
+	batches := (totalLimit + batchSize - 1) / batchSize // 向上取整
+	var endTime int64                                   // 0表示当前时间
+
+	for i := 0; i < batches; i++ {
+		currentBatch := i + 1
+		report("正在获取第 %d/%d 批...", currentBatch, batches)
+
+		// 带重试的获取逻辑
+		var klines []Kline
+		var err error
+		maxRetries := 3
+
+		for retry := 0; retry < maxRetries; retry++ {
+			klines, err = GetKlines(symbol, interval, 0, endTime, batchSize)
+			if err == nil {
+				break
+			}
+
+			if retry < maxRetries-1 {
+				waitTime := time.Duration(retry+1) * time.Second
+				report("  请求失败，%v 后重试 (%d/%d)...", waitTime, retry+1, maxRetries)
+				time.Sleep(waitTime)
+			}
+		}
+
+		if err != nil {
+			return allKlines, fmt.Errorf("批次 %d 获取失败: %w", currentBatch, err)
+		}
+
+		if len(klines) == 0 {
+			report("  第 %d 批未获取到数据，停止", currentBatch)
+			break
+		}
+
+		// 去重并添加到结果集
+		addedCount := 0
+		for _, kline := range klines {
+			if !seen[kline.OpenTime] {
+				seen[kline.OpenTime] = true
+				allKlines = append(allKlines, kline)
+				addedCount++
+			}
+		}
+
+		report("  第 %d 批获取 %d 条，去重后添加 %d 条，累计 %d/%d 条",
+			currentBatch, len(klines), addedCount, len(allKlines), totalLimit)
+
+		// 如果已经获取足够数据，停止
+		if len(allKlines) >= totalLimit {
+			break
+		}
+
+		// 更新 endTime 为当前批次最早的时间 - 1ms（第一条是最早的）
+		if len(klines) > 0 {
+			earliestTime := klines[0].OpenTime
+			endTime = earliestTime - 1
+		}
+
+		// 添加延迟避免触发API限流（除了最后一批）
+		if i < batches-1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}
+
+	// 按时间从新到旧排序（最新的在前面）
+	sort.Slice(allKlines, func(i, j int) bool {
+		return allKlines[i].OpenTime > allKlines[j].OpenTime
+	})
+
+	// 截取到指定数量
+	if len(allKlines) > totalLimit {
+		allKlines = allKlines[:totalLimit]
+	}
+
+	return allKlines, nil
+}
+
+// ToKlineData 将K线转换为指标计算使用的浮点格式，输出按时间从旧到新排列
+func ToKlineData(klines []Kline) ([]indicators.KlineData, error) {
+	result := make([]indicators.KlineData, 0, len(klines))
+
+	for _, k := range klines {
+		var values [5]float64
+		for i, s := range []string{k.Open, k.High, k.Low, k.Close, k.Volume} {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("解析K线 %d 数值失败: %w", k.OpenTime, err)
+			}
+			values[i] = v
+		}
+
+		result = append(result, indicators.KlineData{
+			OpenTime:  k.OpenTime,
+			Open:      values[0],
+			High:      values[1],
+			Low:       values[2],
+			Close:     values[3],
+			Volume:    values[4],
+			CloseTime: k.CloseTime,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].OpenTime < result[j].OpenTime
+	})
+
+	return result, nil
+}
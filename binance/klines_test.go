@@ -0,0 +1,132 @@
+package binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// klinesServer 模拟 /api/v3/klines：返回 openTime <= endTime 的最近 limit 根1分钟K线（升序）。
+// overlap 为 true 时多返回 endTime 之后的 5 根，模拟分页边界重复
+func klinesServer(t *testing.T, total int, overlap bool) (*httptest.Server, *[]string) {
+	t.Helper()
+	var queries []string
+	const base int64 = 1700000000000
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/klines" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		queries = append(queries, r.URL.RawQuery)
+		if q.Get("symbol") == "BAD" {
+			http.Error(w, `{"code":-1121,"msg":"Invalid symbol."}`, http.StatusBadRequest)
+			return
+		}
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		end := total - 1
+		if s := q.Get("endTime"); s != "" {
+			endTime, _ := strconv.ParseInt(s, 10, 64)
+			end = int((endTime - base) / 60000)
+			if overlap {
+				end = min(end+5, total-1)
+			}
+		}
+		start := max(end-limit+1, 0)
+		rows := [][]any{}
+		for i := start; i <= end; i++ {
+			open := base + int64(i)*60000
+			price := fmt.Sprintf("%d.5", 100+i)
+			rows = append(rows, []any{open, price, price, price, price, "1.0", open + 59999, "100", 3, "0.5", "50", "0"})
+		}
+		json.NewEncoder(w).Encode(rows)
+	}))
+	t.Cleanup(srv.Close)
+
+	old := BaseURL
+	BaseURL = srv.URL
+	t.Cleanup(func() { BaseURL = old })
+	return srv, &queries
+}
+
+func TestGetKlines(t *testing.T) {
+	_, queries := klinesServer(t, 10, false)
+
+	klines, err := GetKlines("BTCUSDT", "1m", 0, 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(klines) != 3 || klines[2].Close != "109.5" || klines[2].NumberOfTrades != 3 {
+		t.Fatalf("klines = %+v", klines)
+	}
+	if want := "symbol=BTCUSDT&interval=1m&limit=3"; (*queries)[0] != want {
+		t.Errorf("请求参数 = %s，应为 %s", (*queries)[0], want)
+	}
+
+	if _, err := GetKlines("BAD", "1m", 0, 0, 3); err == nil {
+		t.Errorf("非 200 响应应返回错误")
+	}
+}
+
+func TestGetKlinesBatch(t *testing.T) {
+	for _, overlap := range []bool{false, true} {
+		t.Run(fmt.Sprintf("overlap=%v", overlap), func(t *testing.T) {
+			klinesServer(t, 3000, overlap)
+
+			var progress []string
+			klines, err := GetKlinesBatch("BTCUSDT", "1m", 2200, func(format string, args ...any) {
+				progress = append(progress, fmt.Sprintf(format, args...))
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(klines) != 2200 {
+				t.Fatalf("获取 %d 根K线，应为 2200", len(klines))
+			}
+			seen := make(map[int64]bool)
+			for i, k := range klines {
+				if seen[k.OpenTime] {
+					t.Fatalf("重复的K线 %d", k.OpenTime)
+				}
+				seen[k.OpenTime] = true
+				if i > 0 && k.OpenTime != klines[i-1].OpenTime-60000 {
+					t.Fatalf("第 %d 根K线 %d 不连续或不是从新到旧排列", i, k.OpenTime)
+				}
+			}
+			if klines[0].Close != "3099.5" {
+				t.Errorf("最新一根收盘价 = %s，应为 3099.5", klines[0].Close)
+			}
+			if len(progress) == 0 || progress[0] != "正在获取第 1/3 批..." {
+				t.Errorf("进度 = %q", progress)
+			}
+		})
+	}
+
+	// progress 为 nil 时不输出也不出错
+	klinesServer(t, 1500, false)
+	if klines, err := GetKlinesBatch("BTCUSDT", "1m", 1200, nil); err != nil || len(klines) != 1200 {
+		t.Errorf("GetKlinesBatch(nil) = %d 根, %v", len(klines), err)
+	}
+}
+
+func TestToKlineData(t *testing.T) {
+	klines := []Kline{
+		{OpenTime: 2, Open: "2", High: "3", Low: "1", Close: "2.5", Volume: "10", CloseTime: 3},
+		{OpenTime: 1, Open: "1", High: "2", Low: "0.5", Close: "1.5", Volume: "5", CloseTime: 2},
+	}
+	data, err := ToKlineData(klines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 || data[0].OpenTime != 1 || data[1].Close != 2.5 || data[0].Volume != 5 {
+		t.Errorf("data = %+v，应按时间从旧到新排列", data)
+	}
+
+	klines[0].High = "x"
+	if _, err := ToKlineData(klines); err == nil {
+		t.Errorf("无效数值应返回错误")
+	}
+}
@@ -0,0 +1,85 @@
+package binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Ticker24h 24小时价格变动统计
+type Ticker24h struct {
+	Symbol             string `json:"symbol"`
+	PriceChangePercent string `json:"priceChangePercent"`
+	LastPrice          string `json:"lastPrice"`
+	Volume             string `json:"volume"`
+	QuoteVolume        string `json:"quoteVolume"`
+	Count              int    `json:"count"`
+}
+
+// GetTicker24h 获取所有交易对的24小时统计
+func GetTicker24h() ([]Ticker24h, error) {
+	resp, err := http.Get(BaseURL + "/api/v3/ticker/24hr")
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API 返回错误: %s, 状态码: %d", string(body), resp.StatusCode)
+	}
+
+	var tickers []Ticker24h
+	if err := json.Unmarshal(body, &tickers); err != nil {
+		return nil, fmt.Errorf("解析 JSON 失败: %w", err)
+	}
+
+	return tickers, nil
+}
+
+// TopVolumeSymbols 返回以 quoteAsset 计价、24小时成交额最大的前n个交易对
+func TopVolumeSymbols(quoteAsset string, n int) ([]string, error) {
+	tickers, err := GetTicker24h()
+	if err != nil {
+		return nil, err
+	}
+
+	type symbolVolume struct {
+		symbol string
+		volume float64
+	}
+
+	var candidates []symbolVolume
+	for _, t := range tickers {
+		if !strings.HasSuffix(t.Symbol, quoteAsset) {
+			continue
+		}
+		volume, err := strconv.ParseFloat(t.QuoteVolume, 64)
+		if err != nil || volume == 0 {
+			continue // 停牌或下架的交易对成交额为0
+		}
+		candidates = append(candidates, symbolVolume{t.Symbol, volume})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].volume > candidates[j].volume
+	})
+
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+
+	symbols := make([]string, len(candidates))
+	for i, c := range candidates {
+		symbols[i] = c.symbol
+	}
+	return symbols, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"binance-kline/binance"
+	"binance-kline/indicators"
+	"binance-kline/notify"
+)
+
+func main() {
+	symbolsFlag := flag.String("symbols", "BTCUSDT", "交易对列表，逗号分隔")
+	interval := flag.String("interval", "5m", "K线间隔 (1m, 5m, 15m, 1h, 4h, 1d)")
+	limit := flag.Int("limit", 500, "每个交易对保留的K线数量")
+	delay := flag.Duration("delay", 3*time.Second, "K线收盘后等待多久再拉取（等待交易所生成最终K线）")
+	regime := flag.Bool("regime", false, "按市场状态过滤信号")
+	output := flag.String("output", "data/daemon_signals.csv", "新信号追加写入的CSV文件（为空则不写）")
+	statePath := flag.String("state", "data/daemon_state.json", "状态文件，重启后从上次处理的K线继续")
+	notifyConfig := flag.String("notify", "", "告警渠道配置文件（JSON）")
+	notifyState := flag.String("notify-state", "data/notify_state.json", "已发送告警记录文件")
+	flag.Parse()
+
+	barDuration, err := binance.IntervalDuration(*interval)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	state, err := loadState(*statePath)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	p := &pipeline{
+		interval:   *interval,
+		maxBars:    *limit,
+		state:      state,
+		statePath:  *statePath,
+		outputPath: *output,
+	}
+	if *regime {
+		p.filter = indicators.MeanReversionFilter()
+	}
+	for _, s := range strings.Split(*symbolsFlag, ",") {
+		if s = strings.TrimSpace(strings.ToUpper(s)); s != "" {
+			p.feeds = append(p.feeds, &symbolFeed{symbol: s})
+		}
+	}
+	if len(p.feeds) == 0 {
+		log.Fatal("❌ 没有需要监控的交易对")
+	}
+
+	if *notifyConfig != "" {
+		notifiers, err := notify.LoadNotifiers(*notifyConfig)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		alertState, err := notify.LoadState(*notifyState)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		p.dispatcher = &notify.Dispatcher{Notifiers: notifiers, State: alertState}
+	}
+
+	// SIGINT/SIGTERM 时在两次运行之间退出，不会中断正在进行的处理
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("🚀 信号守护进程启动: %d 个交易对, %s K线", len(p.feeds), *interval)
+
+	// 启动时先运行一次，补上停机期间错过的K线
+	p.runOnce()
+
+	for {
+		next := binance.NextBarClose(time.Now(), barDuration).Add(*delay)
+		log.Printf("下一次运行: %s (北京时间)", next.In(indicators.BeijingLocation).Format("2006-01-02 15:04:05"))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Println("收到退出信号，正在保存状态...")
+			if err := state.save(*statePath); err != nil {
+				log.Printf("❌ 保存状态失败: %v", err)
+			}
+			log.Println("✓ 已退出")
+			return
+		case <-timer.C:
+		}
+
+		p.runOnce()
+	}
+}
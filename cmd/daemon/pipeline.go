@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"binance-kline/binance"
+	"binance-kline/indicators"
+	"binance-kline/notify"
+	"binance-kline/scanner"
+)
+
+// symbolFeed 单个交易对的已收盘K线窗口（按时间从旧到新）
+type symbolFeed struct {
+	symbol string
+	bars   []indicators.KlineData
+}
+
+// update 拉取上次之后的新K线并追加到窗口，窗口最多保留 maxBars 根，返回新增数量。
+// 停机期间错过的K线超过单次请求上限时分页补齐；错过的比窗口还多时丢弃旧窗口，重新获取最近 maxBars 根
+func (f *symbolFeed) update(interval string, maxBars int) (int, error) {
+	barDuration, err := binance.IntervalDuration(interval)
+	if err != nil {
+		return 0, err
+	}
+	if len(f.bars) > 0 {
+		missed := int(time.Since(time.UnixMilli(f.bars[len(f.bars)-1].CloseTime)) / barDuration)
+		if missed >= maxBars {
+			log.Printf("⚠ %s 错过 %d 根K线，超过窗口 %d 根，重新获取最近的K线", f.symbol, missed, maxBars)
+			f.bars = nil
+		}
+	}
+
+	var raw []binance.Kline
+	switch {
+	case len(f.bars) > 0:
+		// 增量获取：从最后一根K线之后开始
+		raw, err = fetchSince(f.symbol, interval, f.bars[len(f.bars)-1].OpenTime+1)
+	case maxBars <= klinesPerRequest:
+		raw, err = binance.GetKlines(f.symbol, interval, 0, 0, maxBars)
+	default:
+		raw, err = binance.GetKlinesBatch(f.symbol, interval, maxBars, func(format string, args ...any) {
+			log.Printf(f.symbol+" "+format, args...)
+		})
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := binance.ToKlineData(raw)
+	if err != nil {
+		return 0, err
+	}
+	data = scanner.ClosedOnly(data, time.Now())
+
+	added := 0
+	for _, k := range data {
+		if len(f.bars) > 0 && k.OpenTime <= f.bars[len(f.bars)-1].OpenTime {
+			continue
+		}
+		f.bars = append(f.bars, k)
+		added++
+	}
+
+	if len(f.bars) > maxBars {
+		f.bars = f.bars[len(f.bars)-maxBars:]
+	}
+
+	return added, nil
+}
+
+// klinesPerRequest 币安单次请求最多返回的K线数量
+const klinesPerRequest = 1000
+
+// fetchSince 从 startTime 开始逐页获取K线，直到返回不足一页（已到最新一根）
+func fetchSince(symbol, interval string, startTime int64) ([]binance.Kline, error) {
+	var raw []binance.Kline
+	for {
+		page, err := binance.GetKlines(symbol, interval, startTime, 0, klinesPerRequest)
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, page...)
+		if len(page) < klinesPerRequest || page[len(page)-1].OpenTime < startTime {
+			return raw, nil
+		}
+		startTime = page[len(page)-1].OpenTime + 1
+	}
+}
+
+// pipeline 每根K线收盘后执行的信号流程
+type pipeline struct {
+	interval   string
+	maxBars    int
+	filter     indicators.RegimeFilter
+	feeds      []*symbolFeed
+	state      *daemonState
+	statePath  string
+	outputPath string             // 信号CSV文件，为空则不写文件
+	dispatcher *notify.Dispatcher // 为 nil 则不发送告警
+}
+
+// runOnce 更新所有交易对并输出新信号，最后保存状态
+func (p *pipeline) runOnce() {
+	for _, feed := range p.feeds {
+		p.process(feed)
+	}
+
+	if err := p.state.save(p.statePath); err != nil {
+		log.Printf("❌ 保存状态失败: %v", err)
+	}
+}
+
+// process 处理单个交易对：更新K线 → 计算指标 → 扫描信号 → 输出上次处理之后的新信号
+func (p *pipeline) process(feed *symbolFeed) {
+	added, err := feed.update(p.interval, p.maxBars)
+	if err != nil {
+		log.Printf("⚠ %s 获取K线失败: %v", feed.symbol, err)
+		return
+	}
+
+	klines := indicators.CalculateIndicators(feed.bars)
+	if klines == nil {
+		log.Printf("⚠ %s 数据不足，无法计算指标（%d 根K线）", feed.symbol, len(feed.bars))
+		return
+	}
+
+	indicators.ClassifyRegimes(klines, indicators.DefaultRegimeConfig())
+	signals := indicators.ScanSignalsWithFilter(klines, p.filter)
+	divergences := indicators.DetectDivergence(signals)
+
+	key := feed.symbol + "_" + p.interval
+	latest := feed.bars[len(feed.bars)-1].CloseTime
+	lastProcessed := p.lastProcessed(key, feed.bars)
+
+	var freshSignals []*indicators.TradingSignal
+	for _, s := range signals {
+		if s.Time.UnixMilli() > lastProcessed {
+			freshSignals = append(freshSignals, s)
+		}
+	}
+
+	var freshDivergences []*indicators.DivergenceSignal
+	for _, d := range divergences {
+		if d.SecondSignal.Time.UnixMilli() > lastProcessed {
+			freshDivergences = append(freshDivergences, d)
+		}
+	}
+
+	last := klines[len(klines)-1]
+	log.Printf("%s 新增 %d 根K线，最新收盘 %s 价格 %.4f RSI %.2f 状态 %s，新信号 %d 个，新背离 %d 个",
+		feed.symbol, added,
+		time.UnixMilli(last.CloseTime).In(indicators.BeijingLocation).Format("2006-01-02 15:04"),
+		last.Close, last.RSI14, last.Regime, len(freshSignals), len(freshDivergences))
+
+	p.emit(feed.symbol, freshSignals, freshDivergences)
+	p.state.LastCloseTime[key] = latest
+}
+
+// lastProcessed 上次已处理到的K线收盘时间。状态中有记录时从该K线之后继续，补上停机期间的信号；
+// 首次运行只处理最新一根K线，避免把历史信号全部重新输出
+func (p *pipeline) lastProcessed(key string, bars []indicators.KlineData) int64 {
+	if closeTime, ok := p.state.LastCloseTime[key]; ok {
+		return closeTime
+	}
+	return bars[len(bars)-2].CloseTime
+}
+
+// emit 将新信号输出到标准输出、CSV文件和告警渠道
+func (p *pipeline) emit(symbol string, signals []*indicators.TradingSignal, divergences []*indicators.DivergenceSignal) {
+	if len(signals) == 0 && len(divergences) == 0 {
+		return
+	}
+
+	var alerts []notify.Alert
+	var records [][]string
+
+	for _, s := range signals {
+		fmt.Printf("%s %s %s\n", symbol, p.interval, s.String())
+		alerts = append(alerts, notify.SignalAlert(symbol, p.interval, s))
+		records = append(records, []string{
+			s.Time.In(indicators.BeijingLocation).Format("2006-01-02 15:04:05"),
+			symbol, p.interval, "信号", string(s.Type),
+			strconv.FormatFloat(s.Price, 'f', -1, 64),
+			strconv.FormatFloat(s.StopLoss, 'f', -1, 64),
+			fmt.Sprintf("%.2f", s.RSI14),
+			fmt.Sprintf("%.4f", s.RiskPercent),
+			string(s.Regime),
+			s.String(),
+		})
+	}
+
+	for _, d := range divergences {
+		fmt.Printf("%s %s %s\n", symbol, p.interval, d.String())
+		alerts = append(alerts, notify.DivergenceAlert(symbol, p.interval, d))
+		records = append(records, []string{
+			d.SecondSignal.Time.In(indicators.BeijingLocation).Format("2006-01-02 15:04:05"),
+			symbol, p.interval, "背离", string(d.Type),
+			strconv.FormatFloat(d.SecondSignal.Price, 'f', -1, 64),
+			strconv.FormatFloat(d.SecondSignal.StopLoss, 'f', -1, 64),
+			fmt.Sprintf("%.2f", d.SecondSignal.RSI14),
+			fmt.Sprintf("%.4f", d.SecondSignal.RiskPercent),
+			string(d.SecondSignal.Regime),
+			d.String(),
+		})
+	}
+
+	if p.outputPath != "" {
+		if err := appendRecords(p.outputPath, records); err != nil {
+			log.Printf("❌ 写入信号文件失败: %v", err)
+		}
+	}
+
+	if p.dispatcher != nil {
+		sent, err := p.dispatcher.Send(alerts)
+		if err != nil {
+			log.Printf("⚠ 部分告警发送失败: %v", err)
+		}
+		if sent > 0 {
+			log.Printf("已发送 %d 条告警", sent)
+		}
+	}
+}
+
+// appendRecords 追加信号记录到CSV文件，文件不存在时先写表头
+func appendRecords(filename string, records [][]string) error {
+	dir := filepath.Dir(filename)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("创建目录失败: %w", err)
+		}
+	}
+
+	_, statErr := os.Stat(filename)
+	newFile := os.IsNotExist(statErr)
+
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if newFile {
+		header := []string{"时间", "交易对", "时间间隔", "类别", "方向", "价格", "止损", "RSI14", "风险%", "市场状态", "详情"}
+		if err := writer.Write(header); err != nil {
+			return fmt.Errorf("写入表头失败: %w", err)
+		}
+	}
+	if err := writer.WriteAll(records); err != nil {
+		return fmt.Errorf("写入数据失败: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"binance-kline/binance"
+	"binance-kline/indicators"
+)
+
+// klinesServer 模拟 /api/v3/klines：1分钟K线从 now 之前 history 分钟开始，最后一根为 now 所在的未收盘K线。
+// 按币安的规则，有 startTime 时返回其后的前 limit 根，否则返回 endTime（默认最新）之前的最后 limit 根
+func klinesServer(t *testing.T, history int) (openMinute int64, queries *[]string) {
+	t.Helper()
+	// 避免测试过程中跨过分钟边界，未收盘的K线变为已收盘
+	if ms := time.Now().UnixMilli() % 60000; ms > 59000 {
+		time.Sleep(time.Duration(60000-ms) * time.Millisecond)
+	}
+	openMinute = time.Now().UnixMilli() / 60000 * 60000
+	first := openMinute - int64(history)*60000
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		requests = append(requests, r.URL.RawQuery)
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		start, end := first, openMinute
+		if s := q.Get("endTime"); s != "" {
+			endTime, _ := strconv.ParseInt(s, 10, 64)
+			end = min(end, endTime/60000*60000)
+		}
+		if s := q.Get("startTime"); s != "" {
+			startTime, _ := strconv.ParseInt(s, 10, 64)
+			start = max(start, (startTime+59999)/60000*60000)
+			end = min(end, start+int64(limit-1)*60000)
+		} else {
+			start = max(start, end-int64(limit-1)*60000)
+		}
+		rows := [][]any{}
+		for open := start; open <= end; open += 60000 {
+			price := fmt.Sprintf("%d.5", 100+(open-first)/60000)
+			rows = append(rows, []any{open, price, price, price, price, "1.0", open + 59999, "100", 3, "0.5", "50", "0"})
+		}
+		json.NewEncoder(w).Encode(rows)
+	}))
+	t.Cleanup(srv.Close)
+
+	old := binance.BaseURL
+	binance.BaseURL = srv.URL
+	t.Cleanup(func() { binance.BaseURL = old })
+	return openMinute, &requests
+}
+
+// minuteBars 以 lastOpen 结尾的 n 根连续1分钟K线
+func minuteBars(lastOpen int64, n int) []indicators.KlineData {
+	bars := make([]indicators.KlineData, n)
+	for i := range bars {
+		open := lastOpen - int64(n-1-i)*60000
+		bars[i] = indicators.KlineData{OpenTime: open, CloseTime: open + 59999}
+	}
+	return bars
+}
+
+func TestSymbolFeedUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		missed   int // 窗口最后一根K线之后错过的已收盘K线数量
+		maxBars  int
+		requests int
+		added    int
+		window   int
+	}{
+		{"没有错过", 0, 3000, 1, 0, 100},
+		{"一次请求补齐", 10, 3000, 1, 10, 110},
+		// 错过 2500 根：分三页获取（1000 + 1000 + 501，最后一根未收盘）
+		{"分页补齐", 2500, 3000, 3, 2500, 2600},
+		{"补齐后超出窗口", 2500, 2550, 3, 2500, 2550},
+		// 错过的比窗口多：丢弃旧窗口，只获取最近 500 根（最后一根未收盘）
+		{"错过的超过窗口", 2500, 500, 1, 499, 499},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			openMinute, queries := klinesServer(t, 5000)
+			lastOpen := openMinute - int64(tt.missed+1)*60000
+			feed := &symbolFeed{symbol: "BTCUSDT", bars: minuteBars(lastOpen, 100)}
+
+			added, err := feed.update("1m", tt.maxBars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(*queries) != tt.requests {
+				t.Errorf("请求 %d 次，应为 %d 次: %v", len(*queries), tt.requests, *queries)
+			}
+			if added != tt.added || len(feed.bars) != tt.window {
+				t.Errorf("新增 %d 根，窗口 %d 根，应为新增 %d 根，窗口 %d 根", added, len(feed.bars), tt.added, tt.window)
+			}
+			// 窗口连续，最后一根是最近收盘的K线
+			for i := 1; i < len(feed.bars); i++ {
+				if feed.bars[i].OpenTime != feed.bars[i-1].OpenTime+60000 {
+					t.Fatalf("第 %d 根K线 %d 与前一根 %d 不连续", i, feed.bars[i].OpenTime, feed.bars[i-1].OpenTime)
+				}
+			}
+			if last := feed.bars[len(feed.bars)-1]; last.OpenTime != openMinute-60000 {
+				t.Errorf("最后一根K线 = %d，应为最近收盘的 %d", last.OpenTime, openMinute-60000)
+			}
+		})
+	}
+}
+
+func TestPipelineLastProcessed(t *testing.T) {
+	bars := minuteBars(1700000000000, 10)
+	p := &pipeline{state: &daemonState{LastCloseTime: map[string]int64{
+		"BTCUSDT_1m": bars[2].CloseTime,
+	}}}
+
+	// 重启后从状态中记录的K线之后继续，停机期间的K线都会处理
+	if got := p.lastProcessed("BTCUSDT_1m", bars); got != bars[2].CloseTime {
+		t.Errorf("已有记录时 lastProcessed = %d，应为 %d", got, bars[2].CloseTime)
+	}
+	// 首次运行只处理最新一根K线
+	if got := p.lastProcessed("ETHUSDT_1m", bars); got != bars[8].CloseTime {
+		t.Errorf("首次运行 lastProcessed = %d，应为倒数第二根的收盘时间 %d", got, bars[8].CloseTime)
+	}
+}
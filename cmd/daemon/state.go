@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// daemonState 守护进程的持久化状态，重启后从上次处理到的K线继续
+type daemonState struct {
+	// LastCloseTime 每个 交易对_间隔 已处理到的最后一根K线收盘时间（毫秒）
+	LastCloseTime map[string]int64 `json:"lastCloseTime"`
+}
+
+// loadState 读取状态文件，文件不存在时返回空状态
+func loadState(path string) (*daemonState, error) {
+	state := &daemonState{LastCloseTime: make(map[string]int64)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("读取状态文件失败: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("解析状态文件失败: %w", err)
+	}
+	if state.LastCloseTime == nil {
+		state.LastCloseTime = make(map[string]int64)
+	}
+
+	return state, nil
+}
+
+// save 写入状态文件（先写临时文件再重命名）
+func (s *daemonState) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化状态失败: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("创建目录失败: %w", err)
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入状态文件失败: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
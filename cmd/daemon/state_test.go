@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "daemon_state.json")
+
+	// 文件不存在时为空状态
+	state, err := loadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.LastCloseTime == nil || len(state.LastCloseTime) != 0 {
+		t.Fatalf("LastCloseTime = %v，应为空 map", state.LastCloseTime)
+	}
+
+	state.LastCloseTime["BTCUSDT_5m"] = 1700000299999
+	state.LastCloseTime["ETHUSDT_5m"] = 1700000599999
+	if err := state.save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("临时文件应已重命名: %v", err)
+	}
+
+	loaded, err := loadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, state) {
+		t.Errorf("读回的状态 = %+v，应为 %+v", loaded, state)
+	}
+
+	// 缺少字段时仍返回可写入的 map
+	if err := os.WriteFile(path, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := loadState(path); err != nil || loaded.LastCloseTime == nil {
+		t.Errorf("loadState({}) = %+v, %v，应为空 map", loaded, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"lastCloseTime":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadState(path); err == nil {
+		t.Errorf("状态文件格式错误时应返回错误")
+	}
+}
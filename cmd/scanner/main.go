@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"binance-kline/binance"
+	"binance-kline/indicators"
+	"binance-kline/notify"
+	"binance-kline/scanner"
+)
+
+func main() {
+	symbolsFlag := flag.String("symbols", "", "交易对列表，逗号分隔（为空则使用成交额排名前N的交易对）")
+	top := flag.Int("top", 20, "未指定 -symbols 时，扫描24小时成交额前N的交易对")
+	quote := flag.String("quote", "USDT", "未指定 -symbols 时，筛选的计价币种")
+	interval := flag.String("interval", "5m", "K线间隔 (1m, 5m, 15m, 1h, 4h, 1d)")
+	limit := flag.Int("limit", 500, "每个交易对获取的K线数量")
+	recent := flag.Int("recent", 3, "只显示最近N根已收盘K线内的信号")
+	workers := flag.Int("workers", 5, "并发请求数")
+	regime := flag.Bool("regime", false, "按市场状态过滤信号（趋势中只做顺势，高波动不出信号）")
+	output := flag.String("output", "", "导出排名CSV文件路径（不指定则只打印）")
+	notifyConfig := flag.String("notify", "", "告警渠道配置文件（JSON），指定后发送新信号告警")
+	notifyState := flag.String("notify-state", "data/notify_state.json", "已发送告警记录文件（跨运行去重）")
+	flag.Parse()
+
+	var symbols []string
+	if *symbolsFlag != "" {
+		for _, s := range strings.Split(*symbolsFlag, ",") {
+			if s = strings.TrimSpace(strings.ToUpper(s)); s != "" {
+				symbols = append(symbols, s)
+			}
+		}
+	} else {
+		var err error
+		symbols, err = binance.TopVolumeSymbols(*quote, *top)
+		if err != nil {
+			fmt.Printf("获取成交额排名失败: %v\n", err)
+			return
+		}
+	}
+
+	if len(symbols) == 0 {
+		fmt.Println("没有需要扫描的交易对")
+		return
+	}
+
+	cfg := scanner.Config{
+		Interval:   *interval,
+		Bars:       *limit,
+		RecentBars: *recent,
+		Workers:    *workers,
+	}
+	if *regime {
+		cfg.RegimeFilter = indicators.MeanReversionFilter()
+	}
+
+	fmt.Printf("正在扫描 %d 个交易对 (%s, 每个 %d 根K线)...\n", len(symbols), *interval, *limit)
+	results, errs := scanner.Scan(symbols, cfg)
+
+	for _, err := range errs {
+		fmt.Printf("⚠ 跳过 %v\n", err)
+	}
+
+	fmt.Printf("\n=== 最近 %d 根K线内的新信号: %d 个 ===\n\n", *recent, len(results))
+	if len(results) > 0 {
+		scanner.PrintTable(results)
+	}
+
+	if *output != "" {
+		if err := scanner.SaveToCSV(results, *output); err != nil {
+			fmt.Printf("保存到CSV失败: %v\n", err)
+			return
+		}
+		fmt.Printf("\n排名已保存到: %s\n", *output)
+	}
+
+	if *notifyConfig != "" {
+		sendAlerts(results, *interval, *notifyConfig, *notifyState)
+	}
+}
+
+// sendAlerts 发送信号和背离告警，已发送过的不重复发送
+func sendAlerts(results []scanner.Result, interval, configPath, statePath string) {
+	notifiers, err := notify.LoadNotifiers(configPath)
+	if err != nil {
+		fmt.Printf("⚠ %v\n", err)
+		return
+	}
+
+	state, err := notify.LoadState(statePath)
+	if err != nil {
+		fmt.Printf("⚠ %v\n", err)
+		return
+	}
+	state.Prune(time.Now().AddDate(0, 0, -30))
+
+	var alerts []notify.Alert
+	for _, r := range results {
+		alerts = append(alerts, notify.SignalAlert(r.Symbol, interval, r.Signal))
+		if r.Divergence != nil {
+			alerts = append(alerts, notify.DivergenceAlert(r.Symbol, interval, r.Divergence))
+		}
+	}
+
+	dispatcher := &notify.Dispatcher{Notifiers: notifiers, State: state}
+	sent, err := dispatcher.Send(alerts)
+	if err != nil {
+		fmt.Printf("⚠ 部分告警发送失败: %v\n", err)
+	}
+	fmt.Printf("已发送 %d 条新告警\n", sent)
+}
@@ -1,50 +1,76 @@
 package main
 
 import (
-	"encoding/csv"
+	"flag"
 	"fmt"
-	"io"
-	"os"
-	"strconv"
 	"time"
 
 	"binance-kline/indicators"
+	"binance-kline/klinecsv"
 )
 
-// 北京时间时区
-var BeijingLocation = time.FixedZone("CST", 8*3600)
-
 func main() {
-	// 读取5分钟CSV文件
-	csvFile := "data/klines_5m.csv"
-	klines, err := loadKlinesFromCSV(csvFile)
+	interval := flag.String("interval", "5m", "K线间隔，用于默认文件名和输出说明")
+	file := flag.String("file", "", "K线CSV文件（默认 data/klines_<interval>.csv）")
+	start := flag.String("start", "", "开始时间（北京时间，2006-01-02 或 2006-01-02 15:04）")
+	end := flag.String("end", "", "结束时间（北京时间，不含）")
+	last := flag.Int("last", 5, "显示最近N根K线的指标")
+	regime := flag.Bool("regime", false, "按市场状态过滤信号（趋势中只做顺势，高波动不出信号）")
+	flag.Parse()
+
+	csvFile := *file
+	if csvFile == "" {
+		csvFile = fmt.Sprintf("data/klines_%s.csv", *interval)
+	}
+
+	var opts klinecsv.Options
+	var err error
+	if opts.Start, err = parseBeijingTime(*start); err != nil {
+		fmt.Printf("开始时间格式错误: %v\n", err)
+		return
+	}
+	if opts.End, err = parseBeijingTime(*end); err != nil {
+		fmt.Printf("结束时间格式错误: %v\n", err)
+		return
+	}
+
+	// 读取CSV文件
+	klines, rowErrors, err := klinecsv.LoadFile(csvFile, opts)
 	if err != nil {
 		fmt.Printf("读取CSV文件失败: %v\n", err)
-		fmt.Println("请先运行: make save-5m")
+		fmt.Printf("请先运行: make save-%s\n", *interval)
 		return
 	}
+	for _, rowErr := range rowErrors {
+		fmt.Printf("⚠ 跳过 %s %v\n", csvFile, rowErr)
+	}
 
-	fmt.Printf("\n============ 5分钟K线背离信号检测 ============\n")
-	fmt.Printf("成功加载 %d 条5分钟K线数据\n\n", len(klines))
+	fmt.Printf("\n============ %s K线背离信号检测 ============\n", *interval)
+	fmt.Printf("成功加载 %d 条%s K线数据\n\n", len(klines), *interval)
 
 	// 计算技术指标
 	fmt.Println("正在计算技术指标 (RSI14, MACD)...")
 	klinesWithIndicators := indicators.CalculateIndicators(klines)
 	if klinesWithIndicators == nil {
-		fmt.Println("数据不足，无法计算指标（至少需要50根K线）")
-		fmt.Println("请运行: make save-5m 获取更多数据")
+		fmt.Printf("数据不足，无法计算指标（至少需要%d根K线，当前 %d 根）\n", indicators.MinKlines, len(klines))
+		fmt.Printf("请运行: make save-%s 获取更多数据\n", *interval)
 		return
 	}
+	indicators.ClassifyRegimes(klinesWithIndicators, indicators.DefaultRegimeConfig())
 
 	fmt.Printf("指标计算完成！\n\n")
 
-	// 显示最后5根K线的指标
-	fmt.Println("=== 最近5根K线指标 ===")
-	printLastNIndicators(klinesWithIndicators, 5)
+	// 显示最后N根K线的指标
+	fmt.Printf("=== 最近%d根K线指标 ===\n", *last)
+	printLastNIndicators(klinesWithIndicators, *last)
 
 	// 扫描交易信号
-	fmt.Println("\n=== 扫描5分钟交易信号 ===")
-	signals := indicators.ScanSignals(klinesWithIndicators)
+	fmt.Printf("\n=== 扫描%s交易信号 ===\n", *interval)
+	var filter indicators.RegimeFilter
+	if *regime {
+		filter = indicators.MeanReversionFilter()
+	}
+	signals := indicators.ScanSignalsWithFilter(klinesWithIndicators, filter)
 
 	if len(signals) == 0 {
 		fmt.Println("未发现符合条件的交易信号")
@@ -75,7 +101,7 @@ func main() {
 
 	// 检测背离信号（核心功能）
 	fmt.Println("\n========================================")
-	fmt.Println("=== 5分钟背离信号检测（强烈反转信号！）===")
+	fmt.Printf("=== %s背离信号检测（强烈反转信号！）===\n", *interval)
 	fmt.Println("========================================")
 
 	divergences := indicators.DetectDivergence(signals)
@@ -119,80 +145,22 @@ func main() {
 		fmt.Printf("🔺 看涨背离: %d 个 (价格↓ 指标↑ → 强烈买入信号)\n", bullishCount)
 		fmt.Printf("🔻 看跌背离: %d 个 (价格↑ 指标↓ → 强烈卖出信号)\n", bearishCount)
 		fmt.Printf("\n⚡ 注意：背离信号是最强烈的反转信号之一，建议重点关注！\n")
-		fmt.Printf("📊 在5分钟级别，背离信号可用于短线交易和波段操作\n")
 	}
 
 	fmt.Println("\n============================================")
 }
 
-// loadKlinesFromCSV 从CSV文件加载K线数据
-func loadKlinesFromCSV(filename string) ([]indicators.KlineData, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-
-	// 跳过表头
-	_, err = reader.Read()
-	if err != nil {
-		return nil, err
+// parseBeijingTime 解析北京时间，空字符串返回零值
+func parseBeijingTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
 	}
-
-	var klines []indicators.KlineData
-
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-
-		// CSV格式：交易对,时间间隔,开盘时间,开盘价,最高价,最低价,收盘价,成交量,收盘时间,成交额,成交笔数,主动买入量,主动买入额
-		if len(record) < 9 {
-			continue
-		}
-
-		// 解析时间（CSV中存储的是北京时间）
-		openTime, err := time.ParseInLocation("2006-01-02 15:04:05", record[2], BeijingLocation)
-		if err != nil {
-			continue
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, indicators.BeijingLocation); err == nil {
+			return t, nil
 		}
-		closeTime, err := time.ParseInLocation("2006-01-02 15:04:05", record[8], BeijingLocation)
-		if err != nil {
-			continue
-		}
-
-		// 解析价格
-		open, _ := strconv.ParseFloat(record[3], 64)
-		high, _ := strconv.ParseFloat(record[4], 64)
-		low, _ := strconv.ParseFloat(record[5], 64)
-		close, _ := strconv.ParseFloat(record[6], 64)
-		volume, _ := strconv.ParseFloat(record[7], 64)
-
-		kline := indicators.KlineData{
-			OpenTime:  openTime.UnixMilli(),
-			Open:      open,
-			High:      high,
-			Low:       low,
-			Close:     close,
-			Volume:    volume,
-			CloseTime: closeTime.UnixMilli(),
-		}
-
-		klines = append(klines, kline)
 	}
-
-	// 反转数据，使其按时间从旧到新排列
-	for i, j := 0, len(klines)-1; i < j; i, j = i+1, j-1 {
-		klines[i], klines[j] = klines[j], klines[i]
-	}
-
-	return klines, nil
+	return time.Time{}, fmt.Errorf("无法解析 %q", s)
 }
 
 // printLastNIndicators 打印最后N根K线的指标
@@ -202,13 +170,13 @@ func printLastNIndicators(klines []indicators.KlineWithIndicators, n int) {
 		start = 0
 	}
 
-	fmt.Printf("%-20s %10s %10s %10s %10s %8s %10s %10s\n",
-		"时间(北京)", "开盘", "最高", "最低", "收盘", "RSI14", "MACD", "信号线")
-	fmt.Println("---------------------------------------------------------------------------------------------------")
+	fmt.Printf("%-20s %10s %10s %10s %10s %8s %10s %10s %-16s\n",
+		"时间(北京)", "开盘", "最高", "最低", "收盘", "RSI14", "MACD", "信号线", "市场状态")
+	fmt.Println("--------------------------------------------------------------------------------------------------------------------")
 
 	for i := start; i < len(klines); i++ {
 		k := klines[i]
-		timeStr := time.UnixMilli(k.CloseTime).In(BeijingLocation).Format("2006-01-02 15:04")
+		timeStr := time.UnixMilli(k.CloseTime).In(indicators.BeijingLocation).Format("2006-01-02 15:04")
 
 		crossInfo := ""
 		if k.MacdCrossUp {
@@ -217,7 +185,7 @@ func printLastNIndicators(klines []indicators.KlineWithIndicators, n int) {
 			crossInfo = " [死叉↓]"
 		}
 
-		fmt.Printf("%-20s %10.2f %10.2f %10.2f %10.2f %8.2f %10.4f %10.4f%s\n",
-			timeStr, k.Open, k.High, k.Low, k.Close, k.RSI14, k.MACD, k.MACDSignal, crossInfo)
+		fmt.Printf("%-20s %10.2f %10.2f %10.2f %10.2f %8.2f %10.4f %10.4f %-16s%s\n",
+			timeStr, k.Open, k.High, k.Low, k.Close, k.RSI14, k.MACD, k.MACDSignal, k.Regime, crossInfo)
 	}
 }
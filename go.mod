@@ -1,7 +1,7 @@
 module binance-kline
 
-go 1.23.2
+go 1.24
 
 toolchain go1.24.5
 
-require github.com/markcheno/go-talib v0.0.0-20250114000313-ec55a20c902f // indirect
+require github.com/markcheno/go-talib v0.0.0-20250114000313-ec55a20c902f
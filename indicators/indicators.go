@@ -17,12 +17,16 @@ type KlineData struct {
 
 // Indicators 包含计算后的技术指标
 type Indicators struct {
-	RSI14          float64 // RSI(14)
-	MACD           float64 // MACD线
-	MACDSignal     float64 // 信号线
-	MACDHistogram  float64 // 柱状图
-	MacdCrossUp    bool    // MACD金叉
-	MacdCrossDown  bool    // MACD死叉
+	RSI14         float64      // RSI(14)
+	MACD          float64      // MACD线
+	MACDSignal    float64      // 信号线
+	MACDHistogram float64      // 柱状图
+	MacdCrossUp   bool         // MACD金叉
+	MacdCrossDown bool         // MACD死叉
+	ADX14         float64      // ADX(14) 趋势强度
+	NATR14        float64      // 归一化ATR(14)，ATR占收盘价的百分比
+	TrendSlope    float64      // 收盘价20周期线性回归斜率，占收盘价的百分比/根
+	Regime        MarketRegime // 市场状态（由 ClassifyRegimes 标记）
 }
 
 // KlineWithIndicators 带指标的K线数据
@@ -31,21 +35,28 @@ type KlineWithIndicators struct {
 	Indicators
 }
 
+// MinKlines 计算指标至少需要的K线数量，不足时 CalculateIndicators 返回 nil
+const MinKlines = 30
+
 // CalculateIndicators 为K线数据计算技术指标
 func CalculateIndicators(klines []KlineData) []KlineWithIndicators {
 	n := len(klines)
-	if n < 30 {
+	if n < MinKlines {
 		// 数据不足，无法计算指标
 		return nil
 	}
 
 	result := make([]KlineWithIndicators, n)
 
-	// 提取收盘价
+	// 提取收盘价、最高价、最低价
 	closes := make([]float64, n)
+	highs := make([]float64, n)
+	lows := make([]float64, n)
 	for i, k := range klines {
 		result[i].KlineData = k
 		closes[i] = k.Close
+		highs[i] = k.High
+		lows[i] = k.Low
 	}
 
 	// 计算 RSI(14)
@@ -82,6 +93,18 @@ func CalculateIndicators(klines []KlineData) []KlineWithIndicators {
 		}
 	}
 
+	// 计算 ADX(14)、NATR(14) 和 20周期趋势斜率（用于市场状态分类）
+	adx := talib.Adx(highs, lows, closes, 14)
+	natr := talib.Natr(highs, lows, closes, 14)
+	slope := talib.LinearRegSlope(closes, 20)
+	for i := 0; i < n; i++ {
+		result[i].ADX14 = adx[i]
+		result[i].NATR14 = natr[i]
+		if closes[i] != 0 {
+			result[i].TrendSlope = slope[i] / closes[i] * 100
+		}
+	}
+
 	return result
 }
 
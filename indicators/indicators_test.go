@@ -0,0 +1,17 @@
+package indicators
+
+import "testing"
+
+func TestCalculateIndicatorsMinKlines(t *testing.T) {
+	klines := make([]KlineData, MinKlines)
+	for i := range klines {
+		price := 100 + float64(i%7)
+		klines[i] = KlineData{Open: price, High: price + 1, Low: price - 1, Close: price}
+	}
+	if got := CalculateIndicators(klines[:MinKlines-1]); got != nil {
+		t.Errorf("%d 根K线应返回 nil", MinKlines-1)
+	}
+	if got := CalculateIndicators(klines); len(got) != MinKlines {
+		t.Errorf("%d 根K线返回 %d 条结果", MinKlines, len(got))
+	}
+}
@@ -0,0 +1,132 @@
+package indicators
+
+// ========== 市场状态分类 ==========
+
+// MarketRegime 市场状态
+type MarketRegime string
+
+const (
+	RegimeUnknown        MarketRegime = ""                // 未分类（数据不足）
+	RegimeTrendingUp     MarketRegime = "TRENDING_UP"     // 上升趋势
+	RegimeTrendingDown   MarketRegime = "TRENDING_DOWN"   // 下降趋势
+	RegimeRanging        MarketRegime = "RANGING"         // 震荡
+	RegimeHighVolatility MarketRegime = "HIGH_VOLATILITY" // 高波动
+)
+
+// RegimeConfig 市场状态分类参数
+type RegimeConfig struct {
+	ADXTrendThreshold  float64 // ADX 高于该值视为有趋势
+	VolatilityLookback int     // 计算波动率百分位的回看K线数
+	HighVolPercentile  float64 // NATR 百分位高于该值视为高波动（0-100）
+	MinSlopePercent    float64 // 趋势斜率绝对值低于该值视为无方向（%/根）
+}
+
+// DefaultRegimeConfig 返回默认的市场状态分类参数
+func DefaultRegimeConfig() RegimeConfig {
+	return RegimeConfig{
+		ADXTrendThreshold:  25,
+		VolatilityLookback: 100,
+		HighVolPercentile:  90,
+		MinSlopePercent:    0.01,
+	}
+}
+
+// ClassifyRegimes 为每根K线标记市场状态，结果同时写回 klines[i].Regime
+// 判定顺序：
+// 1. NATR 在回看窗口内的百分位 >= HighVolPercentile → 高波动
+// 2. ADX >= ADXTrendThreshold 且斜率超过 MinSlopePercent → 上升/下降趋势
+// 3. 其余 → 震荡
+func ClassifyRegimes(klines []KlineWithIndicators, cfg RegimeConfig) []MarketRegime {
+	regimes := make([]MarketRegime, len(klines))
+
+	for i := range klines {
+		regimes[i] = classifyRegimeAt(klines, i, cfg)
+		klines[i].Regime = regimes[i]
+	}
+
+	return regimes
+}
+
+// classifyRegimeAt 计算单根K线的市场状态
+func classifyRegimeAt(klines []KlineWithIndicators, index int, cfg RegimeConfig) MarketRegime {
+	current := klines[index]
+
+	// ADX 需要约 2 倍周期的数据才有效，未就绪时不分类
+	if current.ADX14 == 0 || current.NATR14 == 0 {
+		return RegimeUnknown
+	}
+
+	if VolatilityPercentile(klines, index, cfg.VolatilityLookback) >= cfg.HighVolPercentile {
+		return RegimeHighVolatility
+	}
+
+	if current.ADX14 >= cfg.ADXTrendThreshold {
+		if current.TrendSlope >= cfg.MinSlopePercent {
+			return RegimeTrendingUp
+		}
+		if current.TrendSlope <= -cfg.MinSlopePercent {
+			return RegimeTrendingDown
+		}
+	}
+
+	return RegimeRanging
+}
+
+// VolatilityPercentile 计算当前K线 NATR 在前n根K线（含当前）中的百分位（0-100）
+func VolatilityPercentile(klines []KlineWithIndicators, currentIndex int, n int) float64 {
+	start := currentIndex - n + 1
+	if start < 0 {
+		start = 0
+	}
+
+	current := klines[currentIndex].NATR14
+	total := 0
+	below := 0
+	for i := start; i <= currentIndex; i++ {
+		if klines[i].NATR14 == 0 {
+			continue // 指标未就绪
+		}
+		total++
+		if klines[i].NATR14 <= current {
+			below++
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return float64(below) / float64(total) * 100
+}
+
+// RegimeFilter 按市场状态允许的信号类型
+// 未出现在过滤器中的市场状态不做限制；映射为空切片表示该状态下屏蔽所有信号
+type RegimeFilter map[MarketRegime][]SignalType
+
+// Allows 判断在指定市场状态下是否允许该类型的信号
+func (f RegimeFilter) Allows(regime MarketRegime, signalType SignalType) bool {
+	allowed, exists := f[regime]
+	if !exists {
+		return true
+	}
+
+	for _, t := range allowed {
+		if t == signalType {
+			return true
+		}
+	}
+	return false
+}
+
+// MeanReversionFilter 适合"RSI超卖/超买 + MACD交叉"这类均值回归信号的过滤器
+//   - 震荡：多空信号都允许
+//   - 上升趋势：只做多（顺势回调买入）
+//   - 下降趋势：只做空（顺势反弹卖出）
+//   - 高波动：屏蔽所有信号
+func MeanReversionFilter() RegimeFilter {
+	return RegimeFilter{
+		RegimeRanging:        {SignalLong, SignalShort},
+		RegimeTrendingUp:     {SignalLong},
+		RegimeTrendingDown:   {SignalShort},
+		RegimeHighVolatility: {},
+	}
+}
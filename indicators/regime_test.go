@@ -0,0 +1,209 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+)
+
+// regimeKlines 只设置市场状态分类用到的指标
+func regimeKlines(values ...[3]float64) []KlineWithIndicators {
+	klines := make([]KlineWithIndicators, len(values))
+	for i, v := range values {
+		klines[i].ADX14, klines[i].NATR14, klines[i].TrendSlope = v[0], v[1], v[2]
+	}
+	return klines
+}
+
+func TestClassifyRegimes(t *testing.T) {
+	cfg := DefaultRegimeConfig()
+	cfg.VolatilityLookback = 5
+	cfg.HighVolPercentile = 90
+
+	// 前4根为低波动背景，最后一根为被测K线：{ADX, NATR, 斜率}
+	background := [][3]float64{{10, 2, 0}, {10, 3, 0}, {10, 4, 0}, {10, 5, 0}}
+	tests := []struct {
+		name string
+		last [3]float64
+		want MarketRegime
+	}{
+		{"ADX 未就绪", [3]float64{0, 1, 0.5}, RegimeUnknown},
+		{"NATR 未就绪", [3]float64{30, 0, 0.5}, RegimeUnknown},
+		{"NATR 为窗口最高 → 高波动", [3]float64{30, 9, 0.5}, RegimeHighVolatility},
+		{"ADX 高、斜率为正 → 上升趋势", [3]float64{30, 1, 0.5}, RegimeTrendingUp},
+		{"ADX 高、斜率为负 → 下降趋势", [3]float64{30, 1, -0.5}, RegimeTrendingDown},
+		{"ADX 等于阈值 → 趋势", [3]float64{25, 1, 0.01}, RegimeTrendingUp},
+		{"ADX 高、斜率过小 → 震荡", [3]float64{30, 1, 0.005}, RegimeRanging},
+		{"ADX 低 → 震荡", [3]float64{15, 1, 0.5}, RegimeRanging},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			klines := regimeKlines(append(background, tt.last)...)
+			regimes := ClassifyRegimes(klines, cfg)
+			if len(regimes) != len(klines) {
+				t.Fatalf("返回 %d 个状态，应为 %d", len(regimes), len(klines))
+			}
+			last := len(klines) - 1
+			if regimes[last] != tt.want {
+				t.Errorf("状态 = %q，应为 %q", regimes[last], tt.want)
+			}
+			if klines[last].Regime != regimes[last] {
+				t.Errorf("klines[i].Regime = %q，没有写回 %q", klines[last].Regime, regimes[last])
+			}
+		})
+	}
+}
+
+func TestVolatilityPercentile(t *testing.T) {
+	klines := regimeKlines([3]float64{0, 0, 0}, [3]float64{0, 1, 0}, [3]float64{0, 2, 0}, [3]float64{0, 3, 0}, [3]float64{0, 2, 0})
+	tests := []struct {
+		index, n int
+		want     float64
+	}{
+		{0, 5, 0},    // 只有未就绪的K线
+		{3, 10, 100}, // 窗口超出开头，跳过 NATR 为 0 的K线
+		{4, 3, 2.0 / 3 * 100},
+		{4, 1, 100},
+	}
+	for _, tt := range tests {
+		if got := VolatilityPercentile(klines, tt.index, tt.n); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("VolatilityPercentile(%d, %d) = %v，应为 %v", tt.index, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestRegimeFilterAllows(t *testing.T) {
+	filter := MeanReversionFilter()
+	tests := []struct {
+		name   string
+		filter RegimeFilter
+		regime MarketRegime
+		signal SignalType
+		want   bool
+	}{
+		{"nil 过滤器允许所有信号", nil, RegimeHighVolatility, SignalShort, true},
+		{"未列出的状态不限制", filter, RegimeUnknown, SignalLong, true},
+		{"震荡做多", filter, RegimeRanging, SignalLong, true},
+		{"震荡做空", filter, RegimeRanging, SignalShort, true},
+		{"上升趋势做多", filter, RegimeTrendingUp, SignalLong, true},
+		{"上升趋势做空", filter, RegimeTrendingUp, SignalShort, false},
+		{"下降趋势做多", filter, RegimeTrendingDown, SignalLong, false},
+		{"下降趋势做空", filter, RegimeTrendingDown, SignalShort, true},
+		{"空切片屏蔽所有信号", filter, RegimeHighVolatility, SignalLong, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Allows(tt.regime, tt.signal); got != tt.want {
+				t.Errorf("Allows(%q, %q) = %v，应为 %v", tt.regime, tt.signal, got, tt.want)
+			}
+		})
+	}
+}
+
+// syntheticKlines 由收盘价函数生成每小时一根的K线，开盘价为上一根收盘价
+func syntheticKlines(n int, closeAt func(i int) float64) []KlineData {
+	klines := make([]KlineData, n)
+	for i := range klines {
+		c := closeAt(i)
+		open := c
+		if i > 0 {
+			open = klines[i-1].Close
+		}
+		klines[i] = KlineData{
+			OpenTime:  int64(i) * 3600000,
+			Open:      open,
+			High:      math.Max(open, c) * 1.003,
+			Low:       math.Min(open, c) * 0.997,
+			Close:     c,
+			CloseTime: int64(i+1)*3600000 - 1,
+		}
+	}
+	return klines
+}
+
+func TestScanSignalsWithFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		closeAt  func(i int) float64
+		majority MarketRegime // 大多数K线的市场状态
+		check    func(t *testing.T, filtered []*TradingSignal)
+	}{
+		{
+			name: "上升趋势中的回调",
+			closeAt: func(i int) float64 {
+				return 100 * math.Pow(1.005, float64(i)) * (1 + 0.06*math.Sin(float64(i)/4))
+			},
+			majority: RegimeTrendingUp,
+			check: func(t *testing.T, filtered []*TradingSignal) {
+				for _, s := range filtered {
+					if s.Type == SignalShort && s.Regime == RegimeTrendingUp {
+						t.Errorf("上升趋势中不应保留做空信号: %v", s)
+					}
+				}
+			},
+		},
+		{
+			name: "震荡",
+			closeAt: func(i int) float64 {
+				return 100 + 5*math.Sin(float64(i)/2.5) + 3*math.Sin(float64(i)/7)
+			},
+			majority: RegimeRanging,
+			check: func(t *testing.T, filtered []*TradingSignal) {
+				if len(filtered) == 0 {
+					t.Errorf("震荡行情应保留信号")
+				}
+				for _, s := range filtered {
+					if s.Regime == RegimeHighVolatility {
+						t.Errorf("高波动时不应保留信号: %v", s)
+					}
+				}
+			},
+		},
+	}
+
+	filter := MeanReversionFilter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			klines := CalculateIndicators(syntheticKlines(300, tt.closeAt))
+			regimes := ClassifyRegimes(klines, DefaultRegimeConfig())
+
+			count := 0
+			for _, r := range regimes[50:] {
+				if r == tt.majority {
+					count++
+				}
+			}
+			if count*2 <= len(regimes)-50 {
+				t.Errorf("%s 只有 %d/%d 根K线，应占多数", tt.majority, count, len(regimes)-50)
+			}
+
+			all := ScanSignals(klines)
+			if len(all) == 0 {
+				t.Fatalf("合成数据没有产生信号")
+			}
+			if nilFiltered := ScanSignalsWithFilter(klines, nil); len(nilFiltered) != len(all) {
+				t.Errorf("nil 过滤器返回 %d 个信号，应与 ScanSignals 相同 (%d)", len(nilFiltered), len(all))
+			}
+
+			// 过滤结果应正好是未过滤信号中 Allows 为 true 的部分，顺序不变
+			var want []*TradingSignal
+			for _, s := range all {
+				if filter.Allows(s.Regime, s.Type) {
+					want = append(want, s)
+				}
+			}
+			filtered := ScanSignalsWithFilter(klines, filter)
+			if len(filtered) != len(want) {
+				t.Fatalf("过滤后 %d 个信号，应为 %d", len(filtered), len(want))
+			}
+			for i := range want {
+				if filtered[i].Time != want[i].Time || filtered[i].Type != want[i].Type {
+					t.Errorf("第 %d 个信号 = %v，应为 %v", i, filtered[i], want[i])
+				}
+			}
+			if len(filtered) == len(all) {
+				t.Errorf("过滤器没有屏蔽任何信号（共 %d 个）", len(all))
+			}
+			tt.check(t, filtered)
+		})
+	}
+}
@@ -19,20 +19,21 @@ const (
 
 // TradingSignal 交易信号
 type TradingSignal struct {
-	Type          SignalType // 信号类型
-	Time          time.Time  // 信号时间
-	Price         float64    // 入场价格
-	StopLoss      float64    // 止损价格
-	RSI14         float64    // 当前RSI值
-	MACD          float64    // 当前MACD值
-	MACDSignal    float64    // 当前信号线值
-	RiskAmount    float64    // 风险金额（入场价 - 止损价）
-	RiskPercent   float64    // 风险百分比
+	Type        SignalType   // 信号类型
+	Time        time.Time    // 信号时间
+	Price       float64      // 入场价格
+	StopLoss    float64      // 止损价格
+	RSI14       float64      // 当前RSI值
+	MACD        float64      // 当前MACD值
+	MACDSignal  float64      // 当前信号线值
+	RiskAmount  float64      // 风险金额（入场价 - 止损价）
+	RiskPercent float64      // 风险百分比
+	Regime      MarketRegime // 信号K线的市场状态
 }
 
 // String 格式化输出信号
 func (s *TradingSignal) String() string {
-	return fmt.Sprintf("[%s] %s | 价格: %.2f | 止损: %.2f | 风险: %.2f (%.2f%%) | RSI: %.2f | MACD: %.4f",
+	str := fmt.Sprintf("[%s] %s | 价格: %.2f | 止损: %.2f | 风险: %.2f (%.2f%%) | RSI: %.2f | MACD: %.4f",
 		s.Type,
 		s.Time.In(BeijingLocation).Format("2006-01-02 15:04:05"),
 		s.Price,
@@ -42,6 +43,10 @@ func (s *TradingSignal) String() string {
 		s.RSI14,
 		s.MACD,
 	)
+	if s.Regime != RegimeUnknown {
+		str += fmt.Sprintf(" | 状态: %s", s.Regime)
+	}
+	return str
 }
 
 // CheckLongSignal 检测做多信号
@@ -79,15 +84,16 @@ func CheckLongSignal(klines []KlineWithIndicators, index int) *TradingSignal {
 	riskPercent := (riskAmount / current.Close) * 100
 
 	return &TradingSignal{
-		Type:         SignalLong,
-		Time:         time.UnixMilli(current.CloseTime),
-		Price:        current.Close,
-		StopLoss:     stopLoss,
-		RSI14:        current.RSI14,
-		MACD:         current.MACD,
-		MACDSignal:   current.MACDSignal,
-		RiskAmount:   riskAmount,
-		RiskPercent:  riskPercent,
+		Type:        SignalLong,
+		Time:        time.UnixMilli(current.CloseTime),
+		Price:       current.Close,
+		StopLoss:    stopLoss,
+		RSI14:       current.RSI14,
+		MACD:        current.MACD,
+		MACDSignal:  current.MACDSignal,
+		RiskAmount:  riskAmount,
+		RiskPercent: riskPercent,
+		Regime:      current.Regime,
 	}
 }
 
@@ -126,20 +132,27 @@ func CheckShortSignal(klines []KlineWithIndicators, index int) *TradingSignal {
 	riskPercent := (riskAmount / current.Close) * 100
 
 	return &TradingSignal{
-		Type:         SignalShort,
-		Time:         time.UnixMilli(current.CloseTime),
-		Price:        current.Close,
-		StopLoss:     stopLoss,
-		RSI14:        current.RSI14,
-		MACD:         current.MACD,
-		MACDSignal:   current.MACDSignal,
-		RiskAmount:   riskAmount,
-		RiskPercent:  riskPercent,
+		Type:        SignalShort,
+		Time:        time.UnixMilli(current.CloseTime),
+		Price:       current.Close,
+		StopLoss:    stopLoss,
+		RSI14:       current.RSI14,
+		MACD:        current.MACD,
+		MACDSignal:  current.MACDSignal,
+		RiskAmount:  riskAmount,
+		RiskPercent: riskPercent,
+		Regime:      current.Regime,
 	}
 }
 
 // ScanSignals 扫描所有K线，检测交易信号
 func ScanSignals(klines []KlineWithIndicators) []*TradingSignal {
+	return ScanSignalsWithFilter(klines, nil)
+}
+
+// ScanSignalsWithFilter 扫描所有K线，按K线的市场状态过滤信号
+// 需要先调用 ClassifyRegimes 标记市场状态；filter 为 nil 时不过滤
+func ScanSignalsWithFilter(klines []KlineWithIndicators, filter RegimeFilter) []*TradingSignal {
 	var signals []*TradingSignal
 
 	// 从第50根开始扫描（确保有足够的历史数据计算指标）
@@ -150,12 +163,12 @@ func ScanSignals(klines []KlineWithIndicators) []*TradingSignal {
 
 	for i := startIndex; i < len(klines); i++ {
 		// 检查做多信号
-		if signal := CheckLongSignal(klines, i); signal != nil {
+		if signal := CheckLongSignal(klines, i); signal != nil && filter.Allows(signal.Regime, signal.Type) {
 			signals = append(signals, signal)
 		}
 
 		// 检查做空信号
-		if signal := CheckShortSignal(klines, i); signal != nil {
+		if signal := CheckShortSignal(klines, i); signal != nil && filter.Allows(signal.Regime, signal.Type) {
 			signals = append(signals, signal)
 		}
 	}
@@ -203,6 +216,12 @@ func (d *DivergenceSignal) String() string {
 	)
 }
 
+// Strength 背离强度：两次信号之间RSI反向变化的幅度（RSI点数）
+// 价格创新低/新高的同时RSI回升/回落得越多，背离越明显
+func (d *DivergenceSignal) Strength() float64 {
+	return math.Abs(d.RSIChange)
+}
+
 // DetectDivergence 检测背离信号
 // 背离是指价格走势与技术指标走势相反的现象，是强烈的反转信号
 //
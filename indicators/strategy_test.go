@@ -0,0 +1,53 @@
+package indicators
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDivergenceStrength(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	signal := func(typ SignalType, minutes int, price, rsi, macd float64) *TradingSignal {
+		return &TradingSignal{Type: typ, Time: base.Add(time.Duration(minutes) * time.Minute), Price: price, RSI14: rsi, MACD: macd}
+	}
+
+	tests := []struct {
+		name    string
+		signals []*TradingSignal
+		typ     DivergenceType
+		want    float64
+	}{
+		{
+			name:    "看涨背离：价格新低，RSI 回升 6 点",
+			signals: []*TradingSignal{signal(SignalLong, 0, 100, 22, -3), signal(SignalLong, 15, 98, 28, -2)},
+			typ:     DivergenceBullish,
+			want:    6,
+		},
+		{
+			name:    "看跌背离：价格新高，RSI 回落 4.5 点（强度取绝对值）",
+			signals: []*TradingSignal{signal(SignalShort, 0, 100, 78, 3), signal(SignalShort, 20, 102, 73.5, 2)},
+			typ:     DivergenceBearish,
+			want:    4.5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			divergences := DetectDivergence(tt.signals)
+			if len(divergences) != 1 {
+				t.Fatalf("检测到 %d 个背离，应为 1", len(divergences))
+			}
+			d := divergences[0]
+			if d.Type != tt.typ {
+				t.Errorf("类型 = %s，应为 %s", d.Type, tt.typ)
+			}
+			if got := d.Strength(); got != tt.want {
+				t.Errorf("Strength() = %v，应为 %v", got, tt.want)
+			}
+		})
+	}
+
+	// 间隔超过 30 分钟不算背离
+	if d := DetectDivergence([]*TradingSignal{signal(SignalLong, 0, 100, 22, -3), signal(SignalLong, 45, 98, 28, -2)}); len(d) != 0 {
+		t.Errorf("间隔 45 分钟检测到 %d 个背离，应为 0", len(d))
+	}
+}
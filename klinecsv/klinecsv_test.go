@@ -0,0 +1,99 @@
+package klinecsv
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-kline/binance"
+	"binance-kline/indicators"
+)
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	var klines []binance.Kline
+	// 与 main.go 相同，按时间从新到旧保存
+	for i := 2; i >= 0; i-- {
+		open := base + int64(i)*900000
+		klines = append(klines, binance.Kline{
+			OpenTime: open, CloseTime: open + 899999,
+			Open: "100.5", High: "101.25", Low: "99", Close: "100.75", Volume: "12.5",
+			QuoteAssetVolume: "1250", NumberOfTrades: 42, TakerBuyBaseAssetVolume: "6", TakerBuyQuoteAssetVolume: "600",
+		})
+	}
+	klines[0].Close = "103"
+
+	filename := filepath.Join(t.TempDir(), "data", "BTCUSDT_15m.csv")
+	if err := Save(klines, filename, "BTCUSDT", "15m"); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, rowErrors, err := LoadFile(filename, Options{})
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("LoadFile: %v %v", err, rowErrors)
+	}
+	if len(loaded) != 3 {
+		t.Fatalf("加载 %d 根K线，应为 3", len(loaded))
+	}
+	for i, k := range loaded {
+		open := base + int64(i)*900000
+		// 时间按秒保存，收盘时间的毫秒部分会被截断
+		want := indicators.KlineData{OpenTime: open, Open: 100.5, High: 101.25, Low: 99, Close: 100.75, Volume: 12.5, CloseTime: open + 899000}
+		if i == 2 {
+			want.Close = 103
+		}
+		if k != want {
+			t.Errorf("第 %d 根 = %+v，应为 %+v", i, k, want)
+		}
+	}
+
+	// 时间范围过滤：[Start, End)
+	filtered, _, err := LoadFile(filename, Options{
+		Start: time.UnixMilli(base + 900000),
+		End:   time.UnixMilli(base + 2*900000),
+	})
+	if err != nil || len(filtered) != 1 || filtered[0].OpenTime != base+900000 {
+		t.Errorf("过滤后 = %+v, %v，应只有第 2 根", filtered, err)
+	}
+}
+
+func TestLoadRowErrors(t *testing.T) {
+	header := strings.Join(Header, ",")
+	rows := []string{
+		header,
+		"BTCUSDT,15m,2024-03-01 08:30:00,1,2,0.5,1.5,10,2024-03-01 08:44:59",    // 第2行
+		"BTCUSDT,15m,2024-03-01 08:15:00,1,x,0.5,1.5,10,2024-03-01 08:29:59",    // 第3行：最高价错误
+		"BTCUSDT,15m,2024-03-01 08:00:00,1,2",                                   // 第4行：字段不足
+		"BTCUSDT,15m,2024/03/01,1,2,0.5,1.5,10,2024-03-01 07:59:59",             // 第5行：时间格式错误
+		`BTCUSDT,15m,"2024-03-01 07:30:00"x,1,2,0.5,1.5,10,2024-03-01 07:44:59`, // 第6行：CSV 引号错误
+		"BTCUSDT,15m,2024-03-01 08:30:00,9,9,9,9,9,2024-03-01 08:44:59",         // 第7行：重复的开盘时间，保留第一条
+		"BTCUSDT,15m,2024-03-01 07:15:00,1,2,0.5,1.25,10,2024-03-01 07:29:59",   // 第8行
+	}
+	klines, rowErrors, err := Load(strings.NewReader(strings.Join(rows, "\n")+"\n"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []int
+	for _, e := range rowErrors {
+		lines = append(lines, e.Line)
+	}
+	if len(lines) != 4 || lines[0] != 3 || lines[1] != 4 || lines[2] != 5 || lines[3] != 6 {
+		t.Fatalf("错误行号 = %v，应为 [3 4 5 6]（%v）", lines, rowErrors)
+	}
+	if msg := rowErrors[0].Error(); !strings.HasPrefix(msg, "第 3 行: ") || !strings.Contains(msg, "最高价") {
+		t.Errorf("错误信息 = %q", msg)
+	}
+
+	if len(klines) != 2 {
+		t.Fatalf("加载 %d 根K线，应为 2", len(klines))
+	}
+	if klines[0].Close != 1.25 || klines[1].Close != 1.5 {
+		t.Errorf("klines = %+v，应按时间从旧到新排列并保留第一条重复记录", klines)
+	}
+
+	if _, _, err := Load(strings.NewReader(""), Options{}); err == nil {
+		t.Errorf("没有表头应返回错误")
+	}
+}
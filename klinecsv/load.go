@@ -0,0 +1,142 @@
+package klinecsv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"binance-kline/indicators"
+)
+
+// Header main.go 保存的K线CSV表头
+var Header = []string{
+	"交易对", "时间间隔", "开盘时间", "开盘价", "最高价", "最低价", "收盘价",
+	"成交量", "收盘时间", "成交额", "成交笔数", "主动买入量", "主动买入额",
+}
+
+// TimeLayout CSV中的时间格式（北京时间）
+const TimeLayout = "2006-01-02 15:04:05"
+
+// Options 加载选项
+type Options struct {
+	Start time.Time // 只保留开盘时间 >= Start 的K线，零值表示不限制
+	End   time.Time // 只保留开盘时间 < End 的K线，零值表示不限制
+}
+
+// RowError 单行解析错误，该行会被跳过
+type RowError struct {
+	Line int // CSV行号（表头为第1行）
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("第 %d 行: %v", e.Line, e.Err)
+}
+
+// LoadFile 从CSV文件加载K线，见 Load
+func LoadFile(filename string, opts Options) ([]indicators.KlineData, []*RowError, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	return Load(file, opts)
+}
+
+// Load 读取 main.go 格式的K线CSV
+// CSV 中的数据是从新到旧保存的，返回结果按开盘时间从旧到新排列，重复的开盘时间只保留一条。
+// 无法解析的行会跳过并在 []*RowError 中返回；只有文件本身无法读取时才返回 error。
+func Load(r io.Reader, opts Options) ([]indicators.KlineData, []*RowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	// 跳过表头
+	if _, err := reader.Read(); err != nil {
+		return nil, nil, fmt.Errorf("读取表头失败: %w", err)
+	}
+
+	var klines []indicators.KlineData
+	var rowErrors []*RowError
+	seen := make(map[int64]bool)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if parseErr, ok := err.(*csv.ParseError); ok {
+				rowErrors = append(rowErrors, &RowError{Line: parseErr.StartLine, Err: parseErr.Err})
+				continue
+			}
+			return nil, rowErrors, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		kline, err := parseRecord(record)
+		if err != nil {
+			rowErrors = append(rowErrors, &RowError{Line: line, Err: err})
+			continue
+		}
+
+		openTime := time.UnixMilli(kline.OpenTime)
+		if !opts.Start.IsZero() && openTime.Before(opts.Start) {
+			continue
+		}
+		if !opts.End.IsZero() && !openTime.Before(opts.End) {
+			continue
+		}
+
+		if seen[kline.OpenTime] {
+			continue
+		}
+		seen[kline.OpenTime] = true
+		klines = append(klines, kline)
+	}
+
+	sort.Slice(klines, func(i, j int) bool {
+		return klines[i].OpenTime < klines[j].OpenTime
+	})
+
+	return klines, rowErrors, nil
+}
+
+// parseRecord 解析一行：交易对,时间间隔,开盘时间,开盘价,最高价,最低价,收盘价,成交量,收盘时间,...
+func parseRecord(record []string) (indicators.KlineData, error) {
+	if len(record) < 9 {
+		return indicators.KlineData{}, fmt.Errorf("字段数量不足: %d", len(record))
+	}
+
+	openTime, err := time.ParseInLocation(TimeLayout, record[2], indicators.BeijingLocation)
+	if err != nil {
+		return indicators.KlineData{}, fmt.Errorf("开盘时间 %q 格式错误", record[2])
+	}
+	closeTime, err := time.ParseInLocation(TimeLayout, record[8], indicators.BeijingLocation)
+	if err != nil {
+		return indicators.KlineData{}, fmt.Errorf("收盘时间 %q 格式错误", record[8])
+	}
+
+	var values [5]float64
+	for i := range values {
+		v, err := strconv.ParseFloat(record[3+i], 64)
+		if err != nil {
+			return indicators.KlineData{}, fmt.Errorf("%s %q 格式错误", Header[3+i], record[3+i])
+		}
+		values[i] = v
+	}
+
+	return indicators.KlineData{
+		OpenTime:  openTime.UnixMilli(),
+		Open:      values[0],
+		High:      values[1],
+		Low:       values[2],
+		Close:     values[3],
+		Volume:    values[4],
+		CloseTime: closeTime.UnixMilli(),
+	}, nil
+}
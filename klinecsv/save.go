@@ -0,0 +1,63 @@
+package klinecsv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"binance-kline/binance"
+	"binance-kline/indicators"
+)
+
+// Save 将K线保存为CSV文件（覆盖已有文件），行顺序与传入顺序一致
+func Save(klines []binance.Kline, filename string, symbol string, interval string) error {
+	// 确保目录存在
+	dir := filepath.Dir(filename)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("创建目录失败: %w", err)
+		}
+	}
+
+	// 以覆盖模式创建文件（而不是追加模式）
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("创建文件失败: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	// 写入表头
+	if err := writer.Write(Header); err != nil {
+		return fmt.Errorf("写入表头失败: %w", err)
+	}
+
+	// 写入数据
+	for _, kline := range klines {
+		record := []string{
+			symbol,
+			interval,
+			time.UnixMilli(kline.OpenTime).In(indicators.BeijingLocation).Format(TimeLayout),
+			kline.Open,
+			kline.High,
+			kline.Low,
+			kline.Close,
+			kline.Volume,
+			time.UnixMilli(kline.CloseTime).In(indicators.BeijingLocation).Format(TimeLayout),
+			kline.QuoteAssetVolume,
+			strconv.Itoa(kline.NumberOfTrades),
+			kline.TakerBuyBaseAssetVolume,
+			kline.TakerBuyQuoteAssetVolume,
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("写入数据失败: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Config 告警渠道配置（JSON文件），未配置的渠道不启用
+//
+//	{
+//	  "webhook":  {"url": "https://example.com/hook"},
+//	  "telegram": {"token": "123:abc", "chatId": "-100123"},
+//	  "email":    {"host": "smtp.example.com", "port": 587, "username": "u", "password": "p",
+//	               "from": "bot@example.com", "to": ["me@example.com"]}
+//	}
+type Config struct {
+	Webhook *struct {
+		URL string `json:"url"`
+	} `json:"webhook"`
+	Telegram *struct {
+		BaseURL string `json:"baseUrl"`
+		Token   string `json:"token"`
+		ChatID  string `json:"chatId"`
+	} `json:"telegram"`
+	Email *struct {
+		Host     string   `json:"host"`
+		Port     int      `json:"port"`
+		Username string   `json:"username"`
+		Password string   `json:"password"`
+		From     string   `json:"from"`
+		To       []string `json:"to"`
+	} `json:"email"`
+}
+
+// LoadNotifiers 读取配置文件并创建所有已配置的渠道
+func LoadNotifiers(path string) ([]Notifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取告警配置失败: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析告警配置失败: %w", err)
+	}
+
+	var notifiers []Notifier
+	if cfg.Webhook != nil && cfg.Webhook.URL != "" {
+		notifiers = append(notifiers, &WebhookNotifier{URL: cfg.Webhook.URL})
+	}
+	if cfg.Telegram != nil && cfg.Telegram.Token != "" {
+		notifiers = append(notifiers, &TelegramNotifier{
+			BaseURL: cfg.Telegram.BaseURL,
+			Token:   cfg.Telegram.Token,
+			ChatID:  cfg.Telegram.ChatID,
+		})
+	}
+	if cfg.Email != nil && cfg.Email.Host != "" {
+		port := cfg.Email.Port
+		if port == 0 {
+			port = 25
+		}
+		notifiers = append(notifiers, &EmailNotifier{
+			Host:     cfg.Email.Host,
+			Port:     port,
+			Username: cfg.Email.Username,
+			Password: cfg.Email.Password,
+			From:     cfg.Email.From,
+			To:       cfg.Email.To,
+		})
+	}
+
+	if len(notifiers) == 0 {
+		return nil, fmt.Errorf("告警配置 %s 中没有启用任何渠道", path)
+	}
+
+	return notifiers, nil
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailNotifier 通过 SMTP 发送告警邮件
+type EmailNotifier struct {
+	Host     string
+	Port     int
+	Username string // 为空时不做认证
+	Password string
+	From     string
+	To       []string
+}
+
+func (e *EmailNotifier) Name() string {
+	return "email"
+}
+
+// Notify 发送告警邮件，主题为告警标题
+func (e *EmailNotifier) Notify(alert Alert) error {
+	addr := net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		auth = smtp.PlainAuth("", e.Username, e.Password, e.Host)
+	}
+
+	if err := smtp.SendMail(addr, auth, e.From, e.To, e.buildMessage(alert)); err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	return nil
+}
+
+// buildMessage 构造 RFC 5322 邮件内容
+func (e *EmailNotifier) buildMessage(alert Alert) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + e.From + "\r\n")
+	b.WriteString("To: " + strings.Join(e.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", alert.Title) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(alert.Text, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/base64"
+	"mime"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// smtpSession 模拟 SMTP 服务器收到的一次投递
+type smtpSession struct {
+	auth string // AUTH PLAIN 的凭证（解码后）
+	from string
+	to   []string
+	data string
+}
+
+// smtpStub 在本地端口运行最小的 SMTP 服务器，只处理一个连接。rejectRcpt 中的收件人返回 550
+func smtpStub(t *testing.T, rejectRcpt string) (host string, port int, done <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	ch := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		var s smtpSession
+		defer func() { ch <- s }()
+
+		tp.PrintfLine("220 stub ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(cmd) {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250-stub")
+				tp.PrintfLine("250 AUTH PLAIN")
+			case "AUTH":
+				_, encoded, _ := strings.Cut(arg, " ")
+				decoded, _ := base64.StdEncoding.DecodeString(encoded)
+				s.auth = string(decoded)
+				tp.PrintfLine("235 ok")
+			case "MAIL":
+				s.from = arg
+				tp.PrintfLine("250 ok")
+			case "RCPT":
+				if rejectRcpt != "" && strings.Contains(arg, rejectRcpt) {
+					tp.PrintfLine("550 no such user")
+					continue
+				}
+				s.to = append(s.to, arg)
+				tp.PrintfLine("250 ok")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				// 按原样读取到结束行，保留 CRLF
+				var data strings.Builder
+				for {
+					raw, err := tp.R.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if raw == ".\r\n" {
+						break
+					}
+					data.WriteString(raw)
+				}
+				s.data = data.String()
+				tp.PrintfLine("250 queued")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("250 ok")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, ch
+}
+
+func TestEmailNotifier(t *testing.T) {
+	host, port, done := smtpStub(t, "")
+	n := &EmailNotifier{
+		Host: host, Port: port, Username: "bot", Password: "secret",
+		From: "bot@example.com", To: []string{"a@example.com", "b@example.com"},
+	}
+	if err := n.Notify(testAlert()); err != nil {
+		t.Fatal(err)
+	}
+	s := <-done
+
+	if s.auth != "\x00bot\x00secret" {
+		t.Errorf("AUTH PLAIN = %q", s.auth)
+	}
+	if s.from != "FROM:<bot@example.com>" {
+		t.Errorf("MAIL = %q", s.from)
+	}
+	if strings.Join(s.to, ",") != "TO:<a@example.com>,TO:<b@example.com>" {
+		t.Errorf("RCPT = %q", s.to)
+	}
+
+	msg, err := textproto.NewReader(bufio.NewReader(strings.NewReader(s.data))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("解析邮件头失败: %v\n%s", err, s.data)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Get("Subject"))
+	if err != nil || subject != "BTCUSDT 15m LONG信号" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+	if msg.Get("To") != "a@example.com, b@example.com" || msg.Get("Content-Type") != "text/plain; charset=UTF-8" {
+		t.Errorf("邮件头 = %v", msg)
+	}
+	if _, body, _ := strings.Cut(s.data, "\r\n\r\n"); body != "入场 42000\r\n止损 41500\r\n" {
+		t.Errorf("正文 = %q，换行应为 CRLF", body)
+	}
+}
+
+func TestEmailNotifierRejected(t *testing.T) {
+	host, port, _ := smtpStub(t, "nobody@")
+	n := &EmailNotifier{Host: host, Port: port, From: "bot@example.com", To: []string{"nobody@example.com"}}
+	err := n.Notify(testAlert())
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("收件人被拒绝时错误 = %v，应包含 550", err)
+	}
+
+	// 端口上没有服务器
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed, _ := strconv.Atoi(strings.TrimPrefix(ln.Addr().String(), "127.0.0.1:"))
+	ln.Close()
+	if err := (&EmailNotifier{Host: "127.0.0.1", Port: closed, From: "a@b", To: []string{"c@d"}}).Notify(testAlert()); err == nil {
+		t.Errorf("连接失败应返回错误")
+	}
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"binance-kline/indicators"
+)
+
+// Alert 一条待发送的告警
+type Alert struct {
+	Key      string    `json:"key"`      // 去重键，同一信号在多次运行中保持不变
+	Title    string    `json:"title"`    // 标题
+	Text     string    `json:"text"`     // 正文
+	Symbol   string    `json:"symbol"`   // 交易对
+	Interval string    `json:"interval"` // K线间隔
+	Time     time.Time `json:"time"`     // 信号时间
+}
+
+// Notifier 告警发送渠道
+type Notifier interface {
+	Name() string
+	Notify(alert Alert) error
+}
+
+// SignalAlert 由交易信号生成告警
+func SignalAlert(symbol, interval string, s *indicators.TradingSignal) Alert {
+	return Alert{
+		Key:      fmt.Sprintf("signal|%s|%s|%s|%d", symbol, interval, s.Type, s.Time.Unix()),
+		Title:    fmt.Sprintf("%s %s %s信号", symbol, interval, s.Type),
+		Text:     s.String(),
+		Symbol:   symbol,
+		Interval: interval,
+		Time:     s.Time,
+	}
+}
+
+// DivergenceAlert 由背离信号生成告警
+func DivergenceAlert(symbol, interval string, d *indicators.DivergenceSignal) Alert {
+	return Alert{
+		Key: fmt.Sprintf("divergence|%s|%s|%s|%d|%d", symbol, interval, d.Type,
+			d.FirstSignal.Time.Unix(), d.SecondSignal.Time.Unix()),
+		Title:    fmt.Sprintf("%s %s %s背离", symbol, interval, d.Type),
+		Text:     d.String(),
+		Symbol:   symbol,
+		Interval: interval,
+		Time:     d.SecondSignal.Time,
+	}
+}
+
+// Dispatcher 将告警发送到所有渠道，并按渠道去重
+type Dispatcher struct {
+	Notifiers []Notifier
+	State     *State // 为 nil 时不去重
+}
+
+// Send 发送尚未发送过的告警，返回成功发送的条数
+// 每个渠道独立记录发送状态：某个渠道失败不会导致其他渠道重复发送，失败的渠道下次运行时重试
+func (d *Dispatcher) Send(alerts []Alert) (int, error) {
+	var errs []error
+	sent := 0
+
+	for _, alert := range alerts {
+		for _, n := range d.Notifiers {
+			key := n.Name() + "|" + alert.Key
+			if d.State != nil && d.State.Seen(key) {
+				continue
+			}
+
+			if err := n.Notify(alert); err != nil {
+				errs = append(errs, fmt.Errorf("%s 发送失败: %w", n.Name(), err))
+				continue
+			}
+
+			sent++
+			if d.State != nil {
+				d.State.Mark(key, time.Now())
+			}
+		}
+	}
+
+	if d.State != nil {
+		if err := d.State.Save(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return sent, errors.Join(errs...)
+}
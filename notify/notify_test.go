@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeNotifier 记录收到的告警，fail 为 true 时返回错误
+type fakeNotifier struct {
+	name string
+	fail bool
+	got  []string
+}
+
+func (f *fakeNotifier) Name() string { return f.name }
+
+func (f *fakeNotifier) Notify(alert Alert) error {
+	if f.fail {
+		return errors.New("unavailable")
+	}
+	f.got = append(f.got, alert.Key)
+	return nil
+}
+
+func alerts(keys ...string) []Alert {
+	var out []Alert
+	for _, k := range keys {
+		out = append(out, Alert{Key: k, Title: k})
+	}
+	return out
+}
+
+func TestDispatcherDedupePerChannel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "notify.json")
+	state, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhook := &fakeNotifier{name: "webhook"}
+	telegram := &fakeNotifier{name: "telegram", fail: true}
+	d := &Dispatcher{Notifiers: []Notifier{webhook, telegram}, State: state}
+
+	// 第一次：telegram 失败，webhook 成功
+	sent, err := d.Send(alerts("a", "b"))
+	if sent != 2 || err == nil || !strings.Contains(err.Error(), "telegram 发送失败") {
+		t.Fatalf("Send = %d, %v；应成功 2 条并返回 telegram 的错误", sent, err)
+	}
+
+	// 第二次（新进程重新读取状态）：telegram 恢复，只补发 telegram；新告警 c 两个渠道都发送
+	state, err = LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	telegram.fail = false
+	d.State = state
+	sent, err = d.Send(alerts("a", "b", "c"))
+	if err != nil || sent != 4 {
+		t.Fatalf("Send = %d, %v；应发送 4 条", sent, err)
+	}
+	if got := strings.Join(webhook.got, ","); got != "a,b,c" {
+		t.Errorf("webhook 收到 %s，应为 a,b,c（不重复）", got)
+	}
+	if got := strings.Join(telegram.got, ","); got != "a,b,c" {
+		t.Errorf("telegram 收到 %s，应为 a,b,c", got)
+	}
+
+	// 第三次：全部已发送
+	if sent, err := d.Send(alerts("a", "b", "c")); sent != 0 || err != nil {
+		t.Errorf("重复发送 = %d, %v，应为 0", sent, err)
+	}
+
+	// 同一批中重复的告警只发送一次
+	if sent, _ := d.Send(alerts("d", "d")); sent != 2 {
+		t.Errorf("同一批重复告警发送 %d 条，应为 2（每个渠道一次）", sent)
+	}
+}
+
+func TestDispatcherWithoutState(t *testing.T) {
+	n := &fakeNotifier{name: "webhook"}
+	d := &Dispatcher{Notifiers: []Notifier{n}}
+	d.Send(alerts("a"))
+	d.Send(alerts("a"))
+	if len(n.got) != 2 {
+		t.Errorf("没有状态时发送 %d 次，应不去重（2 次）", len(n.got))
+	}
+}
+
+func TestStateSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "notify.json")
+
+	state, err := LoadState(path)
+	if err != nil || len(state.Sent) != 0 {
+		t.Fatalf("不存在的文件应返回空状态: %v, %v", state, err)
+	}
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	state.Mark("webhook|old", old)
+	state.Mark("webhook|recent", recent)
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("保存后不应留下临时文件: %v", err)
+	}
+
+	loaded, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Seen("webhook|old") || !loaded.Sent["webhook|recent"].Equal(recent) {
+		t.Errorf("重新读取的状态 = %v", loaded.Sent)
+	}
+
+	loaded.Prune(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	if loaded.Seen("webhook|old") || !loaded.Seen("webhook|recent") {
+		t.Errorf("Prune 后 = %v，应只保留 recent", loaded.Sent)
+	}
+
+	// 覆盖已有文件
+	if err := loaded.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := LoadState(path); len(again.Sent) != 1 {
+		t.Errorf("覆盖保存后 = %v", again.Sent)
+	}
+
+	// 损坏的文件
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadState(path); err == nil {
+		t.Errorf("损坏的状态文件应返回错误")
+	}
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// State 已发送告警的持久化记录，用于跨进程去重
+type State struct {
+	path string
+	mu   sync.Mutex
+	Sent map[string]time.Time `json:"sent"`
+}
+
+// LoadState 读取状态文件，文件不存在时返回空状态
+func LoadState(path string) (*State, error) {
+	state := &State{path: path, Sent: make(map[string]time.Time)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("读取状态文件失败: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("解析状态文件失败: %w", err)
+	}
+	if state.Sent == nil {
+		state.Sent = make(map[string]time.Time)
+	}
+
+	return state, nil
+}
+
+// Seen 判断告警是否已发送
+func (s *State) Seen(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.Sent[key]
+	return ok
+}
+
+// Mark 记录告警已发送
+func (s *State) Mark(key string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Sent[key] = at
+}
+
+// Prune 删除早于指定时间的发送记录，避免状态文件无限增长
+func (s *State) Prune(before time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, at := range s.Sent {
+		if at.Before(before) {
+			delete(s.Sent, key)
+		}
+	}
+}
+
+// Save 写入状态文件（先写临时文件再重命名，避免中途退出损坏文件）
+func (s *State) Save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("序列化状态失败: %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("创建目录失败: %w", err)
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入状态文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("替换状态文件失败: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TelegramBaseURL Telegram Bot API 地址
+const TelegramBaseURL = "https://api.telegram.org"
+
+// TelegramNotifier 通过 Telegram Bot API 的 sendMessage 发送告警
+type TelegramNotifier struct {
+	BaseURL string // 为空时使用 TelegramBaseURL
+	Token   string // Bot token
+	ChatID  string // 接收消息的 chat_id
+	Client  *http.Client
+}
+
+func (t *TelegramNotifier) Name() string {
+	return "telegram"
+}
+
+// Notify 发送告警，消息为标题加正文
+func (t *TelegramNotifier) Notify(alert Alert) error {
+	baseURL := t.BaseURL
+	if baseURL == "" {
+		baseURL = TelegramBaseURL
+	}
+	url := fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimRight(baseURL, "/"), t.Token)
+
+	body, err := json.Marshal(map[string]string{
+		"chat_id": t.ChatID,
+		"text":    alert.Title + "\n" + alert.Text,
+	})
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+
+	return postJSON(t.Client, url, body)
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTelegramNotifier(t *testing.T) {
+	var (
+		gotPath string
+		got     map[string]string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("解析请求体失败: %v", err)
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	// BaseURL 末尾的 / 应被去掉
+	n := &TelegramNotifier{BaseURL: srv.URL + "/", Token: "123:abc", ChatID: "-100123", Client: srv.Client()}
+	if err := n.Notify(testAlert()); err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/bot123:abc/sendMessage" {
+		t.Errorf("路径 = %q", gotPath)
+	}
+	want := map[string]string{"chat_id": "-100123", "text": "BTCUSDT 15m LONG信号\n入场 42000\n止损 41500"}
+	if len(got) != len(want) || got["chat_id"] != want["chat_id"] || got["text"] != want["text"] {
+		t.Errorf("请求体 = %q，应为 %q", got, want)
+	}
+}
+
+func TestTelegramNotifierErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`))
+	}))
+	defer srv.Close()
+
+	err := (&TelegramNotifier{BaseURL: srv.URL, Token: "t", ChatID: "1", Client: srv.Client()}).Notify(testAlert())
+	if err == nil {
+		t.Fatal("403 应返回错误")
+	}
+	if !strings.Contains(err.Error(), "bot was blocked") || !strings.Contains(err.Error(), "403") {
+		t.Errorf("错误 = %v，应包含 Telegram 返回的描述和状态码", err)
+	}
+}
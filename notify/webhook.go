@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// defaultClient 各HTTP渠道默认使用的客户端
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// WebhookNotifier 以 JSON POST 方式把告警发送到任意 URL
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client // 为 nil 时使用默认客户端
+}
+
+func (w *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+// Notify 发送告警，请求体为 Alert 的 JSON
+func (w *WebhookNotifier) Notify(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("序列化告警失败: %w", err)
+	}
+
+	return postJSON(w.Client, w.URL, body)
+}
+
+// postJSON 发送 JSON 请求，非 2xx 状态码视为失败
+func postJSON(client *http.Client, url string, body []byte) error {
+	if client == nil {
+		client = defaultClient
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("返回错误: %s, 状态码: %d", string(respBody), resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testAlert() Alert {
+	return Alert{
+		Key:      "signal|BTCUSDT|15m|LONG|1700000000",
+		Title:    "BTCUSDT 15m LONG信号",
+		Text:     "入场 42000\n止损 41500",
+		Symbol:   "BTCUSDT",
+		Interval: "15m",
+		Time:     time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC),
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var (
+		gotType string
+		got     Alert
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("方法 = %s，应为 POST", r.Method)
+		}
+		gotType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("解析请求体失败: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	alert := testAlert()
+	n := &WebhookNotifier{URL: srv.URL + "/hook", Client: srv.Client()}
+	if err := n.Notify(alert); err != nil {
+		t.Fatal(err)
+	}
+	if gotType != "application/json" {
+		t.Errorf("Content-Type = %q", gotType)
+	}
+	if got != alert {
+		t.Errorf("请求体 = %+v，应为 %+v", got, alert)
+	}
+}
+
+func TestWebhookNotifierErrorStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+	}{
+		{http.StatusBadRequest, "bad payload"},
+		{http.StatusInternalServerError, "boom"},
+		{http.StatusMovedPermanently, ""}, // 3xx 不跟随（没有 Location），也视为失败
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+			w.WriteHeader(tt.status)
+			io.WriteString(w, tt.body)
+		}))
+		err := (&WebhookNotifier{URL: srv.URL, Client: srv.Client()}).Notify(testAlert())
+		srv.Close()
+
+		if err == nil {
+			t.Errorf("状态码 %d 应返回错误", tt.status)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.body) || !strings.Contains(err.Error(), "状态码: "+strconv.Itoa(tt.status)) {
+			t.Errorf("状态码 %d 的错误 = %v，应包含响应内容和状态码", tt.status, err)
+		}
+	}
+
+	// 连接失败
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+	if err := (&WebhookNotifier{URL: url}).Notify(testAlert()); err == nil {
+		t.Errorf("连接失败应返回错误")
+	}
+}
@@ -0,0 +1,281 @@
+package scanner
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"binance-kline/binance"
+	"binance-kline/indicators"
+)
+
+// Config 扫描参数
+type Config struct {
+	Interval     string                  // K线间隔
+	Bars         int                     // 每个交易对获取的K线数量
+	RecentBars   int                     // 只保留最近N根已收盘K线内的信号
+	Workers      int                     // 并发请求数
+	RegimeFilter indicators.RegimeFilter // 市场状态过滤器，nil 表示不过滤
+}
+
+// Result 单个新信号的扫描结果
+type Result struct {
+	Symbol     string
+	Signal     *indicators.TradingSignal
+	Divergence *indicators.DivergenceSignal // 以该信号为第二信号的背离，没有则为nil
+	BarsAgo    int                          // 信号K线距最新已收盘K线的根数（0表示最新一根）
+}
+
+// DivergenceStrength 背离强度，无背离时为0
+func (r *Result) DivergenceStrength() float64 {
+	if r.Divergence == nil {
+		return 0
+	}
+	return r.Divergence.Strength()
+}
+
+// SymbolError 单个交易对的扫描错误
+type SymbolError struct {
+	Symbol string
+	Err    error
+}
+
+func (e *SymbolError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Symbol, e.Err)
+}
+
+// Scan 并发扫描多个交易对，返回排序后的新信号和各交易对的错误
+func Scan(symbols []string, cfg Config) ([]Result, []error) {
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	jobs := make(chan string)
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results []Result
+		errs    []error
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for symbol := range jobs {
+				symbolResults, err := scanSymbol(symbol, cfg)
+
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, &SymbolError{Symbol: symbol, Err: err})
+				} else {
+					results = append(results, symbolResults...)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, symbol := range symbols {
+		jobs <- symbol
+	}
+	close(jobs)
+	wg.Wait()
+
+	Rank(results)
+	return results, errs
+}
+
+// scanSymbol 获取单个交易对的K线并分析
+func scanSymbol(symbol string, cfg Config) ([]Result, error) {
+	var klines []binance.Kline
+	var err error
+	if cfg.Bars <= 1000 {
+		klines, err = binance.GetKlines(symbol, cfg.Interval, 0, 0, cfg.Bars)
+	} else {
+		klines, err = binance.GetKlinesBatch(symbol, cfg.Interval, cfg.Bars, nil) // 并发获取，不输出每批进度
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := binance.ToKlineData(klines)
+	if err != nil {
+		return nil, err
+	}
+
+	return Analyze(symbol, ClosedOnly(data, time.Now()), cfg), nil
+}
+
+// ClosedOnly 去掉尚未收盘的K线（最新一根K线在收盘前数值会变化，金叉死叉可能消失）
+func ClosedOnly(data []indicators.KlineData, now time.Time) []indicators.KlineData {
+	nowMs := now.UnixMilli()
+	for len(data) > 0 && data[len(data)-1].CloseTime >= nowMs {
+		data = data[:len(data)-1]
+	}
+	return data
+}
+
+// Analyze 对按时间从旧到新排列的已收盘K线计算指标、扫描信号和背离，
+// 返回最近 cfg.RecentBars 根K线内出现的信号
+func Analyze(symbol string, data []indicators.KlineData, cfg Config) []Result {
+	klines := indicators.CalculateIndicators(data)
+	if klines == nil {
+		return nil
+	}
+
+	indicators.ClassifyRegimes(klines, indicators.DefaultRegimeConfig())
+	signals := indicators.ScanSignalsWithFilter(klines, cfg.RegimeFilter)
+	divergences := indicators.DetectDivergence(signals)
+
+	divergenceBySignal := make(map[*indicators.TradingSignal]*indicators.DivergenceSignal)
+	for _, div := range divergences {
+		divergenceBySignal[div.SecondSignal] = div
+	}
+
+	// 信号时间为K线收盘时间
+	indexByCloseTime := make(map[int64]int, len(klines))
+	for i, k := range klines {
+		indexByCloseTime[k.CloseTime] = i
+	}
+
+	last := len(klines) - 1
+	var results []Result
+	for _, signal := range signals {
+		index, ok := indexByCloseTime[signal.Time.UnixMilli()]
+		if !ok || last-index >= cfg.RecentBars {
+			continue
+		}
+
+		results = append(results, Result{
+			Symbol:     symbol,
+			Signal:     signal,
+			Divergence: divergenceBySignal[signal],
+			BarsAgo:    last - index,
+		})
+	}
+
+	return results
+}
+
+// Rank 对信号排序：
+// 1. 有背离的信号优先，背离强度高的在前
+// 2. 越新的信号越靠前
+// 3. RSI 偏离50越远越靠前
+// 4. 风险百分比越小越靠前
+func Rank(results []Result) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := &results[i], &results[j]
+
+		if (a.Divergence != nil) != (b.Divergence != nil) {
+			return a.Divergence != nil
+		}
+		if a.DivergenceStrength() != b.DivergenceStrength() {
+			return a.DivergenceStrength() > b.DivergenceStrength()
+		}
+		if a.BarsAgo != b.BarsAgo {
+			return a.BarsAgo < b.BarsAgo
+		}
+
+		extremeA := math.Abs(a.Signal.RSI14 - 50)
+		extremeB := math.Abs(b.Signal.RSI14 - 50)
+		if extremeA != extremeB {
+			return extremeA > extremeB
+		}
+		return a.Signal.RiskPercent < b.Signal.RiskPercent
+	})
+}
+
+// PrintTable 打印排名表
+func PrintTable(results []Result) {
+	fmt.Printf("%-4s %-12s %-6s %-17s %6s %12s %12s %8s %7s %8s %-16s\n",
+		"排名", "交易对", "方向", "时间(北京)", "K线前", "价格", "止损", "RSI14", "风险%", "背离强度", "市场状态")
+	fmt.Println("----------------------------------------------------------------------------------------------------------------------")
+
+	for i, r := range results {
+		divergence := "-"
+		if r.Divergence != nil {
+			divergence = fmt.Sprintf("%.2f", r.DivergenceStrength())
+		}
+		regime := string(r.Signal.Regime)
+		if regime == "" {
+			regime = "-"
+		}
+
+		fmt.Printf("%-4d %-12s %-6s %-17s %6d %12.4f %12.4f %8.2f %7.2f %8s %-16s\n",
+			i+1,
+			r.Symbol,
+			r.Signal.Type,
+			r.Signal.Time.In(indicators.BeijingLocation).Format("2006-01-02 15:04"),
+			r.BarsAgo,
+			r.Signal.Price,
+			r.Signal.StopLoss,
+			r.Signal.RSI14,
+			r.Signal.RiskPercent,
+			divergence,
+			regime,
+		)
+	}
+}
+
+// SaveToCSV 导出排名表
+func SaveToCSV(results []Result, filename string) error {
+	dir := filepath.Dir(filename)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("创建目录失败: %w", err)
+		}
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("创建文件失败: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{
+		"排名", "交易对", "方向", "信号时间", "K线前", "价格", "止损",
+		"RSI14", "MACD", "风险%", "背离类型", "背离强度", "市场状态",
+	}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("写入表头失败: %w", err)
+	}
+
+	for i, r := range results {
+		divergenceType := ""
+		if r.Divergence != nil {
+			divergenceType = string(r.Divergence.Type)
+		}
+
+		record := []string{
+			strconv.Itoa(i + 1),
+			r.Symbol,
+			string(r.Signal.Type),
+			r.Signal.Time.In(indicators.BeijingLocation).Format("2006-01-02 15:04:05"),
+			strconv.Itoa(r.BarsAgo),
+			strconv.FormatFloat(r.Signal.Price, 'f', -1, 64),
+			strconv.FormatFloat(r.Signal.StopLoss, 'f', -1, 64),
+			fmt.Sprintf("%.2f", r.Signal.RSI14),
+			fmt.Sprintf("%.6f", r.Signal.MACD),
+			fmt.Sprintf("%.4f", r.Signal.RiskPercent),
+			divergenceType,
+			fmt.Sprintf("%.2f", r.DivergenceStrength()),
+			string(r.Signal.Regime),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("写入数据失败: %w", err)
+		}
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,145 @@
+package scanner
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"binance-kline/indicators"
+)
+
+// hourlyKlines 由收盘价函数生成每小时一根的K线，开盘价为上一根收盘价
+func hourlyKlines(n int, closeAt func(i int) float64) []indicators.KlineData {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	klines := make([]indicators.KlineData, n)
+	for i := range klines {
+		c := closeAt(i)
+		open := c
+		if i > 0 {
+			open = klines[i-1].Close
+		}
+		klines[i] = indicators.KlineData{
+			OpenTime:  base + int64(i)*3600000,
+			Open:      open,
+			High:      math.Max(open, c) * 1.003,
+			Low:       math.Min(open, c) * 0.997,
+			Close:     c,
+			CloseTime: base + int64(i+1)*3600000 - 1,
+		}
+	}
+	return klines
+}
+
+// oscillating 震荡行情，会产生多空信号
+func oscillating(i int) float64 {
+	return 100 + 8*math.Sin(float64(i)/3) + 3*math.Sin(float64(i)/11)
+}
+
+func TestRank(t *testing.T) {
+	result := func(name string, strength float64, barsAgo int, rsi, risk float64) Result {
+		r := Result{Symbol: name, BarsAgo: barsAgo, Signal: &indicators.TradingSignal{RSI14: rsi, RiskPercent: risk}}
+		if strength != 0 {
+			r.Divergence = &indicators.DivergenceSignal{RSIChange: -strength}
+		}
+		return r
+	}
+	results := []Result{
+		result("新信号", 0, 0, 40, 1),
+		result("RSI 更极端", 0, 0, 25, 2),
+		result("风险更小", 0, 0, 75, 0.5),
+		result("弱背离", 2, 3, 50, 1),
+		result("旧信号", 0, 2, 10, 1),
+		result("强背离", 6, 5, 50, 1),
+		result("并列 A", 0, 1, 30, 1),
+		result("并列 B", 0, 1, 70, 1),
+	}
+
+	Rank(results)
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Symbol)
+	}
+	want := []string{
+		// 有背离的在前，按强度（RSI 变化的绝对值）排序，不看信号新旧
+		"强背离", "弱背离",
+		// 越新越靠前；同一根K线 RSI 偏离 50 越远越靠前，偏离相同时风险越小越靠前
+		"风险更小", "RSI 更极端", "新信号",
+		// 完全相同时保持原顺序
+		"并列 A", "并列 B",
+		"旧信号",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("排序 = %v\n应为 %v", got, want)
+	}
+}
+
+func TestClosedOnly(t *testing.T) {
+	klines := hourlyKlines(5, func(i int) float64 { return 100 })
+	closeOf := func(i int) time.Time { return time.UnixMilli(klines[i].CloseTime) }
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want int
+	}{
+		{"全部已收盘", closeOf(4).Add(time.Millisecond), 5},
+		// 收盘时间为 hh:59:59.999，正好在这一刻仍未收盘
+		{"最后一根正好在收盘时间", closeOf(4), 4},
+		{"最后一根未收盘", closeOf(3).Add(time.Minute), 4},
+		{"末尾两根都未收盘", closeOf(2).Add(time.Millisecond), 3},
+		{"全部未收盘", time.UnixMilli(klines[0].OpenTime), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClosedOnly(klines, tt.now)
+			if len(got) != tt.want {
+				t.Errorf("保留 %d 根，应为 %d 根", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	// 少于 MinKlines 根时无法计算指标
+	if results := Analyze("BTCUSDT", hourlyKlines(indicators.MinKlines-1, oscillating), Config{RecentBars: 100}); results != nil {
+		t.Errorf("%d 根K线得到 %d 个结果，应为 nil", indicators.MinKlines-1, len(results))
+	}
+
+	data := hourlyKlines(200, oscillating)
+	all := Analyze("BTCUSDT", data, Config{RecentBars: len(data)})
+	if len(all) == 0 {
+		t.Fatal("震荡行情应产生信号")
+	}
+	last := len(data) - 1
+	for _, r := range all {
+		if r.Symbol != "BTCUSDT" {
+			t.Errorf("Symbol = %s，应为 BTCUSDT", r.Symbol)
+		}
+		// BarsAgo 为信号K线到最新K线的根数，信号时间为K线收盘时间
+		if closeTime := data[last-r.BarsAgo].CloseTime; r.Signal.Time.UnixMilli() != closeTime {
+			t.Errorf("信号时间 %d 与 BarsAgo=%d 的K线收盘时间 %d 不一致", r.Signal.Time.UnixMilli(), r.BarsAgo, closeTime)
+		}
+		if r.Divergence != nil && r.Divergence.SecondSignal != r.Signal {
+			t.Errorf("背离的第二个信号应为结果中的信号")
+		}
+	}
+
+	// 只保留最近 RecentBars 根K线内的信号
+	recentBars := all[len(all)/2].BarsAgo
+	recent := Analyze("BTCUSDT", data, Config{RecentBars: recentBars})
+	want := 0
+	for _, r := range all {
+		if r.BarsAgo < recentBars {
+			want++
+		}
+	}
+	if len(recent) != want || len(recent) == len(all) {
+		t.Errorf("RecentBars=%d 保留 %d 个信号，应为 %d 个（共 %d 个）", recentBars, len(recent), want, len(all))
+	}
+	for _, r := range recent {
+		if r.BarsAgo >= recentBars {
+			t.Errorf("BarsAgo=%d 超出 RecentBars=%d", r.BarsAgo, recentBars)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// costMethod 由成交记录重建持仓时的默认成本计算方法（环境变量 COST_METHOD=average|fifo）
+var costMethod = bitmex.AverageCost
+
+// ContractPnL 一个合约的已实现 / 未实现盈亏及与钱包 RealisedPNL 的对比，金额为主单位（BTC / USDT）
+type ContractPnL struct {
+	bitmex.RealisedCheck
+	Qty          int     `json:"qty"`
+	EntryPrice   float64 `json:"entryPrice"`
+	CurrentPrice float64 `json:"currentPrice"`
+	Unrealized   float64 `json:"unrealized"`
+}
+
+// PnLReport /api/positions/pnl 响应
+type PnLReport struct {
+	CostMethod string        `json:"costMethod"`
+	Contracts  []ContractPnL `json:"contracts"`
+	Unknown    []string      `json:"unknown"` // 没有合约信息、无法核算的合约
+}
+
+// bookPositions 将持仓核算中未平仓的合约转换为页面使用的仓位，price 返回合约的当前价格
+func bookPositions(book *bitmex.Book, price func(symbol string, a *bitmex.PositionAccount) float64) []Position {
+	positions := []Position{}
+	for _, symbol := range book.Symbols() {
+		a := book.Accounts[symbol]
+		if a.Qty == 0 {
+			continue
+		}
+
+		pos := Position{
+			Symbol:        symbol,
+			Side:          "Long",
+			Qty:           a.Qty,
+			EntryPrice:    a.EntryPrice(),
+			CurrentPrice:  price(symbol, a),
+			SettlCurrency: a.Instrument.SettlCurrency,
+			CostMethod:    string(book.Method),
+			Source:        "executions",
+		}
+		if a.Qty < 0 {
+			pos.Side = "Short"
+		}
+		if pos.EntryPrice > 0 && pos.CurrentPrice > 0 {
+			pos.UnrealizedPNL = bitmex.FromMinorUnits(pos.SettlCurrency, int64(math.Round(a.Unrealized(pos.CurrentPrice))))
+			pos.UnrealizedPNLPercent = (pos.CurrentPrice/pos.EntryPrice - 1.0) * 100
+			if pos.Side == "Short" {
+				pos.UnrealizedPNLPercent = -pos.UnrealizedPNLPercent
+			}
+		}
+		positions = append(positions, pos)
+	}
+	return positions
+}
+
+// handleContractPnL 各合约的已实现 / 未实现盈亏，并与钱包 RealisedPNL 对比
+// 参数: method=average|fifo
+func handleContractPnL(w http.ResponseWriter, r *http.Request) {
+	method := costMethod
+	if m := r.URL.Query().Get("method"); m != "" {
+		parsed, err := bitmex.ParseCostMethod(m)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		method = parsed
+	}
+
+	book := bitmex.BuildBook(rawExecutionsCache, instrumentsCache, method, time.Time{})
+
+	report := PnLReport{CostMethod: string(method), Contracts: []ContractPnL{}, Unknown: book.Unknown}
+	if report.Unknown == nil {
+		report.Unknown = []string{}
+	}
+	for _, check := range bitmex.ReconcileRealised(book, walletCache) {
+		c := ContractPnL{RealisedCheck: check}
+		if a, ok := book.Accounts[check.Symbol]; ok && a.Qty != 0 {
+			c.Qty = a.Qty
+			c.EntryPrice = a.EntryPrice()
+			c.CurrentPrice = a.LastPrice
+			c.Unrealized = bitmex.FromMinorUnits(check.Currency, int64(math.Round(a.Unrealized(a.LastPrice))))
+		}
+		report.Contracts = append(report.Contracts, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,185 @@
+// Package analytics 由钱包历史和每日仓位计算账户的权益曲线和绩效指标（回撤、夏普比率、
+// 月度/年度收益、连续盈亏天数等），以 BTC 或美元计价。
+package analytics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// DailyPositionFile 每日仓位文件（cmd/dailyposition 生成）
+const DailyPositionFile = "daily_position.csv"
+
+// 计价单位
+const (
+	BTC = "BTC"
+	USD = "USD"
+)
+
+// ParseUnit 解析计价单位，空字符串为 BTC
+func ParseUnit(s string) (string, error) {
+	switch s {
+	case "", BTC, "btc", "XBT", "XBt":
+		return BTC, nil
+	case USD, "usd":
+		return USD, nil
+	}
+	return "", fmt.Errorf("未知计价单位: %s（可用: BTC, USD）", s)
+}
+
+// DailyPosition daily_position.csv 的一行
+type DailyPosition struct {
+	Date          time.Time
+	PositionQty   int
+	Price         float64 // XBTUSD 价格
+	Balance       float64 // 钱包余额（BTC）
+	PositionValue float64 // 仓位价值（BTC）
+	PositionRatio float64 // 仓位价值 / 钱包余额
+	Side          string
+}
+
+// ReadDailyPositions 读取 daily_position.csv
+func ReadDailyPositions(filename string) ([]DailyPosition, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var positions []DailyPosition
+	for i, record := range records {
+		if i == 0 || len(record) < 7 {
+			continue // 跳过表头: Date,PositionQty,Price,Balance,PositionValue,PositionRatio,Side
+		}
+		date, err := time.Parse("2006-01-02", record[0])
+		if err != nil {
+			continue
+		}
+		p := DailyPosition{Date: date, Side: record[6]}
+		p.PositionQty, _ = strconv.Atoi(record[1])
+		p.Price, _ = strconv.ParseFloat(record[2], 64)
+		p.Balance, _ = strconv.ParseFloat(record[3], 64)
+		p.PositionValue, _ = strconv.ParseFloat(record[4], 64)
+		p.PositionRatio, _ = strconv.ParseFloat(record[5], 64)
+		positions = append(positions, p)
+	}
+	return positions, nil
+}
+
+// EquityPoint 权益曲线上的一天（UTC 日期），金额为计价单位
+type EquityPoint struct {
+	Date     string  `json:"date"`
+	Balance  float64 `json:"balance"`  // 当日结束时的钱包余额（BTC）
+	Price    float64 `json:"price"`    // 当日 XBTUSD 收盘价，没有价格时为 0
+	Equity   float64 `json:"equity"`   // 权益（按计价单位）
+	Flow     float64 `json:"flow"`     // 当日入金（正）/ 出金（负）
+	Invested float64 `json:"invested"` // 截至当日的累计净入金
+	PnL      float64 `json:"pnl"`      // 当日盈亏 = 权益变化 - 出入金（美元计价时包含 BTC 价格变化）
+	Return   float64 `json:"return"`   // 当日收益率（百分比）
+	NAV      float64 `json:"nav"`      // 单位净值（起始为 1，扣除出入金影响）
+	Drawdown float64 `json:"drawdown"` // 相对净值高点的回撤（百分比，≤ 0）
+	Exposure float64 `json:"exposure"` // 仓位价值 / 钱包余额（daily_position.csv），没有时为 0
+
+	date time.Time
+}
+
+// isPnL 计入盈亏的钱包记录类型，其他类型（Deposit、Withdrawal、Transfer、Conversion 等）为出入金
+func isPnL(transactType string) bool {
+	return transactType == "RealisedPNL" || transactType == "Funding"
+}
+
+// EquityCurve 由 XBt 钱包历史生成每日权益曲线（从第一条记录到最后一条记录的日期）。
+// 权益为当日结束时的钱包余额（已实现），USD 计价时乘以当日收盘价；
+// price 返回 at 时刻的 XBTUSD 价格（没有时返回 0），daily 提供仓位比例，并在没有价格时作为备用。
+// 收益率假定出入金发生在当日结束时：r = (权益 - 出入金) / 前一日权益 - 1。
+func EquityCurve(wallet []bitmex.WalletHistory, daily []DailyPosition, price func(at time.Time) float64, unit string) []EquityPoint {
+	var records []bitmex.WalletHistory
+	for _, h := range wallet {
+		if h.Currency == "XBt" && h.TransactStatus != "Canceled" && h.TransactStatus != "Pending" && !h.Time().IsZero() {
+			records = append(records, h)
+		}
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time().Before(records[j].Time()) })
+
+	positions := make(map[string]DailyPosition, len(daily))
+	for _, p := range daily {
+		positions[p.Date.Format("2006-01-02")] = p
+	}
+
+	first := records[0].Time().UTC().Truncate(24 * time.Hour)
+	last := records[len(records)-1].Time().UTC().Truncate(24 * time.Hour)
+
+	var curve []EquityPoint
+	var balance int64
+	var lastPrice float64
+	prevEquity, invested, nav, peak := 0.0, 0.0, 1.0, 1.0
+	next := 0
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		end := day.Add(24 * time.Hour)
+		var flow int64
+		for ; next < len(records) && records[next].Time().Before(end); next++ {
+			h := records[next]
+			balance = h.WalletBalance
+			if !isPnL(h.TransactType) {
+				flow += h.Amount
+			}
+		}
+
+		date := day.Format("2006-01-02")
+		pos, hasPos := positions[date]
+		p := EquityPoint{Date: date, Balance: float64(balance) / bitmex.SatoshiPerBTC, date: day}
+		if price != nil {
+			p.Price = price(end)
+		}
+		if p.Price == 0 && hasPos {
+			p.Price = pos.Price
+		}
+		if p.Price == 0 {
+			p.Price = lastPrice
+		}
+		lastPrice = p.Price
+		if hasPos {
+			p.Exposure = pos.PositionRatio
+		}
+
+		// 盈亏由扣除出入金后的余额（聪）计算：只有出入金的一天盈亏正好为 0，
+		// 不会因浮点误差计为盈利或亏损日
+		p.Equity = p.Balance
+		p.Flow = float64(flow) / bitmex.SatoshiPerBTC
+		beforeFlow := float64(balance-flow) / bitmex.SatoshiPerBTC
+		if unit == USD {
+			p.Equity *= p.Price
+			p.Flow *= p.Price
+			beforeFlow *= p.Price
+		}
+		invested += p.Flow
+		p.Invested = invested
+		p.PnL = beforeFlow - prevEquity
+		if prevEquity > 0 {
+			p.Return = p.PnL / prevEquity * 100
+			nav *= 1 + p.PnL/prevEquity
+		}
+		if nav > peak {
+			peak = nav
+		}
+		p.NAV = nav
+		p.Drawdown = (nav/peak - 1) * 100
+		prevEquity = p.Equity
+		curve = append(curve, p)
+	}
+	return curve
+}
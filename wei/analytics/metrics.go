@@ -0,0 +1,266 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// TradingDays 年化使用的每年天数（加密货币全年交易）
+const TradingDays = 365
+
+// PeriodReturn 一个月或一年的收益
+type PeriodReturn struct {
+	Period      string  `json:"period"` // 2024-01 / 2024
+	StartEquity float64 `json:"startEquity"`
+	EndEquity   float64 `json:"endEquity"`
+	Flow        float64 `json:"flow"`
+	PnL         float64 `json:"pnl"`
+	Return      float64 `json:"return"` // 百分比，按日收益率复利
+}
+
+// DayReturn 单日收益
+type DayReturn struct {
+	Date   string  `json:"date"`
+	PnL    float64 `json:"pnl"`
+	Return float64 `json:"return"` // 百分比
+}
+
+// Drawdown 一次回撤：从净值高点到最低点，再到恢复到高点
+type Drawdown struct {
+	Depth    float64 `json:"depth"`              // 百分比，≤ 0
+	Peak     string  `json:"peak"`               // 高点日期
+	Trough   string  `json:"trough"`             // 最低点日期
+	Recovery string  `json:"recovery,omitempty"` // 恢复日期，未恢复时为空
+	Days     int     `json:"days"`               // 高点到恢复（未恢复时到最后一天）的天数
+}
+
+// Report 绩效报告，金额为计价单位，收益率、波动率和回撤为百分比
+type Report struct {
+	Unit        string  `json:"unit"`
+	Start       string  `json:"start"`
+	End         string  `json:"end"`
+	Days        int     `json:"days"`
+	StartEquity float64 `json:"startEquity"`
+	EndEquity   float64 `json:"endEquity"`
+	NetFlow     float64 `json:"netFlow"` // 累计入金 - 出金
+	PnL         float64 `json:"pnl"`     // 累计盈亏
+
+	TotalReturn float64 `json:"totalReturn"` // 单位净值的累计收益
+	CAGR        float64 `json:"cagr"`        // 年化收益
+	Volatility  float64 `json:"volatility"`  // 年化波动率
+	Sharpe      float64 `json:"sharpe"`      // 年化夏普比率（无风险利率为 0）
+	Sortino     float64 `json:"sortino"`     // 年化索提诺比率
+	Calmar      float64 `json:"calmar"`      // 年化收益 / 最大回撤
+
+	MaxDrawdown     Drawdown `json:"maxDrawdown"`     // 最大回撤
+	LongestDrawdown Drawdown `json:"longestDrawdown"` // 持续时间最长的回撤
+	CurrentDrawdown float64  `json:"currentDrawdown"` // 当前回撤
+
+	Monthly []PeriodReturn `json:"monthly"`
+	Yearly  []PeriodReturn `json:"yearly"`
+
+	BestDays  []DayReturn `json:"bestDays"`  // 收益率最高的几天
+	WorstDays []DayReturn `json:"worstDays"` // 收益率最低的几天
+
+	WinDays       int     `json:"winDays"`       // 盈利天数
+	LossDays      int     `json:"lossDays"`      // 亏损天数（盈亏为 0 的天数不计）
+	DayWinRate    float64 `json:"dayWinRate"`    // 百分比
+	MaxWinStreak  int     `json:"maxWinStreak"`  // 最长连续盈利天数
+	MaxLossStreak int     `json:"maxLossStreak"` // 最长连续亏损天数
+	CurrentStreak int     `json:"currentStreak"` // 当前连续天数，正为盈利、负为亏损
+
+	AvgExposure float64 `json:"avgExposure"` // 平均仓位比例（有持仓的天数）
+	MaxExposure float64 `json:"maxExposure"` // 最大仓位比例
+}
+
+// Analyze 由权益曲线计算绩效指标，top 为最好 / 最差单日的数量
+func Analyze(curve []EquityPoint, unit string, top int) Report {
+	r := Report{Unit: unit, Monthly: []PeriodReturn{}, Yearly: []PeriodReturn{}, BestDays: []DayReturn{}, WorstDays: []DayReturn{}}
+	if len(curve) == 0 {
+		return r
+	}
+	first, last := curve[0], curve[len(curve)-1]
+	r.Start, r.End, r.Days = first.Date, last.Date, len(curve)
+	r.StartEquity, r.EndEquity = first.Equity, last.Equity
+
+	// 第一天之前没有权益，不计算收益率
+	returns := make([]float64, 0, len(curve))
+	for i, p := range curve {
+		r.NetFlow += p.Flow
+		r.PnL += p.PnL
+		if i > 0 {
+			returns = append(returns, p.Return/100)
+		}
+	}
+
+	r.TotalReturn = (last.NAV - 1) * 100
+	if years := float64(len(returns)) / TradingDays; years > 0 && last.NAV > 0 {
+		r.CAGR = (math.Pow(last.NAV, 1/years) - 1) * 100
+	}
+	mean, std, downside := moments(returns)
+	r.Volatility = std * math.Sqrt(TradingDays) * 100
+	if std > 0 {
+		r.Sharpe = mean / std * math.Sqrt(TradingDays)
+	}
+	if downside > 0 {
+		r.Sortino = mean / downside * math.Sqrt(TradingDays)
+	}
+
+	r.MaxDrawdown, r.LongestDrawdown = drawdowns(curve)
+	r.CurrentDrawdown = last.Drawdown
+	if r.MaxDrawdown.Depth < 0 {
+		r.Calmar = r.CAGR / -r.MaxDrawdown.Depth
+	}
+
+	r.Monthly = periodReturns(curve, func(t time.Time) string { return t.Format("2006-01") })
+	r.Yearly = periodReturns(curve, func(t time.Time) string { return t.Format("2006") })
+	r.BestDays, r.WorstDays = extremeDays(curve[1:], top)
+
+	streak := 0
+	for _, p := range curve[1:] {
+		switch {
+		case p.PnL > 0:
+			r.WinDays++
+			if streak < 0 {
+				streak = 0
+			}
+			streak++
+			r.MaxWinStreak = max(r.MaxWinStreak, streak)
+		case p.PnL < 0:
+			r.LossDays++
+			if streak > 0 {
+				streak = 0
+			}
+			streak--
+			r.MaxLossStreak = max(r.MaxLossStreak, -streak)
+		}
+	}
+	r.CurrentStreak = streak
+	if n := r.WinDays + r.LossDays; n > 0 {
+		r.DayWinRate = float64(r.WinDays) / float64(n) * 100
+	}
+
+	var exposed int
+	for _, p := range curve {
+		e := math.Abs(p.Exposure)
+		if e == 0 {
+			continue
+		}
+		exposed++
+		r.AvgExposure += e
+		r.MaxExposure = math.Max(r.MaxExposure, e)
+	}
+	if exposed > 0 {
+		r.AvgExposure /= float64(exposed)
+	}
+	return r
+}
+
+// moments 日收益率的均值、标准差（样本）和下行标准差（相对 0）
+func moments(returns []float64) (mean, std, downside float64) {
+	n := float64(len(returns))
+	if n < 2 {
+		return 0, 0, 0
+	}
+	for _, x := range returns {
+		mean += x
+	}
+	mean /= n
+	for _, x := range returns {
+		std += (x - mean) * (x - mean)
+		if x < 0 {
+			downside += x * x
+		}
+	}
+	return mean, math.Sqrt(std / (n - 1)), math.Sqrt(downside / n)
+}
+
+// drawdowns 按单位净值找出最大回撤和持续时间最长的回撤
+func drawdowns(curve []EquityPoint) (deepest, longest Drawdown) {
+	var current *Drawdown
+	var peakDate time.Time
+	peak := 0.0
+	finish := func(recovery time.Time, end time.Time) {
+		if current == nil {
+			return
+		}
+		if !recovery.IsZero() {
+			current.Recovery = recovery.Format("2006-01-02")
+		}
+		current.Days = int(end.Sub(peakDate).Hours() / 24)
+		if current.Depth < deepest.Depth {
+			deepest = *current
+		}
+		if current.Days > longest.Days {
+			longest = *current
+		}
+		current = nil
+	}
+
+	for _, p := range curve {
+		if p.NAV >= peak {
+			finish(p.date, p.date)
+			peak, peakDate = p.NAV, p.date
+			continue
+		}
+		if current == nil {
+			current = &Drawdown{Peak: peakDate.Format("2006-01-02")}
+		}
+		if p.Drawdown < current.Depth {
+			current.Depth = p.Drawdown
+			current.Trough = p.Date
+		}
+	}
+	finish(time.Time{}, curve[len(curve)-1].date)
+	return deepest, longest
+}
+
+// periodReturns 按 key（月份 / 年份）汇总，收益率为日收益率复利
+func periodReturns(curve []EquityPoint, key func(time.Time) string) []PeriodReturn {
+	var result []PeriodReturn
+	var prevEquity, growth float64
+	for i, p := range curve {
+		k := key(p.date)
+		if len(result) == 0 || result[len(result)-1].Period != k {
+			if len(result) > 0 {
+				last := &result[len(result)-1]
+				last.Return = (growth - 1) * 100
+			}
+			result = append(result, PeriodReturn{Period: k, StartEquity: prevEquity})
+			growth = 1
+		}
+		cur := &result[len(result)-1]
+		cur.EndEquity = p.Equity
+		cur.Flow += p.Flow
+		cur.PnL += p.PnL
+		if i > 0 {
+			growth *= 1 + p.Return/100
+		}
+		prevEquity = p.Equity
+	}
+	if len(result) > 0 {
+		result[len(result)-1].Return = (growth - 1) * 100
+	}
+	return result
+}
+
+// extremeDays 收益率最高和最低的 n 天
+func extremeDays(curve []EquityPoint, n int) (best, worst []DayReturn) {
+	days := make([]DayReturn, 0, len(curve))
+	for _, p := range curve {
+		if p.Return != 0 {
+			days = append(days, DayReturn{Date: p.Date, PnL: p.PnL, Return: p.Return})
+		}
+	}
+	sort.SliceStable(days, func(i, j int) bool { return days[i].Return > days[j].Return })
+	n = min(n, len(days))
+	best = append([]DayReturn{}, days[:n]...)
+	for i := len(days) - 1; i >= len(days)-n; i-- {
+		worst = append(worst, days[i])
+	}
+	if worst == nil {
+		worst = []DayReturn{}
+	}
+	return best, worst
+}
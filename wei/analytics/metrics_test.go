@@ -0,0 +1,191 @@
+package analytics
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// metricNear 相对误差 1e-9（接近 0 时为绝对误差）
+func metricNear(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+// walletDay 2024-01-<day> 的 XBt 钱包记录，金额为聪
+func walletDay(day int, typ string, amount, balance int64) bitmex.WalletHistory {
+	return bitmex.WalletHistory{TransactType: typ, TransactStatus: "Completed", Currency: "XBt", Amount: amount,
+		WalletBalance: balance, Timestamp: time.Date(2024, 1, day, 12, 0, 0, 0, time.UTC).Format(bitmex.TimeLayout)}
+}
+
+// reportSummary Report 中需要比较的数值指标
+type reportSummary struct {
+	TotalReturn, CAGR, Volatility, Sharpe, Sortino, Calmar, CurrentDrawdown, DayWinRate float64
+	WinDays, LossDays, MaxWinStreak, MaxLossStreak, CurrentStreak                       int
+}
+
+func TestAnalyze(t *testing.T) {
+	annual := math.Sqrt(TradingDays)
+	cagr := func(nav float64, days int) float64 { return (math.Pow(nav, TradingDays/float64(days)) - 1) * 100 }
+
+	tests := []struct {
+		name    string
+		wallet  []bitmex.WalletHistory
+		returns []float64 // 每日收益率（百分比），第一天为 0
+		nav     []float64
+		want    reportSummary
+		deepest Drawdown
+		longest Drawdown
+		monthly PeriodReturn
+	}{
+		{
+			// 日收益率 10%、−20%、10%（当天另有入金 1 BTC，不计入收益）、20%、0（出金）：
+			// 均值 0.04，样本方差 0.092 / 4 = 0.023，下行方差 0.04 / 5 = 0.008
+			name: "回撤后恢复",
+			wallet: []bitmex.WalletHistory{
+				walletDay(1, "Deposit", 100000000, 100000000),
+				walletDay(2, "RealisedPNL", 10000000, 110000000),
+				walletDay(3, "RealisedPNL", -22000000, 88000000),
+				walletDay(4, "Deposit", 100000000, 188000000),
+				walletDay(4, "RealisedPNL", 8800000, 196800000),
+				walletDay(5, "RealisedPNL", 39360000, 236160000),
+				walletDay(6, "Withdrawal", -36160000, 200000000),
+			},
+			returns: []float64{0, 10, -20, 10, 20, 0},
+			nav:     []float64{1, 1.1, 0.88, 0.968, 1.1616, 1.1616},
+			want: reportSummary{
+				TotalReturn: 16.16, CAGR: cagr(1.1616, 5), Volatility: math.Sqrt(0.023) * annual * 100,
+				Sharpe: 0.04 / math.Sqrt(0.023) * annual, Sortino: 0.04 / math.Sqrt(0.008) * annual, Calmar: cagr(1.1616, 5) / 20,
+				DayWinRate: 75, WinDays: 3, LossDays: 1, MaxWinStreak: 2, MaxLossStreak: 1, CurrentStreak: 2,
+			},
+			deepest: Drawdown{Depth: -20, Peak: "2024-01-02", Trough: "2024-01-03", Recovery: "2024-01-05", Days: 3},
+			longest: Drawdown{Depth: -20, Peak: "2024-01-02", Trough: "2024-01-03", Recovery: "2024-01-05", Days: 3},
+			monthly: PeriodReturn{Period: "2024-01", EndEquity: 2, Flow: 1.6384, PnL: 0.3616, Return: 16.16},
+		},
+		{
+			// 日收益率 −30%、50%、−10%、0（入金）、−5%：均值 0.01，样本方差 0.352 / 4 = 0.088，下行方差 0.1025 / 5 = 0.0205；
+			// 第一次回撤最深，第二次持续最久且尚未恢复，
+			// 当前回撤 0.945 × 0.95 / 1.05 − 1 = −14.5%
+			name: "仍在回撤中",
+			wallet: []bitmex.WalletHistory{
+				walletDay(1, "Deposit", 100000000, 100000000),
+				walletDay(2, "RealisedPNL", -30000000, 70000000),
+				walletDay(3, "RealisedPNL", 35000000, 105000000),
+				walletDay(4, "Funding", -10500000, 94500000),
+				walletDay(5, "Deposit", 5500000, 100000000),
+				walletDay(6, "RealisedPNL", -5000000, 95000000),
+			},
+			returns: []float64{0, -30, 50, -10, 0, -5},
+			nav:     []float64{1, 0.7, 1.05, 0.945, 0.945, 0.89775},
+			want: reportSummary{
+				TotalReturn: -10.225, CAGR: cagr(0.89775, 5), Volatility: math.Sqrt(0.088) * annual * 100,
+				Sharpe: 0.01 / math.Sqrt(0.088) * annual, Sortino: 0.01 / math.Sqrt(0.0205) * annual, Calmar: cagr(0.89775, 5) / 30,
+				CurrentDrawdown: -14.5, DayWinRate: 25, WinDays: 1, LossDays: 3, MaxWinStreak: 1, MaxLossStreak: 2, CurrentStreak: -2,
+			},
+			deepest: Drawdown{Depth: -30, Peak: "2024-01-01", Trough: "2024-01-02", Recovery: "2024-01-03", Days: 2},
+			longest: Drawdown{Depth: -14.5, Peak: "2024-01-03", Trough: "2024-01-06", Days: 3},
+			monthly: PeriodReturn{Period: "2024-01", EndEquity: 0.95, Flow: 1.055, PnL: -0.105, Return: -10.225},
+		},
+		{
+			// 只有出入金，收益率全为 0：标准差为 0，比率均为 0 而不是 NaN / Inf
+			name: "净值不变",
+			wallet: []bitmex.WalletHistory{
+				walletDay(1, "Deposit", 100000000, 100000000),
+				walletDay(4, "Withdrawal", -50000000, 50000000),
+			},
+			returns: []float64{0, 0, 0, 0},
+			nav:     []float64{1, 1, 1, 1},
+			monthly: PeriodReturn{Period: "2024-01", EndEquity: 0.5, Flow: 0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curve := EquityCurve(tt.wallet, nil, nil, BTC)
+			if len(curve) != len(tt.returns) {
+				t.Fatalf("权益曲线 %d 天，应为 %d 天", len(curve), len(tt.returns))
+			}
+			for i, p := range curve {
+				if !metricNear(p.Return, tt.returns[i]) || !metricNear(p.NAV, tt.nav[i]) {
+					t.Errorf("%s 收益率 / 净值 = %v / %v，应为 %v / %v", p.Date, p.Return, p.NAV, tt.returns[i], tt.nav[i])
+				}
+			}
+
+			r := Analyze(curve, BTC, 3)
+			got := reportSummary{
+				TotalReturn: r.TotalReturn, CAGR: r.CAGR, Volatility: r.Volatility, Sharpe: r.Sharpe, Sortino: r.Sortino,
+				Calmar: r.Calmar, CurrentDrawdown: r.CurrentDrawdown, DayWinRate: r.DayWinRate,
+				WinDays: r.WinDays, LossDays: r.LossDays, MaxWinStreak: r.MaxWinStreak, MaxLossStreak: r.MaxLossStreak,
+				CurrentStreak: r.CurrentStreak,
+			}
+			floats := [][2]float64{
+				{got.TotalReturn, tt.want.TotalReturn}, {got.CAGR, tt.want.CAGR}, {got.Volatility, tt.want.Volatility},
+				{got.Sharpe, tt.want.Sharpe}, {got.Sortino, tt.want.Sortino}, {got.Calmar, tt.want.Calmar},
+				{got.CurrentDrawdown, tt.want.CurrentDrawdown}, {got.DayWinRate, tt.want.DayWinRate},
+			}
+			match := true
+			for _, f := range floats {
+				match = match && metricNear(f[0], f[1])
+			}
+			got.TotalReturn, got.CAGR, got.Volatility, got.Sharpe, got.Sortino, got.Calmar, got.CurrentDrawdown, got.DayWinRate = 0, 0, 0, 0, 0, 0, 0, 0
+			want := tt.want
+			want.TotalReturn, want.CAGR, want.Volatility, want.Sharpe, want.Sortino, want.Calmar, want.CurrentDrawdown, want.DayWinRate = 0, 0, 0, 0, 0, 0, 0, 0
+			if !match || got != want {
+				t.Errorf("指标 = %+v\n应为 %+v", r, tt.want)
+			}
+
+			if !drawdownMatch(r.MaxDrawdown, tt.deepest) {
+				t.Errorf("最大回撤 = %+v，应为 %+v", r.MaxDrawdown, tt.deepest)
+			}
+			if !drawdownMatch(r.LongestDrawdown, tt.longest) {
+				t.Errorf("最长回撤 = %+v，应为 %+v", r.LongestDrawdown, tt.longest)
+			}
+
+			if len(r.Monthly) != 1 || len(r.Yearly) != 1 {
+				t.Fatalf("月度 / 年度收益 = %+v / %+v，应各为一条", r.Monthly, r.Yearly)
+			}
+			m := r.Monthly[0]
+			if m.Period != tt.monthly.Period || m.StartEquity != 0 || !metricNear(m.EndEquity, tt.monthly.EndEquity) ||
+				!metricNear(m.Flow, tt.monthly.Flow) || !metricNear(m.PnL, tt.monthly.PnL) || !metricNear(m.Return, tt.monthly.Return) {
+				t.Errorf("月度收益 = %+v，应为 %+v", m, tt.monthly)
+			}
+			if y := r.Yearly[0]; y.Period != "2024" || !metricNear(y.Return, tt.monthly.Return) {
+				t.Errorf("年度收益 = %+v，应为 2024 年 %v%%", y, tt.monthly.Return)
+			}
+		})
+	}
+}
+
+func drawdownMatch(a, b Drawdown) bool {
+	depth := metricNear(a.Depth, b.Depth)
+	a.Depth, b.Depth = 0, 0
+	return depth && reflect.DeepEqual(a, b)
+}
+
+func TestAnalyzeBestWorstDays(t *testing.T) {
+	wallet := []bitmex.WalletHistory{
+		walletDay(1, "Deposit", 100000000, 100000000),
+		walletDay(2, "RealisedPNL", 10000000, 110000000),
+		walletDay(3, "RealisedPNL", -22000000, 88000000),
+		walletDay(5, "RealisedPNL", 8800000, 96800000),
+	}
+	r := Analyze(EquityCurve(wallet, nil, nil, BTC), BTC, 2)
+	dates := func(days []DayReturn) []string {
+		var s []string
+		for _, d := range days {
+			s = append(s, d.Date)
+		}
+		return s
+	}
+	// 1 月 4 日收益率为 0，不参与排名
+	if got, want := dates(r.BestDays), []string{"2024-01-02", "2024-01-05"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("最好的几天 = %v，应为 %v", got, want)
+	}
+	if got, want := dates(r.WorstDays), []string{"2024-01-03", "2024-01-05"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("最差的几天 = %v，应为 %v", got, want)
+	}
+	if r := Analyze(nil, BTC, 2); r.Days != 0 || r.Monthly == nil || r.BestDays == nil || r.WorstDays == nil {
+		t.Errorf("空曲线的报告 = %+v，列表应为空而不是 nil", r)
+	}
+}
@@ -0,0 +1,151 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// Contribution 一种出入金类型（Deposit、Withdrawal、Transfer、Conversion 等）的合计，金额为计价单位，正为入金
+type Contribution struct {
+	Type   string  `json:"type"`
+	Count  int     `json:"count"`
+	Amount float64 `json:"amount"`
+}
+
+// Returns 区分出入金和交易结果的收益，金额为计价单位，收益率为百分比
+type Returns struct {
+	Unit        string  `json:"unit"`
+	Start       string  `json:"start"`
+	End         string  `json:"end"`
+	EndEquity   float64 `json:"endEquity"`
+	Deposits    float64 `json:"deposits"`    // 入金合计
+	Withdrawals float64 `json:"withdrawals"` // 出金合计（负值）
+	NetInvested float64 `json:"netInvested"` // 净入金 = 入金 + 出金
+	PnL         float64 `json:"pnl"`         // 交易结果 = 期末权益 - 净入金
+
+	TimeWeighted       float64 `json:"timeWeighted"`       // 时间加权收益（单位净值的累计收益，不受出入金时点影响）
+	TimeWeightedAnnual float64 `json:"timeWeightedAnnual"` // 年化时间加权收益
+	MoneyWeighted      float64 `json:"moneyWeighted"`      // 资金加权收益（年化内部收益率 IRR），无解时为 0
+	MoneyWeightedOK    bool    `json:"moneyWeightedOk"`    // IRR 是否有解
+
+	Contributions []Contribution `json:"contributions"` // 按类型的出入金
+}
+
+// FlowReturns 由权益曲线和钱包历史计算时间加权收益、资金加权收益（IRR）和按类型的出入金。
+// USD 计价时出入金按当日收盘价（权益曲线中的价格）换算。
+func FlowReturns(curve []EquityPoint, wallet []bitmex.WalletHistory, unit string) Returns {
+	r := Returns{Unit: unit, Contributions: []Contribution{}}
+	if len(curve) == 0 {
+		return r
+	}
+	first, last := curve[0], curve[len(curve)-1]
+	r.Start, r.End, r.EndEquity = first.Date, last.Date, last.Equity
+
+	// 投资者视角的现金流：入金为负，出金为正，期末权益视为最后一天全部取出
+	dates := make([]time.Time, 0, len(curve)+1)
+	amounts := make([]float64, 0, len(curve)+1)
+	for _, p := range curve {
+		if p.Flow > 0 {
+			r.Deposits += p.Flow
+		} else {
+			r.Withdrawals += p.Flow
+		}
+		if p.Flow != 0 {
+			dates = append(dates, p.date)
+			amounts = append(amounts, -p.Flow)
+		}
+	}
+	dates = append(dates, last.date)
+	amounts = append(amounts, last.Equity)
+	r.NetInvested = r.Deposits + r.Withdrawals
+	r.PnL = r.EndEquity - r.NetInvested
+
+	r.TimeWeighted = (last.NAV - 1) * 100
+	if years := float64(len(curve)-1) / TradingDays; years > 0 && last.NAV > 0 {
+		r.TimeWeightedAnnual = (math.Pow(last.NAV, 1/years) - 1) * 100
+	}
+	if irr, ok := IRR(dates, amounts); ok {
+		r.MoneyWeighted, r.MoneyWeightedOK = irr*100, true
+	}
+
+	r.Contributions = contributions(curve, wallet, unit)
+	return r
+}
+
+// contributions 按类型汇总 XBt 钱包中的出入金记录。只统计权益曲线日期范围内的记录：
+// 范围外的记录没有当日价格，也不在入金、出金合计中
+func contributions(curve []EquityPoint, wallet []bitmex.WalletHistory, unit string) []Contribution {
+	prices := make(map[string]float64, len(curve))
+	for _, p := range curve {
+		prices[p.Date] = p.Price
+	}
+
+	byType := make(map[string]*Contribution)
+	for _, h := range wallet {
+		if h.Currency != "XBt" || h.TransactStatus == "Canceled" || h.TransactStatus == "Pending" || isPnL(h.TransactType) {
+			continue
+		}
+		price, ok := prices[h.Time().UTC().Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		amount := h.AmountBTC()
+		if unit == USD {
+			amount *= price
+		}
+		c, ok := byType[h.TransactType]
+		if !ok {
+			c = &Contribution{Type: h.TransactType}
+			byType[h.TransactType] = c
+		}
+		c.Count++
+		c.Amount += amount
+	}
+
+	result := make([]Contribution, 0, len(byType))
+	for _, c := range byType {
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Type < result[j].Type })
+	return result
+}
+
+// IRR 现金流（amounts[i] 发生在 dates[i]）的年化内部收益率，使 Σ amounts[i] / (1+r)^(年数) = 0。
+// 在 -99.9% 到 +1,000,000% 之间二分查找，现金流没有正负变化或区间内无解时返回 false
+func IRR(dates []time.Time, amounts []float64) (float64, bool) {
+	if len(dates) < 2 || len(dates) != len(amounts) {
+		return 0, false
+	}
+	start := dates[0]
+	// 以 g = ln(1+r) 为变量，npv 随 g 单调性不保证，只要求区间两端异号
+	npv := func(g float64) float64 {
+		var sum float64
+		for i, a := range amounts {
+			years := dates[i].Sub(start).Hours() / 24 / TradingDays
+			sum += a * math.Exp(-g*years)
+		}
+		return sum
+	}
+
+	lo, hi := math.Log(0.001), math.Log(10001.0)
+	flo, fhi := npv(lo), npv(hi)
+	if flo == 0 {
+		return math.Exp(lo) - 1, true
+	}
+	if math.IsNaN(flo) || math.IsNaN(fhi) || (flo > 0) == (fhi > 0) {
+		return 0, false
+	}
+	for i := 0; i < 200 && hi-lo > 1e-12; i++ {
+		mid := (lo + hi) / 2
+		fmid := npv(mid)
+		if (fmid > 0) == (flo > 0) {
+			lo, flo = mid, fmid
+		} else {
+			hi = mid
+		}
+	}
+	return math.Exp((lo+hi)/2) - 1, true
+}
@@ -0,0 +1,101 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+func TestIRR(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(days float64) time.Time { return start.Add(time.Duration(days * 24 * float64(time.Hour))) }
+
+	tests := []struct {
+		name    string
+		dates   []time.Time
+		amounts []float64
+		want    float64
+		ok      bool
+	}{
+		{"一次入金一次取出", []time.Time{day(0), day(365)}, []float64{-100, 110}, 0.1, true},
+		{"亏损", []time.Time{day(0), day(365)}, []float64{-100, 50}, -0.5, true},
+		{"不足一年按年化", []time.Time{day(0), day(182.5)}, []float64{-100, 110}, 0.21, true},
+		// 年化 21%：100 增长一年为 121，半年时入金的 50 增长半年为 55
+		{"期中入金", []time.Time{day(0), day(182.5), day(365)}, []float64{-100, -50, 176}, 0.21, true},
+		// 年化 10%：半年时取出 60，期末剩余 100 × 1.1 − 60 × 1.1^0.5
+		{"期中出金", []time.Time{day(0), day(182.5), day(365)}, []float64{-100, 60, 110 - 60*math.Sqrt(1.1)}, 0.1, true},
+		{"全部为入金", []time.Time{day(0), day(365)}, []float64{-100, -10}, 0, false},
+		{"全部为取出", []time.Time{day(0), day(365)}, []float64{100, 10}, 0, false},
+		{"只有一笔", []time.Time{day(0)}, []float64{-100}, 0, false},
+		{"长度不一致", []time.Time{day(0), day(365)}, []float64{-100}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := IRR(tt.dates, tt.amounts)
+			if ok != tt.ok || !metricNear(got, tt.want) {
+				t.Errorf("IRR = %v, %v，应为 %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFlowReturns(t *testing.T) {
+	wallet := []bitmex.WalletHistory{
+		walletDay(1, "Deposit", 100000000, 100000000),
+		walletDay(2, "RealisedPNL", 10000000, 110000000),
+		walletDay(3, "Transfer", 50000000, 160000000),
+		walletDay(4, "Withdrawal", -20000000, 140000000),
+		walletDay(4, "Deposit", 10000000, 150000000),
+	}
+	prices := map[string]float64{"2024-01-01": 40000, "2024-01-02": 42000, "2024-01-03": 41000, "2024-01-04": 45000}
+	price := func(at time.Time) float64 { return prices[at.Add(-time.Hour).Format("2006-01-02")] }
+	curve := EquityCurve(wallet, nil, price, USD)
+
+	// 权益曲线之外的记录（曲线之前的入金、之后的出金）没有当日价格，不计入出入金
+	outside := append([]bitmex.WalletHistory{
+		{TransactType: "Deposit", TransactStatus: "Completed", Currency: "XBt", Amount: 300000000,
+			Timestamp: "2023-12-31T12:00:00.000Z"},
+		walletDay(5, "Withdrawal", -150000000, 0),
+		walletDay(4, "Withdrawal", -99999999, 0),
+		walletDay(4, "Conversion", 1, 0),
+	}, wallet...)
+	outside[2].TransactStatus = "Canceled"
+	outside[3].Currency = "USDt"
+
+	r := FlowReturns(curve, outside, USD)
+	if r.Start != "2024-01-01" || r.End != "2024-01-04" || !metricNear(r.EndEquity, 1.5*45000) {
+		t.Errorf("区间 = %s 到 %s，期末权益 %v，应为 2024-01-01 到 2024-01-04，67500", r.Start, r.End, r.EndEquity)
+	}
+	// 入金、出金按每日净额：1 × 40000 + 0.5 × 41000，1 月 4 日净出金 0.1 × 45000
+	if !metricNear(r.Deposits, 60500) || !metricNear(r.Withdrawals, -4500) || !metricNear(r.NetInvested, 56000) ||
+		!metricNear(r.PnL, 11500) {
+		t.Errorf("入金 / 出金 / 净入金 / 盈亏 = %v / %v / %v / %v，应为 60500 / -4500 / 56000 / 11500",
+			r.Deposits, r.Withdrawals, r.NetInvested, r.PnL)
+	}
+	// 按类型统计时不抵消：1 月 4 日入金 0.1 × 45000、出金 0.2 × 45000
+	assertContributions(t, r.Contributions, []Contribution{
+		{Type: "Deposit", Count: 2, Amount: 44500},
+		{Type: "Transfer", Count: 1, Amount: 20500},
+		{Type: "Withdrawal", Count: 1, Amount: -9000},
+	})
+
+	btc := FlowReturns(EquityCurve(wallet, nil, nil, BTC), outside, BTC)
+	assertContributions(t, btc.Contributions, []Contribution{
+		{Type: "Deposit", Count: 2, Amount: 1.1},
+		{Type: "Transfer", Count: 1, Amount: 0.5},
+		{Type: "Withdrawal", Count: 1, Amount: -0.2},
+	})
+}
+
+func assertContributions(t *testing.T, got, want []Contribution) {
+	t.Helper()
+	match := len(got) == len(want)
+	for i := 0; match && i < len(got); i++ {
+		match = got[i].Type == want[i].Type && got[i].Count == want[i].Count && metricNear(got[i].Amount, want[i].Amount)
+	}
+	if !match {
+		t.Errorf("出入金 = %+v，应为 %+v", got, want)
+	}
+}
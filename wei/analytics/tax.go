@@ -0,0 +1,291 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// 税务报告文件（tax 命令生成，前缀可配置）
+const (
+	TaxLotsSuffix    = "_lots.csv"
+	TaxSummarySuffix = "_summary.csv"
+	TaxHTMLSuffix    = "_report.html"
+)
+
+// TaxOptions 税务报告参数
+type TaxOptions struct {
+	Method   bitmex.CostMethod   // 平仓结转成本的方法
+	Location *time.Location      // 年度边界的时区，nil 为 UTC
+	Prices   *bitmex.PriceSeries // XBTUSD 价格，用于把 BTC 金额换算为美元
+	Year     int                 // 只统计该年度，0 为全部年份
+}
+
+// TaxLot 一个税务批次：一次平仓结转的开仓批次。盈亏为结算币种主单位（BTC / USDT）和平仓时换算的美元
+type TaxLot struct {
+	Year        int       `json:"year"`
+	Symbol      string    `json:"symbol"`
+	Currency    string    `json:"currency"` // 结算币种
+	Side        string    `json:"side"`     // 平掉的持仓方向 Long / Short
+	Qty         int       `json:"qty"`
+	Acquired    time.Time `json:"acquired"` // 开仓时间
+	Disposed    time.Time `json:"disposed"` // 平仓时间
+	HoldingDays int       `json:"holdingDays"`
+	LongTerm    bool      `json:"longTerm"` // 持有超过一年
+	EntryPrice  float64   `json:"entryPrice"`
+	ExitPrice   float64   `json:"exitPrice"`
+	Gain        float64   `json:"gain"`    // 已实现盈亏（未扣手续费）
+	XBTUSD      float64   `json:"xbtusd"`  // 平仓时的 XBTUSD 价格
+	GainUSD     float64   `json:"gainUsd"` // 已实现盈亏的美元价值
+}
+
+// TaxYear 一个年度的汇总，金额为美元（每条记录按发生时的价格换算）
+type TaxYear struct {
+	Year           int     `json:"year"`
+	Lots           int     `json:"lots"`
+	Trades         int     `json:"trades"`         // 成交笔数
+	Gains          float64 `json:"gains"`          // 盈利批次合计
+	Losses         float64 `json:"losses"`         // 亏损批次合计（负值）
+	NetGains       float64 `json:"netGains"`       // 已实现净盈亏（未扣手续费）
+	ShortTerm      float64 `json:"shortTerm"`      // 持有一年以内的净盈亏
+	LongTerm       float64 `json:"longTerm"`       // 持有超过一年的净盈亏
+	Fees           float64 `json:"fees"`           // 交易手续费（负值为返佣）
+	FundingIncome  float64 `json:"fundingIncome"`  // 收到的资金费用
+	FundingExpense float64 `json:"fundingExpense"` // 支付的资金费用（正值）
+	Net            float64 `json:"net"`            // 净额 = 已实现净盈亏 - 手续费 + 资金费用收入 - 资金费用支出
+	WalletRealised float64 `json:"walletRealised"` // 钱包 RealisedPNL 合计（已扣手续费），用于核对 NetGains - Fees
+	Unpriced       int     `json:"unpriced"`       // 没有价格、未计入美元金额的记录数
+}
+
+// TaxReport 税务报告
+type TaxReport struct {
+	Method      string    `json:"method"`
+	Timezone    string    `json:"timezone"`
+	PriceSource string    `json:"priceSource"`
+	Generated   time.Time `json:"generated"`
+	Years       []TaxYear `json:"years"`
+	Total       TaxYear   `json:"total"` // 所选年度的合计（Year 为 0）
+	Lots        []TaxLot  `json:"lots,omitempty"`
+	Unknown     []string  `json:"unknown,omitempty"` // 合约信息中没有、无法核算的合约
+}
+
+// BuildTaxReport 由成交记录和钱包历史生成按年度的税务报告：
+// 成交记录按 opts.Method 结转成本得到每个税务批次的已实现盈亏和手续费，钱包 Funding 记录为资金费用收支。
+// 所有金额按发生时刻的 XBTUSD 价格换算为美元，年度按 opts.Location 时区划分
+func BuildTaxReport(executions []bitmex.Execution, wallet []bitmex.WalletHistory, catalog bitmex.Catalog, opts TaxOptions) TaxReport {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	report := TaxReport{
+		Method:    string(opts.Method),
+		Timezone:  loc.String(),
+		Generated: time.Now(),
+		Years:     []TaxYear{},
+		Lots:      []TaxLot{},
+	}
+	if opts.Prices != nil {
+		report.PriceSource = string(opts.Prices.Source)
+	}
+
+	years := make(map[int]*TaxYear)
+	yearOf := func(t time.Time) (*TaxYear, bool) {
+		y := t.In(loc).Year()
+		if opts.Year != 0 && y != opts.Year {
+			return nil, false
+		}
+		if years[y] == nil {
+			years[y] = &TaxYear{Year: y}
+		}
+		return years[y], true
+	}
+	// usd 把结算币种最小单位的金额换算为美元，没有价格时记入 Unpriced
+	usd := func(y *TaxYear, currency string, minor float64, at time.Time) float64 {
+		amount := bitmex.FromMinorUnits(currency, int64(math.Round(minor)))
+		v, ok := bitmex.ToUSD(currency, amount, opts.Prices.At(at))
+		if !ok {
+			y.Unpriced++
+		}
+		return v
+	}
+
+	sorted := make([]bitmex.Execution, len(executions))
+	copy(sorted, executions)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time().Before(sorted[j].Time()) })
+
+	book := bitmex.NewBook(catalog, opts.Method)
+	book.OnClose = func(d bitmex.Disposal) {
+		y, ok := yearOf(d.CloseTime)
+		if !ok {
+			return
+		}
+		inst := book.Accounts[d.Symbol].Instrument
+		lot := TaxLot{
+			Year:        y.Year,
+			Symbol:      d.Symbol,
+			Currency:    inst.SettlCurrency,
+			Side:        "Short",
+			Qty:         d.Qty,
+			Acquired:    d.OpenTime,
+			Disposed:    d.CloseTime,
+			HoldingDays: int(d.CloseTime.Sub(d.OpenTime).Hours() / 24),
+			LongTerm:    d.CloseTime.After(d.OpenTime.AddDate(1, 0, 0)),
+			EntryPrice:  d.EntryPrice,
+			ExitPrice:   d.ExitPrice,
+			Gain:        bitmex.FromMinorUnits(inst.SettlCurrency, int64(math.Round(d.Realized))),
+			XBTUSD:      opts.Prices.At(d.CloseTime),
+		}
+		if d.Long {
+			lot.Side = "Long"
+		}
+		lot.GainUSD = usd(y, inst.SettlCurrency, d.Realized, d.CloseTime)
+
+		y.Lots++
+		if lot.GainUSD >= 0 {
+			y.Gains += lot.GainUSD
+		} else {
+			y.Losses += lot.GainUSD
+		}
+		if lot.LongTerm {
+			y.LongTerm += lot.GainUSD
+		} else {
+			y.ShortTerm += lot.GainUSD
+		}
+		report.Lots = append(report.Lots, lot)
+	}
+	for _, e := range sorted {
+		// 先按时间处理所有成交以保持持仓连续，只统计所选年度
+		_, fee, ok := book.Apply(e)
+		if !ok {
+			continue
+		}
+		if y, in := yearOf(e.Time()); in {
+			y.Trades++
+			y.Fees += usd(y, book.Accounts[e.Symbol].Instrument.SettlCurrency, fee, e.Time())
+		}
+	}
+	sort.Strings(book.Unknown)
+	report.Unknown = book.Unknown
+
+	for _, h := range wallet {
+		if h.TransactStatus == "Canceled" || h.TransactStatus == "Pending" {
+			continue
+		}
+		if h.TransactType != "Funding" && h.TransactType != "RealisedPNL" {
+			continue
+		}
+		y, ok := yearOf(h.Time())
+		if !ok {
+			continue
+		}
+		amount := usd(y, h.Currency, float64(h.Amount), h.Time())
+		switch {
+		case h.TransactType == "RealisedPNL":
+			y.WalletRealised += amount
+		case amount >= 0:
+			y.FundingIncome += amount
+		default:
+			y.FundingExpense -= amount
+		}
+	}
+
+	for _, y := range years {
+		y.NetGains = y.Gains + y.Losses
+		y.Net = y.NetGains - y.Fees + y.FundingIncome - y.FundingExpense
+		report.Years = append(report.Years, *y)
+	}
+	sort.Slice(report.Years, func(i, j int) bool { return report.Years[i].Year < report.Years[j].Year })
+	for _, y := range report.Years {
+		t := &report.Total
+		t.Lots += y.Lots
+		t.Trades += y.Trades
+		t.Gains += y.Gains
+		t.Losses += y.Losses
+		t.NetGains += y.NetGains
+		t.ShortTerm += y.ShortTerm
+		t.LongTerm += y.LongTerm
+		t.Fees += y.Fees
+		t.FundingIncome += y.FundingIncome
+		t.FundingExpense += y.FundingExpense
+		t.Net += y.Net
+		t.WalletRealised += y.WalletRealised
+		t.Unpriced += y.Unpriced
+	}
+	return report
+}
+
+// TaxLotsHeader 税务批次 CSV 表头
+var TaxLotsHeader = []string{
+	"Year", "Symbol", "Currency", "Side", "Qty", "Acquired", "Disposed", "HoldingDays", "Term",
+	"EntryPrice", "ExitPrice", "Gain", "XBTUSD", "Gain_USD",
+}
+
+// TaxSummaryHeader 年度汇总 CSV 表头
+var TaxSummaryHeader = []string{
+	"Year", "Lots", "Trades", "Gains_USD", "Losses_USD", "NetGains_USD", "ShortTerm_USD", "LongTerm_USD",
+	"Fees_USD", "FundingIncome_USD", "FundingExpense_USD", "Net_USD", "WalletRealised_USD", "Unpriced",
+}
+
+// WriteTaxLots 以 CSV 写入税务批次，时间按 loc 时区
+func WriteTaxLots(w io.Writer, lots []TaxLot, loc *time.Location) error {
+	rows := make([][]string, 0, len(lots))
+	for _, l := range lots {
+		term := "Short"
+		if l.LongTerm {
+			term = "Long"
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(l.Year),
+			l.Symbol,
+			l.Currency,
+			l.Side,
+			strconv.Itoa(l.Qty),
+			l.Acquired.In(loc).Format(time.RFC3339),
+			l.Disposed.In(loc).Format(time.RFC3339),
+			strconv.Itoa(l.HoldingDays),
+			term,
+			strconv.FormatFloat(l.EntryPrice, 'f', -1, 64),
+			strconv.FormatFloat(l.ExitPrice, 'f', -1, 64),
+			strconv.FormatFloat(l.Gain, 'f', -1, 64),
+			fmt.Sprintf("%.2f", l.XBTUSD),
+			fmt.Sprintf("%.2f", l.GainUSD),
+		})
+	}
+	return writeRows(w, TaxLotsHeader, rows)
+}
+
+// WriteTaxSummary 以 CSV 写入年度汇总，最后一行为合计（Year 为 Total）
+func WriteTaxSummary(w io.Writer, report TaxReport) error {
+	rows := make([][]string, 0, len(report.Years)+1)
+	for _, y := range append(report.Years, report.Total) {
+		year := strconv.Itoa(y.Year)
+		if y.Year == 0 {
+			year = "Total"
+		}
+		row := []string{year, strconv.Itoa(y.Lots), strconv.Itoa(y.Trades)}
+		for _, v := range []float64{y.Gains, y.Losses, y.NetGains, y.ShortTerm, y.LongTerm,
+			y.Fees, y.FundingIncome, y.FundingExpense, y.Net, y.WalletRealised} {
+			row = append(row, fmt.Sprintf("%.2f", v))
+		}
+		rows = append(rows, append(row, strconv.Itoa(y.Unpriced)))
+	}
+	return writeRows(w, TaxSummaryHeader, rows)
+}
+
+// writeRows 写入 CSV 表头和记录
+func writeRows(w io.Writer, header []string, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("写入表头失败: %w", err)
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("写入记录失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// taxFixture XBTUSD（反向合约）的成交和钱包记录：
+// 2023-01-01 00:00Z 买入 1000 @ 20000（批次 A），2023-06-01 买入 1000 @ 25000（批次 B），
+// 之后在 40000 分三次卖出 400 / 400 / 700，最后一次同时结转 A 的剩余 200 和 B 的 500。
+// XBTUSD 价格 2023-12-31 之前为 16000，之后为 40000
+func taxFixture() ([]bitmex.Execution, []bitmex.WalletHistory, *bitmex.PriceSeries) {
+	trade := func(id, symbol, side string, qty int, px float64, at string) bitmex.Execution {
+		return bitmex.Execution{ExecID: id, ExecType: "Trade", Symbol: symbol, Side: side,
+			LastQty: qty, LastPx: px, Commission: 0.0005, Currency: "USD", TransactTime: at, Timestamp: at}
+	}
+	executions := []bitmex.Execution{
+		trade("e5", "XBTUSD", "Sell", 700, 40000, "2024-01-01T00:00:01.000Z"), // 持有 A 刚超过一年
+		trade("e1", "XBTUSD", "Buy", 1000, 20000, "2023-01-01T00:00:00.000Z"),
+		trade("e2", "XBTUSD", "Buy", 1000, 25000, "2023-06-01T00:00:00.000Z"),
+		trade("e3", "XBTUSD", "Sell", 400, 40000, "2023-12-31T17:00:00.000Z"), // 北京时间 2024-01-01 01:00
+		trade("e4", "XBTUSD", "Sell", 400, 40000, "2024-01-01T00:00:00.000Z"), // 持有 A 正好一年
+		trade("u1", "DOGEUSDZ24", "Buy", 10, 0.1, "2024-02-01T00:00:00.000Z"), // 合约信息中没有
+		{ExecID: "f1", ExecType: "Funding", Symbol: "XBTUSD", LastQty: 1000, LastPx: 20000, TransactTime: "2023-03-01T04:00:00.000Z"},
+	}
+	funding := func(id, status string, amount int64, at string) bitmex.WalletHistory {
+		return bitmex.WalletHistory{TransactID: id, TransactType: "Funding", TransactStatus: status,
+			Currency: "XBt", Amount: amount, Address: "XBTUSD", Timestamp: at}
+	}
+	wallet := []bitmex.WalletHistory{
+		funding("w1", "Completed", 5000, "2023-06-01T04:00:00.000Z"),
+		funding("w2", "Completed", -10000, "2023-12-31T16:30:00.000Z"), // 北京时间 2024-01-01 00:30
+		funding("", "Pending", -99999, "2024-01-02T04:00:00.000Z"),
+		funding("w3", "Canceled", -99999, "2024-01-02T04:00:00.000Z"),
+		{TransactID: "w4", TransactType: "RealisedPNL", TransactStatus: "Completed", Currency: "XBt", Amount: 3000000,
+			Address: "XBTUSD", Timestamp: "2024-01-02T12:00:00.000Z"},
+	}
+	prices := bitmex.NewPriceSeries([]bitmex.Kline{
+		{Timestamp: time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC), Symbol: "XBTUSD", Close: 16000},
+		{Timestamp: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), Symbol: "XBTUSD", Close: 40000},
+	}, 24*time.Hour, bitmex.PriceClose)
+	return executions, wallet, prices
+}
+
+func TestBuildTaxReportLots(t *testing.T) {
+	executions, wallet, prices := taxFixture()
+	beijing, err := bitmex.ParseTimezone("Beijing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := BuildTaxReport(executions, wallet, nil, TaxOptions{Method: bitmex.FIFO, Location: beijing, Prices: prices})
+
+	a := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	// 反向合约盈亏 = 1e8*qty/entry - 1e8*qty/exit（Satoshi）
+	want := []TaxLot{
+		{Year: 2024, Qty: 400, Acquired: a, Disposed: time.Date(2023, 12, 31, 17, 0, 0, 0, time.UTC), HoldingDays: 364, EntryPrice: 20000, Gain: 0.01, GainUSD: 400},
+		{Year: 2024, Qty: 400, Acquired: a, Disposed: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), HoldingDays: 365, EntryPrice: 20000, Gain: 0.01, GainUSD: 400},
+		{Year: 2024, Qty: 200, Acquired: a, Disposed: time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC), HoldingDays: 365, LongTerm: true, EntryPrice: 20000, Gain: 0.005, GainUSD: 200},
+		{Year: 2024, Qty: 500, Acquired: b, Disposed: time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC), HoldingDays: 214, EntryPrice: 25000, Gain: 0.0075, GainUSD: 300},
+	}
+	if len(report.Lots) != len(want) {
+		t.Fatalf("得到 %d 个批次，应为 %d: %+v", len(report.Lots), len(want), report.Lots)
+	}
+	for i, w := range want {
+		l := report.Lots[i]
+		if l.Year != w.Year || l.Symbol != "XBTUSD" || l.Currency != "XBt" || l.Side != "Long" || l.Qty != w.Qty ||
+			!l.Acquired.Equal(w.Acquired) || !l.Disposed.Equal(w.Disposed) || l.HoldingDays != w.HoldingDays || l.LongTerm != w.LongTerm ||
+			l.EntryPrice != w.EntryPrice || l.ExitPrice != 40000 || !taxNear(l.Gain, w.Gain) || l.XBTUSD != 40000 || !taxNear(l.GainUSD, w.GainUSD) {
+			t.Errorf("批次[%d] = %+v\n应为 %+v", i, l, w)
+		}
+	}
+	if len(report.Unknown) != 1 || report.Unknown[0] != "DOGEUSDZ24" {
+		t.Errorf("Unknown = %v", report.Unknown)
+	}
+}
+
+func TestBuildTaxReportYears(t *testing.T) {
+	executions, wallet, prices := taxFixture()
+	beijing, _ := bitmex.ParseTimezone("Beijing")
+
+	// 手续费 0.05%：e1 2500、e2 2000 Satoshi（16000），e3 500、e4 500、e5 875 Satoshi（40000）
+	tests := []struct {
+		name string
+		loc  *time.Location
+		year int
+		want []TaxYear
+	}{
+		{
+			name: "北京时间", loc: beijing,
+			want: []TaxYear{
+				{Year: 2023, Trades: 2, Fees: 0.72, FundingIncome: 0.8, Net: -0.72 + 0.8},
+				{Year: 2024, Lots: 4, Trades: 3, Gains: 1300, NetGains: 1300, ShortTerm: 1100, LongTerm: 200,
+					Fees: 0.75, FundingExpense: 4, Net: 1300 - 0.75 - 4, WalletRealised: 1200},
+			},
+		},
+		{
+			// e3 和 w2 在 UTC 属于 2023 年
+			name: "UTC", loc: time.UTC,
+			want: []TaxYear{
+				{Year: 2023, Lots: 1, Trades: 3, Gains: 400, NetGains: 400, ShortTerm: 400,
+					Fees: 0.92, FundingIncome: 0.8, FundingExpense: 4, Net: 400 - 0.92 + 0.8 - 4},
+				{Year: 2024, Lots: 3, Trades: 2, Gains: 900, NetGains: 900, ShortTerm: 700, LongTerm: 200,
+					Fees: 0.55, Net: 900 - 0.55, WalletRealised: 1200},
+			},
+		},
+		{
+			name: "只统计 2024 年", loc: beijing, year: 2024,
+			want: []TaxYear{
+				{Year: 2024, Lots: 4, Trades: 3, Gains: 1300, NetGains: 1300, ShortTerm: 1100, LongTerm: 200,
+					Fees: 0.75, FundingExpense: 4, Net: 1300 - 0.75 - 4, WalletRealised: 1200},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := BuildTaxReport(executions, wallet, nil, TaxOptions{Method: bitmex.FIFO, Location: tt.loc, Prices: prices, Year: tt.year})
+			if len(report.Years) != len(tt.want) {
+				t.Fatalf("年度 = %+v", report.Years)
+			}
+			var total TaxYear
+			for i, w := range tt.want {
+				assertTaxYear(t, report.Years[i], w)
+				total.Lots += w.Lots
+				total.Trades += w.Trades
+				total.Gains += w.Gains
+				total.NetGains += w.NetGains
+				total.ShortTerm += w.ShortTerm
+				total.LongTerm += w.LongTerm
+				total.Fees += w.Fees
+				total.FundingIncome += w.FundingIncome
+				total.FundingExpense += w.FundingExpense
+				total.Net += w.Net
+				total.WalletRealised += w.WalletRealised
+			}
+			assertTaxYear(t, report.Total, total)
+			if tt.year != 0 {
+				for _, l := range report.Lots {
+					if l.Year != tt.year {
+						t.Errorf("批次年度 %d 不在所选年度", l.Year)
+					}
+				}
+			}
+		})
+	}
+}
+
+// 平均成本把 A、B 合并为一个批次，开仓时间为持仓的开仓时间
+func TestBuildTaxReportAverageCost(t *testing.T) {
+	executions, wallet, prices := taxFixture()
+	report := BuildTaxReport(executions, wallet, nil, TaxOptions{Method: bitmex.AverageCost, Prices: prices})
+
+	if len(report.Lots) != 3 {
+		t.Fatalf("得到 %d 个批次，应为 3（每次平仓一个）", len(report.Lots))
+	}
+	last := report.Lots[2]
+	if last.Qty != 700 || !last.LongTerm || !last.Acquired.Equal(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("最后一个批次 = %+v", last)
+	}
+	// 均价为调和平均 1e8*2000 / (5e6 + 4e6)，三次平仓合计 1500 张
+	entry := 1e8 * 2000 / 9e6
+	gain := (1e8*1500/entry - 1e8*1500/40000) / 1e8 * 40000
+	if !taxNear(report.Total.NetGains, gain) {
+		t.Errorf("NetGains = %v，应为 %v", report.Total.NetGains, gain)
+	}
+}
+
+func assertTaxYear(t *testing.T, got, want TaxYear) {
+	t.Helper()
+	if got.Year != want.Year || got.Lots != want.Lots || got.Trades != want.Trades || got.Unpriced != want.Unpriced ||
+		!taxNear(got.Gains, want.Gains) || !taxNear(got.Losses, want.Losses) || !taxNear(got.NetGains, want.NetGains) ||
+		!taxNear(got.ShortTerm, want.ShortTerm) || !taxNear(got.LongTerm, want.LongTerm) || !taxNear(got.Fees, want.Fees) ||
+		!taxNear(got.FundingIncome, want.FundingIncome) || !taxNear(got.FundingExpense, want.FundingExpense) ||
+		!taxNear(got.Net, want.Net) || !taxNear(got.WalletRealised, want.WalletRealised) {
+		t.Errorf("年度 = %+v\n应为 %+v", got, want)
+	}
+}
+
+func taxNear(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
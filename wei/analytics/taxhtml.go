@@ -0,0 +1,126 @@
+package analytics
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+// taxTemplate 可打印的税务报告，每个年度的批次明细单独分页
+var taxTemplate = template.Must(template.New("tax").Funcs(template.FuncMap{
+	"usd": func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"num": func(v float64) string { return fmt.Sprintf("%g", v) },
+	"term": func(long bool) string {
+		if long {
+			return "长期"
+		}
+		return "短期"
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="UTF-8">
+<title>BitMEX 税务报告</title>
+<style>
+body { font-family: -apple-system, "PingFang SC", "Microsoft YaHei", sans-serif; font-size: 12px; color: #222; margin: 24px; }
+h1 { font-size: 20px; margin-bottom: 4px; }
+h2 { font-size: 15px; margin-top: 28px; border-bottom: 1px solid #999; padding-bottom: 4px; }
+.meta { color: #555; margin-bottom: 16px; }
+table { border-collapse: collapse; width: 100%; margin-top: 8px; }
+th, td { border: 1px solid #ccc; padding: 3px 6px; text-align: right; white-space: nowrap; }
+th { background: #f0f0f0; }
+td.text, th.text { text-align: left; }
+tr.total td { font-weight: bold; background: #fafafa; }
+.neg { color: #c00; }
+.note { color: #555; margin-top: 8px; }
+@media print {
+	body { margin: 0; font-size: 10px; }
+	.page { page-break-before: always; }
+	th { background: #eee !important; -webkit-print-color-adjust: exact; print-color-adjust: exact; }
+}
+</style>
+</head>
+<body>
+<h1>BitMEX 税务报告</h1>
+<div class="meta">
+成本计算方法: {{.Report.Method}} ｜ 年度时区: {{.Report.Timezone}} ｜ 美元价格: XBTUSD {{.Report.PriceSource}} ｜
+生成时间: {{.Generated}}
+</div>
+
+<h2>年度汇总（美元）</h2>
+<table>
+<tr><th class="text">年度</th><th>批次</th><th>成交</th><th>盈利</th><th>亏损</th><th>已实现净盈亏</th><th>短期</th><th>长期</th>
+<th>手续费</th><th>资金费用收入</th><th>资金费用支出</th><th>净额</th><th>钱包已实现盈亏</th></tr>
+{{range .Report.Years}}<tr>
+<td class="text">{{.Year}}</td><td>{{.Lots}}</td><td>{{.Trades}}</td><td>{{usd .Gains}}</td><td class="neg">{{usd .Losses}}</td>
+<td>{{usd .NetGains}}</td><td>{{usd .ShortTerm}}</td><td>{{usd .LongTerm}}</td><td>{{usd .Fees}}</td>
+<td>{{usd .FundingIncome}}</td><td>{{usd .FundingExpense}}</td><td>{{usd .Net}}</td><td>{{usd .WalletRealised}}</td>
+</tr>
+{{end}}{{with .Report.Total}}<tr class="total">
+<td class="text">合计</td><td>{{.Lots}}</td><td>{{.Trades}}</td><td>{{usd .Gains}}</td><td class="neg">{{usd .Losses}}</td>
+<td>{{usd .NetGains}}</td><td>{{usd .ShortTerm}}</td><td>{{usd .LongTerm}}</td><td>{{usd .Fees}}</td>
+<td>{{usd .FundingIncome}}</td><td>{{usd .FundingExpense}}</td><td>{{usd .Net}}</td><td>{{usd .WalletRealised}}</td>
+</tr>{{end}}
+</table>
+<div class="note">
+净额 = 已实现净盈亏 − 手续费 + 资金费用收入 − 资金费用支出。每笔金额按发生时刻的 XBTUSD 价格换算为美元；
+钱包已实现盈亏（已扣手续费）用于核对“已实现净盈亏 − 手续费”。持有超过一年的批次为长期。
+{{if .Report.Total.Unpriced}}<br>⚠ {{.Report.Total.Unpriced}} 条记录没有价格，未计入美元金额。{{end}}
+{{if .Report.Unknown}}<br>⚠ 合约信息中没有以下合约，未计入: {{range .Report.Unknown}}{{.}} {{end}}{{end}}
+</div>
+
+{{range .Years}}
+<div class="page">
+<h2>{{.Year}} 年税务批次（{{len .Lots}} 条）</h2>
+<table>
+<tr><th class="text">合约</th><th class="text">方向</th><th>数量</th><th class="text">开仓时间</th><th class="text">平仓时间</th>
+<th>持有天数</th><th class="text">期限</th><th>开仓价</th><th>平仓价</th><th class="text">币种</th><th>盈亏</th><th>XBTUSD</th><th>盈亏（美元）</th></tr>
+{{range .Lots}}<tr>
+<td class="text">{{.Symbol}}</td><td class="text">{{.Side}}</td><td>{{.Qty}}</td>
+<td class="text">{{.Acquired}}</td><td class="text">{{.Disposed}}</td><td>{{.HoldingDays}}</td><td class="text">{{term .LongTerm}}</td>
+<td>{{num .EntryPrice}}</td><td>{{num .ExitPrice}}</td><td class="text">{{.Currency}}</td><td>{{num .Gain}}</td>
+<td>{{usd .XBTUSD}}</td><td{{if lt .GainUSD 0.0}} class="neg"{{end}}>{{usd .GainUSD}}</td>
+</tr>
+{{end}}</table>
+</div>
+{{end}}
+</body>
+</html>
+`))
+
+// taxLotView 税务批次的显示格式（时间按报告时区）
+type taxLotView struct {
+	TaxLot
+	Acquired, Disposed string
+}
+
+// WriteTaxHTML 写入可打印的 HTML 税务报告，包含 report.Lots 中各年度的批次明细，时间按 loc 时区
+func WriteTaxHTML(w io.Writer, report TaxReport, loc *time.Location) error {
+	type yearLots struct {
+		Year int
+		Lots []taxLotView
+	}
+	var years []yearLots
+	for _, l := range report.Lots {
+		if len(years) == 0 || years[len(years)-1].Year != l.Year {
+			years = append(years, yearLots{Year: l.Year})
+		}
+		y := &years[len(years)-1]
+		y.Lots = append(y.Lots, taxLotView{
+			TaxLot:   l,
+			Acquired: l.Acquired.In(loc).Format("2006-01-02 15:04"),
+			Disposed: l.Disposed.In(loc).Format("2006-01-02 15:04"),
+		})
+	}
+
+	data := struct {
+		Report    TaxReport
+		Generated string
+		Years     []yearLots
+	}{report, report.Generated.In(loc).Format("2006-01-02 15:04:05"), years}
+	if err := taxTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("生成 HTML 报告失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+package bitmex
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// CostMethod 持仓成本的计算方法
+type CostMethod string
+
+const (
+	AverageCost CostMethod = "average" // 平均成本：加仓时合并成本，减仓按均价结转
+	FIFO        CostMethod = "fifo"    // 先进先出：减仓从最早的开仓批次开始结转
+)
+
+// ParseCostMethod 解析成本计算方法，空字符串为 AverageCost
+func ParseCostMethod(s string) (CostMethod, error) {
+	switch CostMethod(s) {
+	case "", AverageCost:
+		return AverageCost, nil
+	case FIFO:
+		return FIFO, nil
+	}
+	return "", fmt.Errorf("未知成本计算方法: %s（可用: average, fifo）", s)
+}
+
+// Lot 一个未平仓的开仓批次
+type Lot struct {
+	Time  time.Time
+	Qty   int     // 剩余数量（绝对值）
+	Price float64 // 开仓价格
+}
+
+// Disposal 一次平仓结转的开仓批次，盈亏为结算币种最小单位（未扣手续费）。
+// 先进先出时每个被平掉的批次一条，平均成本时每次平仓一条
+type Disposal struct {
+	Symbol     string
+	Long       bool      // 平掉的是多头
+	Qty        int       // 平仓数量（绝对值）
+	OpenTime   time.Time // 批次开仓时间，平均成本时为当前持仓的开仓时间
+	CloseTime  time.Time
+	EntryPrice float64
+	ExitPrice  float64
+	Realized   float64
+}
+
+// PositionAccount 一个合约的持仓核算。按时间顺序处理成交，
+// 持仓回到零或反手时重新开始计算成本，盈亏和手续费单位为结算币种最小单位。
+type PositionAccount struct {
+	Instrument Instrument
+	Method     CostMethod
+	Qty        int       // 当前持仓，多头为正
+	OpenTime   time.Time // 当前持仓的开仓时间，空仓时为零值
+	LastPrice  float64   // 最后成交价
+	LastTime   time.Time
+	Lots       []Lot   // 未平仓批次（按开仓时间），AverageCost 时合并为一个批次
+	Realized   float64 // 累计已实现盈亏（未扣手续费）
+	Fees       float64 // 累计手续费（负值为返佣）
+	Fills      int
+	OnClose    func(Disposal) // 不为 nil 时每次结转开仓批次都会调用
+
+	costValue float64 // 当前持仓的成本价值
+}
+
+// NewPositionAccount 创建合约的持仓核算
+func NewPositionAccount(inst Instrument, method CostMethod) *PositionAccount {
+	return &PositionAccount{Instrument: inst, Method: method}
+}
+
+// Fill 处理一笔成交，qty 多头为正、空头为负，commission 为手续费率。
+// 返回本次成交的已实现盈亏和手续费。反手时先平掉原有持仓，剩余部分按成交价开新仓。
+func (a *PositionAccount) Fill(qty int, price, commission float64, at time.Time) (realized, fee float64) {
+	if qty == 0 {
+		return 0, 0
+	}
+	inst := a.Instrument
+	fee = commission * math.Abs(inst.Value(float64(qty), price))
+	a.Fees += fee
+	a.Fills++
+	a.LastPrice = price
+	a.LastTime = at
+
+	// 减仓 / 平仓
+	if a.Qty != 0 && (qty > 0) != (a.Qty > 0) {
+		closing := min(abs(qty), abs(a.Qty))
+		sign := 1
+		if a.Qty < 0 {
+			sign = -1
+		}
+		realized = a.close(closing, sign, price, at)
+		a.Qty -= sign * closing
+		qty += sign * closing
+		if a.Qty == 0 {
+			a.Lots = nil
+			a.costValue = 0
+			a.OpenTime = time.Time{}
+		}
+	}
+
+	// 开仓 / 加仓（含反手后的剩余部分）
+	if qty != 0 {
+		if a.Qty == 0 {
+			a.OpenTime = at
+		}
+		a.Qty += qty
+		a.costValue += math.Abs(inst.Value(float64(qty), price))
+		if a.Method == AverageCost && len(a.Lots) > 0 {
+			a.Lots[0].Qty += abs(qty)
+			a.Lots[0].Price = a.EntryPrice()
+		} else {
+			a.Lots = append(a.Lots, Lot{Time: at, Qty: abs(qty), Price: price})
+		}
+	}
+
+	a.Realized += realized
+	return realized, fee
+}
+
+// close 平掉 qty 张（绝对值）方向为 sign 的持仓，返回已实现盈亏
+func (a *PositionAccount) close(qty, sign int, price float64, at time.Time) float64 {
+	inst := a.Instrument
+	dispose := func(n int, lot Lot, pnl float64) {
+		if a.OnClose != nil {
+			a.OnClose(Disposal{
+				Symbol: inst.Symbol, Long: sign > 0, Qty: n, OpenTime: lot.Time, CloseTime: at,
+				EntryPrice: lot.Price, ExitPrice: price, Realized: pnl,
+			})
+		}
+	}
+	if a.Method == AverageCost {
+		entry := a.EntryPrice()
+		a.costValue -= a.costValue * float64(qty) / float64(abs(a.Qty))
+		a.Lots[0].Qty -= qty
+		pnl := inst.PnL(float64(sign*qty), entry, price)
+		dispose(qty, Lot{Time: a.OpenTime, Price: entry}, pnl)
+		return pnl
+	}
+
+	var pnl float64
+	for qty > 0 && len(a.Lots) > 0 {
+		lot := &a.Lots[0]
+		n := min(qty, lot.Qty)
+		lotPnL := inst.PnL(float64(sign*n), lot.Price, price)
+		dispose(n, *lot, lotPnL)
+		pnl += lotPnL
+		a.costValue -= math.Abs(inst.Value(float64(n), lot.Price))
+		lot.Qty -= n
+		qty -= n
+		if lot.Qty == 0 {
+			a.Lots = a.Lots[1:]
+		}
+	}
+	return pnl
+}
+
+// EntryPrice 当前持仓的开仓均价（反向合约为调和平均），空仓时为 0
+func (a *PositionAccount) EntryPrice() float64 {
+	if a.Qty == 0 {
+		return 0
+	}
+	return a.Instrument.AvgPrice(float64(abs(a.Qty)), a.costValue)
+}
+
+// Unrealized 按 mark 价格计算的未实现盈亏（最小单位）
+func (a *PositionAccount) Unrealized(mark float64) float64 {
+	if a.Qty == 0 || mark <= 0 {
+		return 0
+	}
+	if a.Method == AverageCost {
+		return a.Instrument.PnL(float64(a.Qty), a.EntryPrice(), mark)
+	}
+	sign := 1
+	if a.Qty < 0 {
+		sign = -1
+	}
+	var pnl float64
+	for _, lot := range a.Lots {
+		pnl += a.Instrument.PnL(float64(sign*lot.Qty), lot.Price, mark)
+	}
+	return pnl
+}
+
+// Book 所有合约的持仓核算
+type Book struct {
+	Method   CostMethod
+	Accounts map[string]*PositionAccount
+	Unknown  []string       // 合约信息中没有、无法核算的合约
+	OnClose  func(Disposal) // 设置给新建的各合约持仓核算，见 PositionAccount.OnClose
+
+	catalog Catalog
+}
+
+// NewBook 创建持仓核算
+func NewBook(catalog Catalog, method CostMethod) *Book {
+	return &Book{Method: method, Accounts: make(map[string]*PositionAccount), catalog: catalog}
+}
+
+// Apply 处理一笔成交，只处理 Trade 类型（资金费用等记录不改变持仓）。
+// 返回本次成交的已实现盈亏和手续费（最小单位），未处理时 ok 为 false
+func (b *Book) Apply(e Execution) (realized, fee float64, ok bool) {
+	if e.ExecType != "Trade" || e.LastQty == 0 || e.LastPx <= 0 {
+		return 0, 0, false
+	}
+	a, found := b.Accounts[e.Symbol]
+	if !found {
+		inst, known := b.catalog.Lookup(e.Symbol)
+		if !known {
+			for _, s := range b.Unknown {
+				if s == e.Symbol {
+					return 0, 0, false
+				}
+			}
+			b.Unknown = append(b.Unknown, e.Symbol)
+			return 0, 0, false
+		}
+		a = NewPositionAccount(inst, b.Method)
+		a.OnClose = b.OnClose
+		b.Accounts[e.Symbol] = a
+	}
+
+	qty := e.LastQty
+	if e.Side != "Buy" {
+		qty = -qty
+	}
+	realized, fee = a.Fill(qty, e.LastPx, e.Commission, e.Time())
+	return realized, fee, true
+}
+
+// BuildBook 按时间顺序处理截至 until（含，零值表示全部）的成交
+func BuildBook(executions []Execution, catalog Catalog, method CostMethod, until time.Time) *Book {
+	sorted := make([]Execution, 0, len(executions))
+	for _, e := range executions {
+		if until.IsZero() || !e.Time().After(until) {
+			sorted = append(sorted, e)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time().Before(sorted[j].Time()) })
+
+	book := NewBook(catalog, method)
+	for _, e := range sorted {
+		book.Apply(e)
+	}
+	sort.Strings(book.Unknown)
+	return book
+}
+
+// Clone 复制持仓核算，复制后各合约的批次互不影响（不复制 OnClose）
+func (b *Book) Clone() *Book {
+	c := &Book{
+		Method:   b.Method,
+		Accounts: make(map[string]*PositionAccount, len(b.Accounts)),
+		Unknown:  append([]string(nil), b.Unknown...),
+		catalog:  b.catalog,
+	}
+	for symbol, a := range b.Accounts {
+		copied := *a
+		copied.Lots = append([]Lot(nil), a.Lots...)
+		copied.OnClose = nil
+		c.Accounts[symbol] = &copied
+	}
+	return c
+}
+
+// Symbols 按代码排序的合约列表
+func (b *Book) Symbols() []string {
+	symbols := make([]string, 0, len(b.Accounts))
+	for symbol := range b.Accounts {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// RealisedCheck 一个合约的已实现盈亏与钱包 RealisedPNL 的对比，金额为主单位（BTC / USDT）
+type RealisedCheck struct {
+	Symbol     string  `json:"symbol"`
+	Currency   string  `json:"currency"`
+	Realized   float64 `json:"realized"`   // 成交记录计算的已实现盈亏（未扣手续费）
+	Fees       float64 `json:"fees"`       // 手续费
+	Net        float64 `json:"net"`        // Realized - Fees
+	Wallet     float64 `json:"wallet"`     // 钱包 RealisedPNL 合计
+	Funding    float64 `json:"funding"`    // 钱包 Funding 合计（早期的 RealisedPNL 包含资金费用）
+	Difference float64 `json:"difference"` // Wallet - Net
+}
+
+// ReconcileRealised 对比各合约由成交计算的已实现盈亏（扣除手续费）与钱包 RealisedPNL 记录。
+// 钱包每天结算一次，最近一次结算之后的已实现盈亏还不在钱包中；早期的 RealisedPNL 还包含资金费用，
+// 因此同时列出钱包中单独记录的 Funding 以便判断差额来源。
+func ReconcileRealised(book *Book, wallet []WalletHistory) []RealisedCheck {
+	checks := make(map[string]*RealisedCheck)
+	get := func(symbol, currency string) *RealisedCheck {
+		c, ok := checks[symbol]
+		if !ok {
+			c = &RealisedCheck{Symbol: symbol, Currency: currency}
+			checks[symbol] = c
+		}
+		return c
+	}
+
+	for symbol, a := range book.Accounts {
+		c := get(symbol, a.Instrument.SettlCurrency)
+		c.Realized = FromMinorUnits(c.Currency, int64(math.Round(a.Realized)))
+		c.Fees = FromMinorUnits(c.Currency, int64(math.Round(a.Fees)))
+		c.Net = c.Realized - c.Fees
+	}
+	for _, h := range wallet {
+		if h.TransactStatus == "Canceled" || h.Address == "" {
+			continue
+		}
+		switch h.TransactType {
+		case "RealisedPNL":
+			c := get(h.Address, h.Currency)
+			c.Wallet += FromMinorUnits(h.Currency, h.Amount)
+		case "Funding":
+			c := get(h.Address, h.Currency)
+			c.Funding += FromMinorUnits(h.Currency, h.Amount)
+		}
+	}
+
+	result := make([]RealisedCheck, 0, len(checks))
+	for _, c := range checks {
+		c.Difference = c.Wallet - c.Net
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Symbol < result[j].Symbol })
+	return result
+}
@@ -0,0 +1,194 @@
+package bitmex
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var (
+	// 反向合约：1 张 = 1 美元，盈亏以 XBt 结算
+	testInverse = Instrument{Symbol: "XBTUSD", SettlCurrency: "XBt", Multiplier: -100000000, IsInverse: true}
+	// 正向合约：盈亏以 USDt 结算
+	testLinear = Instrument{Symbol: "XBTUSDT", SettlCurrency: "USDt", Multiplier: 1}
+)
+
+type testFill struct {
+	qty   int
+	price float64
+}
+
+func TestPositionAccountFill(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(i int) time.Time { return t0.Add(time.Duration(i) * time.Hour) }
+
+	tests := []struct {
+		name      string
+		inst      Instrument
+		method    CostMethod
+		fills     []testFill // 第 i 笔成交的时间为 at(i)
+		qty       int
+		entry     float64
+		realized  float64
+		lots      []Lot
+		openTime  time.Time
+		disposals []Disposal // 只比较 Long、Qty、OpenTime、EntryPrice、ExitPrice、Realized
+	}{
+		{
+			name: "平均成本部分平仓", inst: testLinear, method: AverageCost,
+			fills: []testFill{{10, 100}, {10, 200}, {-5, 180}},
+			qty:   15, entry: 150, realized: 150,
+			lots:     []Lot{{Time: at(0), Qty: 15, Price: 150}},
+			openTime: at(0),
+			disposals: []Disposal{
+				{Long: true, Qty: 5, OpenTime: at(0), EntryPrice: 150, ExitPrice: 180, Realized: 150},
+			},
+		},
+		{
+			name: "先进先出平仓跨多个批次", inst: testLinear, method: FIFO,
+			fills: []testFill{{10, 100}, {10, 200}, {10, 300}, {-25, 180}},
+			qty:   5, entry: 300, realized: 10*80 + 10*-20 + 5*-120,
+			lots:     []Lot{{Time: at(2), Qty: 5, Price: 300}},
+			openTime: at(0),
+			disposals: []Disposal{
+				{Long: true, Qty: 10, OpenTime: at(0), EntryPrice: 100, ExitPrice: 180, Realized: 800},
+				{Long: true, Qty: 10, OpenTime: at(1), EntryPrice: 200, ExitPrice: 180, Realized: -200},
+				{Long: true, Qty: 5, OpenTime: at(2), EntryPrice: 300, ExitPrice: 180, Realized: -600},
+			},
+		},
+		{
+			name: "先进先出反手", inst: testLinear, method: FIFO,
+			fills: []testFill{{10, 100}, {5, 110}, {-25, 120}},
+			qty:   -10, entry: 120, realized: 10*20 + 5*10,
+			lots:     []Lot{{Time: at(2), Qty: 10, Price: 120}},
+			openTime: at(2),
+			disposals: []Disposal{
+				{Long: true, Qty: 10, OpenTime: at(0), EntryPrice: 100, ExitPrice: 120, Realized: 200},
+				{Long: true, Qty: 5, OpenTime: at(1), EntryPrice: 110, ExitPrice: 120, Realized: 50},
+			},
+		},
+		{
+			name: "平均成本反手后平仓", inst: testLinear, method: AverageCost,
+			fills: []testFill{{10, 100}, {-25, 120}, {15, 110}},
+			qty:   0, entry: 0, realized: 200 + 150,
+			disposals: []Disposal{
+				{Long: true, Qty: 10, OpenTime: at(0), EntryPrice: 100, ExitPrice: 120, Realized: 200},
+				{Long: false, Qty: 15, OpenTime: at(1), EntryPrice: 120, ExitPrice: 110, Realized: 150},
+			},
+		},
+		{
+			// 成本价值 2e6 + 2.5e6 XBt，均价为调和平均 1e8*2000/4.5e6
+			name: "反向合约平均成本", inst: testInverse, method: AverageCost,
+			fills: []testFill{{1000, 50000}, {1000, 40000}, {-2000, 50000}},
+			qty:   0, realized: 4500000 - 4000000,
+			disposals: []Disposal{
+				{Long: true, Qty: 2000, OpenTime: at(0), EntryPrice: 1e8 * 2000 / 4.5e6, ExitPrice: 50000, Realized: 500000},
+			},
+		},
+		{
+			// 同样的价格，正向合约的盈亏与价格差成正比，反向合约与价格倒数差成正比
+			name: "反向合约空头", inst: testInverse, method: FIFO,
+			fills: []testFill{{-1000, 50000}, {600, 40000}},
+			qty:   -400, entry: 50000, realized: 600 * (1e8/40000 - 1e8/50000),
+			lots:     []Lot{{Time: at(0), Qty: 400, Price: 50000}},
+			openTime: at(0),
+			disposals: []Disposal{
+				{Long: false, Qty: 600, OpenTime: at(0), EntryPrice: 50000, ExitPrice: 40000, Realized: 300000},
+			},
+		},
+		{
+			name: "正向合约空头", inst: testLinear, method: FIFO,
+			fills: []testFill{{-1000, 50000}, {600, 40000}},
+			qty:   -400, entry: 50000, realized: 600 * 10000,
+			lots:     []Lot{{Time: at(0), Qty: 400, Price: 50000}},
+			openTime: at(0),
+			disposals: []Disposal{
+				{Long: false, Qty: 600, OpenTime: at(0), EntryPrice: 50000, ExitPrice: 40000, Realized: 6000000},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewPositionAccount(tt.inst, tt.method)
+			var disposals []Disposal
+			a.OnClose = func(d Disposal) { disposals = append(disposals, d) }
+
+			var realized float64
+			for i, f := range tt.fills {
+				r, _ := a.Fill(f.qty, f.price, 0, at(i))
+				realized += r
+			}
+
+			if a.Qty != tt.qty {
+				t.Errorf("Qty = %d，应为 %d", a.Qty, tt.qty)
+			}
+			if !approx(a.EntryPrice(), tt.entry) {
+				t.Errorf("EntryPrice = %v，应为 %v", a.EntryPrice(), tt.entry)
+			}
+			if !approx(a.Realized, tt.realized) || !approx(realized, tt.realized) {
+				t.Errorf("Realized = %v（各笔合计 %v），应为 %v", a.Realized, realized, tt.realized)
+			}
+			if !a.OpenTime.Equal(tt.openTime) {
+				t.Errorf("OpenTime = %v，应为 %v", a.OpenTime, tt.openTime)
+			}
+			if len(a.Lots) != len(tt.lots) {
+				t.Fatalf("Lots = %+v，应为 %+v", a.Lots, tt.lots)
+			}
+			for i, lot := range tt.lots {
+				if got := a.Lots[i]; !got.Time.Equal(lot.Time) || got.Qty != lot.Qty || !approx(got.Price, lot.Price) {
+					t.Errorf("Lots[%d] = %+v，应为 %+v", i, got, lot)
+				}
+			}
+
+			if len(disposals) != len(tt.disposals) {
+				t.Fatalf("结转 %d 次，应为 %d: %+v", len(disposals), len(tt.disposals), disposals)
+			}
+			for i, w := range tt.disposals {
+				d := disposals[i]
+				if d.Symbol != tt.inst.Symbol || d.Long != w.Long || d.Qty != w.Qty || !d.OpenTime.Equal(w.OpenTime) ||
+					!approx(d.EntryPrice, w.EntryPrice) || d.ExitPrice != w.ExitPrice || !approx(d.Realized, w.Realized) {
+					t.Errorf("结转[%d] = %+v\n应为 %+v", i, d, w)
+				}
+			}
+		})
+	}
+}
+
+func TestPositionAccountFees(t *testing.T) {
+	a := NewPositionAccount(testInverse, FIFO)
+	_, fee := a.Fill(1000, 50000, 0.00075, time.Now())
+	if !approx(fee, 0.00075*2000000) {
+		t.Errorf("taker 手续费 = %v", fee)
+	}
+	_, rebate := a.Fill(-1000, 40000, -0.00025, time.Now())
+	if !approx(rebate, -0.00025*2500000) || !approx(a.Fees, fee+rebate) || a.Fills != 2 {
+		t.Errorf("maker 返佣 = %v，累计 %v，%d 笔", rebate, a.Fees, a.Fills)
+	}
+}
+
+// 平均成本和先进先出结转的成本不同，但已实现与未实现盈亏的合计相同
+func TestPositionAccountUnrealized(t *testing.T) {
+	for _, inst := range []Instrument{testInverse, testLinear} {
+		avg := NewPositionAccount(inst, AverageCost)
+		fifo := NewPositionAccount(inst, FIFO)
+		for i, f := range []testFill{{1000, 50000}, {3000, 40000}, {-500, 45000}} {
+			at := time.Unix(int64(i), 0)
+			avg.Fill(f.qty, f.price, 0, at)
+			fifo.Fill(f.qty, f.price, 0, at)
+		}
+		if got, want := avg.Realized+avg.Unrealized(48000), fifo.Realized+fifo.Unrealized(48000); !approx(got, want) {
+			t.Errorf("%s: 平均成本合计 %v，先进先出合计 %v", inst.Symbol, got, want)
+		}
+		if avg.Realized == fifo.Realized {
+			t.Errorf("%s: 两种方法结转的已实现盈亏应不同", inst.Symbol)
+		}
+		if avg.Unrealized(0) != 0 {
+			t.Errorf("%s: 没有标记价格时未实现盈亏应为 0", inst.Symbol)
+		}
+	}
+}
+
+// approx 浮点数是否近似相等（相对误差，绝对值小于 1 时为绝对误差）
+func approx(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
@@ -0,0 +1,208 @@
+package bitmex
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultBaseURL BitMEX REST API 地址
+const DefaultBaseURL = "https://www.bitmex.com/api/v1"
+
+// TimeLayout BitMEX 接口使用的时间格式（UTC，毫秒精度）
+const TimeLayout = "2006-01-02T15:04:05.000Z"
+
+// Client BitMEX REST API 客户端
+type Client struct {
+	BaseURL     string        // API 地址，测试时可指向本地服务
+	HTTPClient  *http.Client  // 为 nil 时使用 10 秒超时的默认客户端
+	Credentials Credentials   // 私有接口使用的凭证
+	PageDelay   time.Duration // 分页请求之间的间隔，避免触发限流
+
+	// Logf 分页进度输出，为 nil 时不输出
+	Logf func(format string, args ...any)
+}
+
+// NewClient 创建使用默认地址的客户端
+func NewClient(creds Credentials) *Client {
+	return &Client{
+		BaseURL:     DefaultBaseURL,
+		HTTPClient:  &http.Client{Timeout: 10 * time.Second},
+		Credentials: creds,
+		PageDelay:   500 * time.Millisecond,
+	}
+}
+
+// APIError 接口返回非 200 状态码
+type APIError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API request failed with status: %s, body: %s", e.Status, e.Body)
+}
+
+// Get 请求 path（如 /execution），auth 为 true 时对请求签名，结果解析到 out（为 nil 则丢弃）
+func (c *Client) Get(path string, query url.Values, auth bool, out any) error {
+	return c.Do(http.MethodGet, path, query, nil, auth, out)
+}
+
+// Do 发送请求，body 不为 nil 时以 JSON 发送
+func (c *Client) Do(method, path string, query url.Values, body any, auth bool, out any) error {
+	endpoint := c.BaseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var payload []byte
+	var reader io.Reader
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if auth {
+		SignRequest(req, c.Credentials, string(payload))
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &APIError{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(data)}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("解析数据失败: %w", err)
+	}
+	return nil
+}
+
+// logf 输出进度
+func (c *Client) logf(format string, args ...any) {
+	if c.Logf != nil {
+		c.Logf(format, args...)
+	}
+}
+
+// Query 历史记录查询条件
+type Query struct {
+	Symbol    string         // 交易对，为空表示全部
+	StartTime time.Time      // 起始时间（含），零值表示不限制
+	EndTime   time.Time      // 结束时间（含），零值表示不限制
+	Filter    map[string]any // BitMEX filter 参数，如 {"ordStatus": "New"}
+}
+
+// values 转换为查询参数
+func (q Query) values() (url.Values, error) {
+	values := url.Values{}
+	if q.Symbol != "" {
+		values.Set("symbol", q.Symbol)
+	}
+	if !q.StartTime.IsZero() {
+		values.Set("startTime", q.StartTime.UTC().Format(TimeLayout))
+	}
+	if !q.EndTime.IsZero() {
+		values.Set("endTime", q.EndTime.UTC().Format(TimeLayout))
+	}
+	if len(q.Filter) > 0 {
+		filter, err := json.Marshal(q.Filter)
+		if err != nil {
+			return nil, err
+		}
+		values.Set("filter", string(filter))
+	}
+	return values, nil
+}
+
+// Endpoint 按 start 偏移分页的历史数据接口
+type Endpoint struct {
+	Path     string     // 接口路径，如 /execution
+	PageSize int        // 每页最大记录数
+	Auth     bool       // 是否需要签名
+	Params   url.Values // 固定参数，如 K线的 binSize
+}
+
+// FetchPages 从第 offset 条记录开始逐页获取（按时间正序），每页调用一次 onPage，
+// next 为下一页的偏移量。onPage 返回错误时停止。
+func FetchPages[T any](c *Client, ep Endpoint, q Query, offset int, onPage func(page []T, next int) error) error {
+	values, err := q.values()
+	if err != nil {
+		return err
+	}
+	for key, v := range ep.Params {
+		values[key] = v
+	}
+	values.Set("count", strconv.Itoa(ep.PageSize))
+	values.Set("reverse", "false")
+
+	for start := offset; ; start += ep.PageSize {
+		values.Set("start", strconv.Itoa(start))
+		c.logf("正在获取记录 %d-%d...\n", start, start+ep.PageSize)
+
+		var page []T
+		if err := c.Get(ep.Path, values, ep.Auth, &page); err != nil {
+			return fmt.Errorf("获取数据失败: %w", err)
+		}
+
+		if len(page) == 0 {
+			c.logf("✓ 所有数据下载完成!\n")
+			return nil
+		}
+
+		if err := onPage(page, start+len(page)); err != nil {
+			return err
+		}
+		c.logf("  已获取 %d 条记录\n", len(page))
+
+		// 返回的记录数少于请求数，说明已经是最后一页
+		if len(page) < ep.PageSize {
+			c.logf("✓ 已到达最后一页\n")
+			return nil
+		}
+
+		time.Sleep(c.PageDelay)
+	}
+}
+
+// fetchAll 获取全部记录
+func fetchAll[T any](c *Client, ep Endpoint, q Query) ([]T, error) {
+	var all []T
+	err := FetchPages(c, ep, q, 0, func(page []T, next int) error {
+		all = append(all, page...)
+		return nil
+	})
+	return all, err
+}
@@ -0,0 +1,222 @@
+package bitmex
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultAccount 未指定 -account 时使用的账户名
+const DefaultAccount = "main"
+
+// Credentials BitMEX API 凭证
+type Credentials struct {
+	APIKey    string `json:"apiKey"`    // API ID
+	APISecret string `json:"apiSecret"` // API Secret
+}
+
+// accountsFile 凭证文件内容：账户名 → 凭证
+type accountsFile map[string]Credentials
+
+// ErrNoCredentials 所有来源都没有找到凭证
+var ErrNoCredentials = errors.New("未找到 BitMEX API 凭证")
+
+// CredentialsDir 凭证文件默认目录 ~/.bitmex
+func CredentialsDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".bitmex"
+	}
+	return filepath.Join(home, ".bitmex")
+}
+
+// CredentialsFile 明文凭证文件路径，可用 BITMEX_CREDENTIALS_FILE 覆盖
+func CredentialsFile() string {
+	if path := os.Getenv("BITMEX_CREDENTIALS_FILE"); path != "" {
+		return path
+	}
+	return filepath.Join(CredentialsDir(), "credentials.json")
+}
+
+// EncryptedCredentialsFile 加密凭证文件路径，可用 BITMEX_CREDENTIALS_ENC 覆盖
+func EncryptedCredentialsFile() string {
+	if path := os.Getenv("BITMEX_CREDENTIALS_ENC"); path != "" {
+		return path
+	}
+	return filepath.Join(CredentialsDir(), "credentials.enc")
+}
+
+// LoadCredentials 按以下顺序查找账户凭证，找到即返回：
+//  1. 环境变量 BITMEX_<ACCOUNT>_API_KEY / BITMEX_<ACCOUNT>_API_SECRET
+//     （main 账户也可以用 BITMEX_API_KEY / BITMEX_API_SECRET）
+//  2. 明文凭证文件 ~/.bitmex/credentials.json，权限必须为 600
+//  3. 加密凭证文件 ~/.bitmex/credentials.enc，口令来自 BITMEX_PASSPHRASE 或终端输入
+func LoadCredentials(account string) (Credentials, error) {
+	if account == "" {
+		account = DefaultAccount
+	}
+
+	if creds, ok := credentialsFromEnv(account); ok {
+		return creds, nil
+	}
+
+	if creds, err := credentialsFromFile(CredentialsFile(), account); err == nil {
+		return creds, nil
+	} else if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, ErrNoCredentials) {
+		return Credentials{}, err
+	}
+
+	encPath := EncryptedCredentialsFile()
+	if _, err := os.Stat(encPath); err == nil {
+		passphrase, err := ReadPassphrase(fmt.Sprintf("请输入 %s 的口令: ", encPath))
+		if err != nil {
+			return Credentials{}, err
+		}
+		accounts, err := LoadEncryptedAccounts(encPath, passphrase)
+		if err != nil {
+			return Credentials{}, err
+		}
+		if creds, ok := accounts[account]; ok && creds.valid() {
+			return creds, nil
+		}
+	}
+
+	return Credentials{}, fmt.Errorf("%w（账户 %s）: 请设置环境变量 %s / %s，或创建 %s",
+		ErrNoCredentials, account, envName(account, "API_KEY"), envName(account, "API_SECRET"), CredentialsFile())
+}
+
+// envName 生成账户对应的环境变量名，如 BITMEX_MAIN_API_KEY
+func envName(account, suffix string) string {
+	name := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(account))
+	return "BITMEX_" + name + "_" + suffix
+}
+
+// credentialsFromEnv 从环境变量读取凭证
+func credentialsFromEnv(account string) (Credentials, bool) {
+	creds := Credentials{
+		APIKey:    os.Getenv(envName(account, "API_KEY")),
+		APISecret: os.Getenv(envName(account, "API_SECRET")),
+	}
+	if creds.valid() {
+		return creds, true
+	}
+
+	if account == DefaultAccount {
+		creds = Credentials{
+			APIKey:    os.Getenv("BITMEX_API_KEY"),
+			APISecret: os.Getenv("BITMEX_API_SECRET"),
+		}
+		if creds.valid() {
+			return creds, true
+		}
+	}
+
+	return Credentials{}, false
+}
+
+// credentialsFromFile 从明文凭证文件读取，文件权限允许组或其他用户访问时拒绝使用
+func credentialsFromFile(path, account string) (Credentials, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return Credentials{}, fmt.Errorf("凭证文件 %s 权限过宽 (%04o)，请执行: chmod 600 %s", path, info.Mode().Perm(), path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	var accounts accountsFile
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return Credentials{}, fmt.Errorf("解析凭证文件 %s 失败: %w", path, err)
+	}
+
+	creds, ok := accounts[account]
+	if !ok || !creds.valid() {
+		return Credentials{}, ErrNoCredentials
+	}
+	return creds, nil
+}
+
+// valid 凭证是否完整
+func (c Credentials) valid() bool {
+	return c.APIKey != "" && c.APISecret != ""
+}
+
+// Masked 返回脱敏后的 API Key，用于日志输出
+func (c Credentials) Masked() string {
+	if len(c.APIKey) <= 6 {
+		return "******"
+	}
+	return c.APIKey[:4] + "******" + c.APIKey[len(c.APIKey)-2:]
+}
+
+// CheckNotEmbedded 检查 dir 目录下（递归）的文本文件（源码、文档、脚本、配置等）中是否包含凭证明文，
+// 只跳过二进制文件和凭证文件本身。凭证一旦写进仓库就会随代码一起提交和分发，发现后拒绝运行
+func CheckNotEmbedded(creds Credentials, dir string) error {
+	if !creds.valid() {
+		return nil
+	}
+	skip := map[string]bool{}
+	for _, path := range []string{CredentialsFile(), EncryptedCredentialsFile()} {
+		if abs, err := filepath.Abs(path); err == nil {
+			skip[abs] = true
+		}
+	}
+
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != dir && (strings.HasPrefix(name, ".") || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if abs, err := filepath.Abs(path); err == nil && skip[abs] {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if isBinary(data) {
+			return nil
+		}
+		content := string(data)
+		if strings.Contains(content, creds.APIKey) || strings.Contains(content, creds.APISecret) {
+			return fmt.Errorf("文件 %s 中包含 API 凭证明文，请删除后改用环境变量或凭证文件，并在 BitMEX 重新生成该 Key", path)
+		}
+		return nil
+	})
+}
+
+// isBinary 与 git 相同，前 8000 字节中有 NUL 字节时视为二进制文件
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
+
+// LoadCredentialsChecked 加载账户凭证，并确认 srcDir 下的源码中没有嵌入该凭证
+func LoadCredentialsChecked(account, srcDir string) (Credentials, error) {
+	creds, err := LoadCredentials(account)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if err := CheckNotEmbedded(creds, srcDir); err != nil {
+		return Credentials{}, err
+	}
+	return creds, nil
+}
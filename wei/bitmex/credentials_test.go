@@ -0,0 +1,282 @@
+package bitmex
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// credentialsEnv 清空凭证相关的环境变量，凭证文件指向临时目录，返回明文和加密文件路径
+func credentialsEnv(t *testing.T) (plain, enc string) {
+	t.Helper()
+	for _, name := range []string{
+		"BITMEX_API_KEY", "BITMEX_API_SECRET", "BITMEX_MAIN_API_KEY", "BITMEX_MAIN_API_SECRET",
+		"BITMEX_SUB_1_API_KEY", "BITMEX_SUB_1_API_SECRET", "BITMEX_PASSPHRASE",
+	} {
+		t.Setenv(name, "")
+	}
+	dir := t.TempDir()
+	plain = filepath.Join(dir, "credentials.json")
+	enc = filepath.Join(dir, "credentials.enc")
+	t.Setenv("BITMEX_CREDENTIALS_FILE", plain)
+	t.Setenv("BITMEX_CREDENTIALS_ENC", enc)
+	return plain, enc
+}
+
+// writeAccounts 写入明文凭证文件
+func writeAccounts(t *testing.T, path string, perm os.FileMode, accounts accountsFile) {
+	t.Helper()
+	data, err := json.Marshal(accounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, perm); err != nil {
+		t.Fatal(err)
+	}
+	// WriteFile 的权限受 umask 影响
+	if err := os.Chmod(path, perm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncryptedAccountsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "credentials.enc")
+	accounts := map[string]Credentials{
+		"main":  {APIKey: "key-main", APISecret: "secret-main"},
+		"sub-1": {APIKey: "key-sub", APISecret: "secret-sub"},
+	}
+	if err := SaveEncryptedAccounts(path, "correct horse", accounts); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("文件权限 = %04o，应为 0600", perm)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "secret-main") || strings.Contains(string(data), "key-sub") {
+		t.Errorf("加密文件中包含明文凭证")
+	}
+	if _, err := os.Stat(path + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("临时文件未删除: %v", err)
+	}
+
+	got, err := LoadEncryptedAccounts(path, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["main"] != accounts["main"] || got["sub-1"] != accounts["sub-1"] {
+		t.Errorf("解密结果 = %+v，应为 %+v", got, accounts)
+	}
+
+	// 每次保存使用新的盐和 nonce
+	if err := SaveEncryptedAccounts(path, "correct horse", accounts); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(path); string(again) == string(data) {
+		t.Errorf("重新保存的密文与之前相同")
+	}
+}
+
+func TestEncryptedAccountsWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	accounts := map[string]Credentials{"main": {APIKey: "key", APISecret: "secret"}}
+	if err := SaveEncryptedAccounts(path, "right", accounts); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadEncryptedAccounts(path, "wrong")
+	if err == nil || !strings.Contains(err.Error(), "口令错误") {
+		t.Errorf("错误口令: err = %v，应为 GCM 认证失败", err)
+	}
+
+	// 篡改密文同样无法通过认证
+	var file encryptedFile
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	file.Ciphertext[0] ^= 1
+	data, _ = json.Marshal(file)
+	os.WriteFile(path, data, 0600)
+	if _, err := LoadEncryptedAccounts(path, "right"); err == nil {
+		t.Errorf("密文被篡改后仍然解密成功")
+	}
+
+	if err := SaveEncryptedAccounts(path, "", accounts); err == nil {
+		t.Errorf("空口令应返回错误")
+	}
+}
+
+func TestLoadCredentialsPrecedence(t *testing.T) {
+	envCreds := Credentials{APIKey: "env-key", APISecret: "env-secret"}
+	fileCreds := Credentials{APIKey: "file-key", APISecret: "file-secret"}
+	encCreds := Credentials{APIKey: "enc-key", APISecret: "enc-secret"}
+
+	tests := []struct {
+		name    string
+		account string
+		env     map[string]string
+		file    accountsFile
+		enc     map[string]Credentials
+		want    Credentials
+		wantErr error
+	}{
+		{name: "环境变量优先", account: "main",
+			env:  map[string]string{"BITMEX_MAIN_API_KEY": "env-key", "BITMEX_MAIN_API_SECRET": "env-secret"},
+			file: accountsFile{"main": fileCreds}, enc: map[string]Credentials{"main": encCreds}, want: envCreds},
+		{name: "main账户的通用环境变量", account: "",
+			env:  map[string]string{"BITMEX_API_KEY": "env-key", "BITMEX_API_SECRET": "env-secret"},
+			file: accountsFile{"main": fileCreds}, want: envCreds},
+		{name: "通用环境变量不用于其他账户", account: "sub-1",
+			env:  map[string]string{"BITMEX_API_KEY": "env-key", "BITMEX_API_SECRET": "env-secret"},
+			file: accountsFile{"sub-1": fileCreds}, want: fileCreds},
+		{name: "不完整的环境变量被忽略", account: "main",
+			env:  map[string]string{"BITMEX_MAIN_API_KEY": "env-key"},
+			file: accountsFile{"main": fileCreds}, want: fileCreds},
+		{name: "账户名转换为环境变量名", account: "sub-1",
+			env:  map[string]string{"BITMEX_SUB_1_API_KEY": "env-key", "BITMEX_SUB_1_API_SECRET": "env-secret"},
+			want: envCreds},
+		{name: "明文文件优先于加密文件", account: "main",
+			file: accountsFile{"main": fileCreds}, enc: map[string]Credentials{"main": encCreds}, want: fileCreds},
+		{name: "明文文件没有该账户时使用加密文件", account: "main",
+			file: accountsFile{"other": fileCreds}, enc: map[string]Credentials{"main": encCreds}, want: encCreds},
+		{name: "只有加密文件", account: "main",
+			enc: map[string]Credentials{"main": encCreds}, want: encCreds},
+		{name: "都没有", account: "main",
+			enc: map[string]Credentials{"other": encCreds}, wantErr: ErrNoCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain, enc := credentialsEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			if tt.file != nil {
+				writeAccounts(t, plain, 0600, tt.file)
+			}
+			if tt.enc != nil {
+				if err := SaveEncryptedAccounts(enc, "passphrase", tt.enc); err != nil {
+					t.Fatal(err)
+				}
+				t.Setenv("BITMEX_PASSPHRASE", "passphrase")
+			}
+
+			got, err := LoadCredentials(tt.account)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v，应为 %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("凭证 = %+v，应为 %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCredentialsFilePermissions(t *testing.T) {
+	tests := []struct {
+		perm os.FileMode
+		ok   bool
+	}{
+		{0600, true},
+		{0400, true},
+		{0640, false},
+		{0604, false},
+		{0644, false},
+		{0660, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.perm.String(), func(t *testing.T) {
+			plain, _ := credentialsEnv(t)
+			writeAccounts(t, plain, tt.perm, accountsFile{"main": {APIKey: "file-key", APISecret: "file-secret"}})
+
+			creds, err := LoadCredentials("main")
+			if tt.ok {
+				if err != nil || creds.APIKey != "file-key" {
+					t.Errorf("权限 %04o: creds = %+v, err = %v", tt.perm, creds, err)
+				}
+				return
+			}
+			// 权限过宽时报错，不会继续尝试加密文件
+			if err == nil || !strings.Contains(err.Error(), "权限过宽") {
+				t.Errorf("权限 %04o: err = %v，应拒绝使用", tt.perm, err)
+			}
+		})
+	}
+}
+
+func TestCheckNotEmbedded(t *testing.T) {
+	plain, _ := credentialsEnv(t)
+	creds := Credentials{APIKey: docAPIKey, APISecret: docAPISecret}
+
+	// 文档中的 Key
+	err := CheckNotEmbedded(creds, "testdata/embedded")
+	if err == nil || !strings.Contains(err.Error(), "README.md") {
+		t.Errorf("testdata/embedded 中的 README.md 含有 API Key，应返回错误，实际为 %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr bool
+	}{
+		{"Go 源码", "main.go", `const apiKey = "` + docAPIKey + `"`, true},
+		{"Markdown", "docs/notes.md", "Key: " + docAPISecret, true},
+		{"Shell 脚本", "run.sh", "export BITMEX_API_KEY=" + docAPIKey, true},
+		{"Python", "tools/fetch.py", "API_SECRET = '" + docAPISecret + "'", true},
+		{"JSON", "config.json", `{"apiKey": "` + docAPIKey + `"}`, true},
+		{"YAML", "deploy.yaml", "apiSecret: " + docAPISecret, true},
+		{".env", ".env", "BITMEX_API_SECRET=" + docAPISecret, true},
+		{"无扩展名", "NOTES", docAPIKey, true},
+		{"二进制文件", "app.bin", "\x00\x01" + docAPIKey, false},
+		{"隐藏目录", ".git/config", docAPIKey, false},
+		{"vendor", "vendor/lib/lib.go", docAPIKey, false},
+		{"没有凭证", "README.md", "使用环境变量 BITMEX_API_KEY", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, tt.file)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := CheckNotEmbedded(creds, dir)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckNotEmbedded = %v，应返回错误: %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.file[strings.LastIndex(tt.file, "/")+1:]) {
+				t.Errorf("错误信息 %q 应包含文件名", err)
+			}
+		})
+	}
+
+	// 凭证文件本身在目录中时不算嵌入
+	t.Run("凭证文件", func(t *testing.T) {
+		dir := filepath.Dir(plain)
+		writeAccounts(t, plain, 0600, accountsFile{DefaultAccount: creds})
+		if err := CheckNotEmbedded(creds, dir); err != nil {
+			t.Errorf("凭证文件不应视为嵌入: %v", err)
+		}
+	})
+
+	// 不完整的凭证不检查
+	if err := CheckNotEmbedded(Credentials{APIKey: docAPIKey}, "testdata/embedded"); err != nil {
+		t.Errorf("凭证不完整时不应检查: %v", err)
+	}
+}
@@ -0,0 +1,469 @@
+package bitmex
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// 默认数据文件名（相对于运行目录）
+const (
+	ExecutionsFile = "executions.csv"
+	WalletFile     = "wallet.csv"
+	OrdersFile     = "orders.csv"
+)
+
+// KlinesFile K线数据文件名，如 klines_XBTUSD_1d.csv
+func KlinesFile(symbol, binSize string) string {
+	return fmt.Sprintf("klines_%s_%s.csv", symbol, binSize)
+}
+
+// ExecutionHeader executions.csv 表头
+var ExecutionHeader = []string{
+	"ExecID", "OrderID", "ClOrdID", "Account", "Symbol", "Side",
+	"LastQty", "LastPx", "OrderQty", "Price", "LeavesQty", "CumQty",
+	"AvgPx", "Commission", "TransactTime", "Timestamp", "OrdType",
+	"ExecType", "OrdStatus", "Currency", "Text",
+}
+
+// WalletHeader wallet.csv 表头
+var WalletHeader = []string{
+	"TransactID", "TransactType", "TransactStatus", "Account", "Currency",
+	"Amount_Satoshi", "Amount_BTC", "Fee_Satoshi", "Fee_BTC",
+	"Timestamp", "WalletBalance_Satoshi", "WalletBalance_BTC",
+	"MarginBalance_Satoshi", "MarginBalance_BTC",
+	"Address", "Tx", "Text",
+}
+
+// OrderHeader orders.csv 表头
+var OrderHeader = []string{
+	"OrderID", "ClOrdID", "ClOrdLinkID", "Account", "Symbol", "Side",
+	"SimpleOrderQty", "OrderQty", "Price", "DisplayQty", "StopPx", "PegOffsetValue",
+	"PegPriceType", "Currency", "SettlCurrency", "OrdType", "TimeInForce", "ExecInst",
+	"ContingencyType", "ExDestination", "OrdStatus", "Triggered", "WorkingIndicator",
+	"OrdRejReason", "SimpleLeavesQty", "LeavesQty", "SimpleCumQty", "CumQty",
+	"AvgPx", "MultiLegReportingType", "Text", "TransactTime", "Timestamp",
+}
+
+// KlineHeader klines_*.csv 表头
+var KlineHeader = []string{"Timestamp", "Symbol", "Open", "High", "Low", "Close", "Volume", "Trades"}
+
+// ReadExecutions 读取 executions.csv
+func ReadExecutions(filename string) ([]Execution, error) {
+	return readCSV(filename, len(ExecutionHeader), parseExecution)
+}
+
+// WriteExecutions 原子写入 executions.csv（先写临时文件再重命名，中途失败不会损坏原文件）
+func WriteExecutions(filename string, executions []Execution) error {
+	return writeCSV(filename, ExecutionHeader, executions, Execution.record)
+}
+
+// ReadWalletHistory 读取 wallet.csv
+func ReadWalletHistory(filename string) ([]WalletHistory, error) {
+	return readCSV(filename, len(WalletHeader), parseWalletHistory)
+}
+
+// WriteWalletHistory 原子写入 wallet.csv
+func WriteWalletHistory(filename string, history []WalletHistory) error {
+	return writeCSV(filename, WalletHeader, history, WalletHistory.record)
+}
+
+// ReadOrders 读取 orders.csv
+func ReadOrders(filename string) ([]Order, error) {
+	return readCSV(filename, len(OrderHeader), parseOrder)
+}
+
+// WriteOrders 原子写入 orders.csv
+func WriteOrders(filename string, orders []Order) error {
+	return writeCSV(filename, OrderHeader, orders, Order.record)
+}
+
+// ReadKlines 读取 klines_*.csv
+func ReadKlines(filename string) ([]Kline, error) {
+	return readCSV(filename, len(KlineHeader), parseKline)
+}
+
+// WriteKlines 原子写入 klines_*.csv
+func WriteKlines(filename string, klines []Kline) error {
+	return writeCSV(filename, KlineHeader, klines, Kline.record)
+}
+
+// readCSV 读取带表头的CSV，每行至少 minFields 列
+func readCSV[T any](filename string, minFields int, parse func(*fieldReader) T) ([]T, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	// 跳过表头
+	if _, err := reader.Read(); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取表头失败: %w", err)
+	}
+
+	var rows []T
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		if len(record) < minFields {
+			return nil, fmt.Errorf("%s 第 %d 行: 字段数量不足: %d", filename, line, len(record))
+		}
+
+		fields := &fieldReader{record: record}
+		row := parse(fields)
+		if fields.err != nil {
+			return nil, fmt.Errorf("%s 第 %d 行: %w", filename, line, fields.err)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// writeCSV 原子写入CSV：写入同目录下的临时文件，刷盘后重命名覆盖目标文件
+func writeCSV[T any](filename string, header []string, rows []T, record func(T) []string) error {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, base+".tmp*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // 重命名成功后删除会失败，忽略即可
+	defer tmp.Close()
+
+	writer := csv.NewWriter(tmp)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("写入表头失败: %w", err)
+	}
+	for _, row := range rows {
+		if err := writer.Write(record(row)); err != nil {
+			return fmt.Errorf("写入记录失败: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("写入记录失败: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// appendCSV 追加记录到 filename，文件不存在或为空时先写表头。用于只增不改的历史文件
+func appendCSV[T any](filename string, header []string, rows []T, record func(T) []string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+	if info.Size() == 0 {
+		if err := writer.Write(header); err != nil {
+			return fmt.Errorf("写入表头失败: %w", err)
+		}
+	}
+	for _, row := range rows {
+		if err := writer.Write(record(row)); err != nil {
+			return fmt.Errorf("写入记录失败: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("写入记录失败: %w", err)
+	}
+	return file.Close()
+}
+
+// fieldReader 按列解析一行CSV，记录第一个解析错误
+type fieldReader struct {
+	record []string
+	err    error
+}
+
+func (f *fieldReader) str(i int) string {
+	return f.record[i]
+}
+
+func (f *fieldReader) int(i int) int {
+	return int(f.int64(i))
+}
+
+func (f *fieldReader) int64(i int) int64 {
+	if f.record[i] == "" {
+		return 0
+	}
+	v, err := strconv.ParseInt(f.record[i], 10, 64)
+	if err != nil && f.err == nil {
+		f.err = fmt.Errorf("第 %d 列 %q 不是整数", i+1, f.record[i])
+	}
+	return v
+}
+
+func (f *fieldReader) float(i int) float64 {
+	if f.record[i] == "" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(f.record[i], 64)
+	if err != nil && f.err == nil {
+		f.err = fmt.Errorf("第 %d 列 %q 不是数字", i+1, f.record[i])
+	}
+	return v
+}
+
+func (f *fieldReader) bool(i int) bool {
+	v, _ := strconv.ParseBool(f.record[i])
+	return v
+}
+
+func (f *fieldReader) time(i int) time.Time {
+	t, err := time.Parse(time.RFC3339, f.record[i])
+	if err != nil && f.err == nil {
+		f.err = fmt.Errorf("第 %d 列 %q 时间格式错误", i+1, f.record[i])
+	}
+	return t
+}
+
+// satoshiToBTC 将 Satoshi 转换为 BTC（保留8位小数）
+func satoshiToBTC(satoshi int64) string {
+	return fmt.Sprintf("%.8f", float64(satoshi)/SatoshiPerBTC)
+}
+
+// priceString 价格保留全部有效数字。XBT 计价的合约（如 ETHM20、LTCM20）价格小于 1，
+// 固定两位小数会丢掉大部分精度，读回后计算的盈亏和均价都不对
+func priceString(price float64) string {
+	return strconv.FormatFloat(price, 'f', -1, 64)
+}
+
+func (e Execution) record() []string {
+	return []string{
+		e.ExecID,
+		e.OrderID,
+		e.ClOrdID,
+		strconv.Itoa(e.Account),
+		e.Symbol,
+		e.Side,
+		strconv.Itoa(e.LastQty),
+		priceString(e.LastPx),
+		strconv.Itoa(e.OrderQty),
+		priceString(e.Price),
+		strconv.Itoa(e.LeavesQty),
+		strconv.Itoa(e.CumQty),
+		priceString(e.AvgPx),
+		fmt.Sprintf("%.8f", e.Commission),
+		e.TransactTime,
+		e.Timestamp,
+		e.OrdType,
+		e.ExecType,
+		e.OrdStatus,
+		e.Currency,
+		e.Text,
+	}
+}
+
+func parseExecution(f *fieldReader) Execution {
+	return Execution{
+		ExecID:       f.str(0),
+		OrderID:      f.str(1),
+		ClOrdID:      f.str(2),
+		Account:      f.int(3),
+		Symbol:       f.str(4),
+		Side:         f.str(5),
+		LastQty:      f.int(6),
+		LastPx:       f.float(7),
+		OrderQty:     f.int(8),
+		Price:        f.float(9),
+		LeavesQty:    f.int(10),
+		CumQty:       f.int(11),
+		AvgPx:        f.float(12),
+		Commission:   f.float(13),
+		TransactTime: f.str(14),
+		Timestamp:    f.str(15),
+		OrdType:      f.str(16),
+		ExecType:     f.str(17),
+		OrdStatus:    f.str(18),
+		Currency:     f.str(19),
+		Text:         f.str(20),
+	}
+}
+
+func (h WalletHistory) record() []string {
+	return []string{
+		h.TransactID,
+		h.TransactType,
+		h.TransactStatus,
+		strconv.Itoa(h.Account),
+		h.Currency,
+		strconv.FormatInt(h.Amount, 10),
+		satoshiToBTC(h.Amount),
+		strconv.FormatInt(h.Fee, 10),
+		satoshiToBTC(h.Fee),
+		h.Timestamp,
+		strconv.FormatInt(h.WalletBalance, 10),
+		satoshiToBTC(h.WalletBalance),
+		strconv.FormatInt(h.MarginBalance, 10),
+		satoshiToBTC(h.MarginBalance),
+		h.Address,
+		h.Tx,
+		h.Text,
+	}
+}
+
+// parseWalletHistory 解析钱包记录，金额取 Satoshi 列，BTC 列由其换算得出
+func parseWalletHistory(f *fieldReader) WalletHistory {
+	return WalletHistory{
+		TransactID:     f.str(0),
+		TransactType:   f.str(1),
+		TransactStatus: f.str(2),
+		Account:        f.int(3),
+		Currency:       f.str(4),
+		Amount:         f.int64(5),
+		Fee:            f.int64(7),
+		Timestamp:      f.str(9),
+		WalletBalance:  f.int64(10),
+		MarginBalance:  f.int64(12),
+		Address:        f.str(14),
+		Tx:             f.str(15),
+		Text:           f.str(16),
+	}
+}
+
+func (o Order) record() []string {
+	return []string{
+		o.OrderID,
+		o.ClOrdID,
+		o.ClOrdLinkID,
+		strconv.Itoa(o.Account),
+		o.Symbol,
+		o.Side,
+		fmt.Sprintf("%.8f", o.SimpleOrderQty),
+		strconv.Itoa(o.OrderQty),
+		priceString(o.Price),
+		strconv.Itoa(o.DisplayQty),
+		priceString(o.StopPx),
+		priceString(o.PegOffsetValue),
+		o.PegPriceType,
+		o.Currency,
+		o.SettlCurrency,
+		o.OrdType,
+		o.TimeInForce,
+		o.ExecInst,
+		o.ContingencyType,
+		o.ExDestination,
+		o.OrdStatus,
+		o.Triggered,
+		strconv.FormatBool(o.WorkingIndicator),
+		o.OrdRejReason,
+		fmt.Sprintf("%.8f", o.SimpleLeavesQty),
+		strconv.Itoa(o.LeavesQty),
+		fmt.Sprintf("%.8f", o.SimpleCumQty),
+		strconv.Itoa(o.CumQty),
+		priceString(o.AvgPx),
+		o.MultiLegReportingType,
+		o.Text,
+		o.TransactTime,
+		o.Timestamp,
+	}
+}
+
+func parseOrder(f *fieldReader) Order {
+	return Order{
+		OrderID:               f.str(0),
+		ClOrdID:               f.str(1),
+		ClOrdLinkID:           f.str(2),
+		Account:               f.int(3),
+		Symbol:                f.str(4),
+		Side:                  f.str(5),
+		SimpleOrderQty:        f.float(6),
+		OrderQty:              f.int(7),
+		Price:                 f.float(8),
+		DisplayQty:            f.int(9),
+		StopPx:                f.float(10),
+		PegOffsetValue:        f.float(11),
+		PegPriceType:          f.str(12),
+		Currency:              f.str(13),
+		SettlCurrency:         f.str(14),
+		OrdType:               f.str(15),
+		TimeInForce:           f.str(16),
+		ExecInst:              f.str(17),
+		ContingencyType:       f.str(18),
+		ExDestination:         f.str(19),
+		OrdStatus:             f.str(20),
+		Triggered:             f.str(21),
+		WorkingIndicator:      f.bool(22),
+		OrdRejReason:          f.str(23),
+		SimpleLeavesQty:       f.float(24),
+		LeavesQty:             f.int(25),
+		SimpleCumQty:          f.float(26),
+		CumQty:                f.int(27),
+		AvgPx:                 f.float(28),
+		MultiLegReportingType: f.str(29),
+		Text:                  f.str(30),
+		TransactTime:          f.str(31),
+		Timestamp:             f.str(32),
+	}
+}
+
+func (k Kline) record() []string {
+	return []string{
+		k.Timestamp.Format(time.RFC3339),
+		k.Symbol,
+		priceString(k.Open),
+		priceString(k.High),
+		priceString(k.Low),
+		priceString(k.Close),
+		fmt.Sprintf("%d", k.Volume),
+		fmt.Sprintf("%d", k.Trades),
+	}
+}
+
+func parseKline(f *fieldReader) Kline {
+	return Kline{
+		Timestamp: f.time(0),
+		Symbol:    f.str(1),
+		Open:      f.float(2),
+		High:      f.float(3),
+		Low:       f.float(4),
+		Close:     f.float(5),
+		Volume:    f.int64(6),
+		Trades:    f.int(7),
+	}
+}
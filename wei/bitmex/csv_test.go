@@ -0,0 +1,81 @@
+package bitmex
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// XBT 计价的期货：1 张为 1 ETH，价格以 XBT 计，盈亏以 XBt 结算
+var testXBTQuoted = Instrument{Symbol: "ETHM20", SettlCurrency: "XBt", Multiplier: 100000000}
+
+// 价格小于 1 的合约写入 CSV 再读回后价格不变
+func TestCSVPriceRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	executions := []Execution{
+		{ExecID: "e1", OrderID: "o1", Symbol: "ETHM20", Side: "Buy", LastQty: 10, LastPx: 0.02345, OrderQty: 10, Price: 0.02345,
+			CumQty: 10, AvgPx: 0.02345, Commission: 0.0005, TransactTime: "2020-05-01T00:00:00.000Z", ExecType: "Trade"},
+		{ExecID: "e2", OrderID: "o2", Symbol: "ETHM20", Side: "Sell", LastQty: 10, LastPx: 0.02398, OrderQty: 10, Price: 0.02398,
+			CumQty: 10, AvgPx: 0.02398, Commission: 0.0005, TransactTime: "2020-05-02T00:00:00.000Z", ExecType: "Trade"},
+		{ExecID: "e3", OrderID: "o3", Symbol: "LTCM20", Side: "Buy", LastQty: 7, LastPx: 0.004525, OrderQty: 7, Price: 0.004525,
+			CumQty: 7, AvgPx: 0.0045235, TransactTime: "2020-05-03T00:00:00.000Z", ExecType: "Trade"},
+		{ExecID: "e4", OrderID: "o4", Symbol: "XBTUSD", Side: "Buy", LastQty: 100, LastPx: 9000.5, OrderQty: 100, Price: 9000.5,
+			CumQty: 100, AvgPx: 9000.5, TransactTime: "2020-05-03T00:00:00.000Z", ExecType: "Trade"},
+	}
+	path := filepath.Join(dir, ExecutionsFile)
+	if err := WriteExecutions(path, executions); err != nil {
+		t.Fatal(err)
+	}
+	readExecutions, err := ReadExecutions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(readExecutions, executions) {
+		t.Errorf("成交读回 = %+v\n应为 %+v", readExecutions, executions)
+	}
+
+	// 读回的成交计算的盈亏与原始数据相同：10 ETH × (0.02398 − 0.02345) XBT
+	catalog := NewCatalog([]Instrument{testXBTQuoted})
+	book := BuildBook(readExecutions, catalog, FIFO, time.Time{})
+	if got := book.Accounts["ETHM20"].Realized; !approx(got, 530000) {
+		t.Errorf("读回后的已实现盈亏 = %.2f XBt，应为 530000", got)
+	}
+
+	orders := []Order{{OrderID: "o1", Symbol: "ETHM20", Side: "Buy", OrderQty: 10, Price: 0.02345, StopPx: 0.0231,
+		PegOffsetValue: -0.0005, OrdStatus: "Filled", CumQty: 10, AvgPx: 0.0234475,
+		TransactTime: "2020-05-01T00:00:00.000Z", Timestamp: "2020-05-01T00:00:00.000Z"}}
+	path = filepath.Join(dir, OrdersFile)
+	if err := WriteOrders(path, orders); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadOrders(path); err != nil || !reflect.DeepEqual(got, orders) {
+		t.Errorf("订单读回 = %+v, %v\n应为 %+v", got, err, orders)
+	}
+
+	klines := []Kline{{Timestamp: time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC), Symbol: "ETHM20",
+		Open: 0.02345, High: 0.023985, Low: 0.0231, Close: 0.02398, Volume: 1200, Trades: 35}}
+	path = filepath.Join(dir, "klines.csv")
+	if err := WriteKlines(path, klines); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadKlines(path); err != nil || !reflect.DeepEqual(got, klines) {
+		t.Errorf("K线读回 = %+v, %v\n应为 %+v", got, err, klines)
+	}
+
+	positions, _ := newSnapshot(time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC), []Position{{Account: 1, Symbol: "ETHM20",
+		Currency: "XBt", CurrentQty: 10, AvgEntryPrice: 0.02345, MarkPrice: 0.023975, LiquidationPrice: 0.0121, Leverage: 2}}, nil)
+	path = filepath.Join(dir, PositionsFile)
+	if err := AppendSnapshot(path, filepath.Join(dir, MarginFile), positions, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadPositionSnapshots(path)
+	if err != nil || len(got) != 1 {
+		t.Fatalf("持仓快照读回 = %+v, %v", got, err)
+	}
+	if p := got[0]; p.AvgEntryPrice != 0.02345 || p.MarkPrice != 0.023975 || p.LiquidationPrice != 0.0121 {
+		t.Errorf("持仓快照读回的价格 = %v / %v / %v，应为 0.02345 / 0.023975 / 0.0121",
+			p.AvgEntryPrice, p.MarkPrice, p.LiquidationPrice)
+	}
+}
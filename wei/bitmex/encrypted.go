@@ -0,0 +1,155 @@
+package bitmex
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// pbkdf2Iterations 口令派生密钥的迭代次数
+const pbkdf2Iterations = 600000
+
+// encryptedFile 加密凭证文件格式（JSON）
+// 明文为 accountsFile 的 JSON，使用 AES-256-GCM 加密，密钥由口令经 PBKDF2-HMAC-SHA256 派生
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// LoadEncryptedAccounts 解密凭证文件，返回所有账户
+func LoadEncryptedAccounts(path, passphrase string) (map[string]Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析加密凭证文件 %s 失败: %w", path, err)
+	}
+	if file.Version != 1 {
+		return nil, fmt.Errorf("不支持的加密凭证文件版本: %d", file.Version)
+	}
+
+	gcm, err := newGCM(passphrase, file.Salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("解密凭证文件失败: 口令错误或文件已损坏")
+	}
+
+	var accounts accountsFile
+	if err := json.Unmarshal(plaintext, &accounts); err != nil {
+		return nil, fmt.Errorf("解析凭证内容失败: %w", err)
+	}
+	return accounts, nil
+}
+
+// SaveEncryptedAccounts 用口令加密所有账户并写入文件（权限 600）
+func SaveEncryptedAccounts(path, passphrase string, accounts map[string]Credentials) error {
+	plaintext, err := json.Marshal(accountsFile(accounts))
+	if err != nil {
+		return err
+	}
+
+	file := encryptedFile{
+		Version:    1,
+		Iterations: pbkdf2Iterations,
+		Salt:       make([]byte, 16),
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(passphrase, file.Salt, file.Iterations)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	file.Ciphertext = gcm.Seal(nil, file.Nonce, plaintext, nil)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("写入加密凭证文件失败: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// newGCM 由口令派生 AES-256 密钥并创建 GCM
+func newGCM(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errors.New("口令不能为空")
+	}
+	if iterations <= 0 {
+		return nil, fmt.Errorf("无效的迭代次数: %d", iterations)
+	}
+
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("派生密钥失败: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ReadPassphrase 读取口令：优先使用环境变量 BITMEX_PASSPHRASE，否则从终端读取
+func ReadPassphrase(prompt string) (string, error) {
+	if passphrase := os.Getenv("BITMEX_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	return ReadSecret(prompt)
+}
+
+// ReadSecret 从终端读取一行敏感输入（关闭回显）
+func ReadSecret(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	if err := stty("-echo"); err == nil {
+		defer func() {
+			stty("echo")
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("读取输入失败: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// stty 设置终端模式（非终端或不支持时返回错误，忽略即可）
+func stty(arg string) error {
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
@@ -0,0 +1,95 @@
+package bitmex
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// 分页历史数据接口
+var (
+	ExecutionEndpoint = Endpoint{Path: "/execution", PageSize: 500, Auth: true}
+	WalletEndpoint    = Endpoint{Path: "/user/walletHistory", PageSize: 10000, Auth: true}
+	OrderEndpoint     = Endpoint{Path: "/order", PageSize: 500, Auth: true}
+)
+
+// instrumentPageSize /instrument 每页最大记录数
+const instrumentPageSize = 500
+
+// KlineEndpoint 已完成K线接口（公开），binSize 为 1m / 5m / 1h / 1d
+func KlineEndpoint(binSize string) Endpoint {
+	return Endpoint{
+		Path:     "/trade/bucketed",
+		PageSize: 1000,
+		Params:   url.Values{"binSize": {binSize}, "partial": {"false"}},
+	}
+}
+
+// Executions 获取成交记录（分页获取全部）
+func (c *Client) Executions(q Query) ([]Execution, error) {
+	return fetchAll[Execution](c, ExecutionEndpoint, q)
+}
+
+// WalletHistory 获取钱包历史记录（分页获取全部）
+func (c *Client) WalletHistory(q Query) ([]WalletHistory, error) {
+	return fetchAll[WalletHistory](c, WalletEndpoint, q)
+}
+
+// Orders 获取订单记录（分页获取全部）
+func (c *Client) Orders(q Query) ([]Order, error) {
+	return fetchAll[Order](c, OrderEndpoint, q)
+}
+
+// Klines 获取已完成的K线（公开接口）
+func (c *Client) Klines(binSize string, q Query) ([]Kline, error) {
+	return fetchAll[Kline](c, KlineEndpoint(binSize), q)
+}
+
+// Positions 获取当前所有持仓
+func (c *Client) Positions() ([]Position, error) {
+	var positions []Position
+	err := c.Get("/position", nil, true, &positions)
+	return positions, err
+}
+
+// Margin 获取指定币种的保证金账户，currency 为空时默认 XBt
+func (c *Client) Margin(currency string) (Margin, error) {
+	if currency == "" {
+		currency = "XBt"
+	}
+	var margin Margin
+	err := c.Get("/user/margin", url.Values{"currency": {currency}}, true, &margin)
+	return margin, err
+}
+
+// Instruments 获取合约信息，activeOnly 为 true 时只返回可交易合约
+func (c *Client) Instruments(activeOnly bool) ([]Instrument, error) {
+	if activeOnly {
+		var active []Instrument
+		err := c.Get("/instrument/active", nil, false, &active)
+		return active, err
+	}
+
+	var all []Instrument
+	for start := 0; ; start += instrumentPageSize {
+		query := url.Values{
+			"count": {strconv.Itoa(instrumentPageSize)},
+			"start": {strconv.Itoa(start)},
+		}
+		var page []Instrument
+		if err := c.Get("/instrument", query, false, &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < instrumentPageSize {
+			break
+		}
+		time.Sleep(c.PageDelay)
+	}
+	return all, nil
+}
+
+// Ping 用 count=1 请求私有接口，检查网络和凭证是否可用
+func (c *Client) Ping(path string) error {
+	return c.Get(path, url.Values{"count": {"1"}}, true, nil)
+}
@@ -0,0 +1,262 @@
+package bitmex
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// FundingFile 资金费用明细文件（funding 命令生成）
+const FundingFile = "funding.csv"
+
+// FundingHeader funding.csv 表头，Amount 单位为结算币种最小单位（XBt 为 Satoshi）
+var FundingHeader = []string{
+	"Time", "Symbol", "Currency", "PositionQty", "MarkPrice", "FundingRate", "Amount", "TransactID",
+}
+
+// fundingMatchWindow 钱包资金费用记录与 Funding 成交记录的最大时间差
+const fundingMatchWindow = time.Minute
+
+// FundingPayment 一次资金费用结算
+type FundingPayment struct {
+	Time        time.Time // 结算时间（精确到分钟）
+	Symbol      string
+	Currency    string  // 结算币种（XBt / USDt）
+	PositionQty int     // 结算时的持仓数量，多头为正，空头为负；未知时为 0
+	MarkPrice   float64 // 结算时的标记价格；没有 Funding 成交记录时为 0
+	Rate        float64 // 资金费率，正值表示多头付给空头；没有 Funding 成交记录时为 0
+	Amount      int64   // 金额（最小单位），正值为收到，负值为支付
+	TransactID  string
+}
+
+// AmountMain 金额（主单位: BTC / USDT）
+func (p FundingPayment) AmountMain() float64 {
+	return FromMinorUnits(p.Currency, p.Amount)
+}
+
+// BuildFundingLedger 由钱包历史中已结算的 Funding 记录（不含 Canceled 和 Pending）生成资金费用明细（金额以钱包记录为准），
+// 并用 ExecType 为 Funding 的成交记录补充费率和标记价格，用 Trade 成交累计结算时的持仓。
+func BuildFundingLedger(wallet []WalletHistory, executions []Execution) []FundingPayment {
+	var records []WalletHistory
+	for _, h := range wallet {
+		if h.TransactType == "Funding" && h.TransactStatus != "Canceled" && h.TransactStatus != "Pending" {
+			records = append(records, h)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time().Before(records[j].Time()) })
+
+	var trades []Execution
+	fundingExecs := make(map[string][]Execution)
+	for _, e := range executions {
+		switch e.ExecType {
+		case "Trade":
+			trades = append(trades, e)
+		case "Funding":
+			fundingExecs[e.Symbol] = append(fundingExecs[e.Symbol], e)
+		}
+	}
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].Time().Before(trades[j].Time()) })
+
+	ledger := make([]FundingPayment, 0, len(records))
+	positions := make(map[string]int)
+	traded := make(map[string]bool)
+	used := make(map[string]bool)
+	next := 0
+	for _, h := range records {
+		// 钱包记录中 Address 为合约代码
+		p := FundingPayment{
+			Time:       h.Time().Truncate(time.Minute),
+			Symbol:     h.Address,
+			Currency:   h.Currency,
+			Amount:     h.Amount,
+			TransactID: h.TransactID,
+		}
+
+		// 结算时刻之前的成交决定结算时的持仓
+		for ; next < len(trades) && trades[next].Time().Before(p.Time); next++ {
+			t := trades[next]
+			traded[t.Symbol] = true
+			if t.Side == "Buy" {
+				positions[t.Symbol] += t.LastQty
+			} else {
+				positions[t.Symbol] -= t.LastQty
+			}
+		}
+		p.PositionQty = positions[p.Symbol]
+
+		if e, ok := matchFundingExecution(fundingExecs[p.Symbol], h.Time(), used); ok {
+			p.Rate = e.Commission
+			p.MarkPrice = e.LastPx
+			if !traded[p.Symbol] {
+				// 没有该合约的成交记录时，由费率和收付方向推断持仓方向：
+				// 费率为正时多头支付，费率为负时多头收到
+				p.PositionQty = e.LastQty
+				if (p.Rate > 0) != (p.Amount < 0) {
+					p.PositionQty = -p.PositionQty
+				}
+			}
+		}
+		ledger = append(ledger, p)
+	}
+	return ledger
+}
+
+// matchFundingExecution 在 execs 中查找与 at 最接近且未使用的 Funding 成交记录
+func matchFundingExecution(execs []Execution, at time.Time, used map[string]bool) (Execution, bool) {
+	best := -1
+	var bestDiff time.Duration
+	for i, e := range execs {
+		if used[e.ExecID] {
+			continue
+		}
+		diff := e.Time().Sub(at)
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff <= fundingMatchWindow && (best < 0 || diff < bestDiff) {
+			best, bestDiff = i, diff
+		}
+	}
+	if best < 0 {
+		return Execution{}, false
+	}
+	used[execs[best].ExecID] = true
+	return execs[best], true
+}
+
+// FilterFunding 按合约和时间范围 [from, to) 筛选，symbol 为空或时间为零值表示不限
+func FilterFunding(ledger []FundingPayment, symbol string, from, to time.Time) []FundingPayment {
+	var result []FundingPayment
+	for _, p := range ledger {
+		if symbol != "" && p.Symbol != symbol {
+			continue
+		}
+		if !from.IsZero() && p.Time.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !p.Time.Before(to) {
+			continue
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// FundingTotal 一组资金费用的汇总，金额为主单位（BTC / USDT）或美元
+type FundingTotal struct {
+	Key      string  `json:"key"` // 日期（2006-01-02）、月份（2006-01）或合约代码，汇总全部时为空
+	Currency string  `json:"currency"`
+	Count    int     `json:"count"`
+	Received float64 `json:"received"` // 收到的资金费用
+	Paid     float64 `json:"paid"`     // 支付的资金费用（正数）
+	Net      float64 `json:"net"`      // 净收入，负值表示持仓成本
+	AvgRate  float64 `json:"avgRate"`  // 有费率记录的结算的平均费率
+}
+
+// fundingKeys 汇总方式
+var fundingKeys = map[string]func(FundingPayment) string{
+	"day":    func(p FundingPayment) string { return p.Time.UTC().Format("2006-01-02") },
+	"month":  func(p FundingPayment) string { return p.Time.UTC().Format("2006-01") },
+	"symbol": func(p FundingPayment) string { return p.Symbol },
+	"all":    func(p FundingPayment) string { return "" },
+}
+
+// SummarizeFunding 按 day / month / symbol / all 汇总资金费用，不同结算币种分别汇总。
+// 结果按 Key、币种排序
+func SummarizeFunding(ledger []FundingPayment, by string) ([]FundingTotal, error) {
+	return summarizeFunding(ledger, by, FundingPayment.AmountMain)
+}
+
+// SummarizeFundingUSD 同 SummarizeFunding，每次结算的金额按结算时的价格换算为美元
+func SummarizeFundingUSD(ledger []FundingPayment, by string, usd *PriceSeries) ([]FundingTotal, error) {
+	return summarizeFunding(ledger, by, func(p FundingPayment) float64 {
+		return usd.USD(p.Currency, p.AmountMain(), p.Time)
+	})
+}
+
+func summarizeFunding(ledger []FundingPayment, by string, value func(FundingPayment) float64) ([]FundingTotal, error) {
+	key, ok := fundingKeys[by]
+	if !ok {
+		return nil, fmt.Errorf("未知汇总方式: %s（可用: day, month, symbol, all）", by)
+	}
+
+	type group struct {
+		total   FundingTotal
+		rateSum float64
+		rates   int
+	}
+	groups := make(map[[2]string]*group)
+	for _, p := range ledger {
+		k := [2]string{key(p), p.Currency}
+		g, ok := groups[k]
+		if !ok {
+			g = &group{total: FundingTotal{Key: k[0], Currency: k[1]}}
+			groups[k] = g
+		}
+
+		amount := value(p)
+		g.total.Count++
+		g.total.Net += amount
+		if amount >= 0 {
+			g.total.Received += amount
+		} else {
+			g.total.Paid -= amount
+		}
+		if p.Rate != 0 {
+			g.rateSum += p.Rate
+			g.rates++
+		}
+	}
+
+	totals := make([]FundingTotal, 0, len(groups))
+	for _, g := range groups {
+		if g.rates > 0 {
+			g.total.AvgRate = g.rateSum / float64(g.rates)
+		}
+		totals = append(totals, g.total)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Key != totals[j].Key {
+			return totals[i].Key < totals[j].Key
+		}
+		return totals[i].Currency < totals[j].Currency
+	})
+	return totals, nil
+}
+
+// WriteFunding 写入 funding.csv
+func WriteFunding(filename string, ledger []FundingPayment) error {
+	return writeCSV(filename, FundingHeader, ledger, FundingPayment.record)
+}
+
+// ReadFunding 读取 funding.csv
+func ReadFunding(filename string) ([]FundingPayment, error) {
+	return readCSV(filename, len(FundingHeader), parseFundingPayment)
+}
+
+func (p FundingPayment) record() []string {
+	return []string{
+		p.Time.Format(TimeLayout),
+		p.Symbol,
+		p.Currency,
+		strconv.Itoa(p.PositionQty),
+		strconv.FormatFloat(p.MarkPrice, 'f', -1, 64),
+		strconv.FormatFloat(p.Rate, 'f', -1, 64),
+		strconv.FormatInt(p.Amount, 10),
+		p.TransactID,
+	}
+}
+
+func parseFundingPayment(f *fieldReader) FundingPayment {
+	return FundingPayment{
+		Time:        f.time(0),
+		Symbol:      f.str(1),
+		Currency:    f.str(2),
+		PositionQty: f.int(3),
+		MarkPrice:   f.float(4),
+		Rate:        f.float(5),
+		Amount:      f.int64(6),
+		TransactID:  f.str(7),
+	}
+}
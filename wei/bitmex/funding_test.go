@@ -0,0 +1,105 @@
+package bitmex
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildFundingLedger(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h, m, s, ms int) string {
+		return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+			time.Duration(s)*time.Second + time.Duration(ms)*time.Millisecond).Format(TimeLayout)
+	}
+	funding := func(id, symbol, status string, amount int64, ts string) WalletHistory {
+		return WalletHistory{TransactID: id, TransactType: "Funding", TransactStatus: status,
+			Currency: "XBt", Amount: amount, Address: symbol, Timestamp: ts}
+	}
+	exec := func(id, execType, symbol, side string, qty int, px, rate float64, ts string) Execution {
+		return Execution{ExecID: id, ExecType: execType, Symbol: symbol, Side: side,
+			LastQty: qty, LastPx: px, Commission: rate, TransactTime: ts, Timestamp: ts}
+	}
+
+	wallet := []WalletHistory{
+		funding("w7", "XBTUSD", "Completed", -15, at(20, 0, 0, 200)),
+		funding("w1", "XBTUSD", "Completed", -33, at(4, 0, 0, 120)),
+		funding("w2", "ETHUSD", "Completed", 10, at(4, 0, 0, 150)),
+		funding("w3", "ETHUSD", "Completed", 5, at(12, 0, 0, 100)),
+		funding("", "XBTUSD", "Pending", -20, at(12, 0, 0, 100)),    // 未结算
+		funding("w5", "XBTUSD", "Canceled", -99, at(12, 0, 0, 100)), // 已撤销
+		funding("w8", "ETHUSD", "Completed", 7, at(20, 0, 0, 300)),  // 窗口内没有 Funding 成交
+		{TransactID: "w6", TransactType: "RealisedPNL", TransactStatus: "Completed", Currency: "XBt", Amount: 500, Address: "XBTUSD", Timestamp: at(12, 0, 0, 0)},
+	}
+	executions := []Execution{
+		// XBTUSD 成交：04:00 时持仓 +200，20:00 时 -200
+		exec("t1", "Trade", "XBTUSD", "Buy", 300, 59000, 0.00075, at(1, 0, 0, 0)),
+		exec("t2", "Trade", "XBTUSD", "Sell", 100, 59500, 0.00075, at(3, 0, 0, 0)),
+		exec("t3", "Trade", "XBTUSD", "Sell", 400, 60500, 0.00075, at(13, 0, 0, 0)),
+
+		// 04:00 有两条候选，取时间最接近的
+		exec("f1", "Funding", "XBTUSD", "Sell", 200, 60000, 0.0001, at(4, 0, 0, 0)),
+		exec("f1b", "Funding", "XBTUSD", "Sell", 200, 60001, 0.0003, at(4, 0, 50, 0)),
+		// 20:00：超出窗口的不匹配；有成交记录时持仓以成交为准，不用 Funding 成交的数量
+		exec("f7x", "Funding", "XBTUSD", "Buy", 999, 60900, -0.0005, at(19, 58, 30, 0)),
+		exec("f7", "Funding", "XBTUSD", "Buy", 999, 61000, -0.0002, at(20, 0, 45, 0)),
+
+		// ETHUSD 没有成交记录，由费率和收付方向推断持仓方向
+		exec("f2", "Funding", "ETHUSD", "Buy", 50, 3000, 0.0002, at(4, 0, 0, 0)),    // 费率为正时收到 → 空头
+		exec("f3", "Funding", "ETHUSD", "Sell", 50, 3100, -0.0001, at(12, 0, 0, 0)), // 费率为负时收到 → 多头
+		exec("f8", "Funding", "ETHUSD", "Sell", 50, 3200, 0.0001, at(20, 2, 0, 0)),  // 超出窗口
+	}
+
+	minute := func(h int) time.Time { return day.Add(time.Duration(h) * time.Hour) }
+	want := []FundingPayment{
+		{Time: minute(4), Symbol: "XBTUSD", Currency: "XBt", PositionQty: 200, MarkPrice: 60000, Rate: 0.0001, Amount: -33, TransactID: "w1"},
+		{Time: minute(4), Symbol: "ETHUSD", Currency: "XBt", PositionQty: -50, MarkPrice: 3000, Rate: 0.0002, Amount: 10, TransactID: "w2"},
+		{Time: minute(12), Symbol: "ETHUSD", Currency: "XBt", PositionQty: 50, MarkPrice: 3100, Rate: -0.0001, Amount: 5, TransactID: "w3"},
+		{Time: minute(20), Symbol: "XBTUSD", Currency: "XBt", PositionQty: -200, MarkPrice: 61000, Rate: -0.0002, Amount: -15, TransactID: "w7"},
+		{Time: minute(20), Symbol: "ETHUSD", Currency: "XBt", PositionQty: 0, MarkPrice: 0, Rate: 0, Amount: 7, TransactID: "w8"},
+	}
+
+	got := BuildFundingLedger(wallet, executions)
+	if len(got) != len(want) {
+		t.Fatalf("得到 %d 条资金费用，应为 %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if g := got[i]; !g.Time.Equal(w.Time) || g.Symbol != w.Symbol || g.Currency != w.Currency || g.PositionQty != w.PositionQty ||
+			g.MarkPrice != w.MarkPrice || g.Rate != w.Rate || g.Amount != w.Amount || g.TransactID != w.TransactID {
+			t.Errorf("第 %d 条 = %+v\n应为 %+v", i, g, w)
+		}
+	}
+}
+
+func TestSummarizeFunding(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	ledger := []FundingPayment{
+		{Time: day.Add(4 * time.Hour), Symbol: "XBTUSD", Currency: "XBt", Rate: 0.0001, Amount: -30000},
+		{Time: day.Add(12 * time.Hour), Symbol: "XBTUSD", Currency: "XBt", Rate: 0.0003, Amount: 10000},
+		{Time: day.Add(12 * time.Hour), Symbol: "ETHUSDT", Currency: "USDt", Amount: 2500000},
+		{Time: day.AddDate(0, 1, 0), Symbol: "XBTUSD", Currency: "XBt", Amount: -5000},
+	}
+
+	totals, err := SummarizeFunding(ledger, "month")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FundingTotal{
+		{Key: "2024-05", Currency: "USDt", Count: 1, Received: 2.5, Net: 2.5},
+		{Key: "2024-05", Currency: "XBt", Count: 2, Received: 0.0001, Paid: 0.0003, Net: -0.0002, AvgRate: 0.0002},
+		{Key: "2024-06", Currency: "XBt", Count: 1, Paid: 0.00005, Net: -0.00005},
+	}
+	if len(totals) != len(want) {
+		t.Fatalf("汇总 = %+v", totals)
+	}
+	for i, w := range want {
+		g := totals[i]
+		if g.Key != w.Key || g.Currency != w.Currency || g.Count != w.Count ||
+			!approx(g.Received, w.Received) || !approx(g.Paid, w.Paid) || !approx(g.Net, w.Net) || !approx(g.AvgRate, w.AvgRate) {
+			t.Errorf("第 %d 组 = %+v\n应为 %+v", i, g, w)
+		}
+	}
+
+	if _, err := SummarizeFunding(ledger, "week"); err == nil {
+		t.Errorf("未知汇总方式应返回错误")
+	}
+}
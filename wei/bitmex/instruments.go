@@ -0,0 +1,177 @@
+package bitmex
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// InstrumentsFile 合约信息文件（sync instruments 下载）
+const InstrumentsFile = "instruments.csv"
+
+// InstrumentHeader instruments.csv 表头
+var InstrumentHeader = []string{
+	"Symbol", "RootSymbol", "State", "Typ", "Underlying", "QuoteCurrency", "SettlCurrency",
+	"PositionCurrency", "Multiplier", "IsQuanto", "IsInverse", "TickSize", "LotSize",
+	"UnderlyingToSettleMultiplier", "QuoteToSettleMultiplier", "Expiry", "Timestamp",
+}
+
+// ReadInstruments 读取 instruments.csv
+func ReadInstruments(filename string) ([]Instrument, error) {
+	return readCSV(filename, len(InstrumentHeader), parseInstrument)
+}
+
+// WriteInstruments 写入 instruments.csv
+func WriteInstruments(filename string, instruments []Instrument) error {
+	return writeCSV(filename, InstrumentHeader, instruments, Instrument.record)
+}
+
+// Value 持仓价值，单位为结算币种最小单位（XBt 为 Satoshi），符号与 qty 相同。
+//   - 反向合约（XBTUSD 等）: |multiplier| × qty / price
+//   - 正向合约（XBTUSDT、LTCM20 等）和双币种合约（ETHUSD 等）: |multiplier| × qty × price
+func (i Instrument) Value(qty, price float64) float64 {
+	m := math.Abs(float64(i.Multiplier))
+	if i.IsInverse {
+		if price == 0 {
+			return 0
+		}
+		return m * qty / price
+	}
+	return m * qty * price
+}
+
+// PnL qty 张合约从 entry 价格到 exit 价格的盈亏，单位为结算币种最小单位
+func (i Instrument) PnL(qty, entry, exit float64) float64 {
+	if i.IsInverse {
+		return i.Value(qty, entry) - i.Value(qty, exit)
+	}
+	return i.Value(qty, exit) - i.Value(qty, entry)
+}
+
+// AvgPrice 由累计持仓数量和累计开仓价值（Value 之和）反推开仓均价，
+// 反向合约为调和平均，其他合约为加权平均
+func (i Instrument) AvgPrice(qty, value float64) float64 {
+	m := math.Abs(float64(i.Multiplier))
+	if qty == 0 || value == 0 || m == 0 {
+		return 0
+	}
+	if i.IsInverse {
+		return m * qty / value
+	}
+	return value / (m * qty)
+}
+
+// Catalog 按交易对索引的合约信息
+type Catalog map[string]Instrument
+
+// NewCatalog 由合约列表创建索引
+func NewCatalog(instruments []Instrument) Catalog {
+	catalog := make(Catalog, len(instruments))
+	for _, inst := range instruments {
+		catalog[inst.Symbol] = inst
+	}
+	return catalog
+}
+
+// LoadCatalog 读取 instruments.csv 并创建索引
+func LoadCatalog(filename string) (Catalog, error) {
+	instruments, err := ReadInstruments(filename)
+	if err != nil {
+		return nil, err
+	}
+	return NewCatalog(instruments), nil
+}
+
+// xbtInverse 以 XBT 为标的、按美元计价的反向合约：XBTUSD 永续和 XBTZ20 等季度合约
+var xbtInverse = regexp.MustCompile(`^XBT(USD|[FGHJKMNQUVXZ]\d\d)$`)
+
+// Lookup 查找合约信息。instruments.csv 中没有的 XBT 反向合约按 XBTUSD 的规格处理，
+// 其他未知合约返回 false（不能假定其计算方式）
+func (c Catalog) Lookup(symbol string) (Instrument, bool) {
+	if inst, ok := c[symbol]; ok {
+		return inst, true
+	}
+	if xbtInverse.MatchString(symbol) {
+		return Instrument{
+			Symbol:        symbol,
+			RootSymbol:    "XBT",
+			Underlying:    "XBT",
+			QuoteCurrency: "USD",
+			SettlCurrency: "XBt",
+			Multiplier:    -100000000,
+			IsInverse:     true,
+		}, true
+	}
+	return Instrument{}, false
+}
+
+// ToBTC 将结算币种主单位（BTC / USDT）的金额换算为 BTC，USDT 按 xbtPrice 换算。
+// 无法换算（未知币种或没有价格）时返回 false
+func ToBTC(currency string, amount, xbtPrice float64) (float64, bool) {
+	switch currency {
+	case "XBt", "XBT":
+		return amount, true
+	case "USDt", "USDT":
+		if xbtPrice > 0 {
+			return amount / xbtPrice, true
+		}
+	}
+	return 0, false
+}
+
+func (i Instrument) record() []string {
+	return []string{
+		i.Symbol,
+		i.RootSymbol,
+		i.State,
+		i.Typ,
+		i.Underlying,
+		i.QuoteCurrency,
+		i.SettlCurrency,
+		i.PositionCurrency,
+		strconv.FormatInt(i.Multiplier, 10),
+		strconv.FormatBool(i.IsQuanto),
+		strconv.FormatBool(i.IsInverse),
+		strconv.FormatFloat(i.TickSize, 'f', -1, 64),
+		strconv.FormatFloat(i.LotSize, 'f', -1, 64),
+		strconv.FormatFloat(i.UnderlyingToSettleMultiplier, 'f', -1, 64),
+		strconv.FormatFloat(i.QuoteToSettleMultiplier, 'f', -1, 64),
+		i.Expiry,
+		i.Timestamp,
+	}
+}
+
+func parseInstrument(f *fieldReader) Instrument {
+	return Instrument{
+		Symbol:                       f.str(0),
+		RootSymbol:                   f.str(1),
+		State:                        f.str(2),
+		Typ:                          f.str(3),
+		Underlying:                   f.str(4),
+		QuoteCurrency:                f.str(5),
+		SettlCurrency:                f.str(6),
+		PositionCurrency:             f.str(7),
+		Multiplier:                   f.int64(8),
+		IsQuanto:                     f.bool(9),
+		IsInverse:                    f.bool(10),
+		TickSize:                     f.float(11),
+		LotSize:                      f.float(12),
+		UnderlyingToSettleMultiplier: f.float(13),
+		QuoteToSettleMultiplier:      f.float(14),
+		Expiry:                       f.str(15),
+		Timestamp:                    f.str(16),
+	}
+}
+
+// String 合约类型说明，如 "XBTUSD 反向合约 (结算 XBt, 乘数 -100000000)"
+func (i Instrument) String() string {
+	kind := "正向合约"
+	switch {
+	case i.IsInverse:
+		kind = "反向合约"
+	case i.IsQuanto:
+		kind = "双币种合约"
+	}
+	return fmt.Sprintf("%s %s (结算 %s, 乘数 %d)", i.Symbol, kind, i.SettlCurrency, i.Multiplier)
+}
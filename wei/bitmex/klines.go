@@ -0,0 +1,183 @@
+package bitmex
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NativeBinSizes BitMEX /trade/bucketed 直接提供的K线周期
+var NativeBinSizes = []string{"1m", "5m", "1h", "1d"}
+
+// weekOrigin 周K线的对齐起点（周一 00:00 UTC）
+var weekOrigin = time.Date(1970, 1, 5, 0, 0, 0, 0, time.UTC)
+
+// ParseTimeframe 解析K线周期，如 1m、15m、4h、1d、1w
+func ParseTimeframe(tf string) (time.Duration, error) {
+	if len(tf) < 2 {
+		return 0, fmt.Errorf("无效的K线周期: %q", tf)
+	}
+	n, err := strconv.Atoi(tf[:len(tf)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("无效的K线周期: %q", tf)
+	}
+
+	var unit time.Duration
+	switch tf[len(tf)-1] {
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("无效的K线周期: %q（单位应为 m / h / d / w）", tf)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// SourceBinSize 合成 tf 周期K线所用的 BitMEX 原始周期：能整除 tf 的最大原生周期，
+// 如 15m → 5m、4h → 1h、1w → 1d。tf 本身是原生周期时返回 tf
+func SourceBinSize(tf string) (string, error) {
+	d, err := ParseTimeframe(tf)
+	if err != nil {
+		return "", err
+	}
+	for i := len(NativeBinSizes) - 1; i >= 0; i-- {
+		bin := NativeBinSizes[i]
+		bd, _ := ParseTimeframe(bin)
+		if d%bd == 0 {
+			return bin, nil
+		}
+	}
+	return "", fmt.Errorf("无法由 BitMEX K线合成 %s 周期（需为 1m 的整数倍）", tf)
+}
+
+// bucketEnd 包含 [start, start+source) 的 d 周期K线的结束时间。
+// 1d 及以上按 UTC 零点对齐，周线从周一开始
+func bucketEnd(start time.Time, d time.Duration) time.Time {
+	origin := time.Unix(0, 0).UTC()
+	if d%(7*24*time.Hour) == 0 {
+		origin = weekOrigin
+	}
+	n := start.Sub(origin) / d
+	return origin.Add((n + 1) * d)
+}
+
+// Resample 将 source 周期的K线（按时间排序，不重复）合成为 tf 周期。
+// 与 BitMEX 一致，Timestamp 为K线的结束时间。只输出包含全部 tf/source 根原始K线的完整K线，
+// 开头、结尾不完整以及中间有缺失的K线都不输出。
+func Resample(klines []Kline, source, tf string) ([]Kline, error) {
+	sd, err := ParseTimeframe(source)
+	if err != nil {
+		return nil, err
+	}
+	d, err := ParseTimeframe(tf)
+	if err != nil {
+		return nil, err
+	}
+	if d < sd || d%sd != 0 {
+		return nil, fmt.Errorf("%s 不是 %s 的整数倍", tf, source)
+	}
+	perBucket := int(d / sd)
+
+	var result []Kline
+	var cur Kline
+	count := 0 // 当前K线包含的原始K线数
+	flush := func() {
+		if !cur.Timestamp.IsZero() && count == perBucket {
+			result = append(result, cur)
+		}
+	}
+
+	for _, k := range klines {
+		end := bucketEnd(k.Timestamp.Add(-sd), d)
+		if !end.Equal(cur.Timestamp) {
+			flush()
+			cur = Kline{
+				Timestamp: end,
+				Symbol:    k.Symbol,
+				Open:      k.Open,
+				High:      k.High,
+				Low:       k.Low,
+			}
+			count = 0
+		}
+		if k.High > cur.High {
+			cur.High = k.High
+		}
+		if k.Low < cur.Low {
+			cur.Low = k.Low
+		}
+		cur.Close = k.Close
+		cur.Volume += k.Volume
+		cur.Trades += k.Trades
+		count++
+	}
+	flush()
+	return result, nil
+}
+
+// ResampleFile 读取 src 文件中 source 周期的K线，合成 tf 周期后写入 dst，返回K线数
+func ResampleFile(src, dst, source, tf string) (int, error) {
+	klines, err := ReadKlines(src)
+	if err != nil {
+		return 0, fmt.Errorf("读取 %s 失败: %w", src, err)
+	}
+	resampled, err := Resample(klines, source, tf)
+	if err != nil {
+		return 0, err
+	}
+	if err := WriteKlines(dst, resampled); err != nil {
+		return 0, fmt.Errorf("保存 %s 失败: %w", dst, err)
+	}
+	return len(resampled), nil
+}
+
+// ParseKlinesFile 从K线文件名（如 klines_XBTUSD_4h.csv）解析交易对和周期
+func ParseKlinesFile(filename string) (symbol, tf string, ok bool) {
+	name := filepath.Base(filename)
+	if !strings.HasPrefix(name, "klines_") || !strings.HasSuffix(name, ".csv") {
+		return "", "", false
+	}
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "klines_"), ".csv")
+	i := strings.LastIndex(name, "_")
+	if i <= 0 {
+		return "", "", false
+	}
+	symbol, tf = name[:i], name[i+1:]
+	if _, err := ParseTimeframe(tf); err != nil {
+		return "", "", false
+	}
+	return symbol, tf, true
+}
+
+// FinestKlinesFile 在 dir 中查找 symbol 周期最小的K线文件，没有时 ok 为 false
+func FinestKlinesFile(dir, symbol string) (filename string, binSize time.Duration, ok bool) {
+	files, _ := filepath.Glob(filepath.Join(dir, "klines_"+symbol+"_*.csv"))
+	for _, f := range files {
+		s, tf, valid := ParseKlinesFile(f)
+		if !valid || s != symbol {
+			continue
+		}
+		d, _ := ParseTimeframe(tf)
+		if !ok || d < binSize {
+			filename, binSize, ok = f, d, true
+		}
+	}
+	return filename, binSize, ok
+}
+
+// ClosePriceAt 截至 at 最后一根已结束K线的收盘价（klines 按时间排序），没有时返回 0
+func ClosePriceAt(klines []Kline, at time.Time) float64 {
+	i := sort.Search(len(klines), func(j int) bool { return klines[j].Timestamp.After(at) })
+	if i == 0 {
+		return 0
+	}
+	return klines[i-1].Close
+}
@@ -0,0 +1,185 @@
+package bitmex
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// sourceKlines 从 start 开始每隔 step 一根的K线（Timestamp 为结束时间），
+// 第 i 根的开盘价为 100+i，最高/最低为开盘价 ±1，收盘价为开盘价 +0.5，成交量为 i+1
+func sourceKlines(start time.Time, step time.Duration, n int) []Kline {
+	klines := make([]Kline, n)
+	for i := range klines {
+		open := 100 + float64(i)
+		klines[i] = Kline{
+			Timestamp: start.Add(time.Duration(i+1) * step),
+			Symbol:    "XBTUSD",
+			Open:      open,
+			High:      open + 1,
+			Low:       open - 1,
+			Close:     open + 0.5,
+			Volume:    int64(i + 1),
+			Trades:    1,
+		}
+	}
+	return klines
+}
+
+// without 去掉第 i 根K线
+func without(klines []Kline, i int) []Kline {
+	return append(append([]Kline(nil), klines[:i]...), klines[i+1:]...)
+}
+
+func TestResample(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC) // 周三
+	monday := time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		klines []Kline
+		source string
+		tf     string
+		want   []Kline // 只比较 Timestamp、Open、High、Low、Close、Volume、Trades
+	}{
+		{
+			name:   "15m←5m",
+			klines: sourceKlines(day, 5*time.Minute, 6),
+			source: "5m", tf: "15m",
+			want: []Kline{
+				{Timestamp: day.Add(15 * time.Minute), Open: 100, High: 103, Low: 99, Close: 102.5, Volume: 6, Trades: 3},
+				{Timestamp: day.Add(30 * time.Minute), Open: 103, High: 106, Low: 102, Close: 105.5, Volume: 15, Trades: 3},
+			},
+		},
+		{
+			name:   "4h←1h",
+			klines: sourceKlines(day, time.Hour, 8),
+			source: "1h", tf: "4h",
+			want: []Kline{
+				{Timestamp: day.Add(4 * time.Hour), Open: 100, High: 104, Low: 99, Close: 103.5, Volume: 10, Trades: 4},
+				{Timestamp: day.Add(8 * time.Hour), Open: 104, High: 108, Low: 103, Close: 107.5, Volume: 26, Trades: 4},
+			},
+		},
+		{
+			name:   "1w←1d 从周一开始",
+			klines: sourceKlines(monday, 24*time.Hour, 14),
+			source: "1d", tf: "1w",
+			want: []Kline{
+				{Timestamp: monday.AddDate(0, 0, 7), Open: 100, High: 107, Low: 99, Close: 106.5, Volume: 28, Trades: 7},
+				{Timestamp: monday.AddDate(0, 0, 14), Open: 107, High: 114, Low: 106, Close: 113.5, Volume: 77, Trades: 7},
+			},
+		},
+		{
+			// 周三开始的日线：第一周不完整
+			name:   "1w←1d 开头不完整",
+			klines: sourceKlines(day, 24*time.Hour, 12),
+			source: "1d", tf: "1w",
+			want: []Kline{
+				{Timestamp: monday.AddDate(0, 0, 14), Open: 105, High: 112, Low: 104, Close: 111.5, Volume: 63, Trades: 7},
+			},
+		},
+		{
+			// 01:00 开始的 1h 数据：00:00-04:00 只有 3 根
+			name:   "4h←1h 开头不完整",
+			klines: sourceKlines(day.Add(time.Hour), time.Hour, 7),
+			source: "1h", tf: "4h",
+			want: []Kline{
+				{Timestamp: day.Add(8 * time.Hour), Open: 103, High: 107, Low: 102, Close: 106.5, Volume: 22, Trades: 4},
+			},
+		},
+		{
+			name:   "4h←1h 结尾不完整",
+			klines: sourceKlines(day, time.Hour, 6),
+			source: "1h", tf: "4h",
+			want: []Kline{
+				{Timestamp: day.Add(4 * time.Hour), Open: 100, High: 104, Low: 99, Close: 103.5, Volume: 10, Trades: 4},
+			},
+		},
+		{
+			// 第二根 4h 缺少 05:00-06:00
+			name:   "4h←1h 中间缺失",
+			klines: without(sourceKlines(day, time.Hour, 12), 5),
+			source: "1h", tf: "4h",
+			want: []Kline{
+				{Timestamp: day.Add(4 * time.Hour), Open: 100, High: 104, Low: 99, Close: 103.5, Volume: 10, Trades: 4},
+				{Timestamp: day.Add(12 * time.Hour), Open: 108, High: 112, Low: 107, Close: 111.5, Volume: 42, Trades: 4},
+			},
+		},
+		{
+			name:   "相同周期",
+			klines: sourceKlines(day, time.Hour, 2),
+			source: "1h", tf: "1h",
+			want: []Kline{
+				{Timestamp: day.Add(time.Hour), Open: 100, High: 101, Low: 99, Close: 100.5, Volume: 1, Trades: 1},
+				{Timestamp: day.Add(2 * time.Hour), Open: 101, High: 102, Low: 100, Close: 101.5, Volume: 2, Trades: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resample(tt.klines, tt.source, tt.tf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("得到 %d 根K线，应为 %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, w := range tt.want {
+				w.Symbol = "XBTUSD"
+				if !got[i].Timestamp.Equal(w.Timestamp) || got[i] != w {
+					t.Errorf("第 %d 根 = %+v\n应为 %+v", i, got[i], w)
+				}
+			}
+		})
+	}
+}
+
+func TestResampleInvalid(t *testing.T) {
+	for _, tt := range []struct{ source, tf string }{
+		{"1h", "30m"}, // 小于原始周期
+		{"1h", "90m"}, // 不是整数倍
+		{"1h", "4x"},
+		{"x", "4h"},
+	} {
+		if _, err := Resample(nil, tt.source, tt.tf); err == nil {
+			t.Errorf("Resample(%s → %s) 应返回错误", tt.source, tt.tf)
+		}
+	}
+}
+
+func TestSourceBinSize(t *testing.T) {
+	for tf, want := range map[string]string{
+		"1m": "1m", "3m": "1m", "15m": "5m", "1h": "1h", "2h": "1h", "4h": "1h", "1d": "1d", "1w": "1d",
+	} {
+		if got, err := SourceBinSize(tf); err != nil || got != want {
+			t.Errorf("SourceBinSize(%s) = %s, %v，应为 %s", tf, got, err, want)
+		}
+	}
+	if _, err := SourceBinSize("0h"); err == nil {
+		t.Errorf("SourceBinSize(0h) 应返回错误")
+	}
+}
+
+func TestParseKlinesFile(t *testing.T) {
+	tests := []struct {
+		filename   string
+		symbol, tf string
+		ok         bool
+	}{
+		{"klines_XBTUSD_4h.csv", "XBTUSD", "4h", true},
+		{filepath.Join("data", "klines_XBTUSD_1w.csv"), "XBTUSD", "1w", true},
+		{"klines_ETH_USDT_15m.csv", "ETH_USDT", "15m", true}, // 交易对中的下划线
+		{KlinesFile("XBTUSD", "1m"), "XBTUSD", "1m", true},
+		{"klines_XBTUSD.csv", "", "", false},
+		{"klines__4h.csv", "", "", false},
+		{"klines_XBTUSD_4x.csv", "", "", false},
+		{"klines_XBTUSD_4h.txt", "", "", false},
+		{"executions.csv", "", "", false},
+	}
+	for _, tt := range tests {
+		symbol, tf, ok := ParseKlinesFile(tt.filename)
+		if symbol != tt.symbol || tf != tt.tf || ok != tt.ok {
+			t.Errorf("ParseKlinesFile(%q) = %q, %q, %v，应为 %q, %q, %v", tt.filename, symbol, tf, ok, tt.symbol, tt.tf, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package bitmex
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// orderIDBatch 按 orderID 查询时每次请求的ID数量（避免URL过长）
+const orderIDBatch = 100
+
+// IsOpen 订单是否仍在挂单中（状态之后还会变化）
+func (o Order) IsOpen() bool {
+	switch o.OrdStatus {
+	case "New", "PartiallyFilled", "PendingNew", "PendingCancel", "PendingReplace", "Untriggered":
+		return true
+	}
+	return false
+}
+
+// OrdersByID 按 orderID 获取订单的最新状态
+func (c *Client) OrdersByID(ids []string) ([]Order, error) {
+	var orders []Order
+	for start := 0; start < len(ids); start += orderIDBatch {
+		end := min(start+orderIDBatch, len(ids))
+		batch, err := c.Orders(Query{Filter: map[string]any{"orderID": ids[start:end]}})
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, batch...)
+	}
+	return orders, nil
+}
+
+func orderIDs(orders []Order) []string {
+	ids := make([]string, len(orders))
+	for i, o := range orders {
+		ids[i] = o.OrderID
+	}
+	return ids
+}
+
+// OrderTransition 订单的一次状态变化
+type OrderTransition struct {
+	RecordedAt time.Time // 同步时发现变化的时间
+	OrderID    string
+	Symbol     string
+	Side       string
+	OldStatus  string
+	NewStatus  string
+	OldCumQty  int
+	NewCumQty  int
+	AvgPx      float64
+	Timestamp  string // 订单最后更新时间（BitMEX timestamp）
+}
+
+// OrderHistoryHeader orders_history.csv 表头
+var OrderHistoryHeader = []string{
+	"RecordedAt", "OrderID", "Symbol", "Side", "OldStatus", "NewStatus",
+	"OldCumQty", "NewCumQty", "AvgPx", "Timestamp",
+}
+
+// OrderHistoryFile 订单文件对应的状态历史文件，如 orders.csv → orders_history.csv
+func OrderHistoryFile(ordersFile string) string {
+	return strings.TrimSuffix(ordersFile, ".csv") + "_history.csv"
+}
+
+// orderTransitions 从订单变化中提取状态或成交数量有变化的记录
+func orderTransitions(changes []Change[Order], now time.Time) []OrderTransition {
+	var transitions []OrderTransition
+	for _, ch := range changes {
+		if ch.Old.OrdStatus == ch.New.OrdStatus && ch.Old.CumQty == ch.New.CumQty {
+			continue
+		}
+		transitions = append(transitions, OrderTransition{
+			RecordedAt: now.UTC(),
+			OrderID:    ch.New.OrderID,
+			Symbol:     ch.New.Symbol,
+			Side:       ch.New.Side,
+			OldStatus:  ch.Old.OrdStatus,
+			NewStatus:  ch.New.OrdStatus,
+			OldCumQty:  ch.Old.CumQty,
+			NewCumQty:  ch.New.CumQty,
+			AvgPx:      ch.New.AvgPx,
+			Timestamp:  ch.New.Timestamp,
+		})
+	}
+	return transitions
+}
+
+// AppendOrderTransitions 追加状态变化到历史文件，文件不存在时先写表头
+func AppendOrderTransitions(filename string, transitions []OrderTransition) error {
+	return appendCSV(filename, OrderHistoryHeader, transitions, OrderTransition.record)
+}
+
+func (t OrderTransition) record() []string {
+	return []string{
+		t.RecordedAt.Format(TimeLayout),
+		t.OrderID,
+		t.Symbol,
+		t.Side,
+		t.OldStatus,
+		t.NewStatus,
+		strconv.Itoa(t.OldCumQty),
+		strconv.Itoa(t.NewCumQty),
+		priceString(t.AvgPx),
+		t.Timestamp,
+	}
+}
+
+// ReadOrderTransitions 读取订单状态历史
+func ReadOrderTransitions(filename string) ([]OrderTransition, error) {
+	return readCSV(filename, len(OrderHistoryHeader), func(f *fieldReader) OrderTransition {
+		return OrderTransition{
+			RecordedAt: f.time(0),
+			OrderID:    f.str(1),
+			Symbol:     f.str(2),
+			Side:       f.str(3),
+			OldStatus:  f.str(4),
+			NewStatus:  f.str(5),
+			OldCumQty:  f.int(6),
+			NewCumQty:  f.int(7),
+			AvgPx:      f.float(8),
+			Timestamp:  f.str(9),
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package bitmex
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// 统计区间
+const (
+	PeriodToday  = "today"  // 今日
+	PeriodWeek   = "wtd"    // 本周至今（周一开始）
+	PeriodMonth  = "mtd"    // 本月至今
+	PeriodYear   = "ytd"    // 本年至今
+	PeriodAll    = "all"    // 全部
+	PeriodCustom = "custom" // 自定义日期范围
+)
+
+// Beijing 北京时间（UTC+8，没有夏令时，不依赖系统时区数据）
+var Beijing = time.FixedZone("Beijing", 8*3600)
+
+// ParseTimezone 解析统计时区：UTC（默认）或 Beijing（Asia/Shanghai、CST、+8）
+func ParseTimezone(name string) (*time.Location, error) {
+	switch name {
+	case "", "UTC", "utc":
+		return time.UTC, nil
+	case "Beijing", "beijing", "Asia/Shanghai", "CST", "+8", "UTC+8":
+		return Beijing, nil
+	}
+	return nil, fmt.Errorf("未知时区: %s（可用: UTC, Beijing）", name)
+}
+
+// Period 统计区间 [Start, End)，Start 为零值表示从最早的记录开始
+type Period struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+// PeriodToDate 截至 now 的统计区间（today / wtd / mtd / ytd / all），日期边界按 loc 时区计算
+func PeriodToDate(name string, now time.Time, loc *time.Location) (Period, error) {
+	local := now.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	p := Period{Name: name, End: now}
+	switch name {
+	case PeriodToday:
+		p.Start = day
+	case PeriodWeek:
+		p.Start = day.AddDate(0, 0, -(int(local.Weekday())+6)%7)
+	case PeriodMonth:
+		p.Start = time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, loc)
+	case PeriodYear:
+		p.Start = time.Date(local.Year(), 1, 1, 0, 0, 0, 0, loc)
+	case PeriodAll:
+	default:
+		return Period{}, fmt.Errorf("未知统计区间: %s（可用: today, wtd, mtd, ytd, all, custom）", name)
+	}
+	return p, nil
+}
+
+// CustomPeriod 自定义日期范围 [from, to)，日期格式 2006-01-02，按 loc 时区的零点计算；
+// to 为空时到 now
+func CustomPeriod(from, to string, now time.Time, loc *time.Location) (Period, error) {
+	p := Period{Name: PeriodCustom, End: now}
+	if from == "" {
+		return Period{}, fmt.Errorf("自定义区间需要 from 日期")
+	}
+	start, err := time.ParseInLocation("2006-01-02", from, loc)
+	if err != nil {
+		return Period{}, fmt.Errorf("日期格式错误: %q（应为 2006-01-02）", from)
+	}
+	p.Start = start
+	if to != "" {
+		end, err := time.ParseInLocation("2006-01-02", to, loc)
+		if err != nil {
+			return Period{}, fmt.Errorf("日期格式错误: %q（应为 2006-01-02）", to)
+		}
+		if !end.After(start) {
+			return Period{}, fmt.Errorf("结束日期 %s 应晚于起始日期 %s", to, from)
+		}
+		p.End = end
+	}
+	return p, nil
+}
+
+// Contains t 是否在区间内
+func (p Period) Contains(t time.Time) bool {
+	return (p.Start.IsZero() || !t.Before(p.Start)) && t.Before(p.End)
+}
+
+// PeriodPnL 一个结算币种在区间内的盈亏，金额为主单位（BTC / USDT）或美元
+type PeriodPnL struct {
+	Currency     string  `json:"currency"`
+	Realised     float64 `json:"realised"`     // 钱包 RealisedPNL 合计（已扣除交易手续费）
+	Funding      float64 `json:"funding"`      // 钱包 Funding 合计
+	Fees         float64 `json:"fees"`         // 区间内成交的交易手续费（已包含在 Realised 中，负值为返佣）
+	Net          float64 `json:"net"`          // Realised + Funding
+	StartBalance float64 `json:"startBalance"` // 区间开始时的钱包余额
+	EndBalance   float64 `json:"endBalance"`   // 区间结束时的钱包余额
+	Return       float64 `json:"return"`       // Net / StartBalance（百分比），没有起始余额时为 0
+	Settlements  int     `json:"settlements"`  // RealisedPNL 和 Funding 记录数
+
+	realised, funding       int64 // 最小单位
+	realisedUSD, fundingUSD float64
+}
+
+// ComputePeriodPnL 按记录时间统计区间内各结算币种的净盈亏（已实现盈亏和资金费用，均已扣除手续费）。
+// executions 只用于统计交易手续费，可以为 nil。usd 不为 nil 时金额换算为美元：
+// 每条记录按记录时刻的价格换算，起止余额按区间起止时刻的价格换算。
+func ComputePeriodPnL(wallet []WalletHistory, executions []Execution, catalog Catalog, p Period, usd *PriceSeries) []PeriodPnL {
+	records := make([]WalletHistory, 0, len(wallet))
+	for _, h := range wallet {
+		if h.TransactStatus != "Canceled" && h.TransactStatus != "Pending" {
+			records = append(records, h)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time().Before(records[j].Time()) })
+
+	totals := make(map[string]*PeriodPnL)
+	get := func(currency string) *PeriodPnL {
+		t, ok := totals[currency]
+		if !ok {
+			t = &PeriodPnL{Currency: currency}
+			totals[currency] = t
+		}
+		return t
+	}
+	for _, h := range records {
+		at := h.Time()
+		t := get(h.Currency)
+		balance := FromMinorUnits(h.Currency, h.WalletBalance)
+		if !p.Start.IsZero() && at.Before(p.Start) {
+			t.StartBalance, t.EndBalance = balance, balance
+			continue
+		}
+		if !at.Before(p.End) {
+			continue
+		}
+		t.EndBalance = balance
+		switch h.TransactType {
+		case "RealisedPNL":
+			t.realised += h.Amount
+			t.realisedUSD += usd.USD(h.Currency, FromMinorUnits(h.Currency, h.Amount), at)
+			t.Settlements++
+		case "Funding":
+			t.funding += h.Amount
+			t.fundingUSD += usd.USD(h.Currency, FromMinorUnits(h.Currency, h.Amount), at)
+			t.Settlements++
+		}
+	}
+
+	fees := make(map[string]float64)
+	feesUSD := make(map[string]float64)
+	for _, e := range executions {
+		if e.ExecType != "Trade" || !p.Contains(e.Time()) {
+			continue
+		}
+		if inst, ok := catalog.Lookup(e.Symbol); ok {
+			fee := e.Commission * math.Abs(inst.Value(float64(e.LastQty), e.LastPx))
+			fees[inst.SettlCurrency] += fee
+			feesUSD[inst.SettlCurrency] += usd.USD(inst.SettlCurrency, FromMinorUnits(inst.SettlCurrency, int64(math.Round(fee))), e.Time())
+		}
+	}
+	for currency, fee := range fees {
+		get(currency).Fees = FromMinorUnits(currency, int64(math.Round(fee)))
+		if usd != nil {
+			get(currency).Fees = feesUSD[currency]
+		}
+	}
+
+	result := make([]PeriodPnL, 0, len(totals))
+	for _, t := range totals {
+		t.Realised = FromMinorUnits(t.Currency, t.realised)
+		t.Funding = FromMinorUnits(t.Currency, t.funding)
+		t.Net = FromMinorUnits(t.Currency, t.realised+t.funding)
+		if usd != nil {
+			t.Realised, t.Funding, t.Net = t.realisedUSD, t.fundingUSD, t.realisedUSD+t.fundingUSD
+			t.StartBalance = usd.USD(t.Currency, t.StartBalance, p.Start)
+			t.EndBalance = usd.USD(t.Currency, t.EndBalance, p.End)
+		}
+		if t.StartBalance > 0 {
+			t.Return = t.Net / t.StartBalance * 100
+		}
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Currency < result[j].Currency })
+	return result
+}
@@ -0,0 +1,176 @@
+package bitmex
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPeriodToDate(t *testing.T) {
+	utc := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2024, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	beijing := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, Beijing)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		loc  *time.Location
+		want map[string]time.Time
+	}{
+		{
+			// UTC 12 月 31 日（周二）20:00
+			name: "UTC 年末",
+			now:  utc(12, 31, 20),
+			loc:  time.UTC,
+			want: map[string]time.Time{
+				PeriodToday: utc(12, 31, 0),
+				PeriodWeek:  utc(12, 30, 0),
+				PeriodMonth: utc(12, 1, 0),
+				PeriodYear:  utc(1, 1, 0),
+			},
+		},
+		{
+			// 同一时刻在北京时间已是 2025 年 1 月 1 日（周三）04:00，月和年都已切换，周仍从 12 月 30 日开始
+			name: "北京时间跨年",
+			now:  utc(12, 31, 20),
+			loc:  Beijing,
+			want: map[string]time.Time{
+				PeriodToday: beijing(2025, 1, 1),
+				PeriodWeek:  beijing(2024, 12, 30),
+				PeriodMonth: beijing(2025, 1, 1),
+				PeriodYear:  beijing(2025, 1, 1),
+			},
+		},
+		{
+			// UTC 3 月 3 日是周日，本周从上个月的 2 月 26 日（周一）开始
+			name: "UTC 周日跨月",
+			now:  utc(3, 3, 17),
+			loc:  time.UTC,
+			want: map[string]time.Time{
+				PeriodToday: utc(3, 3, 0),
+				PeriodWeek:  utc(2, 26, 0),
+				PeriodMonth: utc(3, 1, 0),
+				PeriodYear:  utc(1, 1, 0),
+			},
+		},
+		{
+			// 同一时刻在北京时间已是 3 月 4 日（周一）01:00，新的一周从当天开始
+			name: "北京时间周一",
+			now:  utc(3, 3, 17),
+			loc:  Beijing,
+			want: map[string]time.Time{
+				PeriodToday: beijing(2024, 3, 4),
+				PeriodWeek:  beijing(2024, 3, 4),
+				PeriodMonth: beijing(2024, 3, 1),
+				PeriodYear:  beijing(2024, 1, 1),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, start := range tt.want {
+				p, err := PeriodToDate(name, tt.now, tt.loc)
+				if err != nil {
+					t.Fatalf("PeriodToDate(%s) 失败: %v", name, err)
+				}
+				if !p.Start.Equal(start) || !p.End.Equal(tt.now) {
+					t.Errorf("%s = [%v, %v)，应为 [%v, %v)", name, p.Start, p.End, start, tt.now)
+				}
+			}
+			p, err := PeriodToDate(PeriodAll, tt.now, tt.loc)
+			if err != nil || !p.Start.IsZero() {
+				t.Errorf("all 的起始时间 = %v, %v，应为零值", p.Start, err)
+			}
+		})
+	}
+
+	if _, err := PeriodToDate("week", utc(3, 3, 17), time.UTC); err == nil {
+		t.Errorf("未知区间应返回错误")
+	}
+}
+
+func TestComputePeriodPnL(t *testing.T) {
+	catalog := NewCatalog([]Instrument{testInverse, testLinear})
+	at := func(day, hour int) string {
+		return time.Date(2024, 12, day, hour, 0, 0, 0, time.UTC).Format(TimeLayout)
+	}
+	record := func(typ, status, currency string, amount, balance int64, timestamp string) WalletHistory {
+		return WalletHistory{TransactType: typ, TransactStatus: status, Currency: currency, Amount: amount,
+			WalletBalance: balance, Timestamp: timestamp}
+	}
+	wallet := []WalletHistory{
+		record("RealisedPNL", "Completed", "XBt", 4000000, 104800000, at(31, 19)),
+		record("Deposit", "Completed", "XBt", 100000000, 100000000, at(30, 12)),
+		// UTC 12 月 31 日 12:00，北京时间当天 20:00
+		record("RealisedPNL", "Completed", "XBt", 1000000, 101000000, at(31, 12)),
+		// 北京时间 2025 年 1 月 1 日零点整，计入当天
+		record("Funding", "Completed", "XBt", -200000, 100800000, at(31, 16)),
+		record("RealisedPNL", "Canceled", "XBt", 5000000, 0, at(31, 17)),
+		record("Withdrawal", "Pending", "XBt", -50000000, 0, at(31, 18)),
+		// 结束时刻不计入
+		record("RealisedPNL", "Completed", "XBt", 3000000, 107800000, at(31, 20)),
+		record("RealisedPNL", "Completed", "USDt", 1000000, 5000000, at(31, 18)),
+	}
+	executions := []Execution{
+		// 手续费 0.0005 × 1e8 × 100 / 50000 = 100 聪
+		reconcileFill("o1", "XBTUSD", 100, 50000, 0.0005, at(31, 17)),
+		// 北京时间 12 月 31 日，不计入北京时间的今天
+		reconcileFill("o2", "XBTUSD", -100, 40000, 0.0005, at(31, 15)),
+	}
+	now := time.Date(2024, 12, 31, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		loc  *time.Location
+		want []PeriodPnL
+	}{
+		{
+			name: "北京时间",
+			loc:  Beijing,
+			want: []PeriodPnL{
+				{Currency: "USDt", Realised: 1, Net: 1, EndBalance: 5, Settlements: 1},
+				{Currency: "XBt", Realised: 0.04, Funding: -0.002, Fees: 0.000001, Net: 0.038,
+					StartBalance: 1.01, EndBalance: 1.048, Return: 0.038 / 1.01 * 100, Settlements: 2},
+			},
+		},
+		{
+			// UTC 的今天从 12 月 31 日零点开始，包含 12:00 的已实现盈亏和 15:00 的成交（手续费 125 聪）
+			name: "UTC",
+			loc:  time.UTC,
+			want: []PeriodPnL{
+				{Currency: "USDt", Realised: 1, Net: 1, EndBalance: 5, Settlements: 1},
+				{Currency: "XBt", Realised: 0.05, Funding: -0.002, Fees: 0.00000225, Net: 0.048,
+					StartBalance: 1, EndBalance: 1.048, Return: 4.8, Settlements: 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := PeriodToDate(PeriodToday, now, tt.loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ComputePeriodPnL(wallet, executions, catalog, p, nil)
+			if len(got) != len(tt.want) {
+				t.Fatalf("得到 %d 个币种，应为 %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				fields := [][2]float64{
+					{g.Realised, w.Realised}, {g.Funding, w.Funding}, {g.Fees, w.Fees}, {g.Net, w.Net},
+					{g.StartBalance, w.StartBalance}, {g.EndBalance, w.EndBalance}, {g.Return, w.Return},
+				}
+				match := g.Currency == w.Currency && g.Settlements == w.Settlements
+				for _, f := range fields {
+					match = match && math.Abs(f[0]-f[1]) < 1e-12
+				}
+				if !match {
+					t.Errorf("%s = %+v\n应为 %+v", w.Currency, g, w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package bitmex
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// 交易所持仓和保证金快照文件，每次运行 snapshot 命令追加一组记录
+const (
+	PositionsFile = "positions.csv"
+	MarginFile    = "margin.csv"
+)
+
+// PositionSnapshot 快照时刻交易所返回的一个持仓
+type PositionSnapshot struct {
+	SnapshotTime time.Time
+	Position
+}
+
+// MarginSnapshot 快照时刻交易所返回的一个币种的保证金账户
+type MarginSnapshot struct {
+	SnapshotTime time.Time
+	Margin
+}
+
+// PositionSnapshotHeader positions.csv 表头，金额单位为结算币种最小单位（XBt 为 Satoshi）
+var PositionSnapshotHeader = []string{
+	"SnapshotTime", "Account", "Symbol", "Currency", "CurrentQty", "AvgEntryPrice",
+	"MarkPrice", "LiquidationPrice", "Leverage", "CrossMargin", "HomeNotional", "ForeignNotional",
+	"PosMargin", "MaintMargin", "UnrealisedPnl", "RealisedPnl", "Timestamp",
+}
+
+// MarginSnapshotHeader margin.csv 表头，金额单位为该币种最小单位（XBt 为 Satoshi）
+var MarginSnapshotHeader = []string{
+	"SnapshotTime", "Account", "Currency", "WalletBalance", "MarginBalance", "AvailableMargin",
+	"UnrealisedPnl", "RealisedPnl", "InitMargin", "MaintMargin", "MarginLeverage", "Timestamp",
+}
+
+// Margins 获取所有币种的保证金账户
+func (c *Client) Margins() ([]Margin, error) {
+	var margins []Margin
+	err := c.Get("/user/margin", url.Values{"currency": {"all"}}, true, &margins)
+	return margins, err
+}
+
+// TakeSnapshot 获取当前持仓（只保留未平仓的）和所有币种的保证金，记为同一快照时刻
+func TakeSnapshot(c *Client, at time.Time) ([]PositionSnapshot, []MarginSnapshot, error) {
+	positions, err := c.Positions()
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+	margins, err := c.Margins()
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取保证金失败: %w", err)
+	}
+
+	ps, ms := newSnapshot(at, positions, margins)
+	return ps, ms, nil
+}
+
+// newSnapshot 将持仓（只保留未平仓的）和保证金记为同一快照时刻
+func newSnapshot(at time.Time, positions []Position, margins []Margin) ([]PositionSnapshot, []MarginSnapshot) {
+	at = at.UTC().Truncate(time.Millisecond)
+	var ps []PositionSnapshot
+	for _, p := range positions {
+		if p.CurrentQty != 0 {
+			ps = append(ps, PositionSnapshot{SnapshotTime: at, Position: p})
+		}
+	}
+	ms := make([]MarginSnapshot, len(margins))
+	for i, m := range margins {
+		ms[i] = MarginSnapshot{SnapshotTime: at, Margin: m}
+	}
+	return ps, ms
+}
+
+// ErrSnapshotNotReady 实时 position 或 margin 表还没有收到初始数据
+var ErrSnapshotNotReady = errors.New("实时持仓或保证金数据尚未就绪")
+
+// StreamSnapshot 由实时 position 和 margin 表的当前内容生成快照，与 TakeSnapshot 一样只保留未平仓的持仓。
+// 两个表都收到 partial 之前返回 ErrSnapshotNotReady
+func StreamSnapshot(s *Stream, at time.Time) ([]PositionSnapshot, []MarginSnapshot, error) {
+	positionRows, marginRows := s.Table("position"), s.Table("margin")
+	if positionRows == nil || marginRows == nil {
+		return nil, nil, ErrSnapshotNotReady
+	}
+	positions, err := DecodeRows[Position](positionRows)
+	if err != nil {
+		return nil, nil, err
+	}
+	margins, err := DecodeRows[Margin](marginRows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ps, ms := newSnapshot(at, positions, margins)
+	return ps, ms, nil
+}
+
+// AppendSnapshot 追加一次快照到持仓和保证金文件
+func AppendSnapshot(positionsFile, marginFile string, positions []PositionSnapshot, margins []MarginSnapshot) error {
+	if err := appendCSV(positionsFile, PositionSnapshotHeader, positions, PositionSnapshot.record); err != nil {
+		return fmt.Errorf("保存 %s 失败: %w", positionsFile, err)
+	}
+	if err := appendCSV(marginFile, MarginSnapshotHeader, margins, MarginSnapshot.record); err != nil {
+		return fmt.Errorf("保存 %s 失败: %w", marginFile, err)
+	}
+	return nil
+}
+
+// ReadPositionSnapshots 读取所有持仓快照
+func ReadPositionSnapshots(filename string) ([]PositionSnapshot, error) {
+	return readCSV(filename, len(PositionSnapshotHeader), parsePositionSnapshot)
+}
+
+// ReadMarginSnapshots 读取所有保证金快照
+func ReadMarginSnapshots(filename string) ([]MarginSnapshot, error) {
+	return readCSV(filename, len(MarginSnapshotHeader), parseMarginSnapshot)
+}
+
+// LatestSnapshot 返回最近一次快照的时刻、持仓和保证金。
+// 每次快照都会记录保证金，而没有持仓时不会写入持仓记录，因此以保证金确定快照时刻。
+func LatestSnapshot(positions []PositionSnapshot, margins []MarginSnapshot) (time.Time, []PositionSnapshot, []MarginSnapshot) {
+	var latest time.Time
+	for _, m := range margins {
+		if m.SnapshotTime.After(latest) {
+			latest = m.SnapshotTime
+		}
+	}
+	if latest.IsZero() {
+		return latest, nil, nil
+	}
+
+	var ps []PositionSnapshot
+	for _, p := range positions {
+		if p.SnapshotTime.Equal(latest) {
+			ps = append(ps, p)
+		}
+	}
+	var ms []MarginSnapshot
+	for _, m := range margins {
+		if m.SnapshotTime.Equal(latest) {
+			ms = append(ms, m)
+		}
+	}
+	return latest, ps, ms
+}
+
+// PositionCheck 交易所持仓与成交记录累计持仓的对比
+type PositionCheck struct {
+	Symbol        string `json:"symbol"`
+	ExchangeQty   int    `json:"exchangeQty"`   // 交易所返回的持仓数量
+	ExecutionsQty int    `json:"executionsQty"` // 成交记录累计的持仓数量
+	Match         bool   `json:"match"`
+}
+
+// CheckPositions 用成交记录累计各交易对的净持仓，与快照中的持仓逐一对比。
+// 只统计 Trade 类型的成交（资金费用等记录不改变持仓），任一方不为零的交易对都会列出。
+func CheckPositions(positions []PositionSnapshot, executions []Execution) []PositionCheck {
+	net := make(map[string]int)
+	for _, e := range executions {
+		if e.ExecType != "Trade" {
+			continue
+		}
+		if e.Side == "Buy" {
+			net[e.Symbol] += e.LastQty
+		} else {
+			net[e.Symbol] -= e.LastQty
+		}
+	}
+
+	exchange := make(map[string]int)
+	for _, p := range positions {
+		exchange[p.Symbol] += p.CurrentQty
+	}
+
+	symbols := make(map[string]bool)
+	for symbol, qty := range net {
+		if qty != 0 {
+			symbols[symbol] = true
+		}
+	}
+	for symbol := range exchange {
+		symbols[symbol] = true
+	}
+
+	checks := make([]PositionCheck, 0, len(symbols))
+	for symbol := range symbols {
+		checks = append(checks, PositionCheck{
+			Symbol:        symbol,
+			ExchangeQty:   exchange[symbol],
+			ExecutionsQty: net[symbol],
+			Match:         exchange[symbol] == net[symbol],
+		})
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Symbol < checks[j].Symbol })
+	return checks
+}
+
+func (p PositionSnapshot) record() []string {
+	return []string{
+		p.SnapshotTime.Format(TimeLayout),
+		strconv.Itoa(p.Account),
+		p.Symbol,
+		p.Currency,
+		strconv.Itoa(p.CurrentQty),
+		priceString(p.AvgEntryPrice),
+		priceString(p.MarkPrice),
+		priceString(p.LiquidationPrice),
+		fmt.Sprintf("%.2f", p.Leverage),
+		strconv.FormatBool(p.CrossMargin),
+		fmt.Sprintf("%.8f", p.HomeNotional),
+		fmt.Sprintf("%.2f", p.ForeignNotional),
+		strconv.FormatInt(p.PosMargin, 10),
+		strconv.FormatInt(p.MaintMargin, 10),
+		strconv.FormatInt(p.UnrealisedPnl, 10),
+		strconv.FormatInt(p.RealisedPnl, 10),
+		p.Timestamp,
+	}
+}
+
+func parsePositionSnapshot(f *fieldReader) PositionSnapshot {
+	return PositionSnapshot{
+		SnapshotTime: f.time(0),
+		Position: Position{
+			Account:          f.int(1),
+			Symbol:           f.str(2),
+			Currency:         f.str(3),
+			CurrentQty:       f.int(4),
+			AvgEntryPrice:    f.float(5),
+			MarkPrice:        f.float(6),
+			LiquidationPrice: f.float(7),
+			Leverage:         f.float(8),
+			CrossMargin:      f.bool(9),
+			HomeNotional:     f.float(10),
+			ForeignNotional:  f.float(11),
+			PosMargin:        f.int64(12),
+			MaintMargin:      f.int64(13),
+			UnrealisedPnl:    f.int64(14),
+			RealisedPnl:      f.int64(15),
+			Timestamp:        f.str(16),
+			IsOpen:           f.int(4) != 0,
+		},
+	}
+}
+
+func (m MarginSnapshot) record() []string {
+	return []string{
+		m.SnapshotTime.Format(TimeLayout),
+		strconv.Itoa(m.Account),
+		m.Currency,
+		strconv.FormatInt(m.WalletBalance, 10),
+		strconv.FormatInt(m.MarginBalance, 10),
+		strconv.FormatInt(m.AvailableMargin, 10),
+		strconv.FormatInt(m.UnrealisedPnl, 10),
+		strconv.FormatInt(m.RealisedPnl, 10),
+		strconv.FormatInt(m.InitMargin, 10),
+		strconv.FormatInt(m.MaintMargin, 10),
+		fmt.Sprintf("%.4f", m.MarginLeverage),
+		m.Timestamp,
+	}
+}
+
+func parseMarginSnapshot(f *fieldReader) MarginSnapshot {
+	return MarginSnapshot{
+		SnapshotTime: f.time(0),
+		Margin: Margin{
+			Account:         f.int(1),
+			Currency:        f.str(2),
+			WalletBalance:   f.int64(3),
+			MarginBalance:   f.int64(4),
+			AvailableMargin: f.int64(5),
+			UnrealisedPnl:   f.int64(6),
+			RealisedPnl:     f.int64(7),
+			InitMargin:      f.int64(8),
+			MaintMargin:     f.int64(9),
+			MarginLeverage:  f.float(10),
+			Timestamp:       f.str(11),
+		},
+	}
+}
@@ -0,0 +1,65 @@
+package bitmex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckPositions(t *testing.T) {
+	snapshot := func(symbol string, qty int) PositionSnapshot {
+		return PositionSnapshot{Position: Position{Symbol: symbol, CurrentQty: qty}}
+	}
+	fill := func(symbol string, qty int) Execution {
+		return reconcileFill("o-"+symbol, symbol, qty, 40000, 0, reconcileTime(1, 0, 0, 0))
+	}
+
+	tests := []struct {
+		name       string
+		positions  []PositionSnapshot
+		executions []Execution
+		want       []PositionCheck
+	}{
+		{
+			name:      "一致",
+			positions: []PositionSnapshot{snapshot("XBTUSD", 300), snapshot("ETHUSD", -20)},
+			executions: []Execution{
+				fill("XBTUSD", 500), fill("XBTUSD", -200), fill("ETHUSD", -20),
+				// 资金费用不改变持仓
+				{Symbol: "XBTUSD", Side: "Buy", LastQty: 300, ExecType: "Funding"},
+			},
+			want: []PositionCheck{
+				{Symbol: "ETHUSD", ExchangeQty: -20, ExecutionsQty: -20, Match: true},
+				{Symbol: "XBTUSD", ExchangeQty: 300, ExecutionsQty: 300, Match: true},
+			},
+		},
+		{
+			name:       "数量不一致",
+			positions:  []PositionSnapshot{snapshot("XBTUSD", 300)},
+			executions: []Execution{fill("XBTUSD", 500)},
+			want:       []PositionCheck{{Symbol: "XBTUSD", ExchangeQty: 300, ExecutionsQty: 500}},
+		},
+		{
+			name:       "只有交易所持仓",
+			positions:  []PositionSnapshot{snapshot("XBTUSDT", 1000)},
+			executions: []Execution{fill("XBTUSD", 100), fill("XBTUSD", -100)},
+			want:       []PositionCheck{{Symbol: "XBTUSDT", ExchangeQty: 1000}},
+		},
+		{
+			// 成交记录累计为 0 的交易对不列出
+			name:       "只有成交记录持仓",
+			executions: []Execution{fill("XBTUSD", -100), fill("ETHUSD", 10), fill("ETHUSD", -10)},
+			want:       []PositionCheck{{Symbol: "XBTUSD", ExecutionsQty: -100}},
+		},
+		{
+			name: "没有持仓",
+			want: []PositionCheck{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckPositions(tt.positions, tt.executions); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckPositions = %+v\n应为 %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package bitmex
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReconcileFile 核对结果文件（reconcile 命令生成）
+const ReconcileFile = "reconcile.csv"
+
+// ReconcileHeader reconcile.csv 表头，金额为主单位（BTC / USDT）
+var ReconcileHeader = []string{"Check", "ID", "Time", "Symbol", "Currency", "Expected", "Actual", "Difference", "Detail"}
+
+// 核对项
+const (
+	CheckOrders  = "orders"  // 订单成交数量与成交记录
+	CheckPnL     = "pnl"     // 每日已实现盈亏与钱包 RealisedPNL
+	CheckBalance = "balance" // 钱包余额连续性
+)
+
+// settlementHour 钱包 RealisedPNL 每天的结算时刻（UTC）
+const settlementHour = 12
+
+// Discrepancy 一条不一致记录
+type Discrepancy struct {
+	Check    string // CheckOrders / CheckPnL / CheckBalance
+	ID       string // 订单 OrderID、钱包 TransactID 等，多个用空格分隔
+	Time     time.Time
+	Symbol   string
+	Currency string
+	Expected float64
+	Actual   float64
+	Detail   string
+}
+
+// Difference Actual - Expected
+func (d Discrepancy) Difference() float64 {
+	return d.Actual - d.Expected
+}
+
+// CheckOrderFills 核对订单与成交记录：每个有成交的订单（CumQty > 0 或状态为 Filled），
+// 其 Trade 成交记录的数量之和应等于 CumQty；orders.csv 时间范围内的成交记录也应能找到对应订单。
+func CheckOrderFills(orders []Order, executions []Execution) []Discrepancy {
+	filled := make(map[string]int)
+	fills := make(map[string]Execution)
+	for _, e := range executions {
+		if e.ExecType != "Trade" || e.OrderID == "" {
+			continue
+		}
+		filled[e.OrderID] += e.LastQty
+		if _, ok := fills[e.OrderID]; !ok {
+			fills[e.OrderID] = e
+		}
+	}
+
+	var result []Discrepancy
+	known := make(map[string]bool, len(orders))
+	var first time.Time
+	for _, o := range orders {
+		known[o.OrderID] = true
+		if t := o.Time(); !t.IsZero() && (first.IsZero() || t.Before(first)) {
+			first = t
+		}
+		if o.CumQty == 0 && o.OrdStatus != "Filled" {
+			continue
+		}
+		if got := filled[o.OrderID]; got != o.CumQty {
+			detail := fmt.Sprintf("%s 订单 CumQty %d，成交记录合计 %d", o.OrdStatus, o.CumQty, got)
+			if got == 0 {
+				detail = fmt.Sprintf("%s 订单 CumQty %d，没有成交记录", o.OrdStatus, o.CumQty)
+			}
+			result = append(result, Discrepancy{
+				Check:    CheckOrders,
+				ID:       o.OrderID,
+				Time:     o.Time(),
+				Symbol:   o.Symbol,
+				Expected: float64(o.CumQty),
+				Actual:   float64(got),
+				Detail:   detail,
+			})
+		}
+	}
+
+	// orders.csv 最早的订单之前的成交不要求有订单记录（下载范围不同）
+	for id, e := range fills {
+		if known[id] || first.IsZero() || e.Time().Before(first) {
+			continue
+		}
+		result = append(result, Discrepancy{
+			Check:  CheckOrders,
+			ID:     id,
+			Time:   e.Time(),
+			Symbol: e.Symbol,
+			Actual: float64(filled[id]),
+			Detail: fmt.Sprintf("成交记录合计 %d，orders.csv 中没有该订单", filled[id]),
+		})
+	}
+	sortDiscrepancies(result)
+	return result
+}
+
+// settlementDate 时刻 t 的已实现盈亏计入哪一天的钱包结算（当天 12:00 UTC 之前计入当天，12:00:00 整及之后计入次日）
+func settlementDate(t time.Time) string {
+	return t.UTC().Add((24 - settlementHour) * time.Hour).Format("2006-01-02")
+}
+
+// dailyKey 合约每个结算日的汇总键
+type dailyKey struct {
+	date   string
+	symbol string
+}
+
+// CheckDailyPnL 按合约和结算日对比成交记录计算的已实现盈亏（平均成本，扣除手续费）与钱包 RealisedPNL。
+// 钱包每天 12:00 UTC 结算前 24 小时的已实现盈亏；没有单独 Funding 记录的结算日，
+// 钱包 RealisedPNL 包含资金费用，按 Funding 成交记录的费率、标记价格和当时持仓计入。
+// 只核对钱包 RealisedPNL 的时间范围内的结算日，差额超过 tolerance（主单位）的列出。
+func CheckDailyPnL(wallet []WalletHistory, executions []Execution, catalog Catalog, tolerance float64) []Discrepancy {
+	type walletDay struct {
+		amount  int64
+		ids     []string
+		at      time.Time
+		funding bool // 有单独的 Funding 记录
+	}
+	days := make(map[dailyKey]*walletDay)
+	getDay := func(k dailyKey) *walletDay {
+		d, ok := days[k]
+		if !ok {
+			d = &walletDay{}
+			days[k] = d
+		}
+		return d
+	}
+	var firstDate, lastDate string
+	currencies := make(map[string]string)
+	for _, h := range wallet {
+		if h.TransactStatus == "Canceled" || h.Address == "" {
+			continue
+		}
+		switch h.TransactType {
+		case "RealisedPNL":
+			date := h.Time().UTC().Format("2006-01-02")
+			d := getDay(dailyKey{date, h.Address})
+			d.amount += h.Amount
+			d.ids = append(d.ids, h.TransactID)
+			d.at = h.Time()
+			currencies[h.Address] = h.Currency
+			if firstDate == "" || date < firstDate {
+				firstDate = date
+			}
+			if date > lastDate {
+				lastDate = date
+			}
+		case "Funding":
+			getDay(dailyKey{settlementDate(h.Time()), h.Address}).funding = true
+		}
+	}
+	if lastDate == "" {
+		return nil
+	}
+
+	sorted := make([]Execution, len(executions))
+	copy(sorted, executions)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time().Before(sorted[j].Time()) })
+
+	// 成交记录按结算日汇总的净已实现盈亏（最小单位）
+	computed := make(map[dailyKey]float64)
+	funding := make(map[dailyKey]float64)
+	book := NewBook(catalog, AverageCost)
+	for _, e := range sorted {
+		k := dailyKey{settlementDate(e.Time()), e.Symbol}
+		switch e.ExecType {
+		case "Trade":
+			realized, fee, ok := book.Apply(e)
+			if ok {
+				computed[k] += realized - fee
+				currencies[e.Symbol] = book.Accounts[e.Symbol].Instrument.SettlCurrency
+			}
+		case "Funding":
+			// 费率为正时多头支付、空头收到
+			if a, ok := book.Accounts[e.Symbol]; ok && a.Qty != 0 {
+				funding[k] -= e.Commission * a.Instrument.Value(float64(a.Qty), e.LastPx)
+			}
+		}
+	}
+
+	keys := make(map[dailyKey]bool)
+	for k := range days {
+		keys[k] = true
+	}
+	for k := range computed {
+		keys[k] = true
+	}
+
+	var result []Discrepancy
+	for k := range keys {
+		if k.date < firstDate || k.date > lastDate {
+			continue
+		}
+		d := days[k]
+		if d == nil {
+			d = &walletDay{}
+		}
+		if d.ids == nil && computed[k] == 0 {
+			continue
+		}
+		expected := computed[k]
+		if !d.funding {
+			expected += funding[k]
+		}
+
+		currency := currencies[k.symbol]
+		exp := FromMinorUnits(currency, int64(math.Round(expected)))
+		act := FromMinorUnits(currency, d.amount)
+		if math.Abs(act-exp) <= tolerance {
+			continue
+		}
+
+		at := d.at
+		if at.IsZero() {
+			at, _ = time.Parse("2006-01-02", k.date)
+			at = at.Add(settlementHour * time.Hour)
+		}
+		detail := "成交记录计算的已实现盈亏与钱包 RealisedPNL 不一致"
+		switch {
+		case d.ids == nil:
+			detail = "有成交记录的已实现盈亏，钱包中没有 RealisedPNL"
+		case computed[k] == 0:
+			detail = "钱包有 RealisedPNL，没有对应的成交记录"
+		}
+		result = append(result, Discrepancy{
+			Check:    CheckPnL,
+			ID:       strings.Join(d.ids, " "),
+			Time:     at,
+			Symbol:   k.symbol,
+			Currency: currency,
+			Expected: exp,
+			Actual:   act,
+			Detail:   detail,
+		})
+	}
+	sortDiscrepancies(result)
+	return result
+}
+
+// CheckWalletBalances 核对钱包余额的连续性：上一条余额 + 金额 = 本条余额。
+// 同一时刻的多条记录（每日结算）共用结算后的余额，合并核对。
+// BitMEX 的提现金额已包含手续费，同时接受 上一条余额 + 金额 - 手续费 = 本条余额。
+// 已取消和未完成（Pending）的记录不影响余额，跳过。
+func CheckWalletBalances(wallet []WalletHistory) []Discrepancy {
+	byCurrency := make(map[string][]WalletHistory)
+	for _, h := range wallet {
+		if h.TransactStatus == "Canceled" || h.TransactStatus == "Pending" {
+			continue
+		}
+		byCurrency[h.Currency] = append(byCurrency[h.Currency], h)
+	}
+
+	var result []Discrepancy
+	for currency, records := range byCurrency {
+		sort.SliceStable(records, func(i, j int) bool { return records[i].Time().Before(records[j].Time()) })
+
+		var balance int64
+		started := false
+		for i := 0; i < len(records); {
+			j := i
+			var amount, fee int64
+			var ids []string
+			for ; j < len(records) && records[j].Timestamp == records[i].Timestamp; j++ {
+				amount += records[j].Amount
+				fee += records[j].Fee
+				ids = append(ids, records[j].TransactID)
+			}
+			group := records[i:j]
+			actual := group[len(group)-1].WalletBalance
+			if started && balance+amount != actual && balance+amount-fee != actual {
+				types := make([]string, len(group))
+				for n, h := range group {
+					types[n] = h.TransactType
+				}
+				result = append(result, Discrepancy{
+					Check:    CheckBalance,
+					ID:       strings.Join(ids, " "),
+					Time:     group[0].Time(),
+					Currency: currency,
+					Expected: FromMinorUnits(currency, balance+amount),
+					Actual:   FromMinorUnits(currency, actual),
+					Detail: fmt.Sprintf("%s: 上一条余额 %.8f + 金额 %.8f（手续费 %.8f）≠ 余额",
+						strings.Join(types, "+"), FromMinorUnits(currency, balance),
+						FromMinorUnits(currency, amount), FromMinorUnits(currency, fee)),
+				})
+			}
+			balance, started = actual, true
+			i = j
+		}
+	}
+	sortDiscrepancies(result)
+	return result
+}
+
+// sortDiscrepancies 按时间排序，同一时间按合约和 ID
+func sortDiscrepancies(ds []Discrepancy) {
+	sort.SliceStable(ds, func(i, j int) bool {
+		if !ds[i].Time.Equal(ds[j].Time) {
+			return ds[i].Time.Before(ds[j].Time)
+		}
+		if ds[i].Symbol != ds[j].Symbol {
+			return ds[i].Symbol < ds[j].Symbol
+		}
+		return ds[i].ID < ds[j].ID
+	})
+}
+
+// WriteDiscrepancies 写入 reconcile.csv
+func WriteDiscrepancies(filename string, ds []Discrepancy) error {
+	return writeCSV(filename, ReconcileHeader, ds, Discrepancy.record)
+}
+
+func (d Discrepancy) record() []string {
+	at := ""
+	if !d.Time.IsZero() {
+		at = d.Time.Format(TimeLayout)
+	}
+	format := "%.8f"
+	if d.Check == CheckOrders {
+		format = "%.0f"
+	}
+	return []string{
+		d.Check,
+		d.ID,
+		at,
+		d.Symbol,
+		d.Currency,
+		fmt.Sprintf(format, d.Expected),
+		fmt.Sprintf(format, d.Actual),
+		fmt.Sprintf(format, d.Difference()),
+		d.Detail,
+	}
+}
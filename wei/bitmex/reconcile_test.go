@@ -0,0 +1,205 @@
+package bitmex
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// reconcileTime 2024-03-<day> hh:mm:ss UTC
+func reconcileTime(day, hour, minute, second int) string {
+	return time.Date(2024, 3, day, hour, minute, second, 0, time.UTC).Format(TimeLayout)
+}
+
+func reconcileFill(orderID, symbol string, qty int, price, commission float64, at string) Execution {
+	side := "Buy"
+	if qty < 0 {
+		side, qty = "Sell", -qty
+	}
+	return Execution{
+		ExecID: orderID + "-" + at, OrderID: orderID, Symbol: symbol, Side: side, LastQty: qty, LastPx: price,
+		Commission: commission, TransactTime: at, Timestamp: at, ExecType: "Trade",
+	}
+}
+
+// reconcileFunding 资金费用记录：Commission 为费率，LastPx 为标记价格
+func reconcileFunding(symbol string, rate, mark float64, at string) Execution {
+	return Execution{Symbol: symbol, LastPx: mark, Commission: rate, TransactTime: at, Timestamp: at, ExecType: "Funding"}
+}
+
+func TestSettlementDate(t *testing.T) {
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		{time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), "2024-03-02"},
+		{time.Date(2024, 3, 2, 11, 59, 59, 999999999, time.UTC), "2024-03-02"},
+		// 12:00:00 整是下一个结算周期的开始
+		{time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC), "2024-03-03"},
+		{time.Date(2024, 3, 2, 23, 59, 59, 0, time.UTC), "2024-03-03"},
+		// 北京时间 3 月 2 日 19:30 为 UTC 11:30
+		{time.Date(2024, 3, 2, 19, 30, 0, 0, time.FixedZone("CST", 8*3600)), "2024-03-02"},
+	}
+	for _, tt := range tests {
+		if got := settlementDate(tt.at); got != tt.want {
+			t.Errorf("settlementDate(%v) = %s，应为 %s", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestCheckDailyPnL(t *testing.T) {
+	catalog := NewCatalog([]Instrument{testInverse, testLinear})
+	pnl := func(id, symbol, currency string, amount int64, day int) WalletHistory {
+		return WalletHistory{TransactID: id, TransactType: "RealisedPNL", TransactStatus: "Completed", Currency: currency,
+			Amount: amount, Address: symbol, Timestamp: reconcileTime(day, 12, 0, 0)}
+	}
+
+	executions := []Execution{
+		// 3/2 一致：1e8 × (100/40000 − 100/50000) = 50000 聪
+		reconcileFill("o1", "XBTUSD", 100, 40000, 0, reconcileTime(1, 13, 0, 0)),
+		reconcileFill("o2", "XBTUSD", -100, 50000, 0, reconcileTime(2, 10, 0, 0)),
+		// 12:00:00 整的开仓计入 3/3；3/3 亏损 50000 聪
+		reconcileFill("o3", "XBTUSD", 100, 50000, 0, reconcileTime(2, 12, 0, 0)),
+		reconcileFill("o4", "XBTUSD", -100, 40000, 0, reconcileTime(3, 11, 59, 58)),
+		// 11:59:59 的开仓仍在 3/3，12:00:00 整的平仓计入 3/4
+		reconcileFill("o5", "XBTUSD", 100, 40000, 0, reconcileTime(3, 11, 59, 59)),
+		reconcileFill("o6", "XBTUSD", -100, 50000, 0, reconcileTime(3, 12, 0, 0)),
+		// 3/5 不一致：两笔各 0.00075 × 1e8 × 100/40000 = 187.5 聪手续费，钱包只有 300
+		reconcileFill("o7", "XBTUSD", 100, 40000, 0.00075, reconcileTime(4, 20, 0, 0)),
+		reconcileFill("o8", "XBTUSD", -100, 40000, 0.00075, reconcileTime(5, 8, 0, 0)),
+		// 3/6 资金费用：反向合约空头 100 张收到 0.0001 × 1e8 × 100/40000 = 25 聪；
+		// 正向合约多头 1000 张支付 0.0001 × 1000 × 50000 = 5000（USDt 最小单位）
+		reconcileFill("o9", "XBTUSD", -100, 40000, 0, reconcileTime(5, 13, 0, 0)),
+		reconcileFill("o10", "XBTUSDT", 1000, 50000, 0, reconcileTime(5, 13, 0, 0)),
+		reconcileFunding("XBTUSD", 0.0001, 40000, reconcileTime(5, 20, 0, 0)),
+		reconcileFunding("XBTUSDT", 0.0001, 50000, reconcileTime(5, 20, 0, 0)),
+		// 3/7 钱包有单独的 Funding 记录，RealisedPNL 不包含资金费用
+		reconcileFunding("XBTUSD", 0.0001, 40000, reconcileTime(6, 20, 0, 0)),
+	}
+	wallet := []WalletHistory{
+		pnl("r2", "XBTUSD", "XBt", 50000, 2),
+		pnl("r3", "XBTUSD", "XBt", -50000, 3),
+		pnl("r4", "XBTUSD", "XBt", 50000, 4),
+		pnl("r5", "XBTUSD", "XBt", -300, 5),
+		pnl("r6", "XBTUSD", "XBt", 25, 6),
+		pnl("r6u", "XBTUSDT", "USDt", -5000, 6),
+		{TransactID: "f7", TransactType: "Funding", TransactStatus: "Completed", Currency: "XBt", Amount: 25,
+			Address: "XBTUSD", Timestamp: reconcileTime(6, 20, 0, 0)},
+		pnl("r7", "XBTUSD", "XBt", 0, 7),
+		{TransactID: "c7", TransactType: "RealisedPNL", TransactStatus: "Canceled", Currency: "XBt", Amount: 999999,
+			Address: "XBTUSD", Timestamp: reconcileTime(7, 12, 0, 0)},
+	}
+
+	got := CheckDailyPnL(wallet, executions, catalog, 1e-8)
+	if len(got) != 1 {
+		t.Fatalf("得到 %d 条不一致，应只有 3/5 一条: %+v", len(got), got)
+	}
+	d := got[0]
+	if d.Check != CheckPnL || d.ID != "r5" || d.Symbol != "XBTUSD" || d.Currency != "XBt" ||
+		!approx(d.Expected, -0.00000375) || !approx(d.Actual, -0.000003) {
+		t.Errorf("不一致记录 = %+v，应为 r5 Expected −0.00000375 Actual −0.000003", d)
+	}
+	if !d.Time.Equal(time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)) || !strings.Contains(d.Detail, "不一致") {
+		t.Errorf("不一致记录的时间 / 说明 = %v / %s", d.Time, d.Detail)
+	}
+
+	// 钱包少了一天：成交记录有盈亏但钱包没有
+	var withoutDay4 []WalletHistory
+	for _, h := range wallet {
+		if h.TransactID != "r4" {
+			withoutDay4 = append(withoutDay4, h)
+		}
+	}
+	got = CheckDailyPnL(withoutDay4, executions, catalog, 1e-8)
+	if len(got) != 2 || got[0].ID != "" || !approx(got[0].Expected, 0.0005) || !strings.Contains(got[0].Detail, "没有 RealisedPNL") {
+		t.Errorf("缺少 3/4 的钱包记录: %+v", got)
+	}
+	if len(got) > 0 && !got[0].Time.Equal(time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("缺少钱包记录时的时间 = %v，应为 3/4 12:00", got[0].Time)
+	}
+
+	if got := CheckDailyPnL(nil, executions, catalog, 1e-8); got != nil {
+		t.Errorf("没有钱包记录时 = %+v，应为 nil", got)
+	}
+}
+
+func TestCheckOrderFills(t *testing.T) {
+	order := func(id, status string, cumQty, minute int) Order {
+		ts := reconcileTime(1, 0, minute, 0)
+		return Order{OrderID: id, Symbol: "XBTUSD", OrdStatus: status, OrderQty: 200, CumQty: cumQty, TransactTime: ts, Timestamp: ts}
+	}
+	orders := []Order{
+		order("o1", "Filled", 200, 10),
+		order("o2", "PartiallyFilled", 100, 11),
+		order("o3", "Filled", 50, 12),
+		order("o4", "New", 0, 13),
+		order("o5", "Canceled", 0, 14),
+	}
+	executions := []Execution{
+		reconcileFill("o1", "XBTUSD", 80, 40000, 0, reconcileTime(1, 0, 10, 1)),
+		reconcileFill("o1", "XBTUSD", 120, 40000, 0, reconcileTime(1, 0, 10, 2)),
+		reconcileFill("o2", "XBTUSD", 60, 40000, 0, reconcileTime(1, 0, 11, 1)),
+		{OrderID: "o3", Symbol: "XBTUSD", LastQty: 50, ExecType: "Funding", TransactTime: reconcileTime(1, 0, 12, 1)},
+		reconcileFill("o-missing", "XBTUSD", 30, 40000, 0, reconcileTime(1, 0, 20, 0)),
+		// orders.csv 最早的订单之前的成交不要求有订单记录
+		reconcileFill("o-old", "XBTUSD", 30, 40000, 0, reconcileTime(1, 0, 1, 0)),
+	}
+
+	got := CheckOrderFills(orders, executions)
+	want := []struct {
+		id               string
+		expected, actual float64
+		detail           string
+	}{
+		{"o2", 100, 60, "成交记录合计 60"},
+		{"o3", 50, 0, "没有成交记录"},
+		{"o-missing", 0, 30, "orders.csv 中没有该订单"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("得到 %d 条不一致，应为 %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		d := got[i]
+		if d.Check != CheckOrders || d.ID != w.id || d.Expected != w.expected || d.Actual != w.actual || !strings.Contains(d.Detail, w.detail) {
+			t.Errorf("第 %d 条 = %+v，应为 %s Expected %v Actual %v（%s）", i, d, w.id, w.expected, w.actual, w.detail)
+		}
+	}
+}
+
+func TestCheckWalletBalances(t *testing.T) {
+	record := func(id, typ, status, currency string, amount, fee, balance int64, day, hour int) WalletHistory {
+		return WalletHistory{TransactID: id, TransactType: typ, TransactStatus: status, Currency: currency,
+			Amount: amount, Fee: fee, WalletBalance: balance, Timestamp: reconcileTime(day, hour, 0, 0)}
+	}
+	wallet := []WalletHistory{
+		record("d1", "Deposit", "Completed", "XBt", 100000000, 0, 100000000, 1, 8),
+		// 同一时刻的结算记录共用结算后的余额
+		record("p2", "RealisedPNL", "Completed", "XBt", 50000, 0, 100049975, 2, 12),
+		record("f2", "Funding", "Completed", "XBt", -25, 0, 100049975, 2, 12),
+		// 提现金额已包含手续费
+		record("w3", "Withdrawal", "Completed", "XBt", -1020000, 20000, 99029975, 3, 9),
+		// 提现金额不含手续费
+		record("w4", "Withdrawal", "Completed", "XBt", -1000000, 20000, 98009975, 4, 9),
+		// 已取消和未完成的记录不影响余额
+		record("c5", "Withdrawal", "Canceled", "XBt", -5000000, 0, 1, 5, 9),
+		record("q5", "Withdrawal", "Pending", "XBt", -5000000, 0, 2, 5, 10),
+		// 余额多了 1000 聪
+		record("t6", "Transfer", "Completed", "XBt", 5000, 0, 98015975, 6, 9),
+		record("p7", "RealisedPNL", "Completed", "XBt", 100, 0, 98016075, 7, 12),
+		// 其他币种单独核对
+		record("u1", "Deposit", "Completed", "USDt", 1000000000, 0, 1000000000, 1, 8),
+		record("u2", "RealisedPNL", "Completed", "USDt", -5000, 0, 999995000, 2, 12),
+	}
+
+	got := CheckWalletBalances(wallet)
+	if len(got) != 1 {
+		t.Fatalf("得到 %d 条不一致，应只有 t6 一条: %+v", len(got), got)
+	}
+	d := got[0]
+	if d.Check != CheckBalance || d.ID != "t6" || d.Currency != "XBt" || !approx(d.Expected, 0.98014975) || !approx(d.Actual, 0.98015975) {
+		t.Errorf("不一致记录 = %+v，应为 t6 Expected 0.98014975 Actual 0.98015975", d)
+	}
+	if !strings.Contains(d.Detail, "Transfer") {
+		t.Errorf("说明 %q 应包含记录类型", d.Detail)
+	}
+}
@@ -0,0 +1,117 @@
+package bitmex
+
+import (
+	"sort"
+	"sync"
+)
+
+// Recorder 缓存实时推送的记录，Flush 时按主键合并写入 CSV。
+// 使用与 sync 命令相同的 Dataset，两者写入的文件格式和去重规则一致，可以交替使用。
+type Recorder struct {
+	mu      sync.Mutex
+	buffers map[string]recordBuffer
+}
+
+// recordBuffer 某个 Dataset 的待写入记录
+type recordBuffer interface {
+	add(rows []Row) error
+	flush() (SyncResult, error)
+	file() string
+}
+
+type buffer[T any] struct {
+	ds   Dataset[T]
+	rows []T
+}
+
+func (b *buffer[T]) add(rows []Row) error {
+	decoded, err := DecodeRows[T](rows)
+	if err != nil {
+		return err
+	}
+	b.rows = append(b.rows, decoded...)
+	return nil
+}
+
+func (b *buffer[T]) flush() (SyncResult, error) {
+	if len(b.rows) == 0 {
+		return SyncResult{}, nil
+	}
+	result, err := b.ds.Apply(b.rows)
+	if err != nil {
+		return result, err
+	}
+	b.rows = nil
+	return result, nil
+}
+
+func (b *buffer[T]) file() string {
+	return b.ds.File
+}
+
+// NewRecorder 创建空的 Recorder，用 RecordTable 指定需要保存的表
+func NewRecorder() *Recorder {
+	return &Recorder{buffers: make(map[string]recordBuffer)}
+}
+
+// RecordTable 将实时表 table 的记录保存到 ds。
+// table 可以带交易对，如 "tradeBin1m:XBTUSD" 只保存该交易对的K线。
+func RecordTable[T any](r *Recorder, table string, ds Dataset[T]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buffers[table] = &buffer[T]{ds: ds}
+}
+
+// Record 缓存一条表消息中的记录。delete 消息（如订单结束后移出实时表）不影响已保存的记录
+func (r *Recorder) Record(u TableUpdate) error {
+	if u.Action == "delete" {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 按 table:symbol 优先，其次 table 分组
+	groups := make(map[recordBuffer][]Row)
+	for _, row := range u.Rows {
+		b, ok := r.buffers[u.Table+":"+row.String("symbol")]
+		if !ok {
+			b, ok = r.buffers[u.Table]
+		}
+		if ok {
+			groups[b] = append(groups[b], row)
+		}
+	}
+	for b, rows := range groups {
+		if err := b.add(rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush 将缓存的记录写入各自的 CSV 文件，返回 文件名 → 合并结果（只包含有新数据的文件）。
+// 写入失败的记录保留在缓存中，下次 Flush 时重试。
+func (r *Recorder) Flush() (map[string]SyncResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tables := make([]string, 0, len(r.buffers))
+	for table := range r.buffers {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	results := make(map[string]SyncResult)
+	for _, table := range tables {
+		b := r.buffers[table]
+		result, err := b.flush()
+		if err != nil {
+			return results, err
+		}
+		if result.Fetched > 0 {
+			results[b.file()] = result
+		}
+	}
+	return results, nil
+}
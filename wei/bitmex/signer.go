@@ -0,0 +1,30 @@
+package bitmex
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Sign 生成 BitMEX API 签名
+// signature = hex(HMAC_SHA256(apiSecret, verb + path + expires + body))
+// path 为包含 /api/v1 前缀和查询字符串的完整路径，GET 请求 body 为空
+func Sign(apiSecret, verb, path string, expires int64, body string) string {
+	mac := hmac.New(sha256.New, []byte(apiSecret))
+	mac.Write([]byte(verb + path + strconv.FormatInt(expires, 10) + body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest 为请求设置 api-key / api-expires / api-signature 认证头
+// 签名有效期为当前时间 + 60 秒
+func SignRequest(req *http.Request, creds Credentials, body string) {
+	expires := time.Now().Unix() + 60
+	path := req.URL.RequestURI()
+
+	req.Header.Set("api-key", creds.APIKey)
+	req.Header.Set("api-expires", strconv.FormatInt(expires, 10))
+	req.Header.Set("api-signature", Sign(creds.APISecret, req.Method, path, expires, body))
+}
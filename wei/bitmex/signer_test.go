@@ -0,0 +1,75 @@
+package bitmex
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// BitMEX API 文档中的签名示例
+const (
+	docAPIKey    = "LAqUlngMIQkIUjXMUreyu3qn"
+	docAPISecret = "chNOOS4KvNXR_Xq4k4c9qsfoKWvnDecLATCRlcBwyKDYnWgO"
+)
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name    string
+		verb    string
+		path    string
+		expires int64
+		body    string
+		want    string
+	}{
+		{"GET", "GET", "/api/v1/instrument", 1518064236, "",
+			"c7682d435d0cfe87c16098df34ef2eb5a549d4c5a3c2b1f0f77b8af73423bf00"},
+		{"GET带查询参数", "GET", "/api/v1/instrument?filter=%7B%22symbol%22%3A+%22XBTM15%22%7D", 1518064237, "",
+			"e2f422547eecb5b3cb29ade2127e21b858b235b386bfa45e1c1756eb3383919f"},
+		{"POST", "POST", "/api/v1/order", 1518064238,
+			`{"symbol":"XBTM15","price":219.0,"clOrdID":"mm_bitmex_1a/oemUeQ4CAJZgP3fjHsA","orderQty":98}`,
+			"1749cd2ccae4aa49048ae09f0b95110cee706e0944e6a14ad0b3a8cb45bd336b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(docAPISecret, tt.verb, tt.path, tt.expires, tt.body); got != tt.want {
+				t.Errorf("Sign = %s，应为 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://www.bitmex.com/api/v1/execution/tradeHistory?count=500&reverse=true", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := Credentials{APIKey: docAPIKey, APISecret: docAPISecret}
+
+	before := time.Now().Unix()
+	SignRequest(req, creds, "")
+
+	if got := req.Header.Get("api-key"); got != docAPIKey {
+		t.Errorf("api-key = %q", got)
+	}
+	expires, err := strconv.ParseInt(req.Header.Get("api-expires"), 10, 64)
+	if err != nil {
+		t.Fatalf("api-expires = %q: %v", req.Header.Get("api-expires"), err)
+	}
+	if expires < before+60 || expires > time.Now().Unix()+60 {
+		t.Errorf("api-expires = %d，应为当前时间 + 60 秒", expires)
+	}
+	// 签名使用包含查询字符串的路径
+	want := Sign(docAPISecret, "GET", "/api/v1/execution/tradeHistory?count=500&reverse=true", expires, "")
+	if got := req.Header.Get("api-signature"); got != want {
+		t.Errorf("api-signature = %s，应为 %s", got, want)
+	}
+
+	post, _ := http.NewRequest("POST", "https://www.bitmex.com/api/v1/order", strings.NewReader("{}"))
+	SignRequest(post, creds, `{"symbol":"XBTUSD"}`)
+	expires, _ = strconv.ParseInt(post.Header.Get("api-expires"), 10, 64)
+	if got, want := post.Header.Get("api-signature"), Sign(docAPISecret, "POST", "/api/v1/order", expires, `{"symbol":"XBTUSD"}`); got != want {
+		t.Errorf("POST api-signature = %s，应为 %s", got, want)
+	}
+}
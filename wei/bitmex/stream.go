@@ -0,0 +1,372 @@
+package bitmex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultStreamURL BitMEX 实时接口地址
+const DefaultStreamURL = "wss://ws.bitmex.com/realtime"
+
+// Row 实时表中的一行，保留原始 JSON 字段，update 消息只覆盖其中变化的字段
+type Row map[string]json.RawMessage
+
+// String 返回字符串字段的值，不存在或不是字符串时返回空
+func (r Row) String(field string) string {
+	var s string
+	json.Unmarshal(r[field], &s)
+	return s
+}
+
+// DecodeRows 将实时表的行解析为 T（如 Execution、Order、Position）
+func DecodeRows[T any](rows []Row) ([]T, error) {
+	out := make([]T, 0, len(rows))
+	for _, row := range rows {
+		data, err := json.Marshal(row)
+		if err != nil {
+			return nil, err
+		}
+		var v T
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("解析实时数据失败: %w", err)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// TableUpdate 一条表消息处理后的结果。
+// partial/insert/update 的 Rows 为合并后的完整行，delete 的 Rows 为被删除的行。
+type TableUpdate struct {
+	Table  string // 表名，如 execution、order、tradeBin1m
+	Action string // partial / insert / update / delete
+	Rows   []Row
+}
+
+// table 一个订阅主题的本地镜像：partial 初始化，之后按 keys 增量维护
+type table struct {
+	keys []string
+	rows []Row
+}
+
+func (t *table) key(row Row) string {
+	parts := make([]string, len(t.keys))
+	for i, k := range t.keys {
+		parts[i] = string(row[k])
+	}
+	return strings.Join(parts, "|")
+}
+
+func (t *table) find(row Row) int {
+	key := t.key(row)
+	return slices.IndexFunc(t.rows, func(r Row) bool { return t.key(r) == key })
+}
+
+// maxTableRows 没有主键的表（如 execution、tradeBin1m）最多保留的行数
+const maxTableRows = 1000
+
+// apply 处理一条表消息，返回受影响的完整行
+func (t *table) apply(action string, data []Row) []Row {
+	var affected []Row
+	switch action {
+	case "partial":
+		t.rows = slices.Clone(data)
+		affected = data
+	case "insert":
+		t.rows = append(t.rows, data...)
+		if len(t.rows) > maxTableRows {
+			t.rows = slices.Clone(t.rows[len(t.rows)-maxTableRows:])
+		}
+		affected = data
+	case "update":
+		for _, change := range data {
+			i := t.find(change)
+			if i < 0 {
+				continue
+			}
+			row := make(Row, len(t.rows[i])+len(change))
+			for k, v := range t.rows[i] {
+				row[k] = v
+			}
+			for k, v := range change {
+				row[k] = v
+			}
+			t.rows[i] = row
+			affected = append(affected, row)
+		}
+	case "delete":
+		for _, del := range data {
+			if i := t.find(del); i >= 0 {
+				affected = append(affected, t.rows[i])
+				t.rows = slices.Delete(t.rows, i, i+1)
+			}
+		}
+	}
+	return affected
+}
+
+// Stream BitMEX 实时数据客户端。
+// 连接后认证并订阅 Topics，为每个表维护本地镜像，断线后自动重连并重新接收 partial。
+type Stream struct {
+	URL         string      // 实时接口地址，测试时可指向本地服务
+	Credentials Credentials // 私有主题（execution、order 等）需要的凭证
+	Topics      []string    // 订阅主题，如 execution、order、tradeBin1m:XBTUSD
+
+	PingInterval   time.Duration // 多久没有收到消息就发送 ping
+	PongTimeout    time.Duration // 发送 ping 后等待回复的时间，期间没有收到任何消息则重连
+	ReconnectDelay time.Duration // 首次重连等待时间，之后每次加倍
+	MaxReconnect   time.Duration // 重连等待时间上限
+
+	// OnUpdate 每条表消息处理后调用（在读取消息的 goroutine 中）
+	OnUpdate func(TableUpdate)
+
+	// Logf 连接状态输出，为 nil 时不输出
+	Logf func(format string, args ...any)
+
+	mu     sync.RWMutex
+	tables map[string]*table
+}
+
+// NewStream 创建使用默认地址和心跳参数的实时客户端
+func NewStream(creds Credentials, topics ...string) *Stream {
+	return &Stream{
+		URL:            DefaultStreamURL,
+		Credentials:    creds,
+		Topics:         topics,
+		PingInterval:   5 * time.Second,
+		PongTimeout:    5 * time.Second,
+		ReconnectDelay: time.Second,
+		MaxReconnect:   time.Minute,
+	}
+}
+
+// StreamError 服务器返回的错误消息（如认证失败、订阅了不存在的主题）
+type StreamError struct {
+	Status  int
+	Message string
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("BitMEX 实时接口错误 (status %d): %s", e.Status, e.Message)
+}
+
+// streamMessage 服务器消息，按出现的字段区分类型
+type streamMessage struct {
+	Table  string   `json:"table"`
+	Action string   `json:"action"`
+	Keys   []string `json:"keys"`
+	Data   []Row    `json:"data"`
+
+	Info      string          `json:"info"`
+	Success   bool            `json:"success"`
+	Subscribe string          `json:"subscribe"`
+	Request   json.RawMessage `json:"request"`
+	Error     string          `json:"error"`
+	Status    int             `json:"status"`
+}
+
+// Run 连接并处理消息直到 ctx 结束。网络错误和心跳超时会按退避时间重连；
+// 认证失败等服务器错误（status 4xx，限流 429 除外）直接返回。
+func (s *Stream) Run(ctx context.Context) error {
+	delay := s.ReconnectDelay
+	for {
+		start := time.Now()
+		err := s.runOnce(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var streamErr *StreamError
+		if errors.As(err, &streamErr) && streamErr.Status >= 400 && streamErr.Status < 500 && streamErr.Status != 429 {
+			return err
+		}
+
+		// 连接保持了一段时间才断开，说明不是持续失败，重置退避时间
+		if time.Since(start) > s.MaxReconnect {
+			delay = s.ReconnectDelay
+		}
+		s.logf("⚠ 实时连接断开: %v，%s 后重连\n", err, delay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, s.MaxReconnect)
+	}
+}
+
+// runOnce 建立一次连接，返回断开原因
+func (s *Stream) runOnce(ctx context.Context) error {
+	ws, err := dialWebSocket(ctx, s.URL)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.tables = make(map[string]*table)
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		ws.close()
+	}()
+
+	if s.Credentials.APIKey != "" {
+		if err := s.authenticate(ws); err != nil {
+			return err
+		}
+	}
+	if len(s.Topics) > 0 {
+		if err := s.send(ws, "subscribe", s.Topics); err != nil {
+			return err
+		}
+	}
+	s.logf("✓ 已连接 %s\n", s.URL)
+
+	// 心跳在单独的 goroutine 中按最后收到消息的时间判断，读取本身不设超时：
+	// 读取超时可能发生在一个帧的中途，之后无法再从正确的位置继续读取
+	var lastRead atomic.Int64
+	var timedOut atomic.Bool
+	lastRead.Store(time.Now().UnixNano())
+	go s.heartbeat(ws, &lastRead, &timedOut, done)
+
+	for {
+		data, err := ws.readMessage()
+		if err != nil {
+			if timedOut.Load() {
+				return errors.New("心跳超时: 没有收到 pong")
+			}
+			return err
+		}
+		lastRead.Store(time.Now().UnixNano())
+
+		if string(data) == "pong" {
+			continue
+		}
+		if err := s.handle(data); err != nil {
+			return err
+		}
+	}
+}
+
+// heartbeat PingInterval 内没有收到消息时发送 ping，发送后 PongTimeout 内仍没有收到任何消息则关闭连接，
+// 使 runOnce 中阻塞的读取返回
+func (s *Stream) heartbeat(ws *wsConn, lastRead *atomic.Int64, timedOut *atomic.Bool, done <-chan struct{}) {
+	ticker := time.NewTicker(max(min(s.PingInterval, s.PongTimeout)/4, 10*time.Millisecond))
+	defer ticker.Stop()
+
+	var pingAt time.Time // 最近一次发送 ping 的时间，之后收到消息则清零
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			last := time.Unix(0, lastRead.Load())
+			if !pingAt.IsZero() && last.After(pingAt) {
+				pingAt = time.Time{}
+			}
+			switch {
+			case !pingAt.IsZero():
+				if now.Sub(pingAt) >= s.PongTimeout {
+					timedOut.Store(true)
+					ws.conn.Close()
+					return
+				}
+			case now.Sub(last) >= s.PingInterval:
+				if err := ws.writeText([]byte("ping")); err != nil {
+					ws.conn.Close()
+					return
+				}
+				pingAt = now
+			}
+		}
+	}
+}
+
+// authenticate 发送 authKeyExpires 认证请求，签名内容为 GET + 路径 + expires
+func (s *Stream) authenticate(ws *wsConn) error {
+	path := "/realtime"
+	if u, err := url.Parse(s.URL); err == nil && u.Path != "" {
+		path = u.Path
+	}
+	expires := time.Now().Unix() + 60
+	signature := Sign(s.Credentials.APISecret, "GET", path, expires, "")
+	return s.send(ws, "authKeyExpires", []any{s.Credentials.APIKey, expires, signature})
+}
+
+func (s *Stream) send(ws *wsConn, op string, args any) error {
+	data, err := json.Marshal(map[string]any{"op": op, "args": args})
+	if err != nil {
+		return err
+	}
+	return ws.writeText(data)
+}
+
+// handle 处理一条服务器消息
+func (s *Stream) handle(data []byte) error {
+	var msg streamMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("解析消息失败: %w", err)
+	}
+
+	switch {
+	case msg.Error != "":
+		return &StreamError{Status: msg.Status, Message: msg.Error}
+	case msg.Info != "":
+		s.logf("%s\n", msg.Info)
+		return nil
+	case msg.Subscribe != "":
+		s.logf("✓ 已订阅 %s\n", msg.Subscribe)
+		return nil
+	case msg.Success:
+		s.logf("✓ 认证成功\n")
+		return nil
+	case msg.Table == "":
+		return nil
+	}
+
+	s.mu.Lock()
+	t, ok := s.tables[msg.Table]
+	if msg.Action == "partial" {
+		t = &table{keys: msg.Keys}
+		s.tables[msg.Table] = t
+	} else if !ok {
+		// partial 之前收到的增量消息无法正确合并，按 BitMEX 文档忽略
+		s.mu.Unlock()
+		return nil
+	}
+	rows := t.apply(msg.Action, msg.Data)
+	s.mu.Unlock()
+
+	if s.OnUpdate != nil && len(rows) > 0 {
+		s.OnUpdate(TableUpdate{Table: msg.Table, Action: msg.Action, Rows: rows})
+	}
+	return nil
+}
+
+// Table 返回表当前的所有行，表尚未收到 partial 时返回 nil
+func (s *Stream) Table(name string) []Row {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if t, ok := s.tables[name]; ok {
+		return append([]Row{}, t.rows...)
+	}
+	return nil
+}
+
+func (s *Stream) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
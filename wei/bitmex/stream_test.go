@@ -0,0 +1,439 @@
+package bitmex
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mockConn 模拟服务器的一个 WebSocket 连接
+type mockConn struct {
+	conn net.Conn
+	recv chan string // 客户端发送的文本消息，连接断开后关闭
+}
+
+// newMockWS 启动本地 WebSocket 服务器，每个连接交给 session 处理（n 为连接序号，从 1 开始），返回 ws:// 地址。
+// session 返回时关闭连接
+func newMockWS(t *testing.T, session func(n int, c *mockConn)) string {
+	t.Helper()
+	var conns atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" {
+			http.Error(w, "需要 WebSocket", http.StatusBadRequest)
+			return
+		}
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + wsAcceptGUID))
+		fmt.Fprintf(brw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+			base64.StdEncoding.EncodeToString(sum[:]))
+		brw.Flush()
+
+		c := &mockConn{conn: conn, recv: make(chan string, 64)}
+		go c.readLoop(brw.Reader)
+		session(int(conns.Add(1)), c)
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/realtime"
+}
+
+func (c *mockConn) readLoop(br *bufio.Reader) {
+	defer close(c.recv)
+	ws := &wsConn{conn: c.conn, br: br}
+	for {
+		_, opcode, payload, err := ws.readFrame()
+		if err != nil || opcode == opClose {
+			return
+		}
+		if opcode == opText {
+			c.recv <- string(payload)
+		}
+	}
+}
+
+// frame 服务器发送的帧（不带掩码）
+func frame(opcode byte, fin bool, payload []byte) []byte {
+	b := opcode
+	if fin {
+		b |= 0x80
+	}
+	out := []byte{b}
+	switch n := len(payload); {
+	case n < 126:
+		out = append(out, byte(n))
+	case n <= 0xFFFF:
+		out = append(out, 126)
+		out = binary.BigEndian.AppendUint16(out, uint16(n))
+	default:
+		out = append(out, 127)
+		out = binary.BigEndian.AppendUint64(out, uint64(n))
+	}
+	return append(out, payload...)
+}
+
+func (c *mockConn) send(msg string) {
+	c.conn.Write(frame(opText, true, []byte(msg)))
+}
+
+// expect 等待客户端的下一条消息
+func (c *mockConn) expect(t *testing.T) string {
+	t.Helper()
+	select {
+	case msg, ok := <-c.recv:
+		if !ok {
+			t.Error("客户端已断开")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Error("等待客户端消息超时")
+		return ""
+	}
+}
+
+// serve 回复客户端的 ping，直到客户端断开
+func (c *mockConn) serve(pong bool) {
+	for msg := range c.recv {
+		if msg == "ping" && pong {
+			c.send("pong")
+		}
+	}
+}
+
+// fixture 读取 testdata 中录制的服务器消息，每行一条
+func fixture(t *testing.T, name string) []string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+// testStream 指向 url 的实时客户端，更新写入 updates，日志写入 logs
+func testStream(url string, updates chan<- TableUpdate, logs *syncLog) *Stream {
+	s := NewStream(Credentials{}, "order")
+	s.URL = url
+	s.PingInterval = time.Second
+	s.PongTimeout = time.Second
+	s.ReconnectDelay = 10 * time.Millisecond
+	s.OnUpdate = func(u TableUpdate) { updates <- u }
+	s.Logf = logs.add
+	return s
+}
+
+type syncLog struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *syncLog) add(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func (l *syncLog) contains(s string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range l.lines {
+		if strings.Contains(line, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// runStream 在后台运行 s，返回停止函数（返回 Run 的结果）
+func runStream(t *testing.T, s *Stream) func() error {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- s.Run(ctx) }()
+	stopped := false
+	stop := func() error {
+		if stopped {
+			return nil
+		}
+		stopped = true
+		cancel()
+		select {
+		case err := <-result:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run 没有在取消后返回")
+			return nil
+		}
+	}
+	t.Cleanup(func() { stop() })
+	return stop
+}
+
+// waitUpdate 等待下一条表更新
+func waitUpdate(t *testing.T, updates <-chan TableUpdate) TableUpdate {
+	t.Helper()
+	select {
+	case u := <-updates:
+		return u
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待表更新超时")
+		return TableUpdate{}
+	}
+}
+
+func TestStreamReplaySession(t *testing.T) {
+	messages := fixture(t, "stream_session.jsonl")
+	url := newMockWS(t, func(n int, c *mockConn) {
+		var auth struct {
+			Op   string
+			Args []json.RawMessage
+		}
+		if err := json.Unmarshal([]byte(c.expect(t)), &auth); err != nil || auth.Op != "authKeyExpires" || len(auth.Args) != 3 {
+			t.Errorf("认证请求 = %+v, %v", auth, err)
+			return
+		}
+		var key, signature string
+		var expires int64
+		json.Unmarshal(auth.Args[0], &key)
+		json.Unmarshal(auth.Args[1], &expires)
+		json.Unmarshal(auth.Args[2], &signature)
+		if key != docAPIKey || signature != Sign(docAPISecret, "GET", "/realtime", expires, "") {
+			t.Errorf("认证参数 key=%s expires=%d signature=%s 不正确", key, expires, signature)
+		}
+
+		if sub := c.expect(t); sub != `{"args":["execution","order","position","margin","wallet","transact","tradeBin1m:XBTUSD"],"op":"subscribe"}` {
+			t.Errorf("订阅请求 = %s", sub)
+		}
+		for _, msg := range messages {
+			c.send(msg)
+		}
+		c.serve(true)
+	})
+
+	updates := make(chan TableUpdate, 64)
+	logs := &syncLog{}
+	s := testStream(url, updates, logs)
+	s.Credentials = Credentials{APIKey: docAPIKey, APISecret: docAPISecret}
+	s.Topics = []string{"execution", "order", "position", "margin", "wallet", "transact", "tradeBin1m:XBTUSD"}
+	stop := runStream(t, s)
+
+	dir := t.TempDir()
+	recorder := NewRecorder()
+	RecordTable(recorder, "execution", ExecutionsDataset(filepath.Join(dir, ExecutionsFile)))
+	RecordTable(recorder, "order", OrdersDataset(filepath.Join(dir, OrdersFile)))
+	RecordTable(recorder, "transact", WalletDataset(filepath.Join(dir, WalletFile)))
+	RecordTable(recorder, "tradeBin1m:XBTUSD", KlinesDataset(filepath.Join(dir, "klines.csv"), "XBTUSD", "1m"))
+
+	// execution 的 partial 没有数据，不产生更新
+	want := []string{
+		"order partial 2", "position partial 2", "margin partial 1", "tradeBin1m partial 1",
+		"wallet partial 1", "transact partial 1",
+		"order update 1", "execution insert 1", "position update 1", "order update 1", "execution insert 1",
+		"order update 1", "order delete 1", "margin update 1", "tradeBin1m insert 1",
+		"transact insert 1", "wallet update 1", "transact update 1", "wallet update 1",
+	}
+	for i, w := range want {
+		u := waitUpdate(t, updates)
+		if got := fmt.Sprintf("%s %s %d", u.Table, u.Action, len(u.Rows)); got != w {
+			t.Fatalf("第 %d 条更新 = %s，应为 %s", i+1, got, w)
+		}
+		if err := recorder.Record(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// update 只覆盖变化的字段，其余字段来自 partial
+	orders, err := DecodeRows[Order](s.Table("order"))
+	if err != nil || len(orders) != 1 {
+		t.Fatalf("order 表 = %+v, %v", orders, err)
+	}
+	if o := orders[0]; o.OrdStatus != "Filled" || o.CumQty != 200 || o.Side != "Buy" || o.Price != 60000 {
+		t.Errorf("合并后的订单 = %+v", o)
+	}
+
+	positions, margins, err := StreamSnapshot(s, time.Date(2024, 5, 1, 0, 1, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 1 || positions[0].Symbol != "XBTUSD" || positions[0].CurrentQty != 380 || positions[0].Leverage != 10 {
+		t.Errorf("持仓快照 = %+v，应只有 XBTUSD 380（保留 partial 中的杠杆）", positions)
+	}
+	if len(margins) != 1 || margins[0].WalletBalance != 10000100 || margins[0].AvailableMargin != 9940513 {
+		t.Errorf("保证金快照 = %+v", margins)
+	}
+
+	wallets, err := DecodeRows[Wallet](s.Table("wallet"))
+	if err != nil || len(wallets) != 1 {
+		t.Fatalf("wallet 表 = %+v, %v", wallets, err)
+	}
+	if w := wallets[0]; w.Amount != 8980100 || w.Withdrawn != 91020000 || w.PendingDebit != 0 || w.Deposited != 100000000 {
+		t.Errorf("合并后的钱包 = %+v", w)
+	}
+
+	results, err := recorder.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, total := range map[string]int{
+		filepath.Join(dir, ExecutionsFile): 2,
+		filepath.Join(dir, OrdersFile):     2, // delete 不删除已保存的订单
+		filepath.Join(dir, "klines.csv"):   2,
+		filepath.Join(dir, WalletFile):     2, // 提现先 Pending 后 Completed，按 transactID 合并
+	} {
+		if results[file].Total != total {
+			t.Errorf("%s: %+v，应有 %d 条记录", filepath.Base(file), results[file], total)
+		}
+	}
+	saved, _ := ReadOrders(filepath.Join(dir, OrdersFile))
+	for _, o := range saved {
+		if o.OrderID == "0b5c6a1e-0002-4f3c-9d0a-000000000002" && o.OrdStatus != "Canceled" {
+			t.Errorf("已撤销订单保存的状态 = %s", o.OrdStatus)
+		}
+	}
+
+	history, _ := ReadWalletHistory(filepath.Join(dir, WalletFile))
+	for _, h := range history {
+		if h.TransactType == "Withdrawal" && (h.TransactStatus != "Completed" || h.Fee != -20000 || h.WalletBalance != 8980100) {
+			t.Errorf("保存的提现记录 = %+v，应为合并 update 后的 Completed 记录", h)
+		}
+	}
+
+	if err := stop(); err != nil {
+		t.Errorf("Run = %v", err)
+	}
+	if !logs.contains("认证成功") || !logs.contains("已订阅 tradeBin1m:XBTUSD") {
+		t.Errorf("日志 = %v", logs.lines)
+	}
+}
+
+// 一个帧在 PingInterval 之后才接收完整：心跳不能打断帧的读取
+func TestStreamSlowFrame(t *testing.T) {
+	partial := fixture(t, "stream_session.jsonl")[10] // order partial
+	pinged := make(chan struct{})
+	url := newMockWS(t, func(n int, c *mockConn) {
+		c.expect(t) // subscribe
+
+		// 消息分为两个分片，第一个分片的数据在中途停顿，停顿期间插入服务器的 ping
+		payload := []byte(partial)
+		first := frame(opText, false, payload[:100])
+		c.conn.Write(first[:60])
+		if c.expect(t) != "ping" {
+			t.Error("停顿期间应发送 ping")
+		}
+		close(pinged)
+		time.Sleep(50 * time.Millisecond)
+		c.conn.Write(first[60:])
+		c.conn.Write(frame(opPing, true, []byte("hb")))
+		c.conn.Write(frame(opContinuation, true, payload[100:]))
+		c.serve(true)
+	})
+
+	updates := make(chan TableUpdate, 8)
+	logs := &syncLog{}
+	s := testStream(url, updates, logs)
+	s.PingInterval = 30 * time.Millisecond
+	stop := runStream(t, s)
+
+	u := waitUpdate(t, updates)
+	if u.Table != "order" || u.Action != "partial" || len(u.Rows) != 2 {
+		t.Errorf("更新 = %s %s %d 行", u.Table, u.Action, len(u.Rows))
+	}
+	<-pinged
+	stop()
+	if logs.contains("实时连接断开") {
+		t.Errorf("不应断线重连: %v", logs.lines)
+	}
+}
+
+func TestStreamHeartbeatTimeout(t *testing.T) {
+	partial := fixture(t, "stream_session.jsonl")[10] // order partial
+	var pings [3]atomic.Int32
+	url := newMockWS(t, func(n int, c *mockConn) {
+		c.expect(t) // subscribe
+		if n == 1 {
+			// 第一个连接不回复 pong
+			for msg := range c.recv {
+				if msg == "ping" {
+					pings[1].Add(1)
+				}
+			}
+			return
+		}
+		c.send(partial)
+		for msg := range c.recv {
+			if msg == "ping" {
+				pings[2].Add(1)
+				c.send("pong")
+			}
+		}
+	})
+
+	updates := make(chan TableUpdate, 8)
+	logs := &syncLog{}
+	s := testStream(url, updates, logs)
+	s.PingInterval = 20 * time.Millisecond
+	s.PongTimeout = 40 * time.Millisecond
+	stop := runStream(t, s)
+
+	// 重连后重新收到 partial
+	if u := waitUpdate(t, updates); u.Table != "order" || u.Action != "partial" {
+		t.Errorf("重连后的更新 = %s %s", u.Table, u.Action)
+	}
+	if !logs.contains("心跳超时") {
+		t.Errorf("日志 = %v，应因心跳超时断开", logs.lines)
+	}
+	if pings[1].Load() != 1 {
+		t.Errorf("第一个连接收到 %d 次 ping，应为 1", pings[1].Load())
+	}
+
+	// 有 pong 回复时连接保持
+	time.Sleep(200 * time.Millisecond)
+	stop()
+	if pings[2].Load() < 2 {
+		t.Errorf("第二个连接收到 %d 次 ping", pings[2].Load())
+	}
+	if strings.Count(strings.Join(logs.lines, ""), "已连接") != 2 {
+		t.Errorf("日志 = %v，应只连接两次", logs.lines)
+	}
+}
+
+func TestStreamAuthError(t *testing.T) {
+	var conns atomic.Int32
+	url := newMockWS(t, func(n int, c *mockConn) {
+		conns.Add(1)
+		c.expect(t)
+		c.send(`{"status":401,"error":"Invalid API Key.","meta":{},"request":{"op":"authKeyExpires","args":["x",1,"y"]}}`)
+		c.serve(false)
+	})
+
+	s := testStream(url, make(chan TableUpdate, 1), &syncLog{})
+	s.Credentials = Credentials{APIKey: "x", APISecret: "y"}
+	err := s.Run(context.Background())
+
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) || streamErr.Status != 401 || streamErr.Message != "Invalid API Key." {
+		t.Fatalf("err = %v，应为 401 错误", err)
+	}
+	if conns.Load() != 1 {
+		t.Errorf("连接 %d 次，认证失败不应重连", conns.Load())
+	}
+}
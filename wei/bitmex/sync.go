@@ -0,0 +1,432 @@
+package bitmex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Dataset 一类可增量同步的数据及其 CSV 文件，用 ExecutionsDataset 等函数创建
+type Dataset[T any] struct {
+	Title    string // 中文名称，用于输出
+	File     string // CSV 文件路径
+	endpoint Endpoint
+	query    Query
+	key      func(T) string    // 去重主键
+	time     func(T) time.Time // 排序及计算增量起点的时间
+	final    func(T) bool      // 记录不会再变化；nil 表示所有记录下载后都不再变化
+	read     func(string) ([]T, error)
+	write    func(string, []T) error
+	record   func(T) []string // CSV 行，用于判断记录是否有更新
+
+	// open 记录是否仍处于活动状态（如未成交订单）。增量下载未覆盖到的活动记录
+	// 会交给 refresh 按主键重新获取最新状态。
+	open    func(T) bool
+	refresh func(c *Client, rows []T) ([]T, error)
+
+	// onChange 已有记录内容变化时调用（在 CSV 写入成功之后），用于记录状态变化历史
+	onChange func(changes []Change[T]) error
+}
+
+// Change 一条已有记录的内容变化
+type Change[T any] struct {
+	Old T
+	New T
+}
+
+// ExecutionsDataset 成交记录，以 execID 去重
+func ExecutionsDataset(file string) Dataset[Execution] {
+	return Dataset[Execution]{
+		Title:    "交易记录",
+		File:     file,
+		endpoint: ExecutionEndpoint,
+		key:      func(e Execution) string { return e.ExecID },
+		time:     Execution.Time,
+		read:     ReadExecutions,
+		write:    WriteExecutions,
+		record:   Execution.record,
+	}
+}
+
+// WalletDataset 钱包历史，以 transactID 去重。
+// 状态为 Pending 的记录（包括没有 transactID 的未实现盈亏快照）之后还会变化，下次同步时重新获取。
+func WalletDataset(file string) Dataset[WalletHistory] {
+	return Dataset[WalletHistory]{
+		Title:    "钱包历史记录",
+		File:     file,
+		endpoint: WalletEndpoint,
+		key: func(h WalletHistory) string {
+			if h.TransactID == "" {
+				return "pending:" + h.TransactType + ":" + h.Currency + ":" + h.Address
+			}
+			return h.TransactID
+		},
+		time:   WalletHistory.Time,
+		final:  func(h WalletHistory) bool { return h.TransactID != "" && h.TransactStatus != "Pending" },
+		read:   ReadWalletHistory,
+		write:  WriteWalletHistory,
+		record: WalletHistory.record,
+	}
+}
+
+// OrdersDataset 订单记录，以 orderID 去重。
+// 已保存的未完成订单（New / PartiallyFilled 等）每次同步都按 orderID 重新获取，
+// 状态变化追加记录到 orders_history.csv。
+func OrdersDataset(file string) Dataset[Order] {
+	return Dataset[Order]{
+		Title:    "订单记录",
+		File:     file,
+		endpoint: OrderEndpoint,
+		key:      func(o Order) string { return o.OrderID },
+		time:     Order.Time,
+		read:     ReadOrders,
+		write:    WriteOrders,
+		record:   Order.record,
+		open:     Order.IsOpen,
+		refresh:  func(c *Client, orders []Order) ([]Order, error) { return c.OrdersByID(orderIDs(orders)) },
+		onChange: func(changes []Change[Order]) error {
+			return AppendOrderTransitions(OrderHistoryFile(file), orderTransitions(changes, time.Now()))
+		},
+	}
+}
+
+// KlinesDataset K线，以交易对+时间去重
+func KlinesDataset(file, symbol, binSize string) Dataset[Kline] {
+	return Dataset[Kline]{
+		Title:    "K线数据",
+		File:     file,
+		endpoint: KlineEndpoint(binSize),
+		query:    Query{Symbol: symbol},
+		key:      func(k Kline) string { return k.Symbol + "|" + k.Timestamp.UTC().Format(time.RFC3339) },
+		time:     func(k Kline) time.Time { return k.Timestamp },
+		read:     ReadKlines,
+		write:    WriteKlines,
+		record:   Kline.record,
+	}
+}
+
+// SyncResult 一次同步的结果
+type SyncResult struct {
+	StartTime time.Time // 增量下载起点，零值表示全量
+	Resumed   bool      // 是否从上次中断的检查点继续
+	Fetched   int       // 本次下载的记录数（含与已有记录重复的）
+	New       int       // 新增记录数
+	Updated   int       // 内容有变化的已有记录数
+	Removed   int       // 不再返回的未完成记录数（如已消失的未实现盈亏快照）
+	Deduped   int       // 原文件中主键重复而被合并的记录数
+	Refreshed int       // 按主键重新获取状态的活动记录数（如未成交订单）
+	Total     int       // 同步后文件中的记录数
+}
+
+// Checkpoint 同步进度。下载的每一页先保存在 <File>.sync/ 目录中，
+// 中断后再次同步会从 Offset 继续，全部完成后才合并写入 CSV 并删除该目录。
+type Checkpoint struct {
+	Full      bool      `json:"full"`      // 是否为全量下载
+	StartTime time.Time `json:"startTime"` // 本次同步的查询起点
+	Offset    int       `json:"offset"`    // 已下载的记录数，即下一页的 start
+	Pages     int       `json:"pages"`     // 已保存的页数
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Load 读取 CSV 文件中的全部记录
+func (ds Dataset[T]) Load() ([]T, error) {
+	return ds.read(ds.File)
+}
+
+// checkpointDir 检查点目录
+func (ds Dataset[T]) checkpointDir() string {
+	return ds.File + ".sync"
+}
+
+func (ds Dataset[T]) pageFile(n int) string {
+	return filepath.Join(ds.checkpointDir(), fmt.Sprintf("page-%06d.csv", n))
+}
+
+// Sync 下载新记录并合并到 CSV 文件。
+// full 为 false 时从已有记录计算增量起点（含该时刻，重复记录按主键去重）；
+// full 为 true 时重新下载全部记录，原文件备份为 .bak。
+func Sync[T any](c *Client, ds Dataset[T], full bool) (SyncResult, error) {
+	var existing []T
+	if !full {
+		rows, err := ds.read(ds.File)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return SyncResult{}, fmt.Errorf("读取 %s 失败: %w", ds.File, err)
+		}
+		existing = rows
+	}
+
+	cp, fetched, err := ds.loadCheckpoint()
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	result := SyncResult{}
+	if cp != nil && cp.Full == full {
+		result.Resumed = true
+		c.logf("从检查点继续: 已下载 %d 条记录（%d 页）\n", cp.Offset, cp.Pages)
+	} else {
+		if cp != nil {
+			c.logf("⚠ 检查点的下载模式不同，重新开始\n")
+		}
+		if err := os.RemoveAll(ds.checkpointDir()); err != nil {
+			return SyncResult{}, err
+		}
+		cp = &Checkpoint{Full: full, StartTime: ds.incrementalStart(existing)}
+		fetched = nil
+		if err := ds.saveCheckpoint(cp); err != nil {
+			return SyncResult{}, err
+		}
+	}
+	result.StartTime = cp.StartTime
+
+	q := ds.query
+	q.StartTime = cp.StartTime
+	err = FetchPages(c, ds.endpoint, q, cp.Offset, func(page []T, next int) error {
+		if err := ds.write(ds.pageFile(cp.Pages+1), page); err != nil {
+			return fmt.Errorf("保存下载进度失败: %w", err)
+		}
+		cp.Pages++
+		cp.Offset = next
+		fetched = append(fetched, page...)
+		return ds.saveCheckpoint(cp)
+	})
+	if err != nil {
+		return result, fmt.Errorf("%w（进度已保存，再次运行将从第 %d 条继续）", err, cp.Offset)
+	}
+	result.Fetched = len(fetched)
+
+	merged, changes := ds.merge(existing, fetched, &result)
+
+	// 增量下载没有覆盖到的活动记录，按主键重新获取最新状态
+	if ds.refresh != nil && len(existing) > 0 {
+		stale := ds.staleOpen(merged, fetched)
+		if len(stale) > 0 {
+			c.logf("重新获取 %d 条未完成%s的状态...\n", len(stale), ds.Title)
+			fresh, err := ds.refresh(c, stale)
+			if err != nil {
+				return result, fmt.Errorf("更新未完成记录失败: %w（进度已保存，再次运行将继续）", err)
+			}
+			result.Refreshed = len(stale)
+
+			var refreshChanges []Change[T]
+			merged, refreshChanges = ds.merge(merged, fresh, &result)
+			changes = append(changes, refreshChanges...)
+		}
+	}
+	result.Total = len(merged)
+
+	if full {
+		if err := backup(ds.File); err != nil {
+			return result, fmt.Errorf("备份文件失败: %w", err)
+		}
+	}
+	if result.New+result.Updated+result.Removed+result.Deduped > 0 || full {
+		if err := ds.write(ds.File, merged); err != nil {
+			return result, fmt.Errorf("保存失败: %w", err)
+		}
+	}
+
+	if ds.onChange != nil && len(changes) > 0 {
+		if err := ds.onChange(changes); err != nil {
+			return result, fmt.Errorf("保存变化历史失败: %w", err)
+		}
+	}
+
+	if err := os.RemoveAll(ds.checkpointDir()); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// Apply 将 rows 按主键合并到 CSV 文件并返回合并结果，用于实时推送的数据。
+// 与 Sync 不同，不会删除文件中已有的记录。
+func (ds Dataset[T]) Apply(rows []T) (SyncResult, error) {
+	existing, err := ds.read(ds.File)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return SyncResult{}, fmt.Errorf("读取 %s 失败: %w", ds.File, err)
+	}
+
+	upsert := ds
+	upsert.final = nil
+	result := SyncResult{Fetched: len(rows)}
+	merged, changes := upsert.merge(existing, rows, &result)
+	result.Total = len(merged)
+
+	if result.New+result.Updated+result.Deduped > 0 {
+		if err := ds.write(ds.File, merged); err != nil {
+			return result, fmt.Errorf("保存失败: %w", err)
+		}
+	}
+	if ds.onChange != nil && len(changes) > 0 {
+		if err := ds.onChange(changes); err != nil {
+			return result, fmt.Errorf("保存变化历史失败: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// backup 将 filename 备份为 filename.bak。使用硬链接，原文件在写入新内容前始终存在
+func backup(filename string) error {
+	if _, err := os.Stat(filename); err != nil {
+		return nil
+	}
+	bak := filename + ".bak"
+	if err := os.Remove(bak); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Link(filename, bak); err == nil {
+		return nil
+	}
+
+	// 不支持硬链接的文件系统退回到复制
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(bak, data, 0644)
+}
+
+// incrementalStart 增量下载起点：已完成记录的最晚时间，和未完成记录的最早时间，取较早者
+func (ds Dataset[T]) incrementalStart(rows []T) time.Time {
+	var latestFinal, earliestPending time.Time
+	for _, row := range rows {
+		t := ds.time(row)
+		if t.IsZero() {
+			continue
+		}
+		if ds.final == nil || ds.final(row) {
+			if t.After(latestFinal) {
+				latestFinal = t
+			}
+		} else if earliestPending.IsZero() || t.Before(earliestPending) {
+			earliestPending = t
+		}
+	}
+
+	if !earliestPending.IsZero() && (latestFinal.IsZero() || earliestPending.Before(latestFinal)) {
+		return earliestPending
+	}
+	return latestFinal
+}
+
+// staleOpen 返回 merged 中本次下载没有返回的活动记录
+func (ds Dataset[T]) staleOpen(merged, fetched []T) []T {
+	seen := make(map[string]bool, len(fetched))
+	for _, row := range fetched {
+		seen[ds.key(row)] = true
+	}
+
+	var stale []T
+	for _, row := range merged {
+		if ds.open(row) && !seen[ds.key(row)] {
+			stale = append(stale, row)
+		}
+	}
+	return stale
+}
+
+// merge 按主键合并：新记录追加，已有记录被新内容替换（返回这些变化）；
+// 下载范围内未再出现的未完成记录视为已失效并删除。结果按时间排序（同一时间保持原顺序）。
+func (ds Dataset[T]) merge(existing, fetched []T, result *SyncResult) ([]T, []Change[T]) {
+	index := make(map[string]int, len(existing))
+	merged := make([]T, 0, len(existing)+len(fetched))
+	for _, row := range existing {
+		key := ds.key(row)
+		if i, ok := index[key]; ok {
+			merged[i] = row
+			result.Deduped++
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, row)
+	}
+
+	var changes []Change[T]
+	seen := make(map[string]bool, len(fetched))
+	for _, row := range fetched {
+		key := ds.key(row)
+		seen[key] = true
+
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			merged = append(merged, row)
+			result.New++
+			continue
+		}
+		if !slices.Equal(ds.record(merged[i]), ds.record(row)) {
+			changes = append(changes, Change[T]{Old: merged[i], New: row})
+			merged[i] = row
+			result.Updated++
+		}
+	}
+
+	if ds.final != nil && len(existing) > 0 {
+		kept := merged[:0]
+		for _, row := range merged {
+			if !ds.final(row) && !seen[ds.key(row)] {
+				result.Removed++
+				continue
+			}
+			kept = append(kept, row)
+		}
+		merged = kept
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return ds.time(merged[i]).Before(ds.time(merged[j]))
+	})
+	return merged, changes
+}
+
+// loadCheckpoint 读取检查点和已保存的页，没有检查点时返回 nil
+func (ds Dataset[T]) loadCheckpoint() (*Checkpoint, []T, error) {
+	data, err := os.ReadFile(filepath.Join(ds.checkpointDir(), "checkpoint.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, nil, fmt.Errorf("解析检查点失败: %w（可删除 %s 后重新同步）", err, ds.checkpointDir())
+	}
+
+	var rows []T
+	for n := 1; n <= cp.Pages; n++ {
+		page, err := ds.read(ds.pageFile(n))
+		if err != nil {
+			return nil, nil, fmt.Errorf("读取检查点数据失败: %w（可删除 %s 后重新同步）", err, ds.checkpointDir())
+		}
+		rows = append(rows, page...)
+	}
+	return &cp, rows, nil
+}
+
+// saveCheckpoint 原子写入检查点
+func (ds Dataset[T]) saveCheckpoint(cp *Checkpoint) error {
+	if err := os.MkdirAll(ds.checkpointDir(), 0755); err != nil {
+		return err
+	}
+	cp.UpdatedAt = time.Now().UTC()
+
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(ds.checkpointDir(), "checkpoint.json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
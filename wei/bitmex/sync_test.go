@@ -0,0 +1,424 @@
+package bitmex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pageServer 模拟按 start 偏移分页的 /execution 接口，按 startTime 过滤（含）
+type pageServer struct {
+	mu      sync.Mutex
+	records []Execution
+	failAt  int   // start 大于等于该值时返回 503，小于 0 表示不失败
+	starts  []int // 收到的 start 参数
+	since   []string
+}
+
+func (s *pageServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := r.URL.Query()
+	start, _ := strconv.Atoi(q.Get("start"))
+	count, _ := strconv.Atoi(q.Get("count"))
+	s.starts = append(s.starts, start)
+	s.since = append(s.since, q.Get("startTime"))
+	if s.failAt >= 0 && start >= s.failAt {
+		http.Error(w, `{"error":{"message":"overloaded"}}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	var matched []Execution
+	since, _ := time.Parse(TimeLayout, q.Get("startTime"))
+	for _, e := range s.records {
+		if !e.Time().Before(since) {
+			matched = append(matched, e)
+		}
+	}
+	page := []Execution{}
+	if start < len(matched) {
+		page = matched[start:min(start+count, len(matched))]
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+var syncBase = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func testExecution(n int) Execution {
+	ts := syncBase.Add(time.Duration(n) * time.Hour).Format(TimeLayout)
+	return Execution{
+		ExecID: fmt.Sprintf("exec-%02d", n), OrderID: fmt.Sprintf("order-%02d", n), Symbol: "XBTUSD",
+		Side: "Buy", LastQty: 100, LastPx: 60000 + float64(n), Commission: 0.0005,
+		TransactTime: ts, Timestamp: ts, ExecType: "Trade", OrdStatus: "Filled", Currency: "USD",
+	}
+}
+
+// syncFixture 已有 exec-01..03（exec-02 重复一行）的成交文件，服务器上有 exec-01..10，其中 exec-03 的内容有更新
+func syncFixture(t *testing.T) (Dataset[Execution], *pageServer, *Client) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "executions.csv")
+	existing := []Execution{testExecution(1), testExecution(2), testExecution(2), testExecution(3)}
+	if err := WriteExecutions(file, existing); err != nil {
+		t.Fatal(err)
+	}
+
+	server := &pageServer{failAt: -1}
+	for n := 1; n <= 10; n++ {
+		server.records = append(server.records, testExecution(n))
+	}
+	server.records[2].Commission = 0.00075
+
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	ds := ExecutionsDataset(file)
+	ds.endpoint = Endpoint{Path: "/execution", PageSize: 3}
+	return ds, server, &Client{BaseURL: ts.URL}
+}
+
+func execIDs(t *testing.T, ds Dataset[Execution]) []string {
+	t.Helper()
+	rows, err := ds.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, e := range rows {
+		ids = append(ids, e.ExecID)
+	}
+	return ids
+}
+
+func TestSyncResumeFromCheckpoint(t *testing.T) {
+	ds, server, client := syncFixture(t)
+	before, _ := os.ReadFile(ds.File)
+
+	// 增量起点为 exec-03，服务器返回 exec-03..10 共 8 条，每页 3 条；第二页失败
+	server.failAt = 3
+	result, err := Sync(client, ds, false)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v，应为 503 错误", err)
+	}
+	if !result.StartTime.Equal(testExecution(3).Time()) {
+		t.Errorf("StartTime = %v，应为 exec-03 的时间", result.StartTime)
+	}
+	if after, _ := os.ReadFile(ds.File); string(after) != string(before) {
+		t.Errorf("中断后 CSV 文件被修改")
+	}
+
+	cp, pages, err := ds.loadCheckpoint()
+	if err != nil || cp == nil {
+		t.Fatalf("检查点 = %v, %v", cp, err)
+	}
+	if cp.Offset != 3 || cp.Pages != 1 || cp.Full || len(pages) != 3 {
+		t.Errorf("检查点 = %+v（已保存 %d 条），应为 Offset 3、1 页", cp, len(pages))
+	}
+
+	// 再次同步从第 3 条继续，使用同一个起点
+	server.failAt = -1
+	server.starts, server.since = nil, nil
+	result, err = Sync(client, ds, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(server.starts) == 0 || server.starts[0] != 3 {
+		t.Errorf("继续时的 start = %v，应从 3 开始", server.starts)
+	}
+	for _, since := range server.since {
+		if since != testExecution(3).TransactTime {
+			t.Errorf("继续时的 startTime = %s，应沿用检查点的起点", since)
+		}
+	}
+
+	want := SyncResult{
+		StartTime: testExecution(3).Time(),
+		Resumed:   true,
+		Fetched:   8, // 检查点中的 3 条 + 继续下载的 5 条
+		New:       7, // exec-04..10
+		Updated:   1, // exec-03 手续费变化
+		Deduped:   1, // 原文件中重复的 exec-02
+		Total:     10,
+	}
+	if result != want {
+		t.Errorf("SyncResult = %+v\n应为 %+v", result, want)
+	}
+
+	ids := execIDs(t, ds)
+	seen := make(map[string]bool)
+	for i, id := range ids {
+		if seen[id] {
+			t.Errorf("重复的记录 %s", id)
+		}
+		seen[id] = true
+		if want := testExecution(i + 1).ExecID; id != want {
+			t.Errorf("第 %d 条 = %s，应为 %s（按时间排序）", i, id, want)
+		}
+	}
+	if len(ids) != 10 {
+		t.Errorf("共 %d 条记录，应为 10", len(ids))
+	}
+	rows, _ := ds.Load()
+	if rows[2].Commission != 0.00075 {
+		t.Errorf("exec-03 手续费 = %v，应更新为 0.00075", rows[2].Commission)
+	}
+
+	if _, err := os.Stat(ds.checkpointDir()); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("完成后检查点目录未删除: %v", err)
+	}
+	assertNoTempFiles(t, filepath.Dir(ds.File))
+
+	// 没有新数据时不改写文件
+	result, err = Sync(client, ds, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.New+result.Updated+result.Deduped+result.Removed != 0 || result.Resumed || result.Total != 10 {
+		t.Errorf("重复同步 = %+v，应没有变化", result)
+	}
+}
+
+func TestSyncFullBackup(t *testing.T) {
+	ds, server, client := syncFixture(t)
+	before, _ := os.ReadFile(ds.File)
+
+	// 增量同步留下的检查点与全量模式不同，应重新开始
+	server.failAt = 3
+	if _, err := Sync(client, ds, false); err == nil {
+		t.Fatal("应返回错误")
+	}
+	server.failAt = -1
+	server.starts, server.since = nil, nil
+
+	result, err := Sync(client, ds, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Resumed || !result.StartTime.IsZero() || server.starts[0] != 0 || server.since[0] != "" {
+		t.Errorf("全量同步 = %+v，start = %v，应从头下载", result, server.starts)
+	}
+	if result.Fetched != 10 || result.New != 10 || result.Total != 10 {
+		t.Errorf("SyncResult = %+v", result)
+	}
+
+	if bak, err := os.ReadFile(ds.File + ".bak"); err != nil || string(bak) != string(before) {
+		t.Errorf("备份文件内容与原文件不同: %v", err)
+	}
+	if ids := execIDs(t, ds); len(ids) != 10 {
+		t.Errorf("共 %d 条记录，应为 10", len(ids))
+	}
+	assertNoTempFiles(t, filepath.Dir(ds.File))
+}
+
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp*"))
+	if len(matches) > 0 {
+		t.Errorf("残留临时文件: %v", matches)
+	}
+}
+
+// orderServer 模拟 /order 接口：带 filter 的请求按 orderID 返回订单，其余请求按 startTime 分页
+type orderServer struct {
+	mu      sync.Mutex
+	orders  []Order
+	batches [][]string // 每次按 orderID 查询的ID
+}
+
+func (s *orderServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := r.URL.Query()
+	matched := []Order{}
+	if filter := q.Get("filter"); filter != "" {
+		var f struct {
+			OrderID []string `json:"orderID"`
+		}
+		if err := json.Unmarshal([]byte(filter), &f); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.batches = append(s.batches, f.OrderID)
+		ids := make(map[string]bool)
+		for _, id := range f.OrderID {
+			ids[id] = true
+		}
+		for _, o := range s.orders {
+			if ids[o.OrderID] {
+				matched = append(matched, o)
+			}
+		}
+	} else {
+		since, _ := time.Parse(TimeLayout, q.Get("startTime"))
+		for _, o := range s.orders {
+			if !o.Time().Before(since) {
+				matched = append(matched, o)
+			}
+		}
+	}
+
+	start, _ := strconv.Atoi(q.Get("start"))
+	count, _ := strconv.Atoi(q.Get("count"))
+	page := []Order{}
+	if start < len(matched) {
+		page = matched[start:min(start+count, len(matched))]
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+func testOrder(n int, status string, cumQty int) Order {
+	ts := syncBase.Add(time.Duration(n) * time.Minute).Format(TimeLayout)
+	o := Order{
+		OrderID: fmt.Sprintf("order-%03d", n), Symbol: "XBTUSD", Side: "Buy", OrderQty: 100, Price: 60000,
+		OrdType: "Limit", OrdStatus: status, LeavesQty: 100 - cumQty, CumQty: cumQty, TransactTime: ts, Timestamp: ts,
+	}
+	if cumQty > 0 {
+		o.AvgPx = 60000
+	}
+	return o
+}
+
+func TestOrderIsOpen(t *testing.T) {
+	for status, want := range map[string]bool{
+		"New": true, "PartiallyFilled": true, "PendingNew": true, "PendingCancel": true, "PendingReplace": true, "Untriggered": true,
+		"Filled": false, "Canceled": false, "Rejected": false, "Expired": false, "": false,
+	} {
+		if got := (Order{OrdStatus: status}).IsOpen(); got != want {
+			t.Errorf("IsOpen(%q) = %v，应为 %v", status, got, want)
+		}
+	}
+}
+
+// 已保存为 New 的订单不在增量下载范围内，按 orderID 分批重新获取，状态变化写入 orders_history.csv
+func TestSyncRefreshOpenOrders(t *testing.T) {
+	const stored = 250
+	file := filepath.Join(t.TempDir(), OrdersFile)
+	var existing []Order
+	for n := 1; n <= stored; n++ {
+		existing = append(existing, testOrder(n, "New", 0))
+	}
+	if err := WriteOrders(file, existing); err != nil {
+		t.Fatal(err)
+	}
+
+	// 服务器上：大部分已成交，order-002 仍为 New，order-003 部分成交，另有一个新订单 order-251
+	server := &orderServer{}
+	for n := 1; n <= stored; n++ {
+		server.orders = append(server.orders, testOrder(n, "Filled", 100))
+	}
+	server.orders[1] = testOrder(2, "New", 0)
+	server.orders[2] = testOrder(3, "PartiallyFilled", 40)
+	server.orders = append(server.orders, testOrder(stored+1, "Filled", 100))
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	client := &Client{BaseURL: ts.URL}
+
+	ds := OrdersDataset(file)
+	ds.endpoint = Endpoint{Path: "/order", PageSize: 50, Auth: true}
+	result, err := Sync(client, ds, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 增量起点为 order-250，下载到 order-250、251；其余 249 个未完成订单按 orderID 重新获取
+	want := SyncResult{
+		StartTime: testOrder(stored, "New", 0).Time(),
+		Fetched:   2,
+		New:       1,
+		Updated:   249, // order-250（下载）+ 248 个重新获取后有变化的订单（order-002 没有变化）
+		Refreshed: 249,
+		Total:     251,
+	}
+	if result != want {
+		t.Errorf("SyncResult = %+v\n应为 %+v", result, want)
+	}
+
+	var sizes []int
+	requested := make(map[string]bool)
+	for _, batch := range server.batches {
+		sizes = append(sizes, len(batch))
+		for _, id := range batch {
+			if requested[id] {
+				t.Errorf("%s 重复查询", id)
+			}
+			requested[id] = true
+		}
+	}
+	if fmt.Sprint(sizes) != "[100 100 49]" {
+		t.Errorf("按 orderID 查询的批次 = %v，应为 [100 100 49]（每批最多 %d 个）", sizes, orderIDBatch)
+	}
+	if requested[testOrder(stored, "New", 0).OrderID] {
+		t.Errorf("已在增量下载中返回的 order-250 不应重新获取")
+	}
+
+	saved, err := ReadOrders(file)
+	if err != nil || len(saved) != stored+1 {
+		t.Fatalf("orders.csv = %d 条, %v", len(saved), err)
+	}
+	status := make(map[string]Order)
+	for _, o := range saved {
+		status[o.OrderID] = o
+	}
+	for id, want := range map[string]string{"order-001": "Filled", "order-002": "New", "order-003": "PartiallyFilled", "order-250": "Filled", "order-251": "Filled"} {
+		if got := status[id].OrdStatus; got != want {
+			t.Errorf("%s 状态 = %s，应为 %s", id, got, want)
+		}
+	}
+
+	// 每个状态变化一行，没有变化的 order-002 和新订单 order-251 没有记录
+	historyFile := OrderHistoryFile(file)
+	transitions, err := ReadOrderTransitions(historyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 249 {
+		t.Fatalf("orders_history.csv = %d 行，应为 249", len(transitions))
+	}
+	byID := make(map[string]OrderTransition)
+	for _, tr := range transitions {
+		if _, ok := byID[tr.OrderID]; ok {
+			t.Errorf("%s 有多行状态变化", tr.OrderID)
+		}
+		byID[tr.OrderID] = tr
+	}
+	if _, ok := byID["order-002"]; ok {
+		t.Errorf("order-002 状态没有变化，不应记录")
+	}
+	if tr := byID["order-003"]; tr.OldStatus != "New" || tr.NewStatus != "PartiallyFilled" || tr.OldCumQty != 0 || tr.NewCumQty != 40 {
+		t.Errorf("order-003 的状态变化 = %+v", tr)
+	}
+
+	// 服务器没有变化时再次同步：重新获取仍未完成的 order-002、003，不追加历史
+	server.batches = nil
+	if _, err := Sync(client, ds, false); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(server.batches) != "[[order-002 order-003]]" {
+		t.Errorf("再次同步按 orderID 查询 = %v，应只有仍未完成的订单", server.batches)
+	}
+	if transitions, _ := ReadOrderTransitions(historyFile); len(transitions) != 249 {
+		t.Errorf("没有变化时 orders_history.csv = %d 行，应仍为 249", len(transitions))
+	}
+
+	// order-003 全部成交：追加一行
+	server.orders[2] = testOrder(3, "Filled", 100)
+	if _, err := Sync(client, ds, false); err != nil {
+		t.Fatal(err)
+	}
+	transitions, _ = ReadOrderTransitions(historyFile)
+	if last := transitions[len(transitions)-1]; len(transitions) != 250 || last.OrderID != "order-003" ||
+		last.OldStatus != "PartiallyFilled" || last.NewStatus != "Filled" || last.NewCumQty != 100 {
+		t.Errorf("orders_history.csv 最后一行 = %+v（共 %d 行），应为 order-003 PartiallyFilled → Filled", last, len(transitions))
+	}
+}
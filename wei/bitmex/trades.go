@@ -0,0 +1,320 @@
+package bitmex
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TradesFile 完整交易（开仓到平仓）文件（trades 命令生成）
+const TradesFile = "trades.csv"
+
+// TradeHeader trades.csv 表头，金额为结算币种主单位（BTC / USDT）
+var TradeHeader = []string{
+	"Symbol", "Side", "Currency", "OpenTime", "CloseTime", "HoldingHours", "Fills",
+	"MaxQty", "EntryQty", "ExitQty", "EntryPrice", "ExitPrice",
+	"PnL", "Fees", "NetPnL", "NetPnL_BTC", "NetPnL_USD", "MAE_Percent", "MFE_Percent",
+}
+
+// RoundTrip 一个合约从开仓到持仓回到零的完整交易，期间可以加仓、减仓。
+// 持仓方向反转的成交拆成两部分：一部分平掉当前交易，剩余部分开始新交易。
+type RoundTrip struct {
+	Symbol     string
+	Side       string // Long / Short
+	Currency   string // 结算币种（XBt / USDt）
+	OpenTime   time.Time
+	CloseTime  time.Time // 未平仓时为零值
+	LastTime   time.Time // 最后一笔成交时间
+	Fills      int
+	MaxQty     int     // 最大持仓数量（绝对值）
+	EntryQty   int     // 累计开仓数量
+	ExitQty    int     // 累计平仓数量
+	EntryPrice float64 // 开仓均价（反向合约为调和平均）
+	ExitPrice  float64 // 平仓均价
+	PnL        float64 // 已实现盈亏（主单位，未扣手续费）
+	Fees       float64 // 手续费（主单位，负值为返佣）
+	NetPnL     float64 // PnL - Fees
+	NetPnLBTC  float64 // 净盈亏换算为 BTC（USDT 按平仓时的 XBTUSD 价格换算）
+	NetPnLUSD  float64 // 净盈亏换算为美元（BTC 按平仓时的 XBTUSD 价格换算）
+	MAE        float64 // 最大不利波动（相对开仓均价的百分比，≤ 0），没有K线时为 0
+	MFE        float64 // 最大有利波动（相对开仓均价的百分比，≥ 0），没有K线时为 0
+
+	inst       Instrument
+	entryValue float64 // 开仓价值合计（结算币种最小单位）
+	exitValue  float64 // 平仓价值合计
+	pnl, fees  float64 // 最小单位
+}
+
+// IsOpen 是否仍未平仓
+func (t RoundTrip) IsOpen() bool {
+	return t.CloseTime.IsZero()
+}
+
+// HoldingTime 持仓时间，未平仓时计算到 now
+func (t RoundTrip) HoldingTime(now time.Time) time.Duration {
+	if t.IsOpen() {
+		return now.Sub(t.OpenTime)
+	}
+	return t.CloseTime.Sub(t.OpenTime)
+}
+
+// tripBuilder 一个合约的持仓核算和当前交易
+type tripBuilder struct {
+	inst    Instrument
+	account *PositionAccount
+	trip    *RoundTrip
+}
+
+// ReconstructTrades 将成交记录（只使用 Trade 类型）按合约还原为完整交易，按开仓时间排序。
+// xbtPrice 返回某一时刻的 XBTUSD 价格，用于换算 BTC / USD 盈亏，可以为 nil。
+// catalog 中没有的合约无法计算，返回在 unknown 中。
+func ReconstructTrades(executions []Execution, catalog Catalog, xbtPrice func(time.Time) float64) (trips []RoundTrip, unknown []string) {
+	var fills []Execution
+	for _, e := range executions {
+		if e.ExecType == "Trade" && e.LastQty > 0 && e.LastPx > 0 {
+			fills = append(fills, e)
+		}
+	}
+	sort.SliceStable(fills, func(i, j int) bool { return fills[i].Time().Before(fills[j].Time()) })
+
+	builders := make(map[string]*tripBuilder)
+	skipped := make(map[string]bool)
+	for _, e := range fills {
+		b, ok := builders[e.Symbol]
+		if !ok {
+			inst, ok := catalog.Lookup(e.Symbol)
+			if !ok {
+				if !skipped[e.Symbol] {
+					skipped[e.Symbol] = true
+					unknown = append(unknown, e.Symbol)
+				}
+				continue
+			}
+			b = &tripBuilder{inst: inst, account: NewPositionAccount(inst, AverageCost)}
+			builders[e.Symbol] = b
+		}
+
+		qty := e.LastQty
+		if e.Side != "Buy" {
+			qty = -qty
+		}
+		trips = append(trips, b.fill(e, qty)...)
+	}
+	for _, b := range builders {
+		if b.trip != nil {
+			trips = append(trips, *b.trip)
+		}
+	}
+
+	for i := range trips {
+		trips[i].finish(xbtPrice)
+	}
+	sort.SliceStable(trips, func(i, j int) bool { return trips[i].OpenTime.Before(trips[j].OpenTime) })
+	sort.Strings(unknown)
+	return trips, unknown
+}
+
+// fill 处理一笔成交（qty 带方向），返回因此平仓的交易
+func (b *tripBuilder) fill(e Execution, qty int) []RoundTrip {
+	var closed []RoundTrip
+	at := e.Time()
+
+	for qty != 0 {
+		if b.trip == nil {
+			side := "Long"
+			if qty < 0 {
+				side = "Short"
+			}
+			b.trip = &RoundTrip{
+				Symbol:   e.Symbol,
+				Side:     side,
+				Currency: b.inst.SettlCurrency,
+				OpenTime: at,
+				inst:     b.inst,
+			}
+		}
+		t := b.trip
+		t.Fills++
+		t.LastTime = at
+
+		// 同方向为开仓 / 加仓，反方向为减仓 / 平仓（超出持仓的部分留到下一轮开新仓）
+		position := b.account.Qty
+		part := qty
+		if position != 0 && (qty > 0) != (position > 0) && abs(qty) > abs(position) {
+			part = -position
+		}
+		value := math.Abs(b.inst.Value(float64(part), e.LastPx))
+		if position == 0 || (part > 0) == (position > 0) {
+			t.entryValue += value
+			t.EntryQty += abs(part)
+		} else {
+			t.exitValue += value
+			t.ExitQty += abs(part)
+		}
+		realized, fee := b.account.Fill(part, e.LastPx, e.Commission, at)
+		t.pnl += realized
+		t.fees += fee
+		if abs(b.account.Qty) > t.MaxQty {
+			t.MaxQty = abs(b.account.Qty)
+		}
+		qty -= part
+
+		if b.account.Qty == 0 {
+			t.CloseTime = at
+			closed = append(closed, *t)
+			b.trip = nil
+		}
+	}
+	return closed
+}
+
+// finish 计算均价并把最小单位的金额换算为主单位
+func (t *RoundTrip) finish(xbtPrice func(time.Time) float64) {
+	t.EntryPrice = t.inst.AvgPrice(float64(t.EntryQty), t.entryValue)
+	t.ExitPrice = t.inst.AvgPrice(float64(t.ExitQty), t.exitValue)
+	t.PnL = FromMinorUnits(t.Currency, int64(math.Round(t.pnl)))
+	t.Fees = FromMinorUnits(t.Currency, int64(math.Round(t.fees)))
+	t.NetPnL = t.PnL - t.Fees
+
+	var price float64
+	if xbtPrice != nil {
+		price = xbtPrice(t.LastTime)
+	}
+	t.NetPnLBTC, _ = ToBTC(t.Currency, t.NetPnL, price)
+	switch t.Currency {
+	case "XBt", "XBT":
+		t.NetPnLUSD = t.NetPnL * price
+	case "USDt", "USDT":
+		t.NetPnLUSD = t.NetPnL
+	}
+}
+
+// ApplyExcursions 用 symbol 的K线（binSize 周期，按时间排序）计算该合约各交易的 MAE / MFE。
+// 使用持仓期间（未平仓时到 now）有重叠的所有K线，K线周期越小结果越准确
+func ApplyExcursions(trips []RoundTrip, symbol string, klines []Kline, binSize time.Duration, now time.Time) {
+	for i := range trips {
+		t := &trips[i]
+		if t.Symbol != symbol || t.EntryPrice == 0 {
+			continue
+		}
+		end := t.CloseTime
+		if t.IsOpen() {
+			end = now
+		}
+
+		// K线时间为结束时间，找到第一根结束时间晚于开仓时间的K线
+		first := sort.Search(len(klines), func(j int) bool { return klines[j].Timestamp.After(t.OpenTime) })
+		high, low := 0.0, 0.0
+		for _, k := range klines[first:] {
+			if !k.Timestamp.Add(-binSize).Before(end) {
+				break
+			}
+			if high == 0 || k.High > high {
+				high = k.High
+			}
+			if low == 0 || k.Low < low {
+				low = k.Low
+			}
+		}
+		if high == 0 {
+			continue
+		}
+
+		up := (high/t.EntryPrice - 1) * 100
+		down := (low/t.EntryPrice - 1) * 100
+		if t.Side == "Long" {
+			t.MFE, t.MAE = math.Max(up, 0), math.Min(down, 0)
+		} else {
+			t.MFE, t.MAE = math.Max(-down, 0), math.Min(-up, 0)
+		}
+	}
+}
+
+// WriteTrades 写入 trades.csv
+func WriteTrades(filename string, trips []RoundTrip) error {
+	return writeCSV(filename, TradeHeader, trips, RoundTrip.record)
+}
+
+func (t RoundTrip) record() []string {
+	closeTime, holding := "", ""
+	if !t.IsOpen() {
+		closeTime = t.CloseTime.Format(TimeLayout)
+		holding = fmt.Sprintf("%.2f", t.CloseTime.Sub(t.OpenTime).Hours())
+	}
+	return []string{
+		t.Symbol,
+		t.Side,
+		t.Currency,
+		t.OpenTime.Format(TimeLayout),
+		closeTime,
+		holding,
+		strconv.Itoa(t.Fills),
+		strconv.Itoa(t.MaxQty),
+		strconv.Itoa(t.EntryQty),
+		strconv.Itoa(t.ExitQty),
+		formatPrice(t.EntryPrice),
+		formatPrice(t.ExitPrice),
+		fmt.Sprintf("%.8f", t.PnL),
+		fmt.Sprintf("%.8f", t.Fees),
+		fmt.Sprintf("%.8f", t.NetPnL),
+		fmt.Sprintf("%.8f", t.NetPnLBTC),
+		fmt.Sprintf("%.2f", t.NetPnLUSD),
+		fmt.Sprintf("%.2f", t.MAE),
+		fmt.Sprintf("%.2f", t.MFE),
+	}
+}
+
+// formatPrice 均价保留6位小数（反向合约的调和平均价不是整数）
+func formatPrice(price float64) string {
+	return strconv.FormatFloat(math.Round(price*1e6)/1e6, 'f', -1, 64)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// TradeSummary 已平仓交易的统计
+type TradeSummary struct {
+	Closed     int           `json:"closed"`
+	Open       int           `json:"open"`
+	Wins       int           `json:"wins"`    // 净盈亏 > 0
+	Losses     int           `json:"losses"`  // 净盈亏 ≤ 0
+	WinRate    float64       `json:"winRate"` // 百分比
+	NetPnLBTC  float64       `json:"netPnlBtc"`
+	NetPnLUSD  float64       `json:"netPnlUsd"`
+	AvgHolding time.Duration `json:"-"`
+	// AvgHoldingHours 平均持仓时间（小时）
+	AvgHoldingHours float64 `json:"avgHoldingHours"`
+}
+
+// SummarizeTrades 统计已平仓交易的胜率、净盈亏和平均持仓时间
+func SummarizeTrades(trips []RoundTrip) TradeSummary {
+	var s TradeSummary
+	var holding time.Duration
+	for _, t := range trips {
+		if t.IsOpen() {
+			s.Open++
+			continue
+		}
+		s.Closed++
+		if t.NetPnL > 0 {
+			s.Wins++
+		} else {
+			s.Losses++
+		}
+		s.NetPnLBTC += t.NetPnLBTC
+		s.NetPnLUSD += t.NetPnLUSD
+		holding += t.CloseTime.Sub(t.OpenTime)
+	}
+	if s.Closed > 0 {
+		s.WinRate = float64(s.Wins) / float64(s.Closed) * 100
+		s.AvgHolding = holding / time.Duration(s.Closed)
+		s.AvgHoldingHours = s.AvgHolding.Hours()
+	}
+	return s
+}
@@ -0,0 +1,199 @@
+package bitmex
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var tradeBase = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// tradeFill 第 hour 小时的一笔成交，qty 多头为正
+func tradeFill(symbol string, hour, qty int, price, commission float64) Execution {
+	side := "Buy"
+	if qty < 0 {
+		side, qty = "Sell", -qty
+	}
+	return Execution{
+		Symbol: symbol, Side: side, LastQty: qty, LastPx: price, Commission: commission,
+		TransactTime: tradeBase.Add(time.Duration(hour) * time.Hour).Format(TimeLayout), ExecType: "Trade",
+	}
+}
+
+// tripSummary RoundTrip 中需要比较的字段
+type tripSummary struct {
+	Symbol, Side                     string
+	Open, Close                      int // 小时，未平仓时 Close 为 -1
+	Fills, MaxQty, EntryQty, ExitQty int
+	EntryPrice, ExitPrice            float64
+	PnL, Fees, NetPnLBTC, NetPnLUSD  float64
+}
+
+func summarizeTrip(t RoundTrip) tripSummary {
+	hour := func(at time.Time) int { return int(at.Sub(tradeBase) / time.Hour) }
+	s := tripSummary{
+		Symbol: t.Symbol, Side: t.Side, Open: hour(t.OpenTime), Close: -1,
+		Fills: t.Fills, MaxQty: t.MaxQty, EntryQty: t.EntryQty, ExitQty: t.ExitQty,
+		EntryPrice: t.EntryPrice, ExitPrice: t.ExitPrice, PnL: t.PnL, Fees: t.Fees, NetPnLBTC: t.NetPnLBTC, NetPnLUSD: t.NetPnLUSD,
+	}
+	if !t.IsOpen() {
+		s.Close = hour(t.CloseTime)
+	}
+	return s
+}
+
+func tripsMatch(a, b tripSummary) bool {
+	floats := [][2]float64{
+		{a.EntryPrice, b.EntryPrice}, {a.ExitPrice, b.ExitPrice}, {a.PnL, b.PnL},
+		{a.Fees, b.Fees}, {a.NetPnLBTC, b.NetPnLBTC}, {a.NetPnLUSD, b.NetPnLUSD},
+	}
+	for _, f := range floats {
+		if !approx(f[0], f[1]) {
+			return false
+		}
+	}
+	a.EntryPrice, a.ExitPrice, a.PnL, a.Fees, a.NetPnLBTC, a.NetPnLUSD = 0, 0, 0, 0, 0, 0
+	b.EntryPrice, b.ExitPrice, b.PnL, b.Fees, b.NetPnLBTC, b.NetPnLUSD = 0, 0, 0, 0, 0, 0
+	return a == b
+}
+
+func TestReconstructTrades(t *testing.T) {
+	catalog := NewCatalog([]Instrument{testInverse, testLinear})
+	xbtPrice := func(time.Time) float64 { return 50000 }
+
+	tests := []struct {
+		name        string
+		executions  []Execution
+		want        []tripSummary
+		wantUnknown []string
+	}{
+		{
+			// 正向合约：开仓均价 (1000×100 + 3000×200) / 4000 = 175，平仓均价 (2000×250 + 2000×300) / 4000 = 275，
+			// 盈亏 2000×(250−175) + 2000×(300−175) = 400000，手续费 0.0005 × 1800000 = 900（USDt 最小单位）
+			name: "分批加仓减仓到平仓",
+			executions: []Execution{
+				tradeFill("XBTUSDT", 0, 1000, 100, 0.0005),
+				tradeFill("XBTUSDT", 1, 3000, 200, 0.0005),
+				tradeFill("XBTUSDT", 2, -2000, 250, 0.0005),
+				tradeFill("XBTUSDT", 3, -2000, 300, 0.0005),
+			},
+			want: []tripSummary{{
+				Symbol: "XBTUSDT", Side: "Long", Open: 0, Close: 3, Fills: 4, MaxQty: 4000, EntryQty: 4000, ExitQty: 4000,
+				EntryPrice: 175, ExitPrice: 275, PnL: 0.4, Fees: 0.0009, NetPnLBTC: 0.3991 / 50000, NetPnLUSD: 0.3991,
+			}},
+		},
+		{
+			// 卖出 300 张：100 张平掉多头，200 张开空头；反向合约盈亏 1e8×(100/50000 − 100/40000) = −50000 聪，
+			// 空头 1e8×(200/32000 − 200/40000) = 125000 聪
+			name: "一笔成交多翻空",
+			executions: []Execution{
+				tradeFill("XBTUSD", 0, 100, 50000, 0),
+				tradeFill("XBTUSD", 1, -300, 40000, 0),
+				tradeFill("XBTUSD", 2, 200, 32000, 0),
+			},
+			want: []tripSummary{
+				{
+					Symbol: "XBTUSD", Side: "Long", Open: 0, Close: 1, Fills: 2, MaxQty: 100, EntryQty: 100, ExitQty: 100,
+					EntryPrice: 50000, ExitPrice: 40000, PnL: -0.0005, NetPnLBTC: -0.0005, NetPnLUSD: -25,
+				},
+				{
+					Symbol: "XBTUSD", Side: "Short", Open: 1, Close: 2, Fills: 2, MaxQty: 200, EntryQty: 200, ExitQty: 200,
+					EntryPrice: 40000, ExitPrice: 32000, PnL: 0.00125, NetPnLBTC: 0.00125, NetPnLUSD: 62.5,
+				},
+			},
+		},
+		{
+			// 反向合约的开仓均价为调和平均 200 / (100/40000 + 100/60000) = 48000（算术平均为 50000）
+			name: "未平仓交易",
+			executions: []Execution{
+				tradeFill("XBTUSD", 0, 100, 40000, 0),
+				tradeFill("XBTUSD", 1, 100, 60000, 0),
+				tradeFill("XBTUSD", 2, -50, 50000, 0),
+			},
+			want: []tripSummary{{
+				Symbol: "XBTUSD", Side: "Long", Open: 0, Close: -1, Fills: 3, MaxQty: 200, EntryQty: 200, ExitQty: 50,
+				EntryPrice: 48000, ExitPrice: 50000,
+				// 1e8 × (50/48000 − 50/50000) = 4166.67 聪，四舍五入为 4167
+				PnL: 0.00004167, NetPnLBTC: 0.00004167, NetPnLUSD: 0.00004167 * 50000,
+			}},
+		},
+		{
+			name: "跳过未知合约和非 Trade 记录",
+			executions: []Execution{
+				tradeFill("ETHUSD", 0, 10, 3000, 0),
+				tradeFill("DOGEUSDT", 0, 1000, 0.1, 0),
+				tradeFill("XBTUSD", 1, -100, 40000, 0),
+				tradeFill("ETHUSD", 1, -10, 3100, 0),
+				{Symbol: "XBTUSD", Side: "Buy", LastQty: 100, LastPx: 40000, ExecType: "Funding",
+					TransactTime: tradeBase.Add(2 * time.Hour).Format(TimeLayout)},
+				tradeFill("XBTUSD", 3, 100, 40000, 0),
+			},
+			want: []tripSummary{{
+				Symbol: "XBTUSD", Side: "Short", Open: 1, Close: 3, Fills: 2, MaxQty: 100, EntryQty: 100, ExitQty: 100,
+				EntryPrice: 40000, ExitPrice: 40000,
+			}},
+			wantUnknown: []string{"DOGEUSDT", "ETHUSD"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 成交按时间排序后处理，输入顺序不影响结果
+			reversed := make([]Execution, len(tt.executions))
+			for i, e := range tt.executions {
+				reversed[len(reversed)-1-i] = e
+			}
+			trips, unknown := ReconstructTrades(reversed, catalog, xbtPrice)
+			if !reflect.DeepEqual(unknown, tt.wantUnknown) {
+				t.Errorf("unknown = %v，应为 %v", unknown, tt.wantUnknown)
+			}
+			if len(trips) != len(tt.want) {
+				t.Fatalf("得到 %d 个交易，应为 %d: %+v", len(trips), len(tt.want), trips)
+			}
+			for i, trip := range trips {
+				if got := summarizeTrip(trip); !tripsMatch(got, tt.want[i]) {
+					t.Errorf("交易 %d = %+v\n应为 %+v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyExcursions(t *testing.T) {
+	at := func(hour int) time.Time { return tradeBase.Add(time.Duration(hour) * time.Hour) }
+	kline := func(hour int, high, low float64) Kline {
+		return Kline{Timestamp: at(hour), Symbol: "XBTUSD", High: high, Low: low}
+	}
+	// 1小时K线，时间为结束时间
+	klines := []Kline{
+		kline(0, 60000, 20000), // 开仓之前结束，不计入
+		kline(1, 41000, 39000),
+		kline(2, 42000, 38500),
+		kline(3, 40500, 36000),
+		kline(4, 50000, 30000),
+		kline(5, 37000, 35000),
+		kline(6, 90000, 10000), // 开始于 now 之后，不计入
+	}
+	trips := []RoundTrip{
+		{Symbol: "XBTUSD", Side: "Short", OpenTime: at(0), CloseTime: at(3), EntryPrice: 40000},
+		{Symbol: "XBTUSD", Side: "Long", OpenTime: at(3), EntryPrice: 36000}, // 未平仓，计算到 now
+		{Symbol: "XBTUSDT", Side: "Long", OpenTime: at(0), CloseTime: at(3), EntryPrice: 40000},
+		{Symbol: "XBTUSD", Side: "Long", OpenTime: at(10), CloseTime: at(11), EntryPrice: 40000}, // 没有K线
+	}
+	ApplyExcursions(trips, "XBTUSD", klines, time.Hour, at(5))
+
+	tests := []struct {
+		mae, mfe float64
+	}{
+		// 空头：最高 42000 为不利波动 −5%，最低 36000 为有利波动 10%
+		{-5, 10},
+		// 多头：最低 30000 为 −16.67%，最高 50000 为 38.89%
+		{(30000.0/36000 - 1) * 100, (50000.0/36000 - 1) * 100},
+		{0, 0},
+		{0, 0},
+	}
+	for i, tt := range tests {
+		if !approx(trips[i].MAE, tt.mae) || !approx(trips[i].MFE, tt.mfe) {
+			t.Errorf("交易 %d MAE / MFE = %.4f / %.4f，应为 %.4f / %.4f", i, trips[i].MAE, trips[i].MFE, tt.mae, tt.mfe)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package bitmex
+
+import "time"
+
+// SatoshiPerBTC 1 BTC = 100,000,000 Satoshi（BitMEX 的 XBt 单位）
+const SatoshiPerBTC = 100000000.0
+
+// currencyScale 结算币种最小单位与主单位的比例（XBt 为 Satoshi，USDt 为 0.000001 USDT）
+var currencyScale = map[string]float64{"XBt": SatoshiPerBTC, "USDt": 1e6}
+
+// FromMinorUnits 将最小单位的金额换算为主单位（XBt → BTC，USDt → USDT），未知币种原样返回
+func FromMinorUnits(currency string, amount int64) float64 {
+	if scale, ok := currencyScale[currency]; ok {
+		return float64(amount) / scale
+	}
+	return float64(amount)
+}
+
+// Execution 单笔成交记录（/execution）
+type Execution struct {
+	ExecID       string  `json:"execID"`
+	OrderID      string  `json:"orderID"`
+	ClOrdID      string  `json:"clOrdID"`
+	Account      int     `json:"account"`
+	Symbol       string  `json:"symbol"`
+	Side         string  `json:"side"`
+	LastQty      int     `json:"lastQty"`
+	LastPx       float64 `json:"lastPx"`
+	OrderQty     int     `json:"orderQty"`
+	Price        float64 `json:"price"`
+	LeavesQty    int     `json:"leavesQty"`
+	CumQty       int     `json:"cumQty"`
+	AvgPx        float64 `json:"avgPx"`
+	Commission   float64 `json:"commission"`
+	TransactTime string  `json:"transactTime"`
+	Timestamp    string  `json:"timestamp"`
+	OrdType      string  `json:"ordType"`
+	ExecType     string  `json:"execType"`
+	OrdStatus    string  `json:"ordStatus"`
+	Currency     string  `json:"currency"`
+	Text         string  `json:"text"`
+}
+
+// Time 成交时间（TransactTime），无法解析时返回零值
+func (e Execution) Time() time.Time {
+	return parseTime(e.TransactTime)
+}
+
+// WalletHistory 钱包历史记录（/user/walletHistory），金额单位为 Satoshi
+type WalletHistory struct {
+	TransactID     string `json:"transactID"`
+	TransactType   string `json:"transactType"`
+	TransactStatus string `json:"transactStatus"`
+	Account        int    `json:"account"`
+	Currency       string `json:"currency"`
+	Amount         int64  `json:"amount"`
+	Fee            int64  `json:"fee"`
+	WalletBalance  int64  `json:"walletBalance"`
+	MarginBalance  int64  `json:"marginBalance"`
+	Timestamp      string `json:"timestamp"`
+	Address        string `json:"address"`
+	Tx             string `json:"tx"`
+	Text           string `json:"text"`
+}
+
+// Time 记录时间，无法解析时返回零值
+func (h WalletHistory) Time() time.Time {
+	return parseTime(h.Timestamp)
+}
+
+// AmountBTC 金额（BTC）
+func (h WalletHistory) AmountBTC() float64 {
+	return float64(h.Amount) / SatoshiPerBTC
+}
+
+// WalletBalanceBTC 钱包余额（BTC）
+func (h WalletHistory) WalletBalanceBTC() float64 {
+	return float64(h.WalletBalance) / SatoshiPerBTC
+}
+
+// Order 订单记录（/order）
+type Order struct {
+	OrderID               string  `json:"orderID"`
+	ClOrdID               string  `json:"clOrdID"`
+	ClOrdLinkID           string  `json:"clOrdLinkID"`
+	Account               int     `json:"account"`
+	Symbol                string  `json:"symbol"`
+	Side                  string  `json:"side"`
+	SimpleOrderQty        float64 `json:"simpleOrderQty"`
+	OrderQty              int     `json:"orderQty"`
+	Price                 float64 `json:"price"`
+	DisplayQty            int     `json:"displayQty"`
+	StopPx                float64 `json:"stopPx"`
+	PegOffsetValue        float64 `json:"pegOffsetValue"`
+	PegPriceType          string  `json:"pegPriceType"`
+	Currency              string  `json:"currency"`
+	SettlCurrency         string  `json:"settlCurrency"`
+	OrdType               string  `json:"ordType"`
+	TimeInForce           string  `json:"timeInForce"`
+	ExecInst              string  `json:"execInst"`
+	ContingencyType       string  `json:"contingencyType"`
+	ExDestination         string  `json:"exDestination"`
+	OrdStatus             string  `json:"ordStatus"`
+	Triggered             string  `json:"triggered"`
+	WorkingIndicator      bool    `json:"workingIndicator"`
+	OrdRejReason          string  `json:"ordRejReason"`
+	SimpleLeavesQty       float64 `json:"simpleLeavesQty"`
+	LeavesQty             int     `json:"leavesQty"`
+	SimpleCumQty          float64 `json:"simpleCumQty"`
+	CumQty                int     `json:"cumQty"`
+	AvgPx                 float64 `json:"avgPx"`
+	MultiLegReportingType string  `json:"multiLegReportingType"`
+	Text                  string  `json:"text"`
+	TransactTime          string  `json:"transactTime"`
+	Timestamp             string  `json:"timestamp"`
+}
+
+// Time 下单时间（TransactTime），无法解析时返回零值
+func (o Order) Time() time.Time {
+	return parseTime(o.TransactTime)
+}
+
+// Kline K线数据（/trade/bucketed）
+type Kline struct {
+	Timestamp time.Time `json:"timestamp"`
+	Symbol    string    `json:"symbol"`
+	Open      float64   `json:"open"`
+	High      float64   `json:"high"`
+	Low       float64   `json:"low"`
+	Close     float64   `json:"close"`
+	Volume    int64     `json:"volume"`
+	Trades    int       `json:"trades"`
+}
+
+// Position 当前持仓（/position）
+type Position struct {
+	Account          int     `json:"account"`
+	Symbol           string  `json:"symbol"`
+	Currency         string  `json:"currency"`
+	Underlying       string  `json:"underlying"`
+	QuoteCurrency    string  `json:"quoteCurrency"`
+	Leverage         float64 `json:"leverage"`
+	CrossMargin      bool    `json:"crossMargin"`
+	IsOpen           bool    `json:"isOpen"`
+	CurrentQty       int     `json:"currentQty"`
+	AvgEntryPrice    float64 `json:"avgEntryPrice"`
+	MarkPrice        float64 `json:"markPrice"`
+	MarkValue        int64   `json:"markValue"`
+	LiquidationPrice float64 `json:"liquidationPrice"`
+	HomeNotional     float64 `json:"homeNotional"`
+	ForeignNotional  float64 `json:"foreignNotional"`
+	PosMargin        int64   `json:"posMargin"`
+	MaintMargin      int64   `json:"maintMargin"`
+	UnrealisedPnl    int64   `json:"unrealisedPnl"`
+	RealisedPnl      int64   `json:"realisedPnl"`
+	Timestamp        string  `json:"timestamp"`
+}
+
+// Margin 保证金账户（/user/margin），金额单位为该币种最小单位（XBt 为 Satoshi）
+type Margin struct {
+	Account         int     `json:"account"`
+	Currency        string  `json:"currency"`
+	Amount          int64   `json:"amount"`
+	WalletBalance   int64   `json:"walletBalance"`
+	MarginBalance   int64   `json:"marginBalance"`
+	AvailableMargin int64   `json:"availableMargin"`
+	UnrealisedPnl   int64   `json:"unrealisedPnl"`
+	RealisedPnl     int64   `json:"realisedPnl"`
+	InitMargin      int64   `json:"initMargin"`
+	MaintMargin     int64   `json:"maintMargin"`
+	RiskValue       int64   `json:"riskValue"`
+	MarginLeverage  float64 `json:"marginLeverage"`
+	Timestamp       string  `json:"timestamp"`
+}
+
+// Wallet 钱包余额（实时 wallet 表），金额单位为该币种最小单位（XBt 为 Satoshi）。
+// 只有当前状态，没有 transactID，出入金记录来自 transact 表（与 /user/walletHistory 格式相同）
+type Wallet struct {
+	Account       int    `json:"account"`
+	Currency      string `json:"currency"`
+	Deposited     int64  `json:"deposited"`
+	Withdrawn     int64  `json:"withdrawn"`
+	TransferIn    int64  `json:"transferIn"`
+	TransferOut   int64  `json:"transferOut"`
+	Amount        int64  `json:"amount"`
+	PendingCredit int64  `json:"pendingCredit"`
+	PendingDebit  int64  `json:"pendingDebit"`
+	Timestamp     string `json:"timestamp"`
+}
+
+// Instrument 合约信息（/instrument）
+type Instrument struct {
+	Symbol                       string  `json:"symbol"`
+	RootSymbol                   string  `json:"rootSymbol"`
+	State                        string  `json:"state"`
+	Typ                          string  `json:"typ"`
+	Underlying                   string  `json:"underlying"`
+	QuoteCurrency                string  `json:"quoteCurrency"`
+	SettlCurrency                string  `json:"settlCurrency"`
+	PositionCurrency             string  `json:"positionCurrency"`
+	Multiplier                   int64   `json:"multiplier"`
+	IsQuanto                     bool    `json:"isQuanto"`
+	IsInverse                    bool    `json:"isInverse"`
+	TickSize                     float64 `json:"tickSize"`
+	LotSize                      float64 `json:"lotSize"`
+	UnderlyingToSettleMultiplier float64 `json:"underlyingToSettleMultiplier"`
+	QuoteToSettleMultiplier      float64 `json:"quoteToSettleMultiplier"`
+	LastPrice                    float64 `json:"lastPrice"`
+	MarkPrice                    float64 `json:"markPrice"`
+	FundingRate                  float64 `json:"fundingRate"`
+	FundingInterval              string  `json:"fundingInterval"`
+	FundingTimestamp             string  `json:"fundingTimestamp"`
+	Expiry                       string  `json:"expiry"`
+	Timestamp                    string  `json:"timestamp"`
+}
+
+// parseTime 解析 BitMEX 的 RFC3339 时间
+func parseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
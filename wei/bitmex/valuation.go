@@ -0,0 +1,232 @@
+package bitmex
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// PriceSource 美元估值使用的 XBTUSD 价格
+type PriceSource string
+
+const (
+	PriceClose PriceSource = "close" // 截至该时刻最后一根已结束K线的收盘价
+	PriceVWAP  PriceSource = "vwap"  // 当日（UTC）成交量加权平均价，由当日K线的典型价格 (H+L+C)/3 按成交量加权
+)
+
+// ParsePriceSource 解析价格来源，空字符串为 PriceClose
+func ParsePriceSource(s string) (PriceSource, error) {
+	switch PriceSource(s) {
+	case "", PriceClose:
+		return PriceClose, nil
+	case PriceVWAP:
+		return PriceVWAP, nil
+	}
+	return "", fmt.Errorf("未知价格来源: %s（可用: close, vwap）", s)
+}
+
+// PriceSeries XBTUSD 历史价格，用于把 BTC 金额换算为美元。K线周期越小，收盘价越接近记录时刻的价格
+type PriceSeries struct {
+	Source  PriceSource
+	BinSize time.Duration
+
+	klines []Kline            // 按时间排序，时间为K线结束时间
+	vwap   map[string]float64 // UTC 日期 → 当日 VWAP
+}
+
+// NewPriceSeries 由 binSize 周期的 XBTUSD K线创建价格序列
+func NewPriceSeries(klines []Kline, binSize time.Duration, source PriceSource) *PriceSeries {
+	sorted := make([]Kline, len(klines))
+	copy(sorted, klines)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp.Before(sorted[j].Timestamp) })
+
+	s := &PriceSeries{Source: source, BinSize: binSize, klines: sorted}
+	if source == PriceVWAP {
+		type sums struct {
+			value, volume, typical float64
+			count                  int
+		}
+		days := make(map[string]*sums)
+		for _, k := range sorted {
+			day := k.Timestamp.Add(-binSize).UTC().Format("2006-01-02")
+			d, ok := days[day]
+			if !ok {
+				d = &sums{}
+				days[day] = d
+			}
+			typical := (k.High + k.Low + k.Close) / 3
+			d.value += typical * float64(k.Volume)
+			d.volume += float64(k.Volume)
+			d.typical += typical
+			d.count++
+		}
+		s.vwap = make(map[string]float64, len(days))
+		for day, d := range days {
+			if d.volume > 0 {
+				s.vwap[day] = d.value / d.volume
+			} else {
+				s.vwap[day] = d.typical / float64(d.count)
+			}
+		}
+	}
+	return s
+}
+
+// Len K线数量
+func (s *PriceSeries) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.klines)
+}
+
+// At 时刻 t 的 XBTUSD 价格，没有价格时返回 0。VWAP 没有当日K线时使用收盘价
+func (s *PriceSeries) At(t time.Time) float64 {
+	if s == nil {
+		return 0
+	}
+	if s.Source == PriceVWAP {
+		if p, ok := s.vwap[t.UTC().Format("2006-01-02")]; ok {
+			return p
+		}
+	}
+	return ClosePriceAt(s.klines, t)
+}
+
+// ToUSD 将结算币种主单位（BTC / USDT）的金额换算为美元，BTC 按 xbtPrice 换算。
+// 无法换算（未知币种或没有价格）时返回 false
+func ToUSD(currency string, amount, xbtPrice float64) (float64, bool) {
+	switch currency {
+	case "XBt", "XBT":
+		if xbtPrice <= 0 {
+			return 0, false
+		}
+		return amount * xbtPrice, true
+	case "USDt", "USDT":
+		return amount, true
+	}
+	return 0, false
+}
+
+// USD 将 at 时刻的主单位金额换算为美元，无法换算时返回 0
+func (s *PriceSeries) USD(currency string, amount float64, at time.Time) float64 {
+	usd, _ := ToUSD(currency, amount, s.At(at))
+	return usd
+}
+
+// WalletUSDFile 带美元估值的钱包历史（valuation 命令生成）
+const WalletUSDFile = "wallet_usd.csv"
+
+// WalletUSDHeader wallet_usd.csv 表头
+var WalletUSDHeader = []string{
+	"TransactID", "TransactType", "TransactStatus", "Currency", "Timestamp", "Address",
+	"Amount", "Fee", "WalletBalance", "XBTUSD", "Amount_USD", "Fee_USD", "WalletBalance_USD",
+}
+
+// ValuedWalletEntry 一条钱包记录及其美元估值，金额为主单位（BTC / USDT）
+type ValuedWalletEntry struct {
+	WalletHistory
+	Price            float64 // 记录时刻的 XBTUSD 价格
+	AmountUSD        float64
+	FeeUSD           float64
+	WalletBalanceUSD float64
+}
+
+// ValueWallet 按记录时刻的价格为每条钱包记录估值
+func ValueWallet(wallet []WalletHistory, prices *PriceSeries) []ValuedWalletEntry {
+	entries := make([]ValuedWalletEntry, len(wallet))
+	for i, h := range wallet {
+		at := h.Time()
+		price := prices.At(at)
+		e := ValuedWalletEntry{WalletHistory: h, Price: price}
+		e.AmountUSD, _ = ToUSD(h.Currency, FromMinorUnits(h.Currency, h.Amount), price)
+		e.FeeUSD, _ = ToUSD(h.Currency, FromMinorUnits(h.Currency, h.Fee), price)
+		e.WalletBalanceUSD, _ = ToUSD(h.Currency, FromMinorUnits(h.Currency, h.WalletBalance), price)
+		entries[i] = e
+	}
+	return entries
+}
+
+// WriteWalletUSD 写入 wallet_usd.csv
+func WriteWalletUSD(filename string, entries []ValuedWalletEntry) error {
+	return writeCSV(filename, WalletUSDHeader, entries, ValuedWalletEntry.record)
+}
+
+func (e ValuedWalletEntry) record() []string {
+	format := func(amount int64) string {
+		return strconv.FormatFloat(FromMinorUnits(e.Currency, amount), 'f', -1, 64)
+	}
+	return []string{
+		e.TransactID,
+		e.TransactType,
+		e.TransactStatus,
+		e.Currency,
+		e.Timestamp,
+		e.Address,
+		format(e.Amount),
+		format(e.Fee),
+		format(e.WalletBalance),
+		fmt.Sprintf("%.2f", e.Price),
+		fmt.Sprintf("%.2f", e.AmountUSD),
+		fmt.Sprintf("%.2f", e.FeeUSD),
+		fmt.Sprintf("%.2f", e.WalletBalanceUSD),
+	}
+}
+
+// YearValuation 一年的钱包盈亏（RealisedPNL + Funding），分别以 BTC 和美元计
+type YearValuation struct {
+	Year         string  `json:"year"`
+	PnLBTC       float64 `json:"pnlBtc"`       // XBt 盈亏合计
+	PnLUSD       float64 `json:"pnlUsd"`       // 每条记录按当时价格换算为美元后的合计（含 USDT 盈亏）
+	StartBalance float64 `json:"startBalance"` // 年初 XBt 余额（BTC）
+	EndBalance   float64 `json:"endBalance"`   // 年末 XBt 余额（BTC）
+	StartUSD     float64 `json:"startUsd"`     // 年初余额的美元价值
+	EndUSD       float64 `json:"endUsd"`       // 年末余额的美元价值
+}
+
+// ValueYears 按年汇总钱包盈亏的 BTC 和美元金额，以及年初 / 年末余额的美元价值
+func ValueYears(entries []ValuedWalletEntry, prices *PriceSeries) []YearValuation {
+	sorted := make([]ValuedWalletEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.TransactStatus != "Canceled" && e.TransactStatus != "Pending" {
+			sorted = append(sorted, e)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time().Before(sorted[j].Time()) })
+
+	var years []YearValuation
+	var balance float64
+	for _, e := range sorted {
+		year := strconv.Itoa(e.Time().UTC().Year())
+		if len(years) == 0 || years[len(years)-1].Year != year {
+			start, _ := time.Parse("2006", year)
+			years = append(years, YearValuation{
+				Year:         year,
+				StartBalance: balance,
+				EndBalance:   balance,
+				StartUSD:     balance * prices.At(start),
+			})
+		}
+		y := &years[len(years)-1]
+		if e.Currency == "XBt" {
+			balance = FromMinorUnits(e.Currency, e.WalletBalance)
+			y.EndBalance = balance
+		}
+		if e.TransactType == "RealisedPNL" || e.TransactType == "Funding" {
+			if e.Currency == "XBt" {
+				y.PnLBTC += FromMinorUnits(e.Currency, e.Amount)
+			}
+			y.PnLUSD += e.AmountUSD
+		}
+	}
+	for i := range years {
+		end, _ := time.Parse("2006", years[i].Year)
+		at := end.AddDate(1, 0, 0)
+		if i == len(years)-1 && len(sorted) > 0 {
+			at = sorted[len(sorted)-1].Time()
+		}
+		years[i].EndUSD = years[i].EndBalance * prices.At(at)
+	}
+	return years
+}
@@ -0,0 +1,111 @@
+package bitmex
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// valuationKlines XBTUSD 1小时K线（时间为结束时间，倒序给出，检验会重新排序）：
+// 3 月 1 日的典型价格 100 / 120 / 200，成交量 10 / 30 / 10，VWAP = 6600 / 50 = 132；
+// 3 月 2 日没有成交量，VWAP 为典型价格 290 / 310 的平均 300
+func valuationKlines() []Kline {
+	kline := func(day, hour int, high, low, close float64, volume int64) Kline {
+		return Kline{Timestamp: time.Date(2024, 3, day, hour, 0, 0, 0, time.UTC), Symbol: "XBTUSD",
+			Open: close, High: high, Low: low, Close: close, Volume: volume}
+	}
+	return []Kline{
+		kline(2, 2, 320, 300, 310, 0),
+		kline(2, 1, 300, 270, 300, 0),
+		kline(2, 0, 220, 180, 200, 10), // 3 月 1 日 23:00 开始，属于 3 月 1 日
+		kline(1, 2, 130, 110, 120, 30),
+		kline(1, 1, 110, 90, 100, 10),
+	}
+}
+
+func TestPriceSeriesAt(t *testing.T) {
+	at := func(day, hour, minute int) time.Time { return time.Date(2024, 3, day, hour, minute, 0, 0, time.UTC) }
+	closes := NewPriceSeries(valuationKlines(), time.Hour, PriceClose)
+	vwap := NewPriceSeries(valuationKlines(), time.Hour, PriceVWAP)
+	if closes.Len() != 5 || (*PriceSeries)(nil).Len() != 0 {
+		t.Errorf("Len = %d，应为 5", closes.Len())
+	}
+
+	tests := []struct {
+		name       string
+		at         time.Time
+		close, avg float64
+	}{
+		// 第一根K线 01:00 才结束，之前没有价格；VWAP 使用当日全部K线
+		{"第一根K线结束之前", at(1, 0, 30), 0, 132},
+		{"第一根K线结束时", at(1, 1, 0), 100, 132},
+		{"下一根K线未结束", at(1, 1, 59), 100, 132},
+		{"K线结束时间为次日零点", at(2, 0, 0), 200, 300},
+		{"当日没有成交量", at(2, 1, 30), 300, 300},
+		// 3 月 3 日没有K线，VWAP 使用最后的收盘价
+		{"最后一根K线之后", at(3, 12, 0), 310, 310},
+		{"上个月", time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := closes.At(tt.at); !approx(got, tt.close) {
+				t.Errorf("收盘价 At(%v) = %v，应为 %v", tt.at, got, tt.close)
+			}
+			if got := vwap.At(tt.at); !approx(got, tt.avg) {
+				t.Errorf("VWAP At(%v) = %v，应为 %v", tt.at, got, tt.avg)
+			}
+		})
+	}
+	if got := (*PriceSeries)(nil).At(at(1, 12, 0)); got != 0 {
+		t.Errorf("nil 价格序列 At = %v，应为 0", got)
+	}
+}
+
+func TestToUSD(t *testing.T) {
+	tests := []struct {
+		currency string
+		amount   float64
+		price    float64
+		want     float64
+		ok       bool
+	}{
+		{"XBt", 0.5, 40000, 20000, true},
+		{"XBT", -0.25, 40000, -10000, true},
+		{"XBt", 0.5, 0, 0, false},
+		{"USDt", 12.5, 40000, 12.5, true},
+		{"USDT", 12.5, 0, 12.5, true},
+		{"ETH", 1, 40000, 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := ToUSD(tt.currency, tt.amount, tt.price); got != tt.want || ok != tt.ok {
+			t.Errorf("ToUSD(%s, %v, %v) = %v, %v，应为 %v, %v", tt.currency, tt.amount, tt.price, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestValueWallet(t *testing.T) {
+	wallet := []WalletHistory{
+		{TransactID: "w1", TransactType: "Deposit", Currency: "XBt", Amount: 100000000, Fee: 1000000, WalletBalance: 200000000,
+			Timestamp: "2024-03-01T02:30:00.000Z"},
+		{TransactID: "w2", TransactType: "RealisedPNL", Currency: "USDt", Amount: 5000000, WalletBalance: 25000000,
+			Timestamp: "2024-03-01T02:30:00.000Z"},
+		// 第一根K线结束之前没有价格
+		{TransactID: "w3", TransactType: "Deposit", Currency: "XBt", Amount: 100000000, WalletBalance: 100000000,
+			Timestamp: "2024-03-01T00:30:00.000Z"},
+	}
+	got := ValueWallet(wallet, NewPriceSeries(valuationKlines(), time.Hour, PriceClose))
+	want := []ValuedWalletEntry{
+		{WalletHistory: wallet[0], Price: 120, AmountUSD: 120, FeeUSD: 1.2, WalletBalanceUSD: 240},
+		{WalletHistory: wallet[1], Price: 120, AmountUSD: 5, WalletBalanceUSD: 25},
+		{WalletHistory: wallet[2]},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ValueWallet = %+v\n应为 %+v", got, want)
+	}
+
+	// VWAP 按记录当日的均价
+	got = ValueWallet(wallet[:1], NewPriceSeries(valuationKlines(), time.Hour, PriceVWAP))
+	if e := got[0]; e.Price != 132 || !approx(e.AmountUSD, 132) || !approx(e.WalletBalanceUSD, 264) {
+		t.Errorf("VWAP 估值 = %+v，应为价格 132、金额 132、余额 264", e)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"binance-kline/wei/bitmex"
+)
+
+// runCredentials 管理加密凭证文件: credentials set|remove|list [-account main]
+func runCredentials(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: credentials set|remove|list [-account main]")
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("credentials "+action, flag.ExitOnError)
+	account := fs.String("account", bitmex.DefaultAccount, "账户名")
+	fs.Parse(args[1:])
+
+	fmt.Print("=== BitMEX API 凭证管理工具 ===\n\n")
+
+	path := bitmex.EncryptedCredentialsFile()
+	fmt.Printf("加密凭证文件: %s\n\n", path)
+
+	switch action {
+	case "set":
+		return setAccount(path, *account)
+	case "remove":
+		return removeAccount(path, *account)
+	case "list":
+		return listAccounts(path)
+	default:
+		return fmt.Errorf("未知操作: %s（可用: set, remove, list）", action)
+	}
+}
+
+// openAccounts 读取已有的加密凭证文件，文件不存在时要求输入两次新口令
+func openAccounts(path string) (map[string]bitmex.Credentials, string, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		fmt.Println("凭证文件不存在，将创建新文件")
+		passphrase, err := bitmex.ReadPassphrase("设置口令: ")
+		if err != nil {
+			return nil, "", err
+		}
+		if os.Getenv("BITMEX_PASSPHRASE") == "" {
+			confirm, err := bitmex.ReadSecret("再次输入口令: ")
+			if err != nil {
+				return nil, "", err
+			}
+			if confirm != passphrase {
+				return nil, "", fmt.Errorf("两次输入的口令不一致")
+			}
+		}
+		return make(map[string]bitmex.Credentials), passphrase, nil
+	}
+
+	passphrase, err := bitmex.ReadPassphrase("请输入口令: ")
+	if err != nil {
+		return nil, "", err
+	}
+	accounts, err := bitmex.LoadEncryptedAccounts(path, passphrase)
+	if err != nil {
+		return nil, "", err
+	}
+	return accounts, passphrase, nil
+}
+
+// setAccount 添加或更新账户，API Key/Secret 从环境变量或终端读取
+func setAccount(path, account string) error {
+	accounts, passphrase, err := openAccounts(path)
+	if err != nil {
+		return err
+	}
+
+	creds := bitmex.Credentials{
+		APIKey:    os.Getenv("BITMEX_API_KEY"),
+		APISecret: os.Getenv("BITMEX_API_SECRET"),
+	}
+	if creds.APIKey == "" {
+		if creds.APIKey, err = bitmex.ReadSecret("API Key (ID): "); err != nil {
+			return err
+		}
+	}
+	if creds.APISecret == "" {
+		if creds.APISecret, err = bitmex.ReadSecret("API Secret: "); err != nil {
+			return err
+		}
+	}
+	if creds.APIKey == "" || creds.APISecret == "" {
+		return fmt.Errorf("API Key 和 Secret 不能为空")
+	}
+
+	accounts[account] = creds
+	if err := bitmex.SaveEncryptedAccounts(path, passphrase, accounts); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ 已保存账户 %s (API Key: %s)\n", account, creds.Masked())
+	return nil
+}
+
+// removeAccount 删除账户
+func removeAccount(path, account string) error {
+	accounts, passphrase, err := openAccounts(path)
+	if err != nil {
+		return err
+	}
+	if _, ok := accounts[account]; !ok {
+		return fmt.Errorf("账户 %s 不存在", account)
+	}
+
+	delete(accounts, account)
+	if err := bitmex.SaveEncryptedAccounts(path, passphrase, accounts); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ 已删除账户 %s\n", account)
+	return nil
+}
+
+// listAccounts 列出所有账户（API Key 脱敏显示）
+func listAccounts(path string) error {
+	accounts, _, err := openAccounts(path)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(accounts))
+	for name := range accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("共 %d 个账户:\n", len(names))
+	for _, name := range names {
+		fmt.Printf("  %-12s %s\n", name, accounts[name].Masked())
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// runFunding 处理 funding 子命令：由 wallet.csv 和 executions.csv 生成资金费用明细并汇总
+func runFunding(args []string) error {
+	fs := flag.NewFlagSet("funding", flag.ExitOnError)
+	by := fs.String("by", "month", "汇总方式 (day, month, symbol, all)")
+	symbol := fs.String("symbol", "", "只统计指定合约，如 XBTUSD")
+	from := fs.String("from", "", "起始日期（含），如 2024-01-01")
+	to := fs.String("to", "", "结束日期（不含），如 2025-01-01")
+	output := fs.String("o", bitmex.FundingFile, "资金费用明细输出文件，为空时不写入")
+	fs.Parse(args)
+
+	fmt.Print("=== BitMEX 资金费用统计 ===\n\n")
+	if _, err := bitmex.SummarizeFunding(nil, *by); err != nil {
+		return err
+	}
+	fromTime, err := parseDate(*from)
+	if err != nil {
+		return err
+	}
+	toTime, err := parseDate(*to)
+	if err != nil {
+		return err
+	}
+
+	wallet, err := bitmex.ReadWalletHistory(bitmex.WalletFile)
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w（请先运行 sync wallet）", bitmex.WalletFile, err)
+	}
+	executions, err := bitmex.ReadExecutions(bitmex.ExecutionsFile)
+	if os.IsNotExist(err) {
+		fmt.Printf("⚠ 未找到 %s，没有费率、标记价格和结算时持仓\n", bitmex.ExecutionsFile)
+	} else if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w", bitmex.ExecutionsFile, err)
+	}
+
+	ledger := bitmex.BuildFundingLedger(wallet, executions)
+	if *output != "" {
+		if err := bitmex.WriteFunding(*output, ledger); err != nil {
+			return fmt.Errorf("保存 %s 失败: %w", *output, err)
+		}
+		fmt.Printf("✓ %s 现有 %d 条资金费用记录\n\n", *output, len(ledger))
+	}
+
+	ledger = bitmex.FilterFunding(ledger, *symbol, fromTime, toTime)
+	if len(ledger) == 0 {
+		fmt.Println("没有符合条件的资金费用记录")
+		return nil
+	}
+	fmt.Printf("时间范围: %s ~ %s，共 %d 次结算\n\n",
+		ledger[0].Time.Format("2006-01-02"), ledger[len(ledger)-1].Time.Format("2006-01-02"), len(ledger))
+
+	if *by != "all" {
+		totals, _ := bitmex.SummarizeFunding(ledger, *by)
+		for _, t := range totals {
+			printFundingTotal(t.Key, t)
+		}
+		fmt.Println()
+	}
+
+	all, _ := bitmex.SummarizeFunding(ledger, "all")
+	for _, t := range all {
+		printFundingTotal("合计", t)
+	}
+	return nil
+}
+
+// printFundingTotal 打印一行汇总
+func printFundingTotal(label string, t bitmex.FundingTotal) {
+	unit := unitName(t.Currency)
+	fmt.Printf("%s: %d 次, 收到 %.8f, 支付 %.8f, 净收入 %+.8f %s", label, t.Count, t.Received, t.Paid, t.Net, unit)
+	if t.AvgRate != 0 {
+		fmt.Printf(", 平均费率 %+.4f%%", t.AvgRate*100)
+	}
+	fmt.Println()
+}
+
+// parseDate 解析 2006-01-02 格式的日期（UTC），空字符串返回零值
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("日期格式错误: %q（应为 2006-01-02）", s)
+	}
+	return t, nil
+}
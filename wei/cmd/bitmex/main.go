@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `BitMEX 数据工具
+
+用法:
+  go run ./cmd/bitmex <命令> [参数]
+
+命令:
+  sync executions [-update] [-account main]     下载交易记录到 executions.csv
+  sync wallet     [-update] [-account main]     下载钱包历史到 wallet.csv
+  sync orders     [-update] [-account main]     下载订单记录到 orders.csv
+  sync klines     [-update] [-symbol XBTUSD] [-timeframe 1d]
+                                                下载K线到 klines_<SYMBOL>_<TF>.csv
+  sync instruments                              下载合约信息到 instruments.csv
+  snapshot        [-account main]               记录当前持仓和保证金到 positions.csv / margin.csv，
+                                                并与 executions.csv 累计的持仓核对
+  funding         [-by month] [-symbol XBTUSD] [-from 2024-01-01] [-to 2025-01-01]
+                                                由 wallet.csv / executions.csv 生成资金费用明细
+                                                funding.csv，按日 / 月 / 合约汇总
+  trades          [-symbol XBTUSD]              由 executions.csv 还原开仓到平仓的完整交易，
+                                                导出 trades.csv（含 MAE / MFE）
+  reconcile       [-tolerance 0.00001]          核对 executions.csv / wallet.csv / orders.csv：订单成交数量、
+                                                每日已实现盈亏、钱包余额连续性，不一致记录导出 reconcile.csv
+  performance     [-unit all] [-months 12]      由 wallet.csv / daily_position.csv 计算权益曲线、回撤、
+                                                夏普 / 索提诺 / 卡玛比率、月度 / 年度收益（BTC 和 USD 计价）
+  valuation       [-price close] [-o wallet_usd.csv]
+                                                按历史 XBTUSD 价格（收盘价 / VWAP）为钱包记录估值，
+                                                导出 wallet_usd.csv，按年对比 BTC 和美元盈亏
+  tax             [-year 2024] [-method fifo] [-tz UTC] [-price close]
+                                                按年度生成美元计价的已实现盈亏（税务批次）、资金费用和手续费，
+                                                导出 tax_lots.csv / tax_summary.csv / tax_report.html
+  stream          [-account main] [-symbol XBTUSD]
+                                                订阅实时数据，持续写入 executions.csv、
+                                                orders.csv 和 klines_<SYMBOL>_1m.csv
+  credentials set    [-account main]            添加/更新加密凭证
+  credentials remove [-account main]            删除加密凭证中的账户
+  credentials list                              列出加密凭证中的账户
+
+使用 "go run ./cmd/bitmex <命令> -h" 查看命令参数
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "sync":
+		err = runSync(os.Args[2:])
+	case "snapshot":
+		err = runSnapshot(os.Args[2:])
+	case "funding":
+		err = runFunding(os.Args[2:])
+	case "trades":
+		err = runTrades(os.Args[2:])
+	case "reconcile":
+		err = runReconcile(os.Args[2:])
+	case "performance":
+		err = runPerformance(os.Args[2:])
+	case "valuation":
+		err = runValuation(os.Args[2:])
+	case "tax":
+		err = runTax(os.Args[2:])
+	case "stream":
+		err = runStream(os.Args[2:])
+	case "credentials":
+		err = runCredentials(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Printf("未知命令: %s\n\n", os.Args[1])
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+}
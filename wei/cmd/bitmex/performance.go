@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"binance-kline/wei/analytics"
+	"binance-kline/wei/bitmex"
+)
+
+// runPerformance 处理 performance 子命令：由 wallet.csv 和 daily_position.csv 计算绩效指标
+func runPerformance(args []string) error {
+	fs := flag.NewFlagSet("performance", flag.ExitOnError)
+	unit := fs.String("unit", "all", "计价单位 (BTC, USD, all)")
+	months := fs.Int("months", 12, "显示最近几个月的月度收益，0 为全部")
+	top := fs.Int("top", 5, "显示最好 / 最差的天数")
+	source := fs.String("price", "close", "美元计价的价格来源 (close, vwap)")
+	fs.Parse(args)
+
+	units := []string{analytics.BTC, analytics.USD}
+	if *unit != "all" {
+		u, err := analytics.ParseUnit(*unit)
+		if err != nil {
+			return err
+		}
+		units = []string{u}
+	}
+
+	fmt.Print("=== BitMEX 账户绩效 ===\n\n")
+	wallet, err := bitmex.ReadWalletHistory(bitmex.WalletFile)
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w（请先运行 sync wallet）", bitmex.WalletFile, err)
+	}
+	daily, err := analytics.ReadDailyPositions(analytics.DailyPositionFile)
+	if os.IsNotExist(err) {
+		fmt.Printf("⚠ 未找到 %s，没有仓位比例（可运行 make daily-position）\n", analytics.DailyPositionFile)
+	} else if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w", analytics.DailyPositionFile, err)
+	}
+	if _, err := bitmex.ParsePriceSource(*source); err != nil {
+		return err
+	}
+	prices, err := loadPriceSeries(*source)
+	if err != nil {
+		fmt.Printf("⚠ %v，美元计价使用 %s 中的价格\n", err, analytics.DailyPositionFile)
+	}
+
+	for _, u := range units {
+		curve := analytics.EquityCurve(wallet, daily, prices.At, u)
+		if len(curve) == 0 {
+			return fmt.Errorf("%s 中没有 XBt 钱包记录", bitmex.WalletFile)
+		}
+		printReport(analytics.Analyze(curve, u, *top), analytics.FlowReturns(curve, wallet, u), *months)
+	}
+	return nil
+}
+
+// printReport 打印一种计价单位的绩效报告
+func printReport(r analytics.Report, ret analytics.Returns, months int) {
+	amount := "%+.8f"
+	if r.Unit == analytics.USD {
+		amount = "%+.2f"
+	}
+
+	fmt.Printf("--- %s 计价（%s ~ %s，%d 天）---\n", r.Unit, r.Start, r.End, r.Days)
+	fmt.Printf("权益: %s → %s %s\n", fmt.Sprintf(amount, r.StartEquity), fmt.Sprintf(amount, r.EndEquity), r.Unit)
+	fmt.Printf("入金: "+amount+", 出金: "+amount+", 交易盈亏 = 期末权益 - 净入金: "+amount+" %s\n",
+		ret.Deposits, ret.Withdrawals, ret.PnL, r.Unit)
+	for _, c := range ret.Contributions {
+		fmt.Printf("  %s: %d 笔, "+amount+"\n", c.Type, c.Count, c.Amount)
+	}
+	fmt.Printf("时间加权收益: %+.2f%%（年化 %+.2f%%）", ret.TimeWeighted, ret.TimeWeightedAnnual)
+	if ret.MoneyWeightedOK {
+		fmt.Printf(", 资金加权收益（IRR）: 年化 %+.2f%%", ret.MoneyWeighted)
+	} else {
+		fmt.Print(", 资金加权收益（IRR）: 无解")
+	}
+	fmt.Println()
+	fmt.Printf("年化波动率: %.2f%%\n", r.Volatility)
+	fmt.Printf("夏普比率: %.2f, 索提诺比率: %.2f, 卡玛比率: %.2f\n", r.Sharpe, r.Sortino, r.Calmar)
+	printDrawdown("最大回撤", r.MaxDrawdown)
+	printDrawdown("最长回撤", r.LongestDrawdown)
+	fmt.Printf("当前回撤: %.2f%%\n", r.CurrentDrawdown)
+	fmt.Printf("盈利 %d 天, 亏损 %d 天（胜率 %.1f%%）, 最长连续盈利 %d 天, 最长连续亏损 %d 天, 当前连续 %+d 天\n",
+		r.WinDays, r.LossDays, r.DayWinRate, r.MaxWinStreak, r.MaxLossStreak, r.CurrentStreak)
+	if r.MaxExposure > 0 {
+		fmt.Printf("仓位比例: 平均 %.2f, 最大 %.2f\n", r.AvgExposure, r.MaxExposure)
+	}
+
+	fmt.Println("\n年度收益:")
+	for _, y := range r.Yearly {
+		fmt.Printf("  %s: %+8.2f%%  盈亏 "+amount+"\n", y.Period, y.Return, y.PnL)
+	}
+	monthly := r.Monthly
+	if months > 0 && len(monthly) > months {
+		monthly = monthly[len(monthly)-months:]
+	}
+	fmt.Printf("\n月度收益（最近 %d 个月）:\n", len(monthly))
+	for _, m := range monthly {
+		fmt.Printf("  %s: %+8.2f%%  盈亏 "+amount+"\n", m.Period, m.Return, m.PnL)
+	}
+
+	fmt.Println("\n最好的几天:")
+	for _, d := range r.BestDays {
+		fmt.Printf("  %s: %+7.2f%%  "+amount+"\n", d.Date, d.Return, d.PnL)
+	}
+	fmt.Println("最差的几天:")
+	for _, d := range r.WorstDays {
+		fmt.Printf("  %s: %+7.2f%%  "+amount+"\n", d.Date, d.Return, d.PnL)
+	}
+	fmt.Println()
+}
+
+// printDrawdown 打印一次回撤
+func printDrawdown(label string, d analytics.Drawdown) {
+	if d.Peak == "" {
+		fmt.Printf("%s: 无\n", label)
+		return
+	}
+	recovery := d.Recovery
+	if recovery == "" {
+		recovery = "未恢复"
+	}
+	fmt.Printf("%s: %.2f%%（高点 %s, 最低 %s, 恢复 %s, 持续 %d 天）\n", label, d.Depth, d.Peak, d.Trough, recovery, d.Days)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"binance-kline/wei/bitmex"
+)
+
+// runReconcile 处理 reconcile 子命令：核对 executions.csv、wallet.csv 和 orders.csv 是否一致
+func runReconcile(args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	tolerance := fs.Float64("tolerance", 0.00001, "每日已实现盈亏允许的误差（BTC / USDT）")
+	output := fs.String("o", bitmex.ReconcileFile, "不一致记录输出文件，为空时不写入")
+	last := fs.Int("n", 10, "每项核对显示的不一致记录数，0 为全部")
+	fs.Parse(args)
+
+	fmt.Print("=== BitMEX 数据核对 ===\n\n")
+	executions, err := bitmex.ReadExecutions(bitmex.ExecutionsFile)
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w（请先运行 sync executions）", bitmex.ExecutionsFile, err)
+	}
+	wallet, err := bitmex.ReadWalletHistory(bitmex.WalletFile)
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w（请先运行 sync wallet）", bitmex.WalletFile, err)
+	}
+	orders, err := bitmex.ReadOrders(bitmex.OrdersFile)
+	if os.IsNotExist(err) {
+		fmt.Printf("⚠ 未找到 %s，跳过订单核对\n", bitmex.OrdersFile)
+	} else if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w", bitmex.OrdersFile, err)
+	}
+	catalog, err := bitmex.LoadCatalog(bitmex.InstrumentsFile)
+	if err != nil {
+		fmt.Printf("⚠ 读取 %s 失败: %v（只能计算 XBT 反向合约，请先运行 sync instruments）\n", bitmex.InstrumentsFile, err)
+	}
+	fmt.Printf("成交记录 %d 条, 钱包记录 %d 条, 订单 %d 个\n\n", len(executions), len(wallet), len(orders))
+
+	var all []bitmex.Discrepancy
+	checks := []struct {
+		name    string
+		skip    bool
+		results func() []bitmex.Discrepancy
+	}{
+		{"订单成交数量（orders.csv CumQty 与成交记录）", orders == nil, func() []bitmex.Discrepancy {
+			return bitmex.CheckOrderFills(orders, executions)
+		}},
+		{fmt.Sprintf("每日已实现盈亏（误差 %g）", *tolerance), false, func() []bitmex.Discrepancy {
+			return bitmex.CheckDailyPnL(wallet, executions, catalog, *tolerance)
+		}},
+		{"钱包余额连续性", false, func() []bitmex.Discrepancy {
+			return bitmex.CheckWalletBalances(wallet)
+		}},
+	}
+	for _, c := range checks {
+		if c.skip {
+			continue
+		}
+		ds := c.results()
+		all = append(all, ds...)
+		if len(ds) == 0 {
+			fmt.Printf("✓ %s: 一致\n", c.name)
+			continue
+		}
+		fmt.Printf("⚠ %s: %d 处不一致\n", c.name, len(ds))
+		shown := ds
+		if *last > 0 && len(shown) > *last {
+			shown = shown[:*last]
+		}
+		for _, d := range shown {
+			printDiscrepancy(d)
+		}
+		if len(shown) < len(ds) {
+			fmt.Printf("    ... 还有 %d 条\n", len(ds)-len(shown))
+		}
+	}
+
+	if *output != "" && len(all) > 0 {
+		if err := bitmex.WriteDiscrepancies(*output, all); err != nil {
+			return fmt.Errorf("保存 %s 失败: %w", *output, err)
+		}
+		fmt.Printf("\n✓ %d 条不一致记录已保存到 %s\n", len(all), *output)
+	}
+	return nil
+}
+
+// printDiscrepancy 打印一条不一致记录
+func printDiscrepancy(d bitmex.Discrepancy) {
+	var label []string
+	if !d.Time.IsZero() {
+		label = append(label, d.Time.Format("2006-01-02 15:04:05"))
+	}
+	if d.Symbol != "" {
+		label = append(label, d.Symbol)
+	}
+	if d.Check == bitmex.CheckOrders {
+		fmt.Printf("    %s %s: %s\n", strings.Join(label, " "), d.ID, d.Detail)
+		return
+	}
+	unit := unitName(d.Currency)
+	fmt.Printf("    %s %s: 应为 %.8f, 实际 %.8f %s（相差 %+.8f）\n", strings.Join(label, " "), d.Detail,
+		d.Expected, d.Actual, unit, d.Difference())
+	fmt.Printf("      ID: %s\n", d.ID)
+}
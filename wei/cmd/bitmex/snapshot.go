@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// runSnapshot 处理 snapshot 子命令：记录交易所当前持仓和保证金，并与成交记录累计的持仓对比
+func runSnapshot(args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	account := fs.String("account", bitmex.DefaultAccount, "账户名（对应环境变量 BITMEX_<ACCOUNT>_API_KEY 或凭证文件中的账户）")
+	baseURL := fs.String("base-url", bitmex.DefaultBaseURL, "API 地址（测试网: https://testnet.bitmex.com/api/v1）")
+	fs.Parse(args)
+
+	fmt.Print("=== BitMEX 持仓与保证金快照 ===\n\n")
+	client := bitmex.NewClient(bitmex.Credentials{})
+	client.BaseURL = *baseURL
+	if err := connect(client, *account, "/execution"); err != nil {
+		return err
+	}
+
+	positions, margins, err := bitmex.TakeSnapshot(client, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := bitmex.AppendSnapshot(bitmex.PositionsFile, bitmex.MarginFile, positions, margins); err != nil {
+		return err
+	}
+
+	if len(positions) == 0 {
+		fmt.Println("当前没有持仓")
+	}
+	for _, p := range positions {
+		fmt.Printf("持仓 %s: 数量 %d, 开仓均价 %.2f, 标记价格 %.2f, 强平价格 %.2f, 杠杆 %.2fx, 未实现盈亏 %.8f %s\n",
+			p.Symbol, p.CurrentQty, p.AvgEntryPrice, p.MarkPrice, p.LiquidationPrice, p.Leverage,
+			bitmex.FromMinorUnits(p.Currency, p.UnrealisedPnl), unitName(p.Currency))
+	}
+	fmt.Println()
+	for _, m := range margins {
+		unit := unitName(m.Currency)
+		fmt.Printf("保证金 %s: 钱包余额 %.8f, 保证金余额 %.8f, 可用 %.8f, 未实现盈亏 %.8f\n", unit,
+			bitmex.FromMinorUnits(m.Currency, m.WalletBalance), bitmex.FromMinorUnits(m.Currency, m.MarginBalance),
+			bitmex.FromMinorUnits(m.Currency, m.AvailableMargin), bitmex.FromMinorUnits(m.Currency, m.UnrealisedPnl))
+	}
+	fmt.Printf("\n✓ 已追加到 %s 和 %s\n", bitmex.PositionsFile, bitmex.MarginFile)
+
+	// 与成交记录累计的持仓对比
+	execs, err := bitmex.ReadExecutions(bitmex.ExecutionsFile)
+	if os.IsNotExist(err) {
+		fmt.Printf("\n⚠ 未找到 %s，跳过持仓核对\n", bitmex.ExecutionsFile)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w", bitmex.ExecutionsFile, err)
+	}
+
+	fmt.Printf("\n持仓核对（%s）:\n", bitmex.ExecutionsFile)
+	for _, c := range bitmex.CheckPositions(positions, execs) {
+		if c.Match {
+			fmt.Printf("  ✓ %s: %d\n", c.Symbol, c.ExchangeQty)
+		} else {
+			fmt.Printf("  ⚠ %s: 交易所 %d, 成交记录累计 %d（相差 %d，可先运行 sync executions -update）\n",
+				c.Symbol, c.ExchangeQty, c.ExecutionsQty, c.ExchangeQty-c.ExecutionsQty)
+		}
+	}
+	return nil
+}
+
+// unitName 结算币种的主单位名称（XBt → BTC，USDt → USDT）
+func unitName(currency string) string {
+	switch currency {
+	case "XBt":
+		return "BTC"
+	case "USDt":
+		return "USDT"
+	}
+	return currency
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// runStream 处理 stream 子命令：订阅实时数据并持续写入 CSV
+func runStream(args []string) error {
+	fs := flag.NewFlagSet("stream", flag.ExitOnError)
+	account := fs.String("account", bitmex.DefaultAccount, "账户名（对应环境变量 BITMEX_<ACCOUNT>_API_KEY 或凭证文件中的账户）")
+	symbol := fs.String("symbol", "XBTUSD", "1分钟K线的交易对符号")
+	streamURL := fs.String("url", bitmex.DefaultStreamURL, "实时接口地址（测试网: wss://ws.testnet.bitmex.com/realtime）")
+	flush := fs.Duration("flush", 5*time.Second, "写入CSV文件的间隔")
+	snapshot := fs.Duration("snapshot", time.Hour, "追加持仓和保证金快照的间隔，0 表示不保存")
+	fs.Parse(args)
+
+	fmt.Print("=== BitMEX 实时数据 ===\n\n")
+	creds, err := bitmex.LoadCredentialsChecked(*account, ".")
+	if err != nil {
+		return fmt.Errorf("加载API凭证失败: %w", err)
+	}
+	fmt.Printf("账户: %s (API Key: %s)\n", *account, creds.Masked())
+
+	klineTopic := "tradeBin1m:" + *symbol
+	recorder := bitmex.NewRecorder()
+	bitmex.RecordTable(recorder, "execution", bitmex.ExecutionsDataset(bitmex.ExecutionsFile))
+	bitmex.RecordTable(recorder, "order", bitmex.OrdersDataset(bitmex.OrdersFile))
+	bitmex.RecordTable(recorder, "transact", bitmex.WalletDataset(bitmex.WalletFile))
+	bitmex.RecordTable(recorder, klineTopic, bitmex.KlinesDataset(bitmex.KlinesFile(*symbol, "1m"), *symbol, "1m"))
+	fmt.Printf("写入: %s, %s, %s, %s（每 %s）\n",
+		bitmex.ExecutionsFile, bitmex.OrdersFile, bitmex.WalletFile, bitmex.KlinesFile(*symbol, "1m"), *flush)
+	if *snapshot > 0 {
+		fmt.Printf("快照: %s, %s（每 %s）\n", bitmex.PositionsFile, bitmex.MarginFile, *snapshot)
+	}
+	fmt.Println()
+
+	// wallet 为钱包余额的当前状态，出入金记录（与 walletHistory 格式相同）来自 transact
+	stream := bitmex.NewStream(creds, "execution", "order", "position", "margin", "wallet", "transact", klineTopic)
+	stream.URL = *streamURL
+	stream.Logf = func(format string, args ...any) { fmt.Printf(format, args...) }
+	stream.OnUpdate = func(u bitmex.TableUpdate) {
+		if err := recorder.Record(u); err != nil {
+			fmt.Printf("⚠ %v\n", err)
+		}
+		printUpdate(u)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(*flush)
+		defer ticker.Stop()
+		var lastSnapshot time.Time
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				flushRecorder(recorder)
+				if *snapshot > 0 && time.Since(lastSnapshot) >= *snapshot && saveSnapshot(stream) {
+					lastSnapshot = time.Now()
+				}
+			}
+		}
+	}()
+
+	err = stream.Run(ctx)
+	close(done)
+	fmt.Println("\n正在保存...")
+	flushRecorder(recorder)
+	if err != nil {
+		return err
+	}
+	fmt.Println("✓ 已停止")
+	return nil
+}
+
+// flushRecorder 写入缓存的实时记录并打印每个文件的变化
+func flushRecorder(recorder *bitmex.Recorder) {
+	results, err := recorder.Flush()
+	files := make([]string, 0, len(results))
+	for file := range results {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		r := results[file]
+		if r.New+r.Updated > 0 {
+			fmt.Printf("✓ %s: 新增 %d 条, 更新 %d 条, 现有 %d 条记录\n", file, r.New, r.Updated, r.Total)
+		}
+	}
+	if err != nil {
+		fmt.Printf("⚠ 写入失败（下次重试）: %v\n", err)
+	}
+}
+
+// saveSnapshot 由实时持仓和保证金表生成快照并追加到 positions.csv / margin.csv，
+// 格式与 snapshot 命令相同。尚未收到初始数据或写入失败时返回 false，下次重试
+func saveSnapshot(stream *bitmex.Stream) bool {
+	positions, margins, err := bitmex.StreamSnapshot(stream, time.Now())
+	if errors.Is(err, bitmex.ErrSnapshotNotReady) {
+		return false
+	}
+	if err == nil {
+		err = bitmex.AppendSnapshot(bitmex.PositionsFile, bitmex.MarginFile, positions, margins)
+	}
+	if err != nil {
+		fmt.Printf("⚠ 保存快照失败（下次重试）: %v\n", err)
+		return false
+	}
+	fmt.Printf("✓ 快照: %d 个持仓, %d 个币种\n", len(positions), len(margins))
+	return true
+}
+
+// printUpdate 打印一条实时消息的摘要
+func printUpdate(u bitmex.TableUpdate) {
+	if u.Action == "partial" {
+		fmt.Printf("✓ %s 初始数据 %d 条\n", u.Table, len(u.Rows))
+		return
+	}
+	if u.Action == "delete" {
+		return
+	}
+
+	switch u.Table {
+	case "execution":
+		execs, _ := bitmex.DecodeRows[bitmex.Execution](u.Rows)
+		for _, e := range execs {
+			fmt.Printf("[成交] %s %s %s %d @ %.2f (%s)\n", e.TransactTime, e.Symbol, e.Side, e.LastQty, e.LastPx, e.ExecType)
+		}
+	case "order":
+		orders, _ := bitmex.DecodeRows[bitmex.Order](u.Rows)
+		for _, o := range orders {
+			fmt.Printf("[订单] %s %s %s %d @ %.2f 已成交 %d 状态 %s\n", o.OrderID, o.Symbol, o.Side, o.OrderQty, o.Price, o.CumQty, o.OrdStatus)
+		}
+	case "position":
+		positions, _ := bitmex.DecodeRows[bitmex.Position](u.Rows)
+		for _, p := range positions {
+			fmt.Printf("[持仓] %s 数量 %d 均价 %.2f 标记价 %.2f 未实现盈亏 %.8f\n",
+				p.Symbol, p.CurrentQty, p.AvgEntryPrice, p.MarkPrice, float64(p.UnrealisedPnl)/bitmex.SatoshiPerBTC)
+		}
+	case "margin":
+		margins, _ := bitmex.DecodeRows[bitmex.Margin](u.Rows)
+		for _, m := range margins {
+			fmt.Printf("[保证金] %s 钱包 %.8f 保证金余额 %.8f 可用 %.8f\n", m.Currency,
+				float64(m.WalletBalance)/bitmex.SatoshiPerBTC, float64(m.MarginBalance)/bitmex.SatoshiPerBTC, float64(m.AvailableMargin)/bitmex.SatoshiPerBTC)
+		}
+	case "wallet":
+		wallets, _ := bitmex.DecodeRows[bitmex.Wallet](u.Rows)
+		for _, w := range wallets {
+			fmt.Printf("[钱包] %s 余额 %.8f 待入账 %.8f 待出账 %.8f\n", w.Currency,
+				float64(w.Amount)/bitmex.SatoshiPerBTC, float64(w.PendingCredit)/bitmex.SatoshiPerBTC, float64(w.PendingDebit)/bitmex.SatoshiPerBTC)
+		}
+	case "transact":
+		history, _ := bitmex.DecodeRows[bitmex.WalletHistory](u.Rows)
+		for _, h := range history {
+			fmt.Printf("[出入金] %s %s %s %.8f 手续费 %.8f 状态 %s\n", h.Timestamp, h.Currency, h.TransactType,
+				float64(h.Amount)/bitmex.SatoshiPerBTC, float64(h.Fee)/bitmex.SatoshiPerBTC, h.TransactStatus)
+		}
+	case "tradeBin1m":
+		klines, _ := bitmex.DecodeRows[bitmex.Kline](u.Rows)
+		for _, k := range klines {
+			fmt.Printf("[K线] %s %s 开 %.2f 高 %.2f 低 %.2f 收 %.2f 量 %d\n",
+				k.Timestamp.Format("2006-01-02 15:04"), k.Symbol, k.Open, k.High, k.Low, k.Close, k.Volume)
+		}
+	}
+}
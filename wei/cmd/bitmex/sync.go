@@ -0,0 +1,203 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// runSync 处理 sync 子命令: sync executions|wallet|orders|klines [参数]
+func runSync(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: sync executions|wallet|orders|klines|instruments [-update]")
+	}
+	kind := args[0]
+
+	fs := flag.NewFlagSet("sync "+kind, flag.ExitOnError)
+	update := fs.Bool("update", false, "增量更新模式（只下载新记录）")
+	account := fs.String("account", bitmex.DefaultAccount, "账户名（对应环境变量 BITMEX_<ACCOUNT>_API_KEY 或凭证文件中的账户）")
+	symbol := fs.String("symbol", "XBTUSD", "K线交易对符号 (XBTUSD, ETHUSD, etc.)")
+	timeframe := fs.String("timeframe", "1d", "K线时间周期 (1m, 5m, 1h, 1d 直接下载；15m, 4h, 1w 等由较小周期合成)")
+	baseURL := fs.String("base-url", bitmex.DefaultBaseURL, "API 地址（测试网: https://testnet.bitmex.com/api/v1）")
+	fs.Parse(args[1:])
+
+	client := bitmex.NewClient(bitmex.Credentials{})
+	client.BaseURL = *baseURL
+	client.Logf = func(format string, args ...any) { fmt.Printf(format, args...) }
+
+	switch kind {
+	case "executions":
+		fmt.Print("=== BitMEX 历史交易记录下载工具 ===\n\n")
+		if err := connect(client, *account, "/execution"); err != nil {
+			return err
+		}
+		return syncDataset(client, bitmex.ExecutionsDataset(bitmex.ExecutionsFile), *update, func(e bitmex.Execution) {
+			fmt.Printf("  时间: %s\n", e.TransactTime)
+			fmt.Printf("  交易对: %s\n", e.Symbol)
+		})
+
+	case "wallet":
+		fmt.Print("=== BitMEX 钱包历史记录下载工具 ===\n\n")
+		if err := connect(client, *account, "/user/walletHistory"); err != nil {
+			return err
+		}
+		return syncDataset(client, bitmex.WalletDataset(bitmex.WalletFile), *update, func(h bitmex.WalletHistory) {
+			fmt.Printf("  时间: %s\n", h.Timestamp)
+			fmt.Printf("  类型: %s\n", h.TransactType)
+			fmt.Printf("  金额: %.8f BTC\n", h.AmountBTC())
+			fmt.Printf("  钱包余额: %.8f BTC\n", h.WalletBalanceBTC())
+		})
+
+	case "orders":
+		fmt.Print("=== BitMEX 历史订单记录下载工具 ===\n\n")
+		if err := connect(client, *account, "/order"); err != nil {
+			return err
+		}
+		return syncDataset(client, bitmex.OrdersDataset(bitmex.OrdersFile), *update, func(o bitmex.Order) {
+			fmt.Printf("  时间: %s\n", o.TransactTime)
+			fmt.Printf("  交易对: %s\n", o.Symbol)
+			fmt.Printf("  状态: %s\n", o.OrdStatus)
+		})
+
+	case "klines":
+		fmt.Print("=== BitMEX K线数据下载工具 ===\n\n")
+		client.HTTPClient.Timeout = 30 * time.Second
+		return syncKlines(client, *symbol, *timeframe, *update)
+
+	case "instruments":
+		fmt.Print("=== BitMEX 合约信息下载工具 ===\n\n")
+		return syncInstruments(client)
+
+	default:
+		return fmt.Errorf("未知数据类型: %s（可用: executions, wallet, orders, klines, instruments）", kind)
+	}
+}
+
+// connect 加载账户凭证并用 testPath 测试API连接
+func connect(client *bitmex.Client, account, testPath string) error {
+	// 加载API凭证（环境变量 / 凭证文件 / 加密凭证文件）
+	creds, err := bitmex.LoadCredentialsChecked(account, ".")
+	if err != nil {
+		return fmt.Errorf("加载API凭证失败: %w", err)
+	}
+	client.Credentials = creds
+	fmt.Printf("账户: %s (API Key: %s)\n\n", account, creds.Masked())
+
+	fmt.Println("测试API连接...")
+	if err := client.Ping(testPath); err != nil {
+		return fmt.Errorf("API连接失败: %w\n\n提示: 请检查API凭证是否正确", err)
+	}
+	fmt.Print("✓ API连接成功!\n\n")
+	return nil
+}
+
+// syncDataset 同步数据集并打印结果，describe 打印单条记录摘要
+func syncDataset[T any](client *bitmex.Client, ds bitmex.Dataset[T], update bool, describe func(T)) error {
+	if update {
+		fmt.Println("运行模式: 增量更新")
+		if _, err := os.Stat(ds.File); os.IsNotExist(err) {
+			fmt.Printf("⚠ 未找到 %s 文件，将进行全量下载\n", ds.File)
+			update = false
+		}
+	} else {
+		fmt.Println("运行模式: 全量下载")
+	}
+	fmt.Println()
+
+	result, err := bitmex.Sync(client, ds, !update)
+	if err != nil {
+		return fmt.Errorf("同步失败: %w", err)
+	}
+
+	fmt.Println()
+	if !result.StartTime.IsZero() {
+		fmt.Printf("增量起点: %s\n", result.StartTime.UTC().Format(bitmex.TimeLayout))
+	}
+	if result.Resumed {
+		fmt.Println("✓ 已从上次中断处继续下载")
+	}
+	fmt.Printf("下载 %d 条%s: 新增 %d 条, 更新 %d 条, 移除 %d 条",
+		result.Fetched, ds.Title, result.New, result.Updated, result.Removed)
+	if result.Deduped > 0 {
+		fmt.Printf(", 合并重复 %d 条", result.Deduped)
+	}
+	fmt.Println()
+	if result.Refreshed > 0 {
+		fmt.Printf("重新获取未完成%s %d 条\n", ds.Title, result.Refreshed)
+	}
+
+	if update && result.New+result.Updated+result.Removed+result.Deduped == 0 {
+		fmt.Printf("\n✓ 没有新的%s\n", ds.Title)
+		return nil
+	}
+	if _, err := os.Stat(ds.File + ".bak"); err == nil && !update {
+		fmt.Printf("✓ 已备份原文件: %s.bak\n", ds.File)
+	}
+	fmt.Printf("✓ %s 现有 %d 条记录\n", ds.File, result.Total)
+
+	rows, err := ds.Load()
+	if err == nil && len(rows) > 0 {
+		fmt.Println("\n最后一条记录:")
+		describe(rows[len(rows)-1])
+	}
+	return nil
+}
+
+// syncKlines 同步K线。BitMEX 不提供的周期先同步能整除它的最大原生周期
+// （如 4h 同步 1h），再合成写入 klines_<symbol>_<timeframe>.csv
+func syncKlines(client *bitmex.Client, symbol, timeframe string, update bool) error {
+	source, err := bitmex.SourceBinSize(timeframe)
+	if err != nil {
+		return err
+	}
+	if source != timeframe {
+		fmt.Printf("BitMEX 不提供 %s K线，同步 %s K线后合成\n\n", timeframe, source)
+	}
+
+	ds := bitmex.KlinesDataset(bitmex.KlinesFile(symbol, source), symbol, source)
+	err = syncDataset(client, ds, update, func(k bitmex.Kline) {
+		fmt.Printf("  时间: %s\n", k.Timestamp.Format("2006-01-02 15:04:05"))
+		fmt.Printf("  开: %.2f  高: %.2f  低: %.2f  收: %.2f\n", k.Open, k.High, k.Low, k.Close)
+	})
+	if err != nil || source == timeframe {
+		return err
+	}
+
+	dst := bitmex.KlinesFile(symbol, timeframe)
+	n, err := bitmex.ResampleFile(ds.File, dst, source, timeframe)
+	if err != nil {
+		return fmt.Errorf("合成 %s K线失败: %w", timeframe, err)
+	}
+	fmt.Printf("\n✓ 由 %s 合成 %s: %d 条K线\n", ds.File, dst, n)
+	return nil
+}
+
+// syncInstruments 下载全部合约（包括已到期的）信息到 instruments.csv。
+// 合约信息不多且会变化（状态、到期），每次都完整下载替换原文件
+func syncInstruments(client *bitmex.Client) error {
+	instruments, err := client.Instruments(false)
+	if err != nil {
+		return fmt.Errorf("下载合约信息失败: %w", err)
+	}
+	if err := bitmex.WriteInstruments(bitmex.InstrumentsFile, instruments); err != nil {
+		return fmt.Errorf("保存失败: %w", err)
+	}
+
+	var inverse, quanto, linear int
+	for _, inst := range instruments {
+		switch {
+		case inst.IsInverse:
+			inverse++
+		case inst.IsQuanto:
+			quanto++
+		default:
+			linear++
+		}
+	}
+	fmt.Printf("✓ %s 现有 %d 个合约: 反向 %d, 双币种 %d, 正向 %d\n",
+		bitmex.InstrumentsFile, len(instruments), inverse, quanto, linear)
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"binance-kline/wei/analytics"
+	"binance-kline/wei/bitmex"
+)
+
+// runTax 处理 tax 子命令：按年度生成已实现盈亏、资金费用和手续费的美元税务报告（CSV 和可打印的 HTML）
+func runTax(args []string) error {
+	fs := flag.NewFlagSet("tax", flag.ExitOnError)
+	year := fs.Int("year", 0, "只生成该年度，0 为全部年份")
+	method := fs.String("method", "fifo", "成本计算方法 (fifo, average)")
+	tz := fs.String("tz", "UTC", "年度边界的时区 (UTC, Beijing)")
+	source := fs.String("price", "close", "美元价格来源 (close, vwap)")
+	prefix := fs.String("o", "tax", "输出文件前缀，生成 <前缀>_lots.csv、<前缀>_summary.csv、<前缀>_report.html")
+	fs.Parse(args)
+
+	costMethod, err := bitmex.ParseCostMethod(*method)
+	if err != nil {
+		return err
+	}
+	loc, err := bitmex.ParseTimezone(*tz)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print("=== BitMEX 税务报告 ===\n\n")
+	executions, err := bitmex.ReadExecutions(bitmex.ExecutionsFile)
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w（请先运行 sync executions）", bitmex.ExecutionsFile, err)
+	}
+	wallet, err := bitmex.ReadWalletHistory(bitmex.WalletFile)
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w（请先运行 sync wallet）", bitmex.WalletFile, err)
+	}
+	catalog, err := bitmex.LoadCatalog(bitmex.InstrumentsFile)
+	if err != nil {
+		fmt.Printf("⚠ 读取 %s 失败: %v（只能计算 XBT 反向合约，请先运行 sync instruments）\n", bitmex.InstrumentsFile, err)
+	}
+	prices, err := loadPriceSeries(*source)
+	if err != nil {
+		return err
+	}
+
+	report := analytics.BuildTaxReport(executions, wallet, catalog, analytics.TaxOptions{
+		Method: costMethod, Location: loc, Prices: prices, Year: *year,
+	})
+	for _, symbol := range report.Unknown {
+		fmt.Printf("⚠ 未知合约 %s，未计入（请运行 sync instruments）\n", symbol)
+	}
+	if len(report.Years) == 0 {
+		return fmt.Errorf("没有可统计的记录")
+	}
+
+	outputs := []struct {
+		suffix string
+		write  func(io.Writer) error
+	}{
+		{analytics.TaxLotsSuffix, func(w io.Writer) error { return analytics.WriteTaxLots(w, report.Lots, loc) }},
+		{analytics.TaxSummarySuffix, func(w io.Writer) error { return analytics.WriteTaxSummary(w, report) }},
+		{analytics.TaxHTMLSuffix, func(w io.Writer) error { return analytics.WriteTaxHTML(w, report, loc) }},
+	}
+	for _, o := range outputs {
+		filename := *prefix + o.suffix
+		if err := writeFile(filename, o.write); err != nil {
+			return err
+		}
+		fmt.Printf("✓ 导出 %s\n", filename)
+	}
+
+	fmt.Printf("\n成本计算方法 %s，年度时区 %s，金额为美元（按发生时刻的 XBTUSD %s 价格换算）:\n", costMethod, loc, prices.Source)
+	fmt.Println("  年度       批次   已实现净盈亏         手续费   资金费用收入   资金费用支出           净额 钱包已实现盈亏")
+	for _, y := range append(report.Years, report.Total) {
+		label := fmt.Sprintf("%-6d", y.Year)
+		if y.Year == 0 {
+			label = "合计  "
+		}
+		fmt.Printf("  %s %8d %+14.2f %14.2f %14.2f %14.2f %+14.2f %+14.2f\n",
+			label, y.Lots, y.NetGains, y.Fees, y.FundingIncome, y.FundingExpense, y.Net, y.WalletRealised)
+	}
+	if report.Total.Unpriced > 0 {
+		fmt.Printf("⚠ %d 条记录没有价格，未计入美元金额\n", report.Total.Unpriced)
+	}
+	return nil
+}
+
+// writeFile 创建 filename 并写入内容
+func writeFile(filename string, write func(io.Writer) error) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("创建 %s 失败: %w", filename, err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("写入 %s 失败: %w", filename, err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// runTrades 处理 trades 子命令：由 executions.csv 还原完整交易并导出 trades.csv
+func runTrades(args []string) error {
+	fs := flag.NewFlagSet("trades", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "只输出指定合约，如 XBTUSD")
+	output := fs.String("o", bitmex.TradesFile, "输出文件")
+	last := fs.Int("n", 10, "显示最近的交易数")
+	fs.Parse(args)
+
+	fmt.Print("=== BitMEX 交易还原 ===\n\n")
+	executions, err := bitmex.ReadExecutions(bitmex.ExecutionsFile)
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w（请先运行 sync executions）", bitmex.ExecutionsFile, err)
+	}
+	catalog, err := bitmex.LoadCatalog(bitmex.InstrumentsFile)
+	if err != nil {
+		fmt.Printf("⚠ 读取 %s 失败: %v（只能计算 XBT 反向合约，请先运行 sync instruments）\n", bitmex.InstrumentsFile, err)
+	}
+
+	xbtKlines, err := loadTradeKlines(bitmex.KlinesFile("XBTUSD", "1d"))
+	if err != nil {
+		fmt.Printf("⚠ %v，盈亏不换算为美元\n", err)
+	}
+	now := time.Now()
+	trips, unknown := bitmex.ReconstructTrades(executions, catalog, func(t time.Time) float64 {
+		return bitmex.ClosePriceAt(xbtKlines, t)
+	})
+	for _, s := range unknown {
+		fmt.Printf("⚠ 未知合约 %s，跳过\n", s)
+	}
+
+	// 用各合约周期最小的K线计算 MAE / MFE
+	done := make(map[string]bool)
+	for _, t := range trips {
+		if done[t.Symbol] {
+			continue
+		}
+		done[t.Symbol] = true
+		filename, binSize, ok := bitmex.FinestKlinesFile(".", t.Symbol)
+		if !ok {
+			fmt.Printf("⚠ 没有 %s 的K线，不计算 MAE / MFE\n", t.Symbol)
+			continue
+		}
+		klines, err := loadTradeKlines(filename)
+		if err != nil {
+			fmt.Printf("⚠ %v\n", err)
+			continue
+		}
+		bitmex.ApplyExcursions(trips, t.Symbol, klines, binSize, now)
+	}
+
+	if *symbol != "" {
+		var filtered []bitmex.RoundTrip
+		for _, t := range trips {
+			if t.Symbol == *symbol {
+				filtered = append(filtered, t)
+			}
+		}
+		trips = filtered
+	}
+	if err := bitmex.WriteTrades(*output, trips); err != nil {
+		return fmt.Errorf("保存 %s 失败: %w", *output, err)
+	}
+
+	s := bitmex.SummarizeTrades(trips)
+	fmt.Printf("\n✓ %s 现有 %d 笔交易（已平仓 %d, 持仓中 %d）\n\n", *output, len(trips), s.Closed, s.Open)
+	if s.Closed > 0 {
+		fmt.Printf("胜率: %.1f%%（盈利 %d, 亏损 %d）\n", s.WinRate, s.Wins, s.Losses)
+		fmt.Printf("净盈亏: %+.8f BTC / %+.2f USD\n", s.NetPnLBTC, s.NetPnLUSD)
+		fmt.Printf("平均持仓时间: %s\n", s.AvgHolding.Round(time.Minute))
+	}
+
+	start := len(trips) - *last
+	if start < 0 {
+		start = 0
+	}
+	if start < len(trips) {
+		fmt.Println("\n最近的交易:")
+	}
+	for _, t := range trips[start:] {
+		status := "持仓中"
+		if !t.IsOpen() {
+			status = fmt.Sprintf("%s 平仓, 净盈亏 %+.8f %s", t.CloseTime.Format("2006-01-02 15:04"), t.NetPnL, unitName(t.Currency))
+		}
+		fmt.Printf("  %s %s %s: 最大 %d 张, 开仓均价 %.2f, MAE %.2f%%, MFE %.2f%%, %s\n",
+			t.OpenTime.Format("2006-01-02 15:04"), t.Symbol, t.Side, t.MaxQty, t.EntryPrice, t.MAE, t.MFE, status)
+	}
+	return nil
+}
+
+// loadTradeKlines 读取K线文件，文件不存在时返回说明
+func loadTradeKlines(filename string) ([]bitmex.Kline, error) {
+	klines, err := bitmex.ReadKlines(filename)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("未找到 %s", filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %w", filename, err)
+	}
+	return klines, nil
+}
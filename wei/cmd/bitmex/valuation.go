@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"binance-kline/wei/bitmex"
+)
+
+// runValuation 处理 valuation 子命令：按历史 XBTUSD 价格为钱包记录估值，导出 wallet_usd.csv 并按年对比 BTC 和美元盈亏
+func runValuation(args []string) error {
+	fs := flag.NewFlagSet("valuation", flag.ExitOnError)
+	source := fs.String("price", "close", "价格来源 (close, vwap)")
+	output := fs.String("o", bitmex.WalletUSDFile, "输出文件")
+	fs.Parse(args)
+
+	fmt.Print("=== BitMEX 美元估值 ===\n\n")
+	wallet, err := bitmex.ReadWalletHistory(bitmex.WalletFile)
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w（请先运行 sync wallet）", bitmex.WalletFile, err)
+	}
+	prices, err := loadPriceSeries(*source)
+	if err != nil {
+		return err
+	}
+
+	entries := bitmex.ValueWallet(wallet, prices)
+	var missing int
+	for _, e := range entries {
+		if e.Price == 0 {
+			missing++
+		}
+	}
+	if err := bitmex.WriteWalletUSD(*output, entries); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", *output, err)
+	}
+	fmt.Printf("✓ 导出 %d 条记录到 %s\n", len(entries), *output)
+	if missing > 0 {
+		fmt.Printf("⚠ %d 条记录早于第一根K线，没有美元估值\n", missing)
+	}
+
+	fmt.Println("\n年度盈亏（RealisedPNL + Funding，美元按每条记录当时的价格换算）:")
+	fmt.Println("  年份          盈亏 BTC        盈亏 USD    年初余额 USD    年末余额 USD    余额变化 USD")
+	for _, y := range bitmex.ValueYears(entries, prices) {
+		fmt.Printf("  %-6s  %+14.8f  %+14.2f  %14.2f  %14.2f  %+14.2f\n",
+			y.Year, y.PnLBTC, y.PnLUSD, y.StartUSD, y.EndUSD, y.EndUSD-y.StartUSD)
+	}
+	return nil
+}
+
+// loadPriceSeries 由当前目录周期最小的 XBTUSD K线生成美元估值价格序列
+func loadPriceSeries(source string) (*bitmex.PriceSeries, error) {
+	priceSource, err := bitmex.ParsePriceSource(source)
+	if err != nil {
+		return nil, err
+	}
+	filename, binSize, ok := bitmex.FinestKlinesFile(".", "XBTUSD")
+	if !ok {
+		return nil, fmt.Errorf("未找到 XBTUSD K线文件（请先运行 sync klines）")
+	}
+	klines, err := loadTradeKlines(filename)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("✓ 价格: %s（%d 条，价格来源 %s）\n", filename, len(klines), priceSource)
+	return bitmex.NewPriceSeries(klines, binSize, priceSource), nil
+}
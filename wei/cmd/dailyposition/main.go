@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// DailyPosition 每日仓位数据
+type DailyPosition struct {
+	Date          string
+	PositionQty   int
+	Price         float64
+	Balance       float64
+	PositionValue float64
+	PositionRatio float64
+	Side          string
+}
+
+func main() {
+	log.Println("📊 开始计算每日仓位比例（按 BTC 计价）...")
+
+	// 1. 加载数据
+	executions := loadExecutions(bitmex.ExecutionsFile)
+	klines := loadKlines(bitmex.KlinesFile("XBTUSD", "1d"))
+	walletRecords := loadWalletRecords(bitmex.WalletFile)
+	catalog := loadCatalog(bitmex.InstrumentsFile)
+	symbolKlines := loadSymbolKlines(executions)
+
+	log.Printf("✓ 加载 %d 条成交记录", len(executions))
+	log.Printf("✓ 加载 %d 条 K线数据", len(klines))
+	log.Printf("✓ 加载 %d 条钱包记录", len(walletRecords))
+
+	// 2. 计算每日仓位
+	dailyPositions := calculateDailyPositions(executions, klines, symbolKlines, walletRecords, catalog)
+
+	// 3. 保存为 CSV
+	outputFile := "daily_position.csv"
+	if err := saveDailyPositions(dailyPositions, outputFile); err != nil {
+		log.Fatalf("❌ 保存失败: %v", err)
+	}
+
+	log.Printf("✅ 成功保存 %d 天的仓位数据到 %s", len(dailyPositions), outputFile)
+	log.Println("📈 统计信息:")
+	printSummary(dailyPositions)
+}
+
+// loadExecutions 加载所有合约的真实成交记录
+func loadExecutions(filename string) []bitmex.Execution {
+	all, err := bitmex.ReadExecutions(filename)
+	if err != nil {
+		log.Fatalf("读取 %s 失败: %v", filename, err)
+	}
+
+	var executions []bitmex.Execution
+	for _, exec := range all {
+		// 跳过 Funding (资金费率结算)，只保留真实交易
+		if exec.ExecType == "Funding" {
+			continue
+		}
+
+		executions = append(executions, exec)
+	}
+
+	return executions
+}
+
+// loadCatalog 加载合约信息，没有 instruments.csv 时只能计算 XBT 反向合约
+func loadCatalog(filename string) bitmex.Catalog {
+	catalog, err := bitmex.LoadCatalog(filename)
+	if err != nil {
+		log.Printf("⚠ 读取 %s 失败: %v（只计算 XBT 反向合约，请先运行 go run ./cmd/bitmex sync instruments）", filename, err)
+		return bitmex.Catalog{}
+	}
+	log.Printf("✓ 加载 %d 个合约信息", len(catalog))
+	return catalog
+}
+
+// loadSymbolKlines 加载其他合约的日K线（文件存在时），用于计算仓位价值
+func loadSymbolKlines(executions []bitmex.Execution) map[string]map[string]bitmex.Kline {
+	klines := make(map[string]map[string]bitmex.Kline)
+	for _, exec := range executions {
+		if _, ok := klines[exec.Symbol]; ok {
+			continue
+		}
+		klines[exec.Symbol] = nil
+		filename := bitmex.KlinesFile(exec.Symbol, "1d")
+		if _, err := os.Stat(filename); err == nil {
+			klines[exec.Symbol] = loadKlines(filename)
+		}
+	}
+	return klines
+}
+
+// loadKlines 加载 K线数据，按日期索引
+func loadKlines(filename string) map[string]bitmex.Kline {
+	all, err := bitmex.ReadKlines(filename)
+	if err != nil {
+		log.Fatalf("读取 %s 失败: %v", filename, err)
+	}
+
+	klines := make(map[string]bitmex.Kline)
+	for _, kline := range all {
+		// 使用日期作为 key
+		klines[kline.Timestamp.Format("2006-01-02")] = kline
+	}
+
+	return klines
+}
+
+// loadWalletRecords 加载钱包记录
+func loadWalletRecords(filename string) []bitmex.WalletHistory {
+	records, err := bitmex.ReadWalletHistory(filename)
+	if err != nil {
+		log.Fatalf("读取 %s 失败: %v", filename, err)
+	}
+	return records
+}
+
+// calculateDailyPositions 计算每日仓位。
+// 每个合约按其类型（反向 / 正向 / 双币种）计算仓位价值并换算为 BTC 后合计；
+// PositionQty 和 Price 仍为 XBTUSD 的持仓数量和收盘价。
+func calculateDailyPositions(executions []bitmex.Execution, klines map[string]bitmex.Kline,
+	symbolKlines map[string]map[string]bitmex.Kline, walletRecords []bitmex.WalletHistory, catalog bitmex.Catalog) []DailyPosition {
+	// 确定日期范围
+	startDate := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Now()
+
+	var dailyPositions []DailyPosition
+
+	quantities := make(map[string]int)     // 各合约截至当日的累计持仓
+	lastPrices := make(map[string]float64) // 各合约截至当日的最后成交价
+	unknown := make(map[string]bool)
+	next := 0
+
+	// 按日期遍历
+	for date := startDate; date.Before(endDate) || date.Equal(endDate); date = date.AddDate(0, 0, 1) {
+		dateStr := date.Format("2006-01-02")
+
+		// 1. 累加截至该日期的成交
+		for ; next < len(executions) && !executions[next].Time().After(date); next++ {
+			exec := executions[next]
+			if exec.Side == "Buy" {
+				quantities[exec.Symbol] += exec.LastQty
+			} else {
+				quantities[exec.Symbol] -= exec.LastQty
+			}
+			if exec.LastPx > 0 {
+				lastPrices[exec.Symbol] = exec.LastPx
+			}
+		}
+
+		// 2. 获取该日收盘价
+		kline, exists := klines[dateStr]
+		if !exists {
+			continue // 该日无 K线数据，跳过
+		}
+		price := kline.Close
+
+		// 3. 获取该日余额
+		balance := getBalanceAtDate(walletRecords, date)
+		if balance == 0 {
+			continue
+		}
+
+		// 4. 计算各合约仓位价值（BTC，带方向）并求和
+		var netValue float64
+		open := false
+		for symbol, qty := range quantities {
+			if qty == 0 {
+				continue
+			}
+			inst, ok := catalog.Lookup(symbol)
+			if !ok {
+				if !unknown[symbol] {
+					log.Printf("⚠ 未知合约 %s，不计入仓位价值", symbol)
+					unknown[symbol] = true
+				}
+				continue
+			}
+
+			// 合约价格: 该合约的日K线收盘价，没有K线时使用最后成交价
+			symbolPrice := lastPrices[symbol]
+			if symbol == "XBTUSD" {
+				symbolPrice = price
+			} else if k, ok := symbolKlines[symbol][dateStr]; ok {
+				symbolPrice = k.Close
+			}
+			if symbolPrice == 0 {
+				continue
+			}
+
+			value := inst.Value(float64(qty), symbolPrice) // 结算币种最小单位
+			settled := bitmex.FromMinorUnits(inst.SettlCurrency, int64(math.Round(value)))
+			if btc, ok := bitmex.ToBTC(inst.SettlCurrency, settled, price); ok {
+				netValue += btc
+				open = true
+			}
+		}
+
+		var positionValue float64
+		var positionRatio float64
+		var side string
+
+		switch {
+		case !open:
+			side = "Flat"
+		case netValue >= 0:
+			side = "Long"
+			positionValue = netValue
+			positionRatio = positionValue / balance
+		default:
+			side = "Short"
+			positionValue = -netValue
+			positionRatio = -positionValue / balance // Short 为负值
+		}
+
+		dailyPositions = append(dailyPositions, DailyPosition{
+			Date:          dateStr,
+			PositionQty:   quantities["XBTUSD"],
+			Price:         price,
+			Balance:       balance,
+			PositionValue: positionValue,
+			PositionRatio: positionRatio,
+			Side:          side,
+		})
+	}
+
+	return dailyPositions
+}
+
+// getBalanceAtDate 获取指定日期的余额
+func getBalanceAtDate(walletRecords []bitmex.WalletHistory, targetDate time.Time) float64 {
+	var balance float64
+
+	for _, record := range walletRecords {
+		if record.Time().After(targetDate) {
+			break
+		}
+		balance = record.WalletBalanceBTC()
+	}
+
+	return balance
+}
+
+// saveDailyPositions 保存到 CSV
+func saveDailyPositions(positions []DailyPosition, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	// 写入表头
+	header := []string{"Date", "PositionQty", "Price", "Balance", "PositionValue", "PositionRatio", "Side"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	// 写入数据
+	for _, pos := range positions {
+		record := []string{
+			pos.Date,
+			fmt.Sprintf("%d", pos.PositionQty),
+			fmt.Sprintf("%.2f", pos.Price),
+			fmt.Sprintf("%.8f", pos.Balance),
+			fmt.Sprintf("%.8f", pos.PositionValue),
+			fmt.Sprintf("%.4f", pos.PositionRatio),
+			pos.Side,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printSummary 打印统计信息
+func printSummary(positions []DailyPosition) {
+	if len(positions) == 0 {
+		return
+	}
+
+	longCount := 0
+	shortCount := 0
+	flatCount := 0
+	maxLongRatio := 0.0
+	maxShortRatio := 0.0
+
+	for _, pos := range positions {
+		switch pos.Side {
+		case "Long":
+			longCount++
+			if pos.PositionRatio > maxLongRatio {
+				maxLongRatio = pos.PositionRatio
+			}
+		case "Short":
+			shortCount++
+			if math.Abs(pos.PositionRatio) > maxShortRatio {
+				maxShortRatio = math.Abs(pos.PositionRatio)
+			}
+		case "Flat":
+			flatCount++
+		}
+	}
+
+	total := len(positions)
+	fmt.Printf("  总天数: %d\n", total)
+	fmt.Printf("  Long 天数: %d (%.1f%%)\n", longCount, float64(longCount)/float64(total)*100)
+	fmt.Printf("  Short 天数: %d (%.1f%%)\n", shortCount, float64(shortCount)/float64(total)*100)
+	fmt.Printf("  空仓天数: %d (%.1f%%)\n", flatCount, float64(flatCount)/float64(total)*100)
+	fmt.Printf("  最大 Long 倍数: %.2fx\n", maxLongRatio)
+	fmt.Printf("  最大 Short 倍数: %.2fx\n", maxShortRatio)
+
+	// 显示最近5天
+	fmt.Println("\n  最近5天:")
+	start := len(positions) - 5
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i < len(positions); i++ {
+		pos := positions[i]
+		fmt.Printf("    %s: %s %.2fx\n", pos.Date, pos.Side, math.Abs(pos.PositionRatio))
+	}
+}
@@ -39,7 +39,7 @@ echo ""
 # 3. 启动服务器
 echo "3️⃣  启动 Web 服务器..."
 echo "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"
-go run web_server.go > server_test.log 2>&1 &
+go run . > server_test.log 2>&1 &
 SERVER_PID=$!
 echo "服务器 PID: $SERVER_PID"
 echo "等待启动..."
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+
+	"binance-kline/wei/bitmex"
+)
+
+// MarginData 保证金账户（金额已换算为 BTC / USDT）
+type MarginData struct {
+	Currency        string  `json:"currency"`
+	WalletBalance   float64 `json:"walletBalance"`
+	MarginBalance   float64 `json:"marginBalance"`
+	AvailableMargin float64 `json:"availableMargin"`
+	UnrealizedPNL   float64 `json:"unrealizedPnl"`
+	RealizedPNL     float64 `json:"realizedPnl"`
+	MarginLeverage  float64 `json:"marginLeverage"`
+}
+
+// PositionCheck 交易所持仓与成交记录重建持仓的对比
+type PositionCheck struct {
+	Symbol                  string  `json:"symbol"`
+	ExchangeQty             int     `json:"exchangeQty"`
+	ReconstructedQty        int     `json:"reconstructedQty"`
+	ExchangeEntryPrice      float64 `json:"exchangeEntryPrice"`
+	ReconstructedEntryPrice float64 `json:"reconstructedEntryPrice"`
+	QtyMatch                bool    `json:"qtyMatch"`
+}
+
+// ExchangeSnapshot /api/positions/snapshot 响应
+type ExchangeSnapshot struct {
+	SnapshotTime string          `json:"snapshotTime"` // 为空表示还没有快照
+	Positions    []Position      `json:"positions"`
+	Margins      []MarginData    `json:"margins"`
+	Checks       []PositionCheck `json:"checks"`
+}
+
+// 交易所快照缓存（positions.csv / margin.csv）
+var (
+	positionSnapshotCache []bitmex.PositionSnapshot
+	marginSnapshotCache   []bitmex.MarginSnapshot
+)
+
+// loadExchangeSnapshots 加载 snapshot 命令记录的持仓和保证金，读取失败时沿用 prev 的数据
+func loadExchangeSnapshots(d, prev *dataSet) {
+	if positions, err := bitmex.ReadPositionSnapshots(bitmex.PositionsFile); err == nil {
+		d.positionSnapshots = positions
+		log.Printf("✓ 加载 %s: %d 条记录", bitmex.PositionsFile, len(positions))
+	} else {
+		d.positionSnapshots = prev.positionSnapshots
+		log.Printf("⚠ 跳过 %s: %v", bitmex.PositionsFile, err)
+	}
+	if margins, err := bitmex.ReadMarginSnapshots(bitmex.MarginFile); err == nil {
+		d.marginSnapshots = margins
+		log.Printf("✓ 加载 %s: %d 条记录", bitmex.MarginFile, len(margins))
+	} else {
+		d.marginSnapshots = prev.marginSnapshots
+		log.Printf("⚠ 跳过 %s: %v", bitmex.MarginFile, err)
+	}
+}
+
+// latestExchangeSnapshot 最近一次快照，ok 为 false 表示还没有快照
+func latestExchangeSnapshot() (snapshotTime string, positions []Position, margins []MarginData, ok bool) {
+	at, ps, ms := bitmex.LatestSnapshot(positionSnapshotCache, marginSnapshotCache)
+	if at.IsZero() {
+		return "", nil, nil, false
+	}
+
+	positions = []Position{}
+	for _, p := range ps {
+		positions = append(positions, exchangePosition(p, at.Format(bitmex.TimeLayout)))
+	}
+	for _, m := range ms {
+		margins = append(margins, MarginData{
+			Currency:        m.Currency,
+			WalletBalance:   bitmex.FromMinorUnits(m.Currency, m.WalletBalance),
+			MarginBalance:   bitmex.FromMinorUnits(m.Currency, m.MarginBalance),
+			AvailableMargin: bitmex.FromMinorUnits(m.Currency, m.AvailableMargin),
+			UnrealizedPNL:   bitmex.FromMinorUnits(m.Currency, m.UnrealisedPnl),
+			RealizedPNL:     bitmex.FromMinorUnits(m.Currency, m.RealisedPnl),
+			MarginLeverage:  m.MarginLeverage,
+		})
+	}
+	return at.Format(bitmex.TimeLayout), positions, margins, true
+}
+
+// exchangePosition 将交易所持仓转换为页面使用的仓位格式
+func exchangePosition(p bitmex.PositionSnapshot, snapshotTime string) Position {
+	pos := Position{
+		Symbol:           p.Symbol,
+		Side:             "Long",
+		Qty:              p.CurrentQty,
+		EntryPrice:       p.AvgEntryPrice,
+		CurrentPrice:     p.MarkPrice,
+		UnrealizedPNL:    bitmex.FromMinorUnits(p.Currency, p.UnrealisedPnl),
+		SettlCurrency:    p.Currency,
+		LiquidationPrice: p.LiquidationPrice,
+		Leverage:         p.Leverage,
+		Source:           "bitmex",
+		SnapshotTime:     snapshotTime,
+	}
+	if p.CurrentQty < 0 {
+		pos.Side = "Short"
+	}
+	if p.AvgEntryPrice > 0 {
+		percent := (p.MarkPrice/p.AvgEntryPrice - 1.0) * 100
+		if pos.Side == "Short" {
+			percent = -percent
+		}
+		if !math.IsNaN(percent) && !math.IsInf(percent, 0) {
+			pos.UnrealizedPNLPercent = percent
+		}
+	}
+	return pos
+}
+
+// checkPositions 对比交易所持仓和成交记录重建的持仓
+func checkPositions(exchange, reconstructed []Position) []PositionCheck {
+	bySymbol := make(map[string]*PositionCheck)
+	get := func(symbol string) *PositionCheck {
+		c, ok := bySymbol[symbol]
+		if !ok {
+			c = &PositionCheck{Symbol: symbol}
+			bySymbol[symbol] = c
+		}
+		return c
+	}
+	for _, p := range exchange {
+		c := get(p.Symbol)
+		c.ExchangeQty = p.Qty
+		c.ExchangeEntryPrice = p.EntryPrice
+	}
+	for _, p := range reconstructed {
+		c := get(p.Symbol)
+		c.ReconstructedQty = p.Qty
+		c.ReconstructedEntryPrice = p.EntryPrice
+	}
+
+	checks := []PositionCheck{}
+	for _, c := range bySymbol {
+		c.QtyMatch = c.ExchangeQty == c.ReconstructedQty
+		checks = append(checks, *c)
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Symbol < checks[j].Symbol })
+	return checks
+}
+
+// handleExchangeSnapshot 返回最近一次交易所快照及与重建持仓的对比
+func handleExchangeSnapshot(w http.ResponseWriter, r *http.Request) {
+	snapshot := ExchangeSnapshot{Positions: []Position{}, Margins: []MarginData{}, Checks: []PositionCheck{}}
+	if snapshotTime, positions, margins, ok := latestExchangeSnapshot(); ok {
+		snapshot.SnapshotTime = snapshotTime
+		snapshot.Positions = positions
+		snapshot.Margins = margins
+		snapshot.Checks = checkPositions(positions, calculatePositions())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
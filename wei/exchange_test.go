@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckPositions(t *testing.T) {
+	position := func(symbol string, qty int, entry float64) Position {
+		return Position{Symbol: symbol, Qty: qty, EntryPrice: entry}
+	}
+
+	tests := []struct {
+		name          string
+		exchange      []Position
+		reconstructed []Position
+		want          []PositionCheck
+	}{
+		{
+			name:          "一致",
+			exchange:      []Position{position("XBTUSD", 300, 40000.5), position("ETHUSD", -20, 3000)},
+			reconstructed: []Position{position("ETHUSD", -20, 3001), position("XBTUSD", 300, 40000)},
+			want: []PositionCheck{
+				{Symbol: "ETHUSD", ExchangeQty: -20, ReconstructedQty: -20, ExchangeEntryPrice: 3000, ReconstructedEntryPrice: 3001, QtyMatch: true},
+				{Symbol: "XBTUSD", ExchangeQty: 300, ReconstructedQty: 300, ExchangeEntryPrice: 40000.5, ReconstructedEntryPrice: 40000, QtyMatch: true},
+			},
+		},
+		{
+			name:          "数量不一致",
+			exchange:      []Position{position("XBTUSD", 300, 40000)},
+			reconstructed: []Position{position("XBTUSD", -300, 40000)},
+			want: []PositionCheck{
+				{Symbol: "XBTUSD", ExchangeQty: 300, ReconstructedQty: -300, ExchangeEntryPrice: 40000, ReconstructedEntryPrice: 40000},
+			},
+		},
+		{
+			name:          "一方缺少交易对",
+			exchange:      []Position{position("XBTUSDT", 1000, 60000)},
+			reconstructed: []Position{position("XBTUSD", 100, 40000)},
+			want: []PositionCheck{
+				{Symbol: "XBTUSD", ReconstructedQty: 100, ReconstructedEntryPrice: 40000},
+				{Symbol: "XBTUSDT", ExchangeQty: 1000, ExchangeEntryPrice: 60000},
+			},
+		},
+		{
+			name: "没有持仓",
+			want: []PositionCheck{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkPositions(tt.exchange, tt.reconstructed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkPositions = %+v\n应为 %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// FundingData 一次资金费用结算（金额已换算为 BTC / USDT）
+type FundingData struct {
+	Time        string  `json:"time"`
+	Symbol      string  `json:"symbol"`
+	Currency    string  `json:"currency"`
+	PositionQty int     `json:"positionQty"`
+	MarkPrice   float64 `json:"markPrice"`
+	Rate        float64 `json:"rate"`
+	Amount      float64 `json:"amount"`              // 正值为收到，负值为支付
+	AmountUSD   float64 `json:"amountUsd,omitempty"` // 按结算时的价格换算的美元金额（currency=USD 时返回）
+}
+
+// FundingReport /api/funding 响应
+type FundingReport struct {
+	By       string                `json:"by"`
+	Currency string                `json:"currency"`           // 计价单位: BTC（各币种为主单位）或 USD
+	Totals   []bitmex.FundingTotal `json:"totals"`             // 按 by 分组的汇总
+	Summary  []bitmex.FundingTotal `json:"summary"`            // 每个结算币种的合计
+	Payments []FundingData         `json:"payments,omitempty"` // 明细（detail=true 时返回）
+}
+
+// 资金费用明细缓存（由 wallet.csv 和 executions.csv 生成）
+var fundingCache []bitmex.FundingPayment
+
+// loadFunding 由钱包历史和成交记录生成资金费用明细，需要在钱包和成交记录加载之后调用
+func loadFunding(d *dataSet) {
+	if d.wallet == nil {
+		log.Printf("⚠ 跳过资金费用: 没有 %s", bitmex.WalletFile)
+		return
+	}
+	if d.rawExecutions == nil {
+		log.Printf("⚠ 没有 %s，资金费用没有费率和结算时持仓", bitmex.ExecutionsFile)
+	}
+
+	d.funding = bitmex.BuildFundingLedger(d.wallet, d.rawExecutions)
+	log.Printf("✓ 生成资金费用明细: %d 条记录", len(d.funding))
+}
+
+// handleFunding 资金费用汇总
+// 参数: by=day|month|symbol|all（默认 month），symbol，from / to（2006-01-02，to 不含），detail=true 返回明细
+func handleFunding(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	currency, usd, err := parseCurrency(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	by := query.Get("by")
+	if by == "" {
+		by = "month"
+	}
+
+	var from, to time.Time
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		if s := query.Get(p.name); s != "" {
+			t, err := time.Parse("2006-01-02", s)
+			if err != nil {
+				http.Error(w, "日期格式错误，应为 2006-01-02", http.StatusBadRequest)
+				return
+			}
+			*p.t = t
+		}
+	}
+
+	ledger := bitmex.FilterFunding(fundingCache, query.Get("symbol"), from, to)
+	summarize := bitmex.SummarizeFunding
+	if usd != nil {
+		summarize = func(ledger []bitmex.FundingPayment, by string) ([]bitmex.FundingTotal, error) {
+			return bitmex.SummarizeFundingUSD(ledger, by, usd)
+		}
+	}
+	totals, err := summarize(ledger, by)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	summary, _ := summarize(ledger, "all")
+
+	report := FundingReport{By: by, Currency: currency, Totals: totals, Summary: summary}
+	if query.Get("detail") == "true" {
+		report.Payments = make([]FundingData, 0, len(ledger))
+		for _, p := range ledger {
+			report.Payments = append(report.Payments, FundingData{
+				Time:        p.Time.Format(bitmex.TimeLayout),
+				Symbol:      p.Symbol,
+				Currency:    p.Currency,
+				PositionQty: p.PositionQty,
+				MarkPrice:   p.MarkPrice,
+				Rate:        p.Rate,
+				Amount:      p.AmountMain(),
+				AmountUSD:   usd.USD(p.Currency, p.AmountMain(), p.Time),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+
+	"binance-kline/wei/analytics"
+	"binance-kline/wei/bitmex"
+)
+
+// PerformanceData 一种计价单位的绩效报告和权益曲线
+type PerformanceData struct {
+	analytics.Report
+	Curve []analytics.EquityPoint `json:"curve,omitempty"` // curve=true 时返回
+
+	returns analytics.Returns
+}
+
+// ReturnsData /api/returns 中一种计价单位的收益
+type ReturnsData struct {
+	analytics.Returns
+	Curve []UnitValuePoint `json:"curve,omitempty"` // curve=true 时返回
+}
+
+// UnitValuePoint 单位净值曲线上的一天
+type UnitValuePoint struct {
+	Date     string  `json:"date"`
+	NAV      float64 `json:"nav"`      // 单位净值（起始为 1）
+	Equity   float64 `json:"equity"`   // 权益
+	Invested float64 `json:"invested"` // 累计净入金
+}
+
+// 绩效报告缓存（按计价单位），由 wallet.csv、daily_position.csv 和 XBTUSD 价格生成
+var performanceCache map[string]PerformanceData
+
+// loadPerformance 计算 BTC 和 USD 计价的绩效报告（美元价格来自 PRICE_SOURCE），需要在钱包历史和K线加载之后调用
+func loadPerformance(d *dataSet) {
+	d.performance = make(map[string]PerformanceData)
+	if len(d.wallet) == 0 {
+		log.Printf("⚠ 跳过绩效统计: 没有 %s", bitmex.WalletFile)
+		return
+	}
+	daily, err := analytics.ReadDailyPositions(analytics.DailyPositionFile)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("⚠ 读取 %s 失败: %v（绩效统计没有仓位比例）", analytics.DailyPositionFile, err)
+	}
+	for _, unit := range []string{analytics.BTC, analytics.USD} {
+		curve := analytics.EquityCurve(d.wallet, daily, d.prices.At, unit)
+		d.performance[unit] = PerformanceData{
+			Report:  analytics.Analyze(curve, unit, 5),
+			Curve:   curve,
+			returns: analytics.FlowReturns(curve, d.wallet, unit),
+		}
+	}
+	r := d.performance[analytics.BTC].Report
+	log.Printf("✓ 绩效统计: %s ~ %s，累计收益 %+.2f%%，最大回撤 %.2f%%", r.Start, r.End, r.TotalReturn, r.MaxDrawdown.Depth)
+}
+
+// parseUnits 解析 unit 参数（currency 为同义参数），为空时返回 BTC 和 USD
+func parseUnits(r *http.Request) ([]string, error) {
+	u := r.URL.Query().Get("unit")
+	if u == "" {
+		u = r.URL.Query().Get("currency")
+	}
+	if u == "" {
+		return []string{analytics.BTC, analytics.USD}, nil
+	}
+	parsed, err := analytics.ParseUnit(u)
+	if err != nil {
+		return nil, err
+	}
+	return []string{parsed}, nil
+}
+
+// handlePerformance 账户绩效指标
+// 参数: unit=BTC|USD 或 currency=BTC|USD（默认两者都返回），curve=true 返回每日权益曲线
+func handlePerformance(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	units, err := parseUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := make(map[string]PerformanceData, len(units))
+	for _, u := range units {
+		data, ok := performanceCache[u]
+		if !ok {
+			continue
+		}
+		if query.Get("curve") != "true" {
+			data.Curve = nil
+		}
+		result[u] = data
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleReturns 扣除出入金的收益：时间加权收益、资金加权收益（IRR）、净入金
+// 参数: unit=BTC|USD 或 currency=BTC|USD（默认两者都返回），curve=true 返回单位净值曲线
+func handleReturns(w http.ResponseWriter, r *http.Request) {
+	units, err := parseUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := make(map[string]ReturnsData, len(units))
+	for _, u := range units {
+		data, ok := performanceCache[u]
+		if !ok {
+			continue
+		}
+		returns := ReturnsData{Returns: data.returns}
+		if r.URL.Query().Get("curve") == "true" {
+			returns.Curve = make([]UnitValuePoint, len(data.Curve))
+			for i, p := range data.Curve {
+				returns.Curve[i] = UnitValuePoint{Date: p.Date, NAV: p.NAV, Equity: p.Equity, Invested: p.Invested}
+			}
+		}
+		result[u] = returns
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
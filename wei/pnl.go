@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"binance-kline/wei/analytics"
+	"binance-kline/wei/bitmex"
+)
+
+// PeriodPnLResponse /api/pnl 响应
+type PeriodPnLResponse struct {
+	Period     string             `json:"period"`
+	Timezone   string             `json:"timezone"`
+	Start      string             `json:"start,omitempty"` // 全部区间时为空
+	End        string             `json:"end"`
+	Currency   string             `json:"currency"`   // 计价单位: BTC（各币种为主单位）或 USD（按记录时刻的价格换算）
+	Currencies []bitmex.PeriodPnL `json:"currencies"` // 按结算币种
+	Net        float64            `json:"net"`        // 各币种净盈亏按计价单位的合计（BTC 计价时 USDT 按当前 XBTUSD 价格换算）
+	Return     float64            `json:"return"`     // XBt 账户的收益率（百分比）
+}
+
+// 盈亏统计的日期时区（环境变量 PNL_TIMEZONE: UTC / Beijing）
+var pnlLocation = time.UTC
+
+// 区间盈亏缓存，按时区和区间起止时间索引，重新加载数据时清空。
+// 截至当前的区间（today 等）只按起始时间索引：钱包历史只在加载时更新，结束时间不影响结果
+var (
+	periodPnLMu    sync.Mutex
+	periodPnLCache map[string]PeriodPnLResponse
+)
+
+// resetPeriodPnL 清空区间盈亏缓存
+func resetPeriodPnL() {
+	periodPnLMu.Lock()
+	periodPnLCache = make(map[string]PeriodPnLResponse)
+	periodPnLMu.Unlock()
+}
+
+// periodPnL 截至当前的区间盈亏（today / wtd / mtd / ytd / all），usd 不为 nil 时按美元计价
+func periodPnL(name string, loc *time.Location, usd *bitmex.PriceSeries) (PeriodPnLResponse, error) {
+	p, err := bitmex.PeriodToDate(name, time.Now(), loc)
+	if err != nil {
+		return PeriodPnLResponse{}, err
+	}
+	return cachedPeriodPnL(p, loc, false, usd), nil
+}
+
+// cachedPeriodPnL 计算区间盈亏，结果按区间和计价单位缓存
+func cachedPeriodPnL(p bitmex.Period, loc *time.Location, custom bool, usd *bitmex.PriceSeries) PeriodPnLResponse {
+	currency := analytics.BTC
+	if usd != nil {
+		currency = analytics.USD
+	}
+	key := currency + "|" + loc.String() + "|" + p.Name + "|" + p.Start.Format(time.RFC3339)
+	if custom {
+		key += "|" + p.End.Format(time.RFC3339)
+	}
+
+	periodPnLMu.Lock()
+	cached, ok := periodPnLCache[key]
+	periodPnLMu.Unlock()
+	if ok {
+		cached.End = p.End.In(loc).Format(time.RFC3339)
+		return cached
+	}
+
+	resp := PeriodPnLResponse{
+		Period:     p.Name,
+		Timezone:   loc.String(),
+		End:        p.End.In(loc).Format(time.RFC3339),
+		Currency:   currency,
+		Currencies: bitmex.ComputePeriodPnL(walletCache, rawExecutionsCache, instrumentsCache, p, usd),
+	}
+	if !p.Start.IsZero() {
+		resp.Start = p.Start.In(loc).Format(time.RFC3339)
+	}
+	xbtPrice := getClosePriceAtDate("XBTUSD", time.Now())
+	for _, c := range resp.Currencies {
+		if usd != nil {
+			resp.Net += c.Net
+		} else if btc, ok := bitmex.ToBTC(c.Currency, c.Net, xbtPrice); ok {
+			resp.Net += btc
+		}
+		if c.Currency == "XBt" {
+			resp.Return = c.Return
+		}
+	}
+
+	periodPnLMu.Lock()
+	if periodPnLCache != nil {
+		periodPnLCache[key] = resp
+	}
+	periodPnLMu.Unlock()
+	return resp
+}
+
+// handlePnL 区间净盈亏（已实现盈亏 + 资金费用，均已扣除手续费）
+// 参数: period=today|wtd|mtd|ytd|all|custom（默认 today），from / to（custom 时使用，2006-01-02，to 不含），
+// tz=UTC|Beijing（默认 PNL_TIMEZONE），currency=BTC|USD（默认 BTC）
+func handlePnL(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	_, usd, err := parseCurrency(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	loc := pnlLocation
+	if tz := query.Get("tz"); tz != "" {
+		parsed, err := bitmex.ParseTimezone(tz)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		loc = parsed
+	}
+
+	period := query.Get("period")
+	if period == "" {
+		period = bitmex.PeriodToday
+	}
+	var resp PeriodPnLResponse
+	if period == bitmex.PeriodCustom {
+		p, err := bitmex.CustomPeriod(query.Get("from"), query.Get("to"), time.Now(), loc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp = cachedPeriodPnL(p, loc, query.Get("to") != "", usd)
+	} else {
+		if resp, err = periodPnL(period, loc, usd); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
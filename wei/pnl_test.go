@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// 自定义区间与截至当前的区间起始时间相同时，不使用对方的缓存结果
+func TestCachedPeriodPnL(t *testing.T) {
+	savedWallet, savedExecutions, savedInstruments := walletCache, rawExecutionsCache, instrumentsCache
+	t.Cleanup(func() {
+		walletCache, rawExecutionsCache, instrumentsCache = savedWallet, savedExecutions, savedInstruments
+		resetPeriodPnL()
+	})
+	record := func(day int, amount, balance int64) bitmex.WalletHistory {
+		return bitmex.WalletHistory{TransactType: "RealisedPNL", TransactStatus: "Completed", Currency: "XBt",
+			Amount: amount, WalletBalance: balance, Timestamp: time.Date(2024, 12, day, 12, 0, 0, 0, time.UTC).Format(bitmex.TimeLayout)}
+	}
+	walletCache = []bitmex.WalletHistory{record(5, 1000000, 101000000), record(15, 2000000, 103000000)}
+	rawExecutionsCache, instrumentsCache = nil, bitmex.Catalog{}
+	resetPeriodPnL()
+
+	now := time.Date(2024, 12, 20, 12, 0, 0, 0, time.UTC)
+	monthToDate, err := bitmex.PeriodToDate(bitmex.PeriodMonth, now, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	customToDate, err := bitmex.CustomPeriod("2024-12-01", "", now, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstTen, err := bitmex.CustomPeriod("2024-12-01", "2024-12-10", now, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstTwenty, err := bitmex.CustomPeriod("2024-12-01", "2024-12-20", now, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		p       bitmex.Period
+		custom  bool
+		period  string
+		end     string
+		net     float64
+		repeats int
+	}{
+		{"mtd", monthToDate, false, bitmex.PeriodMonth, "2024-12-20T12:00:00Z", 0.03, 2},
+		{"自定义区间没有结束日期", customToDate, false, bitmex.PeriodCustom, "2024-12-20T12:00:00Z", 0.03, 2},
+		{"自定义区间到 12 月 10 日", firstTen, true, bitmex.PeriodCustom, "2024-12-10T00:00:00Z", 0.01, 2},
+		{"自定义区间到 12 月 20 日", firstTwenty, true, bitmex.PeriodCustom, "2024-12-20T00:00:00Z", 0.03, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 第二次读取命中自己的缓存
+			for i := 0; i < tt.repeats; i++ {
+				resp := cachedPeriodPnL(tt.p, time.UTC, tt.custom, nil)
+				if resp.Period != tt.period || resp.Start != "2024-12-01T00:00:00Z" || resp.End != tt.end ||
+					math.Abs(resp.Net-tt.net) > 1e-12 {
+					t.Errorf("第 %d 次: %s [%s, %s) 净盈亏 %v，应为 %s [2024-12-01T00:00:00Z, %s) 净盈亏 %v",
+						i+1, resp.Period, resp.Start, resp.End, resp.Net, tt.period, tt.end, tt.net)
+				}
+			}
+		})
+	}
+	if len(periodPnLCache) != len(tests) {
+		t.Errorf("缓存 %d 条，应为每个区间一条共 %d 条", len(periodPnLCache), len(tests))
+	}
+
+	// 截至当前的区间只按起始时间缓存，结束时间随 now 更新
+	later, _ := bitmex.PeriodToDate(bitmex.PeriodMonth, now.Add(time.Hour), time.UTC)
+	if resp := cachedPeriodPnL(later, time.UTC, false, nil); resp.End != "2024-12-20T13:00:00Z" || resp.Period != bitmex.PeriodMonth {
+		t.Errorf("mtd 缓存结果 = %s 到 %s，应为 mtd 到 2024-12-20T13:00:00Z", resp.Period, resp.End)
+	}
+}
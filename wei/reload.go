@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// dataSet 一次加载的全部数据：CSV 文件中的记录和由它们生成的派生数据。
+// 重新加载时在后台生成新的 dataSet，再在写锁内整体替换全局缓存
+type dataSet struct {
+	klines            map[string][]KlineData
+	instruments       bitmex.Catalog
+	orders            []OrderData
+	executions        []ExecutionData
+	rawExecutions     []bitmex.Execution
+	wallet            []bitmex.WalletHistory
+	dailyPosition     []DailyPositionData
+	positionSnapshots []bitmex.PositionSnapshot
+	marginSnapshots   []bitmex.MarginSnapshot
+
+	// 派生数据
+	prices      *bitmex.PriceSeries
+	funding     []bitmex.FundingPayment
+	trades      []bitmex.RoundTrip
+	performance map[string]PerformanceData
+	snapshots   *snapshotIndex
+}
+
+// dataMu 保护所有数据缓存：API 处理函数在处理期间持有读锁（见 withData），替换缓存时持有写锁
+var dataMu sync.RWMutex
+
+// withData 处理请求期间持有数据读锁，保证一次请求看到的是同一次加载的数据
+func withData(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dataMu.RLock()
+		defer dataMu.RUnlock()
+		h(w, r)
+	}
+}
+
+// currentData 当前全局缓存组成的 dataSet，重新加载时读取失败的文件沿用其中的数据
+func currentData() *dataSet {
+	dataMu.RLock()
+	defer dataMu.RUnlock()
+	return &dataSet{
+		klines:            klinesCache,
+		instruments:       instrumentsCache,
+		orders:            ordersCache,
+		executions:        executionsCache,
+		rawExecutions:     rawExecutionsCache,
+		wallet:            walletCache,
+		dailyPosition:     dailyPositionCache,
+		positionSnapshots: positionSnapshotCache,
+		marginSnapshots:   marginSnapshotCache,
+	}
+}
+
+// install 在写锁内用 d 替换全局缓存，并清空依赖这些数据的区间盈亏缓存
+func (d *dataSet) install() {
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	klinesCache = d.klines
+	instrumentsCache = d.instruments
+	ordersCache = d.orders
+	executionsCache = d.executions
+	rawExecutionsCache = d.rawExecutions
+	walletCache = d.wallet
+	dailyPositionCache = d.dailyPosition
+	positionSnapshotCache = d.positionSnapshots
+	marginSnapshotCache = d.marginSnapshots
+
+	usdPrices = d.prices
+	fundingCache = d.funding
+	tradesCache = d.trades
+	performanceCache = d.performance
+	snapshots = d.snapshots
+	resetPeriodPnL()
+}
+
+// counts 各类数据的记录数
+func (d *dataSet) counts() map[string]int {
+	counts := map[string]int{
+		"instruments":       len(d.instruments),
+		"orders":            len(d.orders),
+		"executions":        len(d.rawExecutions),
+		"wallet":            len(d.wallet),
+		"dailyPosition":     len(d.dailyPosition),
+		"positionSnapshots": len(d.positionSnapshots),
+		"marginSnapshots":   len(d.marginSnapshots),
+		"funding":           len(d.funding),
+		"trades":            len(d.trades),
+	}
+	for key, klines := range d.klines {
+		counts["klines_"+key] = len(klines)
+	}
+	return counts
+}
+
+// ReloadStatus 数据加载状态（GET /api/reload）
+type ReloadStatus struct {
+	LoadedAt      string         `json:"loadedAt"`                // 最近一次加载完成的时间
+	DurationMs    int64          `json:"durationMs"`              // 最近一次加载耗时（毫秒）
+	Trigger       string         `json:"trigger"`                 // startup / api / watch
+	Reloads       int            `json:"reloads"`                 // 启动后重新加载的次数
+	Reloading     bool           `json:"reloading"`               // 是否正在加载
+	WatchInterval string         `json:"watchInterval,omitempty"` // 检查数据文件变化的间隔，为空表示不监视
+	Counts        map[string]int `json:"counts"`                  // 各类数据的记录数
+}
+
+var (
+	reloadMu     sync.Mutex // 同一时间只进行一次加载
+	statusMu     sync.Mutex
+	reloadStatus ReloadStatus
+	loadedFiles  string // 最近一次加载时数据文件的签名
+)
+
+// reloadData 从CSV文件重新加载所有数据并替换缓存，trigger 为触发原因。
+// 已经有加载在进行时不等待，返回 false
+func reloadData(trigger string) (ReloadStatus, bool) {
+	if !reloadMu.TryLock() {
+		return getReloadStatus(), false
+	}
+	defer reloadMu.Unlock()
+
+	statusMu.Lock()
+	reloadStatus.Reloading = true
+	statusMu.Unlock()
+
+	start := time.Now()
+	signature := dataFilesSignature()
+	log.Printf("正在加载数据（%s）...", trigger)
+	d := loadData()
+	d.install()
+
+	statusMu.Lock()
+	loadedFiles = signature
+	if trigger != "startup" {
+		reloadStatus.Reloads++
+	}
+	reloadStatus.LoadedAt = time.Now().Format(time.RFC3339)
+	reloadStatus.DurationMs = time.Since(start).Milliseconds()
+	reloadStatus.Trigger = trigger
+	reloadStatus.Reloading = false
+	reloadStatus.Counts = d.counts()
+	status := reloadStatus
+	statusMu.Unlock()
+	log.Printf("✓ 数据加载完成（%s），耗时 %v", trigger, time.Since(start).Round(time.Millisecond))
+
+	// 向 /api/stream 推送变化
+	publishChanges(status)
+	return status, true
+}
+
+// getReloadStatus 当前加载状态
+func getReloadStatus() ReloadStatus {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	return reloadStatus
+}
+
+// dataFiles 服务器读取的数据文件
+func dataFiles() []string {
+	files, _ := filepath.Glob("klines_*.csv")
+	files = append(files,
+		bitmex.InstrumentsFile, bitmex.OrdersFile, bitmex.ExecutionsFile, bitmex.WalletFile,
+		"daily_position.csv", bitmex.PositionsFile, bitmex.MarginFile)
+	sort.Strings(files)
+	return files
+}
+
+// dataFilesSignature 数据文件的大小和修改时间，任何文件变化（包括新增、删除）都会改变签名
+func dataFilesSignature() string {
+	var sb strings.Builder
+	for _, name := range dataFiles() {
+		if info, err := os.Stat(name); err == nil {
+			fmt.Fprintf(&sb, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return sb.String()
+}
+
+// watchDataFiles 每隔 interval 检查数据文件，文件变化并且在一个间隔内不再变化（写入完成）后在后台重新加载
+func watchDataFiles(interval time.Duration) {
+	statusMu.Lock()
+	reloadStatus.WatchInterval = interval.String()
+	statusMu.Unlock()
+
+	var previous string
+	for range time.Tick(interval) {
+		signature := dataFilesSignature()
+		statusMu.Lock()
+		changed := signature != loadedFiles
+		statusMu.Unlock()
+		if changed && signature == previous {
+			log.Printf("检测到数据文件变化，重新加载")
+			reloadData("watch")
+		}
+		previous = signature
+	}
+}
+
+// handleReload GET 返回加载状态；POST 在后台重新加载所有数据（wait=true 时等待加载完成）
+func handleReload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(getReloadStatus())
+	case http.MethodPost:
+		if r.URL.Query().Get("wait") == "true" {
+			status, ok := reloadData("api")
+			if !ok {
+				http.Error(w, "正在重新加载，请稍后再试", http.StatusConflict)
+				return
+			}
+			json.NewEncoder(w).Encode(status)
+			return
+		}
+		status := getReloadStatus()
+		if status.Reloading {
+			http.Error(w, "正在重新加载，请稍后再试", http.StatusConflict)
+			return
+		}
+		go reloadData("api")
+		status.Reloading = true
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(status)
+	default:
+		http.Error(w, "只支持 GET 和 POST", http.StatusMethodNotAllowed)
+	}
+}
@@ -1,313 +1,267 @@
 package main
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"log"
-	"math"
 	"net/http"
-	"os"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
+
+	"binance-kline/wei/bitmex"
 )
 
-// handleSnapshot 处理历史快照API请求
-func handleSnapshot(w http.ResponseWriter, r *http.Request) {
-	dateStr := r.URL.Query().Get("date")
-	if dateStr == "" {
-		dateStr = time.Now().Format("2006-01-02")
-	}
+// snapshotCheckpointEvery 每处理多少笔成交保存一次持仓核算检查点，查询时最多重放这么多笔成交
+const snapshotCheckpointEvery = 100
 
-	targetDate, err := time.Parse("2006-01-02", dateStr)
-	if err != nil {
-		http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
-		return
-	}
+// snapshotIndex 历史快照的内存索引：各类记录按时间排序，查询时二分查找。
+// 持仓由最近的检查点加上之后的成交重放得到
+type snapshotIndex struct {
+	balanceTimes []int64   // XBt 钱包记录时间（UnixNano）
+	balances     []float64 // 对应记录后的钱包余额（BTC）
 
-	snapshot := generateSnapshot(targetDate)
+	orders     []OrderData     // 按 TimestampUnix 升序
+	executions []ExecutionData // 按 TimestampUnix 升序
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(snapshot)
-}
+	trades      []bitmex.Execution // Trade 成交，按时间升序
+	tradeTimes  []int64            // 成交时间（UnixNano）
+	checkpoints []*bitmex.Book     // checkpoints[i] 为处理前 i*snapshotCheckpointEvery 笔成交后的持仓核算
 
-// generateSnapshot 生成指定日期的快照数据
-func generateSnapshot(targetDate time.Time) DailySnapshot {
-	// 设置日期范围
-	minDate := "2020-05-01"
-	maxDate := time.Now().Format("2006-01-02")
+	prices map[string][]bitmex.Kline // 各合约周期最小的K线，用于持仓估值
+	daily  []KlineData               // XBTUSD 日线，用于图表
 
-	// 1. 获取截至该日期的余额
-	balance := getBalanceAtDate(targetDate)
+	first time.Time // 最早的钱包记录或成交时间
+}
 
-	// 2. 计算截至该日期的持仓
-	positions := getPositionsAtDate(targetDate)
+// 历史快照索引，loadData 时重建
+var snapshots = &snapshotIndex{}
+
+// loadSnapshotIndex 由已加载的钱包历史、订单、成交记录和K线建立快照索引，需要在这些数据加载之后调用
+func loadSnapshotIndex(d *dataSet) {
+	start := time.Now()
+	idx := buildSnapshotIndex(d)
+	d.snapshots = idx
+	log.Printf("✓ 快照索引: %d 条余额, %d 笔成交（%d 个检查点）, %d 个订单, 耗时 %v",
+		len(idx.balances), len(idx.trades), len(idx.checkpoints), len(idx.orders),
+		time.Since(start).Round(time.Millisecond))
+}
 
-	// 3. 只保留BTC相关持仓
-	btcPositions := []Position{}
-	for _, pos := range positions {
-		if strings.Contains(pos.Symbol, "XBT") || pos.Symbol == "XBTUSD" {
-			btcPositions = append(btcPositions, pos)
+// buildSnapshotIndex 由数据集 d 建立快照索引
+func buildSnapshotIndex(d *dataSet) *snapshotIndex {
+	idx := &snapshotIndex{prices: make(map[string][]bitmex.Kline)}
+	observe := func(t time.Time) {
+		if !t.IsZero() && (idx.first.IsZero() || t.Before(idx.first)) {
+			idx.first = t
 		}
 	}
 
-	// 4. 计算未实现盈亏
-	var unrealizedPNL float64
-	for _, pos := range btcPositions {
-		unrealizedPNL += pos.UnrealizedPNL
+	// 钱包余额
+	var wallet []bitmex.WalletHistory
+	for _, h := range d.wallet {
+		if h.Currency == "XBt" && h.TransactStatus != "Canceled" && h.TransactStatus != "Pending" && !h.Time().IsZero() {
+			wallet = append(wallet, h)
+		}
+	}
+	sort.SliceStable(wallet, func(i, j int) bool { return wallet[i].Time().Before(wallet[j].Time()) })
+	for _, h := range wallet {
+		idx.balanceTimes = append(idx.balanceTimes, h.Time().UnixNano())
+		idx.balances = append(idx.balances, h.WalletBalanceBTC())
+	}
+	if len(wallet) > 0 {
+		observe(wallet[0].Time())
 	}
 
-	// 5. 获取当日订单
-	todayOrders := getOrdersAtDate(targetDate)
-
-	// 6. 获取最近50笔成交
-	recentExecs := getRecentExecutions(targetDate, 50)
-
-	// 7. 获取K线数据(该日期前90天)
-	klineData := getKlinesBeforeDate(targetDate, 90)
+	// 订单和成交列表
+	idx.orders = append([]OrderData(nil), d.orders...)
+	sort.SliceStable(idx.orders, func(i, j int) bool { return idx.orders[i].TimestampUnix < idx.orders[j].TimestampUnix })
+	idx.executions = append([]ExecutionData(nil), d.executions...)
+	sort.SliceStable(idx.executions, func(i, j int) bool {
+		return idx.executions[i].TimestampUnix < idx.executions[j].TimestampUnix
+	})
 
-	return DailySnapshot{
-		Date:          targetDate.Format("2006-01-02"),
-		Balance:       balance,
-		TotalEquity:   balance + unrealizedPNL,
-		UnrealizedPNL: unrealizedPNL,
-		BTCPositions:  btcPositions,
-		TodayOrders:   todayOrders,
-		RecentExecs:   recentExecs,
-		KlineData:     klineData,
-		MinDate:       minDate,
-		MaxDate:       maxDate,
+	// 持仓核算检查点
+	for _, e := range d.rawExecutions {
+		if e.ExecType == "Trade" && !e.Time().IsZero() {
+			idx.trades = append(idx.trades, e)
+		}
 	}
-}
-
-// getBalanceAtDate 获取截至指定日期的余额
-func getBalanceAtDate(targetDate time.Time) float64 {
-	file, err := os.Open("wallet.csv")
-	if err != nil {
-		log.Printf("Error opening wallet.csv: %v", err)
-		return 0
+	sort.SliceStable(idx.trades, func(i, j int) bool { return idx.trades[i].Time().Before(idx.trades[j].Time()) })
+	book := bitmex.NewBook(d.instruments, costMethod)
+	idx.tradeTimes = make([]int64, len(idx.trades))
+	for i, e := range idx.trades {
+		if i%snapshotCheckpointEvery == 0 {
+			idx.checkpoints = append(idx.checkpoints, book.Clone())
+		}
+		idx.tradeTimes[i] = e.Time().UnixNano()
+		book.Apply(e)
 	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return 0
+	if len(idx.trades) > 0 {
+		observe(idx.trades[0].Time())
 	}
 
-	var balance float64
-	for i := 1; i < len(records); i++ {
-		timestamp, err := time.Parse(time.RFC3339, records[i][9])
-		if err != nil {
-			continue
+	// K线
+	symbols := map[string]bool{"XBTUSD": true}
+	for _, e := range idx.trades {
+		symbols[e.Symbol] = true
+	}
+	for symbol := range symbols {
+		if klines, _ := cachedKlines(d.klines, symbol, ""); klines != nil {
+			idx.prices[symbol] = klines
 		}
+	}
+	idx.daily = d.klines["XBTUSD_1d"]
+	return idx
+}
 
-		// 只取该日期之前的记录
-		if timestamp.After(targetDate) {
-			break
+// parseSnapshotTime 解析 date 参数：只有日期时为该日结束时（当日所有记录之后），也可以是 RFC3339 或 loc 时区的日期时间
+func parseSnapshotTime(s string, loc *time.Location) (time.Time, bool) {
+	if t, err := time.ParseInLocation("2006-01-02", s, loc); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond), true
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true
 		}
-
-		balance, _ = strconv.ParseFloat(records[i][11], 64)
 	}
-
-	return balance
+	return time.Time{}, false
 }
 
-// getPositionsAtDate 计算截至指定日期的持仓
-func getPositionsAtDate(targetDate time.Time) []Position {
-	positions := []Position{}
-	positionsMap := make(map[string]*Position)
-
-	// 筛选截至目标日期的成交记录
-	filteredExecs := []ExecutionData{}
-	for _, exec := range executionsCache {
-		execTime, err := time.Parse(time.RFC3339, exec.Timestamp)
+// handleSnapshot 处理历史快照API请求
+// 参数: date=2024-01-02（该日结束时）或 2024-01-02T15:30[:00]、RFC3339 时间（默认今天），tz=UTC|Beijing（默认 PNL_TIMEZONE）
+func handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	loc := pnlLocation
+	if tz := query.Get("tz"); tz != "" {
+		parsed, err := bitmex.ParseTimezone(tz)
 		if err != nil {
-			continue
-		}
-
-		if execTime.Before(targetDate) || execTime.Equal(targetDate) {
-			filteredExecs = append(filteredExecs, exec)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
+		loc = parsed
 	}
 
-	// 计算持仓数量
-	for _, exec := range filteredExecs {
-		pos, exists := positionsMap[exec.Symbol]
-		if !exists {
-			pos = &Position{Symbol: exec.Symbol}
-			positionsMap[exec.Symbol] = pos
-		}
-
-		if exec.Side == "Buy" {
-			pos.Qty += exec.Qty
-		} else {
-			pos.Qty -= exec.Qty
-		}
+	dateStr := query.Get("date")
+	if dateStr == "" {
+		dateStr = time.Now().In(loc).Format("2006-01-02")
+	}
+	at, ok := parseSnapshotTime(dateStr, loc)
+	if !ok {
+		http.Error(w, "日期格式错误，应为 2006-01-02、2006-01-02T15:04[:05] 或 RFC3339", http.StatusBadRequest)
+		return
 	}
 
-	// 计算入场均价和未实现盈亏
-	for symbol, pos := range positionsMap {
-		if pos.Qty == 0 {
-			delete(positionsMap, symbol)
-			continue
-		}
-
-		// 计算入场均价
-		var totalCost float64
-		var totalQty int
-
-		for _, exec := range filteredExecs {
-			if exec.Symbol != symbol {
-				continue
-			}
-
-			if exec.Side == "Buy" {
-				totalQty += exec.Qty
-				totalCost += float64(exec.Qty) / exec.Price
-			} else {
-				totalQty -= exec.Qty
-				totalCost -= float64(exec.Qty) / exec.Price
-			}
-		}
+	snapshot := snapshots.generate(at, loc)
 
-		if totalQty != 0 && totalCost != 0 {
-			entryPrice := float64(totalQty) / totalCost
-			if !math.IsNaN(entryPrice) && !math.IsInf(entryPrice, 0) && entryPrice > 0 {
-				pos.EntryPrice = entryPrice
-			}
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
 
-		// 获取该日期的收盘价作为当前价
-		pos.CurrentPrice = getClosePriceAtDate(symbol, targetDate)
+// generate 生成 at 时刻的快照数据，当日订单按 loc 时区的日期
+func (idx *snapshotIndex) generate(at time.Time, loc *time.Location) DailySnapshot {
+	minDate := idx.first
+	if minDate.IsZero() {
+		minDate = at
+	}
 
-		// 设置方向
-		if pos.Qty > 0 {
-			pos.Side = "Long"
-		} else {
-			pos.Side = "Short"
-		}
+	// 1. 截至该时刻的余额
+	balance := idx.balanceAt(at)
 
-		// 计算未实现盈亏
-		if pos.EntryPrice > 0 && pos.CurrentPrice > 0 {
-			qty := float64(pos.Qty)
-			pnl := qty * (1.0/pos.EntryPrice - 1.0/pos.CurrentPrice)
-
-			pnlPercent := (pos.CurrentPrice/pos.EntryPrice - 1.0) * 100
-			if pos.Side == "Short" {
-				pnlPercent = -pnlPercent
-			}
-
-			if !math.IsNaN(pnl) && !math.IsInf(pnl, 0) {
-				pos.UnrealizedPNL = pnl
-			}
-			if !math.IsNaN(pnlPercent) && !math.IsInf(pnlPercent, 0) {
-				pos.UnrealizedPNLPercent = pnlPercent
-			}
+	// 2. 截至该时刻的持仓，只保留BTC相关持仓
+	btcPositions := []Position{}
+	for _, pos := range idx.positionsAt(at) {
+		if strings.Contains(pos.Symbol, "XBT") {
+			btcPositions = append(btcPositions, pos)
 		}
 	}
 
-	for _, pos := range positionsMap {
-		positions = append(positions, *pos)
-	}
+	// 3. 未实现盈亏（换算为 BTC）
+	unrealizedPNL := unrealizedBTC(btcPositions, bitmex.ClosePriceAt(idx.prices["XBTUSD"], at))
 
-	return positions
+	return DailySnapshot{
+		Date:          at.In(loc).Format("2006-01-02"),
+		Time:          at.In(loc).Format(time.RFC3339),
+		Balance:       balance,
+		TotalEquity:   balance + unrealizedPNL,
+		UnrealizedPNL: unrealizedPNL,
+		BTCPositions:  btcPositions,
+		TodayOrders:   idx.ordersOnDay(at, loc),
+		RecentExecs:   idx.recentExecutions(at, 50),
+		KlineData:     idx.klinesBefore(at, 90),
+		MinDate:       minDate.In(loc).Format("2006-01-02"),
+		MaxDate:       time.Now().In(loc).Format("2006-01-02"),
+	}
 }
 
-// getClosePriceAtDate 获取指定日期的K线收盘价
-func getClosePriceAtDate(symbol string, targetDate time.Time) float64 {
-	key := symbol + "_1d"
-	klines, exists := klinesCache[key]
-	if !exists {
+// balanceAt 截至 at（含）的钱包余额
+func (idx *snapshotIndex) balanceAt(at time.Time) float64 {
+	n := sort.Search(len(idx.balanceTimes), func(i int) bool { return idx.balanceTimes[i] > at.UnixNano() })
+	if n == 0 {
 		return 0
 	}
-
-	targetTimestamp := targetDate.Unix()
-	var closePrice float64
-
-	// 查找该日期或之前的最近一条K线
-	for i := len(klines) - 1; i >= 0; i-- {
-		if klines[i].Time <= targetTimestamp {
-			closePrice = klines[i].Close
-			break
-		}
-	}
-
-	return closePrice
+	return idx.balances[n-1]
 }
 
-// getOrdersAtDate 获取指定日期的订单
-func getOrdersAtDate(targetDate time.Time) []OrderData {
-	var orders []OrderData
-
-	for _, order := range ordersCache {
-		orderTime, err := time.Parse(time.RFC3339, order.Timestamp)
-		if err != nil {
-			continue
-		}
-
-		// 判断是否在同一天
-		if orderTime.Year() == targetDate.Year() &&
-			orderTime.Month() == targetDate.Month() &&
-			orderTime.Day() == targetDate.Day() {
-			orders = append(orders, order)
-		}
+// positionsAt 核算截至 at（含）的持仓：从最近的检查点重放之后的成交，当前价格使用 at 时刻的收盘价
+func (idx *snapshotIndex) positionsAt(at time.Time) []Position {
+	n := sort.Search(len(idx.tradeTimes), func(i int) bool { return idx.tradeTimes[i] > at.UnixNano() })
+	if n == 0 || len(idx.checkpoints) == 0 {
+		return []Position{}
 	}
-
-	// 按时间倒序
-	sort.Slice(orders, func(i, j int) bool {
-		return orders[i].TimestampUnix > orders[j].TimestampUnix
+	cp := min(n/snapshotCheckpointEvery, len(idx.checkpoints)-1)
+	book := idx.checkpoints[cp].Clone()
+	for _, e := range idx.trades[cp*snapshotCheckpointEvery : n] {
+		book.Apply(e)
+	}
+	return bookPositions(book, func(symbol string, a *bitmex.PositionAccount) float64 {
+		if price := bitmex.ClosePriceAt(idx.prices[symbol], at); price > 0 {
+			return price
+		}
+		return a.LastPrice
 	})
-
-	return orders
 }
 
-// getRecentExecutions 获取截至指定日期的最近N笔成交
-func getRecentExecutions(targetDate time.Time, limit int) []ExecutionData {
-	var executions []ExecutionData
-
-	for _, exec := range executionsCache {
-		execTime, err := time.Parse(time.RFC3339, exec.Timestamp)
-		if err != nil {
-			continue
-		}
+// ordersOnDay at 所在日期（loc 时区）从零点到 at 的订单，按时间倒序
+func (idx *snapshotIndex) ordersOnDay(at time.Time, loc *time.Location) []OrderData {
+	local := at.In(loc)
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc).Unix()
+	from := sort.Search(len(idx.orders), func(i int) bool { return idx.orders[i].TimestampUnix >= dayStart })
+	to := sort.Search(len(idx.orders), func(i int) bool { return idx.orders[i].TimestampUnix > at.Unix() })
 
-		if execTime.Before(targetDate) || execTime.Equal(targetDate) {
-			executions = append(executions, exec)
-		}
+	orders := make([]OrderData, 0, max(to-from, 0))
+	for i := to - 1; i >= from; i-- {
+		orders = append(orders, idx.orders[i])
 	}
+	return orders
+}
 
-	// 按时间倒序
-	sort.Slice(executions, func(i, j int) bool {
-		return executions[i].TimestampUnix > executions[j].TimestampUnix
-	})
+// recentExecutions 截至 at（含）的最近 limit 笔成交，按时间倒序
+func (idx *snapshotIndex) recentExecutions(at time.Time, limit int) []ExecutionData {
+	to := sort.Search(len(idx.executions), func(i int) bool { return idx.executions[i].TimestampUnix > at.Unix() })
 
-	// 限制返回数量
-	if len(executions) > limit {
-		executions = executions[:limit]
+	executions := make([]ExecutionData, 0, min(to, limit))
+	for i := to - 1; i >= 0 && len(executions) < limit; i-- {
+		executions = append(executions, idx.executions[i])
 	}
-
 	return executions
 }
 
-// getKlinesBeforeDate 获取指定日期前N天的K线数据
-func getKlinesBeforeDate(targetDate time.Time, days int) []KlineData {
-	key := "XBTUSD_1d"
-	klines, exists := klinesCache[key]
-	if !exists {
-		return []KlineData{}
-	}
-
-	targetTimestamp := targetDate.Unix()
-	startTimestamp := targetDate.AddDate(0, 0, -days).Unix()
+// klinesBefore at 之前 days 天内的 XBTUSD 日线
+func (idx *snapshotIndex) klinesBefore(at time.Time, days int) []KlineData {
+	start := at.AddDate(0, 0, -days).Unix()
+	from := sort.Search(len(idx.daily), func(i int) bool { return idx.daily[i].Time >= start })
+	to := sort.Search(len(idx.daily), func(i int) bool { return idx.daily[i].Time > at.Unix() })
+	return append([]KlineData{}, idx.daily[from:max(from, to)]...)
+}
 
-	var result []KlineData
-	for _, kline := range klines {
-		if kline.Time >= startTimestamp && kline.Time <= targetTimestamp {
-			result = append(result, kline)
-		}
+// getClosePriceAtDate 获取指定时刻之前最近一根日线的收盘价
+func getClosePriceAtDate(symbol string, targetDate time.Time) float64 {
+	klines := klinesCache[symbol+"_1d"]
+	n := sort.Search(len(klines), func(i int) bool { return klines[i].Time > targetDate.Unix() })
+	if n == 0 {
+		return 0
 	}
-
-	return result
+	return klines[n-1].Close
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// snapshotTrades 生成 count 笔按分钟递增的成交（倒序返回，检验索引会重新排序），
+// 数量正负交替、不时反手，混有未知合约和非 Trade 记录
+func snapshotTrades(count int) ([]bitmex.Execution, []time.Time) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var executions []bitmex.Execution
+	var times []time.Time
+	for i := 0; i < count; i++ {
+		at := base.Add(time.Duration(i) * time.Minute)
+		qty := ((i*37)%11 - 5) * 100
+		if qty == 0 {
+			qty = 300
+		}
+		side := "Buy"
+		if qty < 0 {
+			side, qty = "Sell", -qty
+		}
+		symbol := "XBTUSD"
+		switch {
+		case i%50 == 7:
+			symbol = "ETHUSD"
+		case i%3 == 2:
+			symbol = "XBTZ24"
+		}
+		executions = append(executions, bitmex.Execution{
+			ExecID: "t" + at.Format("150405"), Symbol: symbol, Side: side, LastQty: qty,
+			LastPx: 40000 + float64((i*53)%1000), Commission: 0.00075,
+			TransactTime: at.Format(time.RFC3339), ExecType: "Trade",
+		})
+		times = append(times, at)
+		if i%40 == 0 {
+			executions = append(executions, bitmex.Execution{
+				ExecID: "f" + at.Format("150405"), Symbol: "XBTUSD", LastQty: 1000, LastPx: 40000,
+				TransactTime: at.Format(time.RFC3339), ExecType: "Funding",
+			})
+		}
+	}
+	for i, j := 0, len(executions)-1; i < j; i, j = i+1, j-1 {
+		executions[i], executions[j] = executions[j], executions[i]
+	}
+	return executions, times
+}
+
+// cloneCheckpoints 深拷贝检查点，用于确认查询不会修改检查点
+func cloneCheckpoints(checkpoints []*bitmex.Book) []*bitmex.Book {
+	copied := make([]*bitmex.Book, len(checkpoints))
+	for i, b := range checkpoints {
+		copied[i] = b.Clone()
+	}
+	return copied
+}
+
+func TestSnapshotPositionsAt(t *testing.T) {
+	saved := costMethod
+	t.Cleanup(func() { costMethod = saved })
+
+	const count = 250
+	executions, times := snapshotTrades(count)
+	lastPrice := func(symbol string, a *bitmex.PositionAccount) float64 { return a.LastPrice }
+
+	for _, method := range []bitmex.CostMethod{bitmex.AverageCost, bitmex.FIFO} {
+		t.Run(string(method), func(t *testing.T) {
+			costMethod = method
+			idx := buildSnapshotIndex(&dataSet{rawExecutions: executions})
+			if len(idx.trades) != count {
+				t.Fatalf("trades = %d，应为 %d", len(idx.trades), count)
+			}
+			if want := (count + snapshotCheckpointEvery - 1) / snapshotCheckpointEvery; len(idx.checkpoints) != want {
+				t.Fatalf("检查点 = %d 个，应为 %d", len(idx.checkpoints), want)
+			}
+			before := cloneCheckpoints(idx.checkpoints)
+
+			// 检查点边界前后，以及倒序查询（检验检查点的批次没有被重放修改）
+			for _, n := range []int{count, 201, 200, 199, 101, 100, 99, 1, 0, 150, 100} {
+				at := times[0].Add(-time.Second)
+				if n > 0 {
+					at = times[n-1]
+				}
+				want := bookPositions(bitmex.BuildBook(executions, nil, method, at), lastPrice)
+				got := idx.positionsAt(at)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("n=%d: positionsAt = %+v\n全量重放 = %+v", n, got, want)
+				}
+				if n > 0 && len(want) == 0 {
+					t.Errorf("n=%d: 测试数据应有未平仓持仓", n)
+				}
+			}
+			if got := idx.positionsAt(times[count-1].Add(time.Hour)); !reflect.DeepEqual(got, idx.positionsAt(times[count-1])) {
+				t.Errorf("最后一笔成交之后的持仓应与最后一笔成交时相同")
+			}
+
+			if !reflect.DeepEqual(idx.checkpoints, before) {
+				t.Errorf("查询修改了检查点")
+			}
+			for i, cp := range idx.checkpoints {
+				want := bitmex.BuildBook(executions, nil, method, times[i*snapshotCheckpointEvery].Add(-time.Second))
+				if !reflect.DeepEqual(bookPositions(cp, lastPrice), bookPositions(want, lastPrice)) {
+					t.Errorf("checkpoints[%d] 应为前 %d 笔成交后的持仓", i, i*snapshotCheckpointEvery)
+				}
+			}
+		})
+	}
+}
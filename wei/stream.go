@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	streamHistory   = 500              // 保留的事件数，重连时 Last-Event-ID 在此范围内可以补发错过的事件
+	streamKeepAlive = 25 * time.Second // 没有事件时发送注释行，防止代理断开空闲连接
+	streamRetry     = 3 * time.Second  // 断线后浏览器重连的间隔
+)
+
+// streamStates 状态类事件，内容为完整的当前状态（与对应 REST 接口相同），变化时推送：
+// reload（/api/reload）、account（/api/account）、positions（/api/positions）、orders（/api/orders/pending）。
+// 另有 execution 事件，内容为新增的成交（按时间倒序，最多 executionsLimit 笔）
+var streamStates = []string{"reload", "account", "positions", "orders"}
+
+// streamEvent 一条推送事件
+type streamEvent struct {
+	seq  uint64
+	typ  string
+	data []byte // JSON
+}
+
+// streamHub 推送事件的发布和订阅。事件 ID 为 "<启动时间>-<序号>"，服务器重启后旧的 ID 失效
+type streamHub struct {
+	mu      sync.Mutex
+	run     int64
+	seq     uint64
+	history []streamEvent              // 最近 streamHistory 条事件
+	states  map[string][]byte          // 各状态类事件最近一次推送的内容
+	execIDs map[string]bool            // 已推送的成交，nil 表示还没有加载过
+	recent  []ExecutionData            // 最近的成交，新连接时发送
+	subs    map[chan struct{}]struct{} // 订阅者，有新事件时收到通知
+}
+
+// 推送事件，每次加载数据后由 publishChanges 更新。服务器不直接连接 BitMEX 实时接口，
+// 事件只在 reloadData 之后产生（启动、RELOAD_INTERVAL 监视到数据文件变化、POST /api/reload）；
+// cmd/bitmex stream 每隔 -flush 写入 CSV 文件，由文件监视触发重新加载
+var streams = &streamHub{
+	run:    time.Now().Unix(),
+	states: make(map[string][]byte),
+	subs:   make(map[chan struct{}]struct{}),
+}
+
+// publishChanges 将本次加载后的账户、持仓、未成交订单和新增成交与上次推送的比较，推送有变化的部分
+func publishChanges(status ReloadStatus) {
+	dataMu.RLock()
+	defer dataMu.RUnlock()
+
+	states := map[string]any{
+		"reload":    status,
+		"account":   calculateAccountInfo(nil),
+		"positions": currentPositions(costMethod, false),
+		"orders":    pendingOrders(),
+	}
+	streams.update(states, executionsCache, latestExecutions(executionsLimit))
+}
+
+// update 记录新的状态和成交，生成事件并通知订阅者
+func (h *streamHub) update(states map[string]any, executions, recent []ExecutionData) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, typ := range streamStates {
+		data, err := json.Marshal(states[typ])
+		if err != nil {
+			log.Printf("⚠ 推送 %s 失败: %v", typ, err)
+			continue
+		}
+		if !bytes.Equal(data, h.states[typ]) {
+			h.states[typ] = data
+			h.append(typ, data)
+		}
+	}
+
+	// 新增成交：第一次加载时只记录，不推送
+	ids := make(map[string]bool, len(executions))
+	var added []ExecutionData
+	for _, e := range executions {
+		ids[e.ExecID] = true
+		if h.execIDs != nil && !h.execIDs[e.ExecID] {
+			added = append(added, e)
+		}
+	}
+	h.execIDs = ids
+	h.recent = recent
+	if len(added) > 0 {
+		sort.SliceStable(added, func(i, j int) bool { return added[i].TimestampUnix > added[j].TimestampUnix })
+		if len(added) > executionsLimit {
+			added = added[:executionsLimit]
+		}
+		if data, err := json.Marshal(added); err == nil {
+			h.append("execution", data)
+		}
+	}
+
+	for ch := range h.subs {
+		select {
+		case ch <- struct{}{}:
+		default: // 已有未处理的通知
+		}
+	}
+}
+
+// append 生成下一个事件，只保留最近 streamHistory 条
+func (h *streamHub) append(typ string, data []byte) {
+	h.seq++
+	h.history = append(h.history, streamEvent{seq: h.seq, typ: typ, data: data})
+	if len(h.history) > streamHistory {
+		h.history = append([]streamEvent(nil), h.history[len(h.history)-streamHistory:]...)
+	}
+}
+
+// id 事件序号对应的事件 ID
+func (h *streamHub) id(seq uint64) string {
+	return fmt.Sprintf("%d-%d", h.run, seq)
+}
+
+// parseID 解析事件 ID，不是本次启动生成的 ID 时返回 false
+func (h *streamHub) parseID(id string) (uint64, bool) {
+	run, seq, ok := strings.Cut(id, "-")
+	if !ok || run != strconv.FormatInt(h.run, 10) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil || n > h.seq {
+		return 0, false
+	}
+	return n, true
+}
+
+// eventsAfter lastID 之后的事件。lastID 为空、无效或已经超出保留范围时，返回当前的完整状态
+func (h *streamHub) eventsAfter(lastID string) []streamEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if seq, ok := h.parseID(lastID); ok && (seq == h.seq || len(h.history) > 0 && seq+1 >= h.history[0].seq) {
+		i := sort.Search(len(h.history), func(i int) bool { return h.history[i].seq > seq })
+		return append([]streamEvent(nil), h.history[i:]...)
+	}
+
+	// 完整状态，事件 ID 均为当前序号，之后从这里继续
+	var events []streamEvent
+	for _, typ := range streamStates {
+		if data := h.states[typ]; data != nil {
+			events = append(events, streamEvent{seq: h.seq, typ: typ, data: data})
+		}
+	}
+	if len(h.recent) > 0 {
+		if data, err := json.Marshal(h.recent); err == nil {
+			events = append(events, streamEvent{seq: h.seq, typ: "execution", data: data})
+		}
+	}
+	return events
+}
+
+// subscribe 注册订阅者，有新事件时通知
+func (h *streamHub) subscribe() chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan struct{}, 1)
+	h.subs[ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe 取消订阅
+func (h *streamHub) unsubscribe(ch chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs, ch)
+}
+
+// handleStream Server-Sent Events 推送账户、持仓、未成交订单和新增成交的变化（只在数据重新加载后推送，见 streams）。
+// 重连时浏览器自动发送 Last-Event-ID（也可用参数 lastEventId），补发之后的事件；无法补发时先发送完整状态
+func handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "不支持推送", http.StatusInternalServerError)
+		return
+	}
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("lastEventId")
+	}
+
+	// 先订阅再取事件，避免漏掉两者之间的更新
+	notify := streams.subscribe()
+	defer streams.unsubscribe(notify)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+
+	send := func() error {
+		for _, e := range streams.eventsAfter(lastID) {
+			lastID = streams.id(e.seq)
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", lastID, e.typ, e.data); err != nil {
+				return err
+			}
+		}
+		flusher.Flush()
+		return nil
+	}
+	if send() != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-notify:
+			if send() != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
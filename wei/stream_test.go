@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func newTestHub() *streamHub {
+	return &streamHub{
+		run:    1700000000,
+		states: make(map[string][]byte),
+		subs:   make(map[chan struct{}]struct{}),
+	}
+}
+
+func testStates(balance float64) map[string]any {
+	return map[string]any{
+		"reload":    map[string]any{"reloads": 1},
+		"account":   map[string]any{"balance": balance},
+		"positions": []Position{{Symbol: "XBTUSD", Side: "Long", Qty: 100}},
+		"orders":    []OrderData{},
+	}
+}
+
+func testExecutionData(ids ...string) []ExecutionData {
+	var executions []ExecutionData
+	for i, id := range ids {
+		executions = append(executions, ExecutionData{ExecID: id, Symbol: "XBTUSD", Qty: 100, TimestampUnix: int64(1000 + i)})
+	}
+	return executions
+}
+
+// eventSummary 事件的类型和序号，便于比较
+func eventSummary(events []streamEvent) []string {
+	var summary []string
+	for _, e := range events {
+		summary = append(summary, e.typ+"@"+strconv.FormatUint(e.seq, 10))
+	}
+	return summary
+}
+
+// assertFullState 完整状态：各状态类事件加最近成交，ID 均为当前序号
+func assertFullState(t *testing.T, h *streamHub, events []streamEvent, recent []ExecutionData) {
+	t.Helper()
+	var types []string
+	for _, e := range events {
+		types = append(types, e.typ)
+		if e.seq != h.seq {
+			t.Errorf("完整状态 %s 的序号 = %d，应为当前序号 %d", e.typ, e.seq, h.seq)
+		}
+		if e.typ != "execution" && string(e.data) != string(h.states[e.typ]) {
+			t.Errorf("完整状态 %s = %s，应为 %s", e.typ, e.data, h.states[e.typ])
+		}
+	}
+	want := append(append([]string{}, streamStates...), "execution")
+	if !reflect.DeepEqual(types, want) {
+		t.Fatalf("完整状态的事件 = %v，应为 %v", types, want)
+	}
+	var got []ExecutionData
+	if err := json.Unmarshal(events[len(events)-1].data, &got); err != nil {
+		t.Fatalf("解析 execution 失败: %v", err)
+	}
+	if !reflect.DeepEqual(got, recent) {
+		t.Errorf("完整状态的 execution = %+v，应为最近成交 %+v", got, recent)
+	}
+}
+
+func TestStreamHubEventsAfter(t *testing.T) {
+	h := newTestHub()
+	notify := h.subscribe()
+
+	// 第一次加载：推送状态，已有成交只记录
+	h.update(testStates(1), testExecutionData("e1", "e2"), testExecutionData("e2", "e1"))
+	if got, want := eventSummary(h.history), []string{"reload@1", "account@2", "positions@3", "orders@4"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("第一次加载的事件 = %v，应为 %v", got, want)
+	}
+	select {
+	case <-notify:
+	default:
+		t.Errorf("更新后订阅者应收到通知")
+	}
+
+	// 首次连接：完整状态
+	assertFullState(t, h, h.eventsAfter(""), testExecutionData("e2", "e1"))
+
+	// 账户变化、新增成交 e3；重复的成交和未变化的状态不推送
+	h.update(testStates(2), testExecutionData("e1", "e2", "e3"), testExecutionData("e3", "e2", "e1"))
+	h.update(testStates(2), testExecutionData("e1", "e2", "e3"), testExecutionData("e3", "e2", "e1"))
+	if got, want := eventSummary(h.history[4:]), []string{"account@5", "execution@6"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("第二次加载的事件 = %v，应为 %v", got, want)
+	}
+	var added []ExecutionData
+	if err := json.Unmarshal(h.history[5].data, &added); err != nil || len(added) != 1 || added[0].ExecID != "e3" {
+		t.Errorf("execution 事件 = %s，应只有新增的 e3", h.history[5].data)
+	}
+
+	tests := []struct {
+		name   string
+		lastID string
+		want   []string // nil 表示完整状态
+	}{
+		{"补发之后的事件", h.id(4), []string{"account@5", "execution@6"}},
+		{"补发最后一条", h.id(5), []string{"execution@6"}},
+		{"已是最新", h.id(6), []string{}},
+		{"从头补发", h.id(0), []string{"reload@1", "account@2", "positions@3", "orders@4", "account@5", "execution@6"}},
+		{"上次启动的 ID", "1699999999-4", nil},
+		{"超出当前序号", h.id(7), nil},
+		{"格式错误", "abc", nil},
+		{"序号错误", "1700000000-x", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := h.eventsAfter(tt.lastID)
+			if tt.want == nil {
+				assertFullState(t, h, events, testExecutionData("e3", "e2", "e1"))
+				return
+			}
+			got := eventSummary(events)
+			if got == nil {
+				got = []string{}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("eventsAfter(%q) = %v，应为 %v", tt.lastID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamHubHistoryLimit(t *testing.T) {
+	h := newTestHub()
+	h.update(testStates(0), nil, testExecutionData("e1"))
+	for i := 1; i <= streamHistory+100; i++ {
+		h.update(testStates(float64(i)), nil, testExecutionData("e1"))
+	}
+	if len(h.history) != streamHistory {
+		t.Fatalf("history = %d 条，应只保留 %d 条", len(h.history), streamHistory)
+	}
+	first := h.history[0].seq
+	if first != h.seq-streamHistory+1 {
+		t.Fatalf("最早保留的序号 = %d，应为 %d", first, h.seq-streamHistory+1)
+	}
+
+	// 最早保留事件的前一条仍可补发全部保留的事件
+	if events := h.eventsAfter(h.id(first - 1)); len(events) != streamHistory || events[0].seq != first {
+		t.Errorf("eventsAfter(%d) = %d 条，应补发全部 %d 条", first-1, len(events), streamHistory)
+	}
+	if events := h.eventsAfter(h.id(h.seq - 1)); len(events) != 1 || events[0].seq != h.seq {
+		t.Errorf("eventsAfter(%d) 应只补发最后一条", h.seq-1)
+	}
+
+	// 已超出保留范围：中间的事件已丢失，发送完整状态
+	assertFullState(t, h, h.eventsAfter(h.id(first-2)), testExecutionData("e1"))
+	assertFullState(t, h, h.eventsAfter(h.id(1)), testExecutionData("e1"))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"binance-kline/wei/analytics"
+	"binance-kline/wei/bitmex"
+)
+
+// handleTax 按年度的美元税务报告：已实现盈亏（税务批次）、资金费用收支和手续费
+// 参数: year（默认全部年份），method=fifo|average（默认 COST_METHOD），tz=UTC|Beijing（默认 PNL_TIMEZONE），
+// format=json|csv|html（csv 为税务批次明细，html 为可打印报告），detail=true 时 json 包含税务批次
+func handleTax(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if usdPrices == nil {
+		http.Error(w, "没有 XBTUSD K线，不能按美元估值", http.StatusServiceUnavailable)
+		return
+	}
+
+	opts := analytics.TaxOptions{Method: costMethod, Location: pnlLocation, Prices: usdPrices}
+	if s := query.Get("method"); s != "" {
+		method, err := bitmex.ParseCostMethod(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Method = method
+	}
+	if s := query.Get("tz"); s != "" {
+		loc, err := bitmex.ParseTimezone(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Location = loc
+	}
+	if s := query.Get("year"); s != "" {
+		year, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, "year 应为年份，如 2024", http.StatusBadRequest)
+			return
+		}
+		opts.Year = year
+	}
+
+	report := analytics.BuildTaxReport(rawExecutionsCache, walletCache, instrumentsCache, opts)
+	name := "tax"
+	if opts.Year != 0 {
+		name = fmt.Sprintf("tax_%d", opts.Year)
+	}
+
+	switch query.Get("format") {
+	case "", "json":
+		if query.Get("detail") != "true" {
+			report.Lots = nil
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename="+name+analytics.TaxLotsSuffix)
+		analytics.WriteTaxLots(w, report.Lots, opts.Location)
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		analytics.WriteTaxHTML(w, report, opts.Location)
+	default:
+		http.Error(w, "未知格式: "+query.Get("format")+"（可用: json, csv, html）", http.StatusBadRequest)
+	}
+}
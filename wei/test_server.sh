@@ -4,7 +4,7 @@ echo "启动 Web 服务器测试..."
 echo ""
 
 # 在后台启动服务器
-go run web_server.go > server.log 2>&1 &
+go run . > server.log 2>&1 &
 SERVER_PID=$!
 
 echo "服务器 PID: $SERVER_PID"
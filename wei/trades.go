@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"binance-kline/wei/bitmex"
+)
+
+// TradeData 一笔完整交易（开仓到平仓）
+type TradeData struct {
+	Symbol       string  `json:"symbol"`
+	Side         string  `json:"side"`
+	Currency     string  `json:"currency"`
+	Status       string  `json:"status"` // open / closed
+	OpenTime     string  `json:"openTime"`
+	CloseTime    string  `json:"closeTime,omitempty"`
+	HoldingHours float64 `json:"holdingHours"` // 未平仓时计算到当前
+	Fills        int     `json:"fills"`
+	MaxQty       int     `json:"maxQty"`
+	EntryQty     int     `json:"entryQty"`
+	ExitQty      int     `json:"exitQty"`
+	EntryPrice   float64 `json:"entryPrice"`
+	ExitPrice    float64 `json:"exitPrice"`
+	PnL          float64 `json:"pnl"`
+	Fees         float64 `json:"fees"`
+	NetPnL       float64 `json:"netPnl"`
+	NetPnLBTC    float64 `json:"netPnlBtc"`
+	NetPnLUSD    float64 `json:"netPnlUsd"`
+	MAE          float64 `json:"mae"` // 百分比
+	MFE          float64 `json:"mfe"` // 百分比
+}
+
+// TradesResponse /api/trades 响应
+type TradesResponse struct {
+	Summary bitmex.TradeSummary `json:"summary"`
+	Trades  []TradeData         `json:"trades"`
+}
+
+// 由成交记录还原的完整交易
+var tradesCache []bitmex.RoundTrip
+
+// loadTrades 由 executions.csv 还原完整交易，需要在K线和合约信息加载之后调用
+func loadTrades(d *dataSet) {
+	xbtKlines, _ := cachedKlines(d.klines, "XBTUSD", "1d")
+	trips, unknown := bitmex.ReconstructTrades(d.rawExecutions, d.instruments, func(t time.Time) float64 {
+		return bitmex.ClosePriceAt(xbtKlines, t)
+	})
+	for _, symbol := range unknown {
+		log.Printf("⚠ 交易还原: 未知合约 %s，跳过", symbol)
+	}
+
+	now := time.Now()
+	done := make(map[string]bool)
+	for _, t := range trips {
+		if done[t.Symbol] {
+			continue
+		}
+		done[t.Symbol] = true
+		if klines, binSize := cachedKlines(d.klines, t.Symbol, ""); klines != nil {
+			bitmex.ApplyExcursions(trips, t.Symbol, klines, binSize, now)
+		}
+	}
+
+	d.trades = trips
+	log.Printf("✓ 还原 %d 笔交易", len(trips))
+}
+
+// cachedKlines 从K线缓存 cache 中取 symbol 的K线，timeframe 为空时取周期最小的
+func cachedKlines(cache map[string][]KlineData, symbol, timeframe string) ([]bitmex.Kline, time.Duration) {
+	var best string
+	var bestSize time.Duration
+	for key := range cache {
+		i := strings.LastIndex(key, "_")
+		if i < 0 {
+			continue
+		}
+		s, tf := key[:i], key[i+1:]
+		if s != symbol || (timeframe != "" && tf != timeframe) {
+			continue
+		}
+		d, err := bitmex.ParseTimeframe(tf)
+		if err != nil {
+			continue
+		}
+		if best == "" || d < bestSize {
+			best, bestSize = key, d
+		}
+	}
+	if best == "" {
+		return nil, 0
+	}
+
+	data := cache[best]
+	klines := make([]bitmex.Kline, len(data))
+	for i, k := range data {
+		klines[i] = bitmex.Kline{
+			Timestamp: time.Unix(k.Time, 0).UTC(),
+			Symbol:    symbol,
+			Open:      k.Open,
+			High:      k.High,
+			Low:       k.Low,
+			Close:     k.Close,
+			Volume:    k.Volume,
+		}
+	}
+	return klines, bestSize
+}
+
+// handleTrades 完整交易列表及统计
+// 参数: symbol，status=open|closed
+func handleTrades(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	status := r.URL.Query().Get("status")
+
+	now := time.Now()
+	var trips []bitmex.RoundTrip
+	trades := []TradeData{}
+	for _, t := range tradesCache {
+		if symbol != "" && t.Symbol != symbol {
+			continue
+		}
+		data := TradeData{
+			Symbol:       t.Symbol,
+			Side:         t.Side,
+			Currency:     t.Currency,
+			Status:       "closed",
+			OpenTime:     t.OpenTime.Format(bitmex.TimeLayout),
+			HoldingHours: t.HoldingTime(now).Hours(),
+			Fills:        t.Fills,
+			MaxQty:       t.MaxQty,
+			EntryQty:     t.EntryQty,
+			ExitQty:      t.ExitQty,
+			EntryPrice:   t.EntryPrice,
+			ExitPrice:    t.ExitPrice,
+			PnL:          t.PnL,
+			Fees:         t.Fees,
+			NetPnL:       t.NetPnL,
+			NetPnLBTC:    t.NetPnLBTC,
+			NetPnLUSD:    t.NetPnLUSD,
+			MAE:          t.MAE,
+			MFE:          t.MFE,
+		}
+		if t.IsOpen() {
+			data.Status = "open"
+		} else {
+			data.CloseTime = t.CloseTime.Format(bitmex.TimeLayout)
+		}
+		if status != "" && data.Status != status {
+			continue
+		}
+		trips = append(trips, t)
+		trades = append(trades, data)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TradesResponse{Summary: bitmex.SummarizeTrades(trips), Trades: trades})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"binance-kline/wei/analytics"
+	"binance-kline/wei/bitmex"
+)
+
+// WalletEntryData 一条钱包记录（金额已换算为 BTC / USDT），currency=USD 时附带美元估值
+type WalletEntryData struct {
+	TransactID       string  `json:"transactId"`
+	TransactType     string  `json:"transactType"`
+	TransactStatus   string  `json:"transactStatus"`
+	Currency         string  `json:"currency"`
+	Time             string  `json:"time"`
+	Amount           float64 `json:"amount"`
+	Fee              float64 `json:"fee"`
+	WalletBalance    float64 `json:"walletBalance"`
+	Price            float64 `json:"price,omitempty"`            // 记录时刻的 XBTUSD 价格
+	AmountUSD        float64 `json:"amountUsd,omitempty"`        // 美元估值
+	FeeUSD           float64 `json:"feeUsd,omitempty"`           // 美元估值
+	WalletBalanceUSD float64 `json:"walletBalanceUsd,omitempty"` // 美元估值
+}
+
+// 美元估值的价格来源（环境变量 PRICE_SOURCE: close / vwap），价格序列由周期最小的 XBTUSD K线生成
+var (
+	priceSource = bitmex.PriceClose
+	usdPrices   *bitmex.PriceSeries
+)
+
+// loadPrices 生成美元估值使用的 XBTUSD 价格序列，需要在K线加载之后调用
+func loadPrices(d *dataSet) {
+	klines, binSize := cachedKlines(d.klines, "XBTUSD", "")
+	if klines == nil {
+		log.Printf("⚠ 没有 XBTUSD K线，不能按美元估值（可运行 make download-klines）")
+		return
+	}
+	d.prices = bitmex.NewPriceSeries(klines, binSize, priceSource)
+	log.Printf("✓ 美元估值: XBTUSD K线 %d 条（周期 %v），价格来源 %s", d.prices.Len(), binSize, priceSource)
+}
+
+// parseCurrency 解析 currency 参数（BTC / USD，默认 BTC）。USD 时返回价格序列，没有 XBTUSD K线时返回错误
+func parseCurrency(r *http.Request) (string, *bitmex.PriceSeries, error) {
+	currency, err := analytics.ParseUnit(r.URL.Query().Get("currency"))
+	if err != nil {
+		return "", nil, err
+	}
+	if currency == analytics.BTC {
+		return currency, nil, nil
+	}
+	if usdPrices == nil {
+		return "", nil, fmt.Errorf("没有 XBTUSD K线，不能按美元估值")
+	}
+	return currency, usdPrices, nil
+}
+
+// handleWallet 钱包历史（按时间倒序）
+// 参数: currency=BTC|USD（USD 时附带记录时刻的价格和美元估值），type=TransactType，limit=返回条数
+func handleWallet(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	_, usd, err := parseCurrency(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := 0
+	if s := query.Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 0 {
+			http.Error(w, "limit 应为非负整数", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries := make([]WalletEntryData, 0, len(walletCache))
+	for i := len(walletCache) - 1; i >= 0; i-- {
+		h := walletCache[i]
+		if t := query.Get("type"); t != "" && h.TransactType != t {
+			continue
+		}
+		e := WalletEntryData{
+			TransactID:     h.TransactID,
+			TransactType:   h.TransactType,
+			TransactStatus: h.TransactStatus,
+			Currency:       h.Currency,
+			Time:           h.Time().Format(time.RFC3339),
+			Amount:         bitmex.FromMinorUnits(h.Currency, h.Amount),
+			Fee:            bitmex.FromMinorUnits(h.Currency, h.Fee),
+			WalletBalance:  bitmex.FromMinorUnits(h.Currency, h.WalletBalance),
+		}
+		if usd != nil {
+			v := bitmex.ValueWallet([]bitmex.WalletHistory{h}, usd)[0]
+			e.Price, e.AmountUSD, e.FeeUSD, e.WalletBalanceUSD = v.Price, v.AmountUSD, v.FeeUSD, v.WalletBalanceUSD
+		}
+		entries = append(entries, e)
+		if limit > 0 && len(entries) >= limit {
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
@@ -95,13 +95,39 @@ function setupEventListeners() {
         }
     });
 
-    // 自动刷新 (每30秒)
-    setInterval(() => {
-        loadPendingOrders();
-        loadExecutions();
-        loadPositions();
-        loadAccountInfo();
-    }, 30000);
+    // 数据变化时由服务器推送；浏览器不支持 EventSource 时每30秒刷新
+    if (window.EventSource) {
+        connectStream();
+    } else {
+        setInterval(() => {
+            loadPendingOrders();
+            loadExecutions();
+            loadPositions();
+            loadAccountInfo();
+        }, 30000);
+    }
+}
+
+// 订阅 /api/stream，断线后浏览器自动重连并带上 Last-Event-ID 补发错过的更新
+function connectStream() {
+    const source = new EventSource(`${API_BASE}/api/stream`);
+    const handlers = {
+        account: renderAccountInfo,
+        positions: renderPositions,
+        orders: renderPendingOrders,
+        execution: addExecutions,
+        reload: status => console.log(`✓ 数据已更新 (${status.trigger}, ${status.loadedAt})`)
+    };
+    Object.entries(handlers).forEach(([type, handler]) => {
+        source.addEventListener(type, (e) => {
+            try {
+                handler(JSON.parse(e.data));
+            } catch (error) {
+                console.error(`Failed to handle ${type} event:`, error);
+            }
+        });
+    });
+    source.onerror = () => console.warn('推送连接断开，正在重连...');
 }
 
 // 页面加载完成后初始化
@@ -105,6 +105,7 @@ class TimeTravelController {
             const snapshot = await API.getSnapshot(dateStr);
 
             // 更新UI
+            this.updateDateRange(snapshot.minDate, snapshot.maxDate);
             this.updateAccountInfo(snapshot);
             this.updateBTCPositions(snapshot.btcPositions);
             this.updateTodayOrders(snapshot.todayOrders);
@@ -126,6 +127,22 @@ class TimeTravelController {
         }
     }
 
+    updateDateRange(minDate, maxDate) {
+        // 使用服务器返回的数据时间范围
+        if (!minDate || !maxDate) return;
+        this.minDate = new Date(minDate);
+        this.maxDate = new Date(maxDate);
+
+        const datePicker = document.getElementById('date-picker');
+        datePicker.min = minDate;
+        datePicker.max = maxDate;
+
+        const range = document.querySelector('.date-range');
+        if (range) {
+            range.textContent = `时间范围: ${minDate} - ${maxDate}`;
+        }
+    }
+
     updateAccountInfo(snapshot) {
         // 更新账户信息
         const totalEquity = document.getElementById('total-equity');
@@ -5,12 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"math"
 	"net/http"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"time"
+
+	"binance-kline/wei/analytics"
+	"binance-kline/wei/bitmex"
 )
 
 // KlineData K线数据
@@ -51,25 +54,36 @@ type ExecutionData struct {
 
 // Position 仓位数据
 type Position struct {
-	Symbol         string  `json:"symbol"`
-	Side           string  `json:"side"`
-	Qty            int     `json:"qty"`
-	EntryPrice     float64 `json:"entryPrice"`
-	CurrentPrice   float64 `json:"currentPrice"`
-	UnrealizedPNL  float64 `json:"unrealizedPnl"`
+	Symbol               string  `json:"symbol"`
+	Side                 string  `json:"side"`
+	Qty                  int     `json:"qty"`
+	EntryPrice           float64 `json:"entryPrice"`
+	CurrentPrice         float64 `json:"currentPrice"`
+	UnrealizedPNL        float64 `json:"unrealizedPnl"`
 	UnrealizedPNLPercent float64 `json:"unrealizedPnlPercent"`
+	SettlCurrency        string  `json:"settlCurrency,omitempty"` // 盈亏的结算币种: XBt(BTC) / USDt(USDT)
+	CostMethod           string  `json:"costMethod,omitempty"`    // 成交记录重建时的成本计算方法: average / fifo
+
+	// 以下字段只有交易所快照（positions.csv）才有
+	LiquidationPrice float64 `json:"liquidationPrice,omitempty"`
+	Leverage         float64 `json:"leverage,omitempty"`
+	Source           string  `json:"source,omitempty"`       // bitmex: 交易所快照; executions: 成交记录重建
+	SnapshotTime     string  `json:"snapshotTime,omitempty"` // 快照时间
 }
 
 // AccountInfo 账户信息
 type AccountInfo struct {
+	Currency        string  `json:"currency"` // 计价单位 BTC / USD
 	Balance         float64 `json:"balance"`
-	TotalEquity     float64 `json:"totalEquity"`     // 总市值 = 余额 + 未实现盈亏
-	UnrealizedPNL   float64 `json:"unrealizedPnl"`   // 总未实现盈亏
+	TotalEquity     float64 `json:"totalEquity"`   // 总市值 = 余额 + 未实现盈亏
+	UnrealizedPNL   float64 `json:"unrealizedPnl"` // 总未实现盈亏
 	TodayPNL        float64 `json:"todayPnl"`
 	TodayPNLPercent float64 `json:"todayPnlPercent"`
 	TotalPNL        float64 `json:"totalPnl"`
 	WinRate         float64 `json:"winRate"`
 	TotalTrades     int     `json:"totalTrades"`
+	Source          string  `json:"source"`                 // bitmex: 余额和未实现盈亏来自交易所快照; executions: 由成交记录估算
+	SnapshotTime    string  `json:"snapshotTime,omitempty"` // 快照时间
 }
 
 // DailyPositionData 每日仓位数据
@@ -85,40 +99,88 @@ type DailyPositionData struct {
 
 // DailySnapshot 每日快照数据
 type DailySnapshot struct {
-	Date          string          `json:"date"`           // 查询日期
-	Balance       float64         `json:"balance"`        // 截至该日期的余额
-	TotalEquity   float64         `json:"totalEquity"`    // 总市值
-	UnrealizedPNL float64         `json:"unrealizedPnl"`  // 未实现盈亏
-	BTCPositions  []Position      `json:"btcPositions"`   // BTC持仓
-	TodayOrders   []OrderData     `json:"todayOrders"`    // 当日订单
-	RecentExecs   []ExecutionData `json:"recentExecs"`    // 近期成交
-	KlineData     []KlineData     `json:"klineData"`      // K线数据
-	MinDate       string          `json:"minDate"`        // 最小日期
-	MaxDate       string          `json:"maxDate"`        // 最大日期
+	Date          string          `json:"date"`          // 查询日期
+	Time          string          `json:"time"`          // 快照时刻（只有日期时为该日结束时）
+	Balance       float64         `json:"balance"`       // 截至该日期的余额
+	TotalEquity   float64         `json:"totalEquity"`   // 总市值
+	UnrealizedPNL float64         `json:"unrealizedPnl"` // 未实现盈亏
+	BTCPositions  []Position      `json:"btcPositions"`  // BTC持仓
+	TodayOrders   []OrderData     `json:"todayOrders"`   // 当日订单
+	RecentExecs   []ExecutionData `json:"recentExecs"`   // 近期成交
+	KlineData     []KlineData     `json:"klineData"`     // K线数据
+	MinDate       string          `json:"minDate"`       // 最小日期
+	MaxDate       string          `json:"maxDate"`       // 最大日期
 }
 
-// 全局数据缓存
+// 全局数据缓存，由 dataMu 保护（见 reload.go）
 var (
 	klinesCache        map[string][]KlineData
+	instrumentsCache   bitmex.Catalog // 合约信息，按合约类型计算均价和盈亏
 	ordersCache        []OrderData
 	executionsCache    []ExecutionData
+	rawExecutionsCache []bitmex.Execution     // executions.csv 原始记录，用于持仓核算和交易还原
+	walletCache        []bitmex.WalletHistory // wallet.csv 钱包历史
 	dailyPositionCache []DailyPositionData
 )
 
 func main() {
+	// 成交记录重建持仓的成本计算方法
+	if method, err := bitmex.ParseCostMethod(os.Getenv("COST_METHOD")); err == nil {
+		costMethod = method
+	} else {
+		log.Fatalf("❌ %v", err)
+	}
+	// 盈亏统计的日期时区
+	if loc, err := bitmex.ParseTimezone(os.Getenv("PNL_TIMEZONE")); err == nil {
+		pnlLocation = loc
+	} else {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// 美元估值的价格来源
+	if source, err := bitmex.ParsePriceSource(os.Getenv("PRICE_SOURCE")); err == nil {
+		priceSource = source
+	} else {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// 数据文件变化的检查间隔（环境变量 RELOAD_INTERVAL，默认 10s，0 为不监视）
+	reloadInterval := 10 * time.Second
+	if s := os.Getenv("RELOAD_INTERVAL"); s != "" {
+		interval, err := time.ParseDuration(s)
+		if err != nil || interval < 0 {
+			log.Fatalf("❌ RELOAD_INTERVAL 格式错误: %q（应为 10s、1m 等，0 为不监视）", s)
+		}
+		reloadInterval = interval
+	}
+
 	// 加载数据
-	log.Println("正在加载数据...")
-	loadData()
-
-	// 设置路由
-	http.HandleFunc("/api/klines", handleKlines)
-	http.HandleFunc("/api/orders", handleOrders)
-	http.HandleFunc("/api/orders/pending", handlePendingOrders)
-	http.HandleFunc("/api/executions", handleExecutions)
-	http.HandleFunc("/api/positions", handlePositions)
-	http.HandleFunc("/api/account", handleAccount)
-	// http.HandleFunc("/api/snapshot", handleSnapshot)        // 新增: 历史快照API (暂时禁用)
-	http.HandleFunc("/api/daily-position", handleDailyPosition) // 每日仓位数据
+	reloadData("startup")
+	if reloadInterval > 0 {
+		go watchDataFiles(reloadInterval)
+		log.Printf("✓ 监视数据文件变化，间隔 %v", reloadInterval)
+	}
+
+	// 设置路由（数据接口经 withData 持有数据读锁，重新加载时整体替换缓存）
+	http.HandleFunc("/api/klines", withData(handleKlines))
+	http.HandleFunc("/api/orders", withData(handleOrders))
+	http.HandleFunc("/api/orders/pending", withData(handlePendingOrders))
+	http.HandleFunc("/api/executions", withData(handleExecutions))
+	http.HandleFunc("/api/positions", withData(handlePositions))
+	http.HandleFunc("/api/positions/snapshot", withData(handleExchangeSnapshot)) // 交易所持仓快照及核对
+	http.HandleFunc("/api/positions/pnl", withData(handleContractPnL))           // 各合约已实现/未实现盈亏
+	http.HandleFunc("/api/account", withData(handleAccount))
+	http.HandleFunc("/api/pnl", withData(handlePnL))                      // 区间盈亏（今日/本周/本月/本年/自定义）
+	http.HandleFunc("/api/wallet", withData(handleWallet))                // 钱包历史（可按美元估值）
+	http.HandleFunc("/api/snapshot", withData(handleSnapshot))            // 历史快照（任意日期 / 时刻的余额、持仓、订单和成交）
+	http.HandleFunc("/api/daily-position", withData(handleDailyPosition)) // 每日仓位数据
+	http.HandleFunc("/api/funding", withData(handleFunding))              // 资金费用汇总
+	http.HandleFunc("/api/trades", withData(handleTrades))                // 完整交易（开仓到平仓）
+	http.HandleFunc("/api/performance", withData(handlePerformance))      // 绩效指标（回撤、夏普比率、月度收益）
+	http.HandleFunc("/api/returns", withData(handleReturns))              // 扣除出入金的收益（TWR / IRR）
+	http.HandleFunc("/api/tax", withData(handleTax))                      // 按年度的美元税务报告（json / csv / html）
+	http.HandleFunc("/api/reload", handleReload)                          // 加载状态（GET）/ 重新加载数据（POST）
+	http.HandleFunc("/api/stream", handleStream)                          // 推送账户、持仓、订单和成交的变化（SSE）
 
 	// 静态文件服务
 	fs := http.FileServer(http.Dir("./web"))
@@ -150,86 +212,113 @@ func enableCORS(next http.Handler) http.Handler {
 	})
 }
 
-// loadData 加载所有CSV数据
-func loadData() {
-	klinesCache = make(map[string][]KlineData)
-
-	// 加载K线数据
-	symbols := []string{"XBTUSD", "ETHUSD"}
-	timeframes := []string{"1d"}  // 只加载日线数据
-
-	for _, symbol := range symbols {
-		for _, tf := range timeframes {
-			filename := fmt.Sprintf("klines_%s_%s.csv", symbol, tf)
-			if klines, err := loadKlines(filename); err == nil {
-				key := fmt.Sprintf("%s_%s", symbol, tf)
-				klinesCache[key] = klines
-				log.Printf("✓ 加载 %s: %d 条记录", filename, len(klines))
-			} else {
-				log.Printf("⚠ 跳过 %s: %v", filename, err)
-			}
+// loadData 加载所有CSV数据并生成派生数据，返回新的数据集（由 install 替换全局缓存）。
+// 读取失败的文件沿用上一次加载的数据
+func loadData() *dataSet {
+	prev := currentData()
+	d := &dataSet{klines: make(map[string][]KlineData)}
+
+	// 加载K线数据：当前目录下所有 klines_<symbol>_<timeframe>.csv
+	files, _ := filepath.Glob("klines_*.csv")
+	for _, filename := range files {
+		symbol, tf, ok := bitmex.ParseKlinesFile(filename)
+		if !ok {
+			continue
 		}
+		key := fmt.Sprintf("%s_%s", symbol, tf)
+		if klines, err := loadKlines(filename); err == nil {
+			d.klines[key] = klines
+			log.Printf("✓ 加载 %s: %d 条记录", filename, len(klines))
+		} else {
+			d.klines[key] = prev.klines[key]
+			log.Printf("⚠ 跳过 %s: %v", filename, err)
+		}
+	}
+	if len(files) == 0 {
+		log.Printf("⚠ 未找到K线文件 klines_*.csv（可运行 make download-klines）")
+	}
+	loadPrices(d)
+
+	// 加载合约信息
+	if catalog, err := bitmex.LoadCatalog(bitmex.InstrumentsFile); err == nil {
+		d.instruments = catalog
+		log.Printf("✓ 加载 %s: %d 个合约", bitmex.InstrumentsFile, len(catalog))
+	} else {
+		d.instruments = prev.instruments
+		log.Printf("⚠ 跳过 %s: %v（只能计算 XBT 反向合约的盈亏，请运行 sync instruments）", bitmex.InstrumentsFile, err)
 	}
 
 	// 加载订单数据
-	if orders, err := loadOrders("orders.csv"); err == nil {
-		ordersCache = orders
+	if orders, err := loadOrders(bitmex.OrdersFile); err == nil {
+		d.orders = orders
 		log.Printf("✓ 加载 orders.csv: %d 条记录", len(orders))
 	} else {
+		d.orders = prev.orders
 		log.Printf("❌ 加载 orders.csv 失败: %v", err)
 	}
 
 	// 加载成交数据
-	if execs, err := loadExecutions("executions.csv"); err == nil {
-		executionsCache = execs
+	if records, execs, err := loadExecutions(bitmex.ExecutionsFile); err == nil {
+		d.rawExecutions = records
+		d.executions = execs
 		log.Printf("✓ 加载 executions.csv: %d 条记录", len(execs))
 	} else {
+		d.rawExecutions = prev.rawExecutions
+		d.executions = prev.executions
 		log.Printf("❌ 加载 executions.csv 失败: %v", err)
 	}
 
+	// 加载钱包历史
+	if wallet, err := bitmex.ReadWalletHistory(bitmex.WalletFile); err == nil {
+		d.wallet = wallet
+		log.Printf("✓ 加载 %s: %d 条记录", bitmex.WalletFile, len(wallet))
+	} else {
+		d.wallet = prev.wallet
+		log.Printf("❌ 加载 %s 失败: %v", bitmex.WalletFile, err)
+	}
+
 	// 加载每日仓位数据
 	if positions, err := loadDailyPosition("daily_position.csv"); err == nil {
-		dailyPositionCache = positions
+		d.dailyPosition = positions
 		log.Printf("✓ 加载 daily_position.csv: %d 条记录", len(positions))
 	} else {
+		d.dailyPosition = prev.dailyPosition
 		log.Printf("❌ 加载 daily_position.csv 失败: %v", err)
 	}
+
+	// 加载交易所持仓和保证金快照
+	loadExchangeSnapshots(d, prev)
+
+	// 生成资金费用明细
+	loadFunding(d)
+
+	// 由成交记录还原完整交易
+	loadTrades(d)
+
+	// 绩效统计
+	loadPerformance(d)
+
+	// 历史快照索引
+	loadSnapshotIndex(d)
+	return d
 }
 
 // loadKlines 加载K线CSV文件
 func loadKlines(filename string) ([]KlineData, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	records, err := bitmex.ReadKlines(filename)
 	if err != nil {
 		return nil, err
 	}
 
 	var klines []KlineData
-	for i, record := range records {
-		if i == 0 {
-			continue // 跳过表头
-		}
-
-		timestamp, _ := time.Parse(time.RFC3339, record[0])
-		open, _ := strconv.ParseFloat(record[2], 64)
-		high, _ := strconv.ParseFloat(record[3], 64)
-		low, _ := strconv.ParseFloat(record[4], 64)
-		close, _ := strconv.ParseFloat(record[5], 64)
-		volume, _ := strconv.ParseInt(record[6], 10, 64)
-
+	for _, k := range records {
 		klines = append(klines, KlineData{
-			Time:   timestamp.Unix(),
-			Open:   open,
-			High:   high,
-			Low:    low,
-			Close:  close,
-			Volume: volume,
+			Time:   k.Timestamp.Unix(),
+			Open:   k.Open,
+			High:   k.High,
+			Low:    k.Low,
+			Close:  k.Close,
+			Volume: k.Volume,
 		})
 	}
 
@@ -238,38 +327,23 @@ func loadKlines(filename string) ([]KlineData, error) {
 
 // loadOrders 加载订单CSV文件
 func loadOrders(filename string) ([]OrderData, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	records, err := bitmex.ReadOrders(filename)
 	if err != nil {
 		return nil, err
 	}
 
 	var orders []OrderData
-	for i, record := range records {
-		if i == 0 {
-			continue
-		}
-
-		timestamp, _ := time.Parse(time.RFC3339, record[31])
-		price, _ := strconv.ParseFloat(record[8], 64)
-		qty, _ := strconv.Atoi(record[7])
-
+	for _, order := range records {
 		orders = append(orders, OrderData{
-			OrderID:       record[0],
-			Symbol:        record[4],
-			Side:          record[5],
-			Price:         price,
-			Qty:           qty,
-			OrderType:     record[15],
-			Status:        record[20],
-			Timestamp:     record[31],
-			TimestampUnix: timestamp.Unix(),
+			OrderID:       order.OrderID,
+			Symbol:        order.Symbol,
+			Side:          order.Side,
+			Price:         order.Price,
+			Qty:           order.OrderQty,
+			OrderType:     order.OrdType,
+			Status:        order.OrdStatus,
+			Timestamp:     order.TransactTime,
+			TimestampUnix: order.Time().Unix(),
 		})
 	}
 
@@ -277,44 +351,28 @@ func loadOrders(filename string) ([]OrderData, error) {
 }
 
 // loadExecutions 加载成交CSV文件
-func loadExecutions(filename string) ([]ExecutionData, error) {
-	file, err := os.Open(filename)
+func loadExecutions(filename string) ([]bitmex.Execution, []ExecutionData, error) {
+	records, err := bitmex.ReadExecutions(filename)
 	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var executions []ExecutionData
-	for i, record := range records {
-		if i == 0 {
-			continue
-		}
-
-		timestamp, _ := time.Parse(time.RFC3339, record[14])
-		price, _ := strconv.ParseFloat(record[7], 64)
-		qty, _ := strconv.Atoi(record[6])
-		commission, _ := strconv.ParseFloat(record[13], 64)
-
+	for _, exec := range records {
 		executions = append(executions, ExecutionData{
-			ExecID:        record[0],
-			OrderID:       record[1],
-			Symbol:        record[4],
-			Side:          record[5],
-			Price:         price,
-			Qty:           qty,
-			Commission:    commission,
-			Timestamp:     record[14],
-			TimestampUnix: timestamp.Unix(),
+			ExecID:        exec.ExecID,
+			OrderID:       exec.OrderID,
+			Symbol:        exec.Symbol,
+			Side:          exec.Side,
+			Price:         exec.LastPx,
+			Qty:           exec.LastQty,
+			Commission:    exec.Commission,
+			Timestamp:     exec.TransactTime,
+			TimestampUnix: exec.Time().Unix(),
 		})
 	}
 
-	return executions, nil
+	return records, executions, nil
 }
 
 // loadDailyPosition 加载每日仓位CSV文件
@@ -406,6 +464,12 @@ func handleOrders(w http.ResponseWriter, r *http.Request) {
 }
 
 func handlePendingOrders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pendingOrders())
+}
+
+// pendingOrders 未成交订单，按时间倒序
+func pendingOrders() []OrderData {
 	var pending []OrderData
 	for _, order := range ordersCache {
 		if order.Status == "New" || order.Status == "PartiallyFilled" {
@@ -417,30 +481,47 @@ func handlePendingOrders(w http.ResponseWriter, r *http.Request) {
 	sort.Slice(pending, func(i, j int) bool {
 		return pending[i].TimestampUnix > pending[j].TimestampUnix
 	})
+	return pending
+}
 
+func handleExecutions(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(pending)
+	json.NewEncoder(w).Encode(latestExecutions(executionsLimit))
 }
 
-func handleExecutions(w http.ResponseWriter, r *http.Request) {
-	executions := executionsCache
+// executionsLimit /api/executions 返回的成交数量
+const executionsLimit = 100
+
+// latestExecutions 最近 limit 笔成交，按时间倒序。在副本上排序，缓存由多个请求并发读取，不能修改
+func latestExecutions(limit int) []ExecutionData {
+	executions := append([]ExecutionData(nil), executionsCache...)
 
 	// 按时间倒序
-	sort.Slice(executions, func(i, j int) bool {
+	sort.SliceStable(executions, func(i, j int) bool {
 		return executions[i].TimestampUnix > executions[j].TimestampUnix
 	})
 
 	// 限制返回数量
-	if len(executions) > 100 {
-		executions = executions[:100]
+	if len(executions) > limit {
+		executions = executions[:limit]
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(executions)
+	return executions
 }
 
+// handlePositions 返回当前仓位：有交易所快照时使用快照，?source=executions 强制使用成交记录重建，
+// ?method=average|fifo 指定重建时的成本计算方法
 func handlePositions(w http.ResponseWriter, r *http.Request) {
-	positions := calculatePositions()
+	method := costMethod
+	if m := r.URL.Query().Get("method"); m != "" {
+		parsed, err := bitmex.ParseCostMethod(m)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		method = parsed
+	}
+
+	positions := currentPositions(method, r.URL.Query().Get("source") == "executions")
 	log.Printf("Positions API called, returning %d positions", len(positions))
 
 	w.Header().Set("Content-Type", "application/json")
@@ -450,143 +531,93 @@ func handlePositions(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleAccount(w http.ResponseWriter, r *http.Request) {
-	account := calculateAccountInfo()
+	_, usd, err := parseCurrency(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	account := calculateAccountInfo(usd)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(account)
 }
 
+// handleDailyPosition 每日仓位数据
+// 参数: currency=BTC|USD（USD 时余额、仓位价值和总市值按当日结束时的价格换算为美元）
 func handleDailyPosition(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(dailyPositionCache)
-}
-
-// calculatePositions 计算当前仓位
-func calculatePositions() []Position {
-	// 初始化为空数组而不是 nil
-	positions := []Position{}
-	positionsMap := make(map[string]*Position)
-
-	for _, exec := range executionsCache {
-		pos, exists := positionsMap[exec.Symbol]
-		if !exists {
-			pos = &Position{Symbol: exec.Symbol}
-			positionsMap[exec.Symbol] = pos
-		}
-
-		if exec.Side == "Buy" {
-			pos.Qty += exec.Qty
-		} else {
-			pos.Qty -= exec.Qty
-		}
-
-		// 简化：使用最后成交价作为当前价
-		pos.CurrentPrice = exec.Price
+	_, usd, err := parseCurrency(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// 计算入场均价
-	for symbol, pos := range positionsMap {
-		if pos.Qty == 0 {
-			delete(positionsMap, symbol)
-			continue
-		}
-
-		var totalCost float64
-		var totalQty int
-
-		for _, exec := range executionsCache {
-			if exec.Symbol != symbol {
-				continue
-			}
-
-			if exec.Side == "Buy" {
-				totalQty += exec.Qty
-				totalCost += float64(exec.Qty) / exec.Price
-			} else {
-				totalQty -= exec.Qty
-				totalCost -= float64(exec.Qty) / exec.Price
-			}
-		}
-
-		if totalQty != 0 && totalCost != 0 {
-			entryPrice := float64(totalQty) / totalCost
-			// 检查是否为有效值
-			if !math.IsNaN(entryPrice) && !math.IsInf(entryPrice, 0) && entryPrice > 0 {
-				pos.EntryPrice = entryPrice
-			}
-		}
-
-		// 设置方向
-		if pos.Qty > 0 {
-			pos.Side = "Long"
-		} else {
-			pos.Side = "Short"
-		}
-
-		// 计算未实现盈亏
-		if pos.EntryPrice > 0 && pos.CurrentPrice > 0 {
-			// 反向合约盈亏计算: PNL = 合约数量 × (1/入场价 - 1/当前价)
-			// 注意: 使用带符号的Qty,这样Long/Short的方向自动正确
-			qty := float64(pos.Qty)
-			pnl := qty * (1.0/pos.EntryPrice - 1.0/pos.CurrentPrice)
-
-			// 百分比计算
-			pnlPercent := (pos.CurrentPrice/pos.EntryPrice - 1.0) * 100
-			// Short仓位时价格变化方向相反
-			if pos.Side == "Short" {
-				pnlPercent = -pnlPercent
-			}
-
-			// 检查是否为 NaN 或 Inf
-			if !math.IsNaN(pnl) && !math.IsInf(pnl, 0) {
-				pos.UnrealizedPNL = pnl
-			}
-			if !math.IsNaN(pnlPercent) && !math.IsInf(pnlPercent, 0) {
-				pos.UnrealizedPNLPercent = pnlPercent
-			}
+	positions := dailyPositionCache
+	if usd != nil {
+		positions = make([]DailyPositionData, len(dailyPositionCache))
+		for i, p := range dailyPositionCache {
+			price := usd.At(time.Unix(p.Time, 0).Add(24 * time.Hour))
+			p.Balance *= price
+			p.PositionValue *= price
+			p.TotalEquity *= price
+			positions[i] = p
 		}
 	}
 
-	for _, pos := range positionsMap {
-		positions = append(positions, *pos)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(positions)
+}
+
+// currentPositions 当前持仓：有交易所快照时使用最近一次快照，否则（或 fromExecutions 为 true 时）由成交记录按 method 重建
+func currentPositions(method bitmex.CostMethod, fromExecutions bool) []Position {
+	if _, snapshot, _, ok := latestExchangeSnapshot(); ok && !fromExecutions {
+		return snapshot
 	}
+	return calculatePositionsWith(method)
+}
 
-	return positions
+// calculatePositions 由成交记录核算当前仓位，当前价格使用各合约的最后成交价
+func calculatePositions() []Position {
+	return calculatePositionsWith(costMethod)
 }
 
-// calculateAccountInfo 计算账户信息
-func calculateAccountInfo() AccountInfo {
-	// 读取wallet.csv获取余额信息
-	file, err := os.Open("wallet.csv")
-	if err != nil {
-		return AccountInfo{}
+// calculatePositionsWith 按指定成本计算方法核算当前仓位
+func calculatePositionsWith(method bitmex.CostMethod) []Position {
+	book := bitmex.BuildBook(rawExecutionsCache, instrumentsCache, method, time.Time{})
+	for _, symbol := range book.Unknown {
+		log.Printf("⚠ 未知合约 %s，跳过持仓核算（请运行 sync instruments）", symbol)
 	}
-	defer file.Close()
+	return bookPositions(book, func(symbol string, a *bitmex.PositionAccount) float64 {
+		return a.LastPrice
+	})
+}
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return AccountInfo{}
+// unrealizedBTC 各仓位未实现盈亏（结算币种）换算为 BTC 后的合计，USDT 结算的合约按 xbtPrice 换算
+func unrealizedBTC(positions []Position, xbtPrice float64) float64 {
+	var total float64
+	for _, pos := range positions {
+		if pnl, ok := bitmex.ToBTC(pos.SettlCurrency, pos.UnrealizedPNL, xbtPrice); ok {
+			total += pnl
+		}
 	}
+	return total
+}
 
-	if len(records) < 2 {
+// calculateAccountInfo 计算账户信息，usd 不为 nil 时金额按美元计价（余额和未实现盈亏按当前价格换算）
+func calculateAccountInfo(usd *bitmex.PriceSeries) AccountInfo {
+	// 钱包历史在启动时加载
+	records := walletCache
+	if len(records) == 0 {
 		return AccountInfo{}
 	}
 
 	// 获取最新余额
-	lastRecord := records[len(records)-1]
-	balance, _ := strconv.ParseFloat(lastRecord[11], 64)
+	balance := records[len(records)-1].WalletBalanceBTC()
 
-	// 计算总盈亏
-	var totalPNL float64
 	var winCount, totalCount int
-
-	for i := 1; i < len(records); i++ {
-		if records[i][1] == "RealisedPNL" {
-			pnl, _ := strconv.ParseFloat(records[i][6], 64)
-			totalPNL += pnl
+	for _, record := range records {
+		if record.TransactType == "RealisedPNL" {
 			totalCount++
-			if pnl > 0 {
+			if record.Amount > 0 {
 				winCount++
 			}
 		}
@@ -597,33 +628,53 @@ func calculateAccountInfo() AccountInfo {
 		winRate = float64(winCount) / float64(totalCount) * 100
 	}
 
-	// 计算今日盈亏 (简化：使用最后10笔)
-	var todayPNL float64
-	startIdx := len(records) - 10
-	if startIdx < 1 {
-		startIdx = 1
+	// RealisedPNL 是每日结算而不是交易，有还原的完整交易时按已平仓交易计算胜率
+	if summary := bitmex.SummarizeTrades(tradesCache); summary.Closed > 0 {
+		winRate = summary.WinRate
+		totalCount = summary.Closed
 	}
 
-	for i := startIdx; i < len(records); i++ {
-		if records[i][1] == "RealisedPNL" {
-			pnl, _ := strconv.ParseFloat(records[i][6], 64)
-			todayPNL += pnl
-		}
+	// 今日和累计净盈亏（已实现盈亏 + 资金费用，按 PNL_TIMEZONE 时区的日期计算）
+	var todayPNL, todayPNLPercent, totalPNL float64
+	if today, err := periodPnL(bitmex.PeriodToday, pnlLocation, usd); err == nil {
+		todayPNL = today.Net
+		todayPNLPercent = today.Return
+	}
+	if all, err := periodPnL(bitmex.PeriodAll, pnlLocation, usd); err == nil {
+		totalPNL = all.Net
 	}
 
-	todayPNLPercent := (todayPNL / balance) * 100
-
-	// 计算所有持仓的未实现盈亏
+	// 计算所有持仓的未实现盈亏（换算为 BTC）
 	positions := calculatePositions()
-	var unrealizedPNL float64
-	for _, pos := range positions {
-		unrealizedPNL += pos.UnrealizedPNL
-	}
+	unrealizedPNL := unrealizedBTC(positions, getClosePriceAtDate("XBTUSD", time.Now()))
 
 	// 总市值 = 余额 + 未实现盈亏
 	totalEquity := balance + unrealizedPNL
+	source, snapshotTime := "executions", ""
+
+	// 有交易所快照时，余额、未实现盈亏和总市值使用交易所的数据
+	if t, _, margins, ok := latestExchangeSnapshot(); ok {
+		for _, m := range margins {
+			if m.Currency == "XBt" {
+				balance = m.WalletBalance
+				unrealizedPNL = m.UnrealizedPNL
+				totalEquity = m.MarginBalance
+				source, snapshotTime = "bitmex", t
+			}
+		}
+	}
+
+	currency := analytics.BTC
+	if usd != nil {
+		price := usd.At(time.Now())
+		balance *= price
+		unrealizedPNL *= price
+		totalEquity *= price
+		currency = analytics.USD
+	}
 
 	return AccountInfo{
+		Currency:        currency,
 		Balance:         balance,
 		TotalEquity:     totalEquity,
 		UnrealizedPNL:   unrealizedPNL,
@@ -632,5 +683,7 @@ func calculateAccountInfo() AccountInfo {
 		TotalPNL:        totalPNL,
 		WinRate:         winRate,
 		TotalTrades:     totalCount,
+		Source:          source,
+		SnapshotTime:    snapshotTime,
 	}
 }